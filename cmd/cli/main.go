@@ -4,9 +4,12 @@ import (
 	"log"
 
 	"github.com/JIIL07/devtoolbox/internal/cli"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
 )
 
 func main() {
+	plugins.RunSandboxHelper()
+
 	if err := cli.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/JIIL07/devtoolbox/internal/api"
 	"github.com/JIIL07/devtoolbox/internal/core"
@@ -11,25 +13,34 @@ import (
 )
 
 func main() {
+	plugins.RunSandboxHelper()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	registry := core.NewGeneratorRegistry()
-
-	pluginManager := plugins.NewPluginManager()
-	customPlugins, err := pluginManager.LoadPlugins()
-	if err == nil {
-		for _, pluginInfo := range customPlugins {
-			if pluginInfo.Type == "python" {
-				pythonPlugin := plugins.NewPythonPlugin(pluginInfo.Name, pluginInfo.Description, pluginInfo.Path)
-				registry.Register(pythonPlugin)
-			}
-		}
+	registry, discovery, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load plugins: %v", err)
 	}
+	for _, conflict := range discovery.Conflicts {
+		log.Printf("Plugin conflict: %s", conflict)
+	}
+	for _, err := range discovery.Errors {
+		log.Printf("Skipped plugin: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+	go reloader.Watch(context.Background(), 2*time.Second, func(report *core.ReloadReport, err error) {
+		if err != nil {
+			log.Printf("Failed to reload plugins: %v", err)
+			return
+		}
+		log.Printf("Reloaded plugins: %s", report)
+	})
 
-	handler := api.NewHandler(registry)
+	handler := api.NewReloadableHandler(reloader)
 
 	router := gin.Default()
 
@@ -39,6 +50,7 @@ func main() {
 	router.GET("/health", handler.Health)
 	router.GET("/generators", handler.ListGenerators)
 	router.POST("/generate", handler.Generate)
+	router.POST("/admin/reload", api.AdminMiddleware(), handler.Reload)
 
 	log.Printf("Starting server on port %s", port)
 	if err := router.Run(":" + port); err != nil {
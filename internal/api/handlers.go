@@ -1,20 +1,53 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
 )
 
 type Handler struct {
-	registry *core.GeneratorRegistry
+	reloader *core.Reloader
+
+	// listing caches the response of ListGenerators until the registry
+	// changes; generation counts the changes.
+	listingMu  sync.Mutex
+	listing    *generatorListing
+	generation int
+}
+
+type generatorListing struct {
+	etag string
+	body []byte
 }
 
 func NewHandler(registry *core.GeneratorRegistry) *Handler {
-	return &Handler{
-		registry: registry,
+	return NewReloadableHandler(core.NewReloader(registry, nil))
+}
+
+// NewReloadableHandler serves the current registry of reloader. Every request
+// holds on to the registry it started with, so a reload never stops plugins
+// that are still in use.
+func NewReloadableHandler(reloader *core.Reloader) *Handler {
+	handler := &Handler{
+		reloader: reloader,
 	}
+	reloader.Subscribe(func(core.Change) {
+		handler.listingMu.Lock()
+		handler.listing = nil
+		handler.generation++
+		handler.listingMu.Unlock()
+	})
+	return handler
 }
 
 func (h *Handler) Generate(c *gin.Context) {
@@ -26,7 +59,16 @@ func (h *Handler) Generate(c *gin.Context) {
 		return
 	}
 
-	generator, exists := h.registry.Get(req.Template)
+	registry, release := h.reloader.Acquire()
+	defer release()
+
+	generator, exists := registry.Get(req.Template)
+	if _, disabled := registry.Disabled(req.Template); disabled {
+		c.JSON(http.StatusNotFound, GenerateResponse{
+			Error: "Template disabled: " + req.Template,
+		})
+		return
+	}
 	if !exists {
 		c.JSON(http.StatusNotFound, GenerateResponse{
 			Error: "Template not found: " + req.Template,
@@ -34,33 +76,207 @@ func (h *Handler) Generate(c *gin.Context) {
 		return
 	}
 
-	code, err := generator.Generate(req.Input)
+	format, err := core.ParseFormat(req.Format)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, GenerateResponse{
-			Error: "Generation failed: " + err.Error(),
+		c.JSON(http.StatusBadRequest, GenerateResponse{
+			Error: err.Error(),
 		})
 		return
 	}
 
+	generator, err = core.Configure(generator, registry.Options(req.Template, req.Options))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, GenerateResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+
+	if plugin, ok := core.StructuredPlugin(generator); ok {
+		h.generatePlugin(c, plugin, req.Input, format)
+		return
+	}
+
+	code, err := core.GenerateWithFormat(c.Request.Context(), generator, req.Input, format)
+	if err != nil {
+		generationFailed(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, GenerateResponse{
 		Code: code,
 	})
 }
 
+func (h *Handler) generatePlugin(c *gin.Context, plugin plugins.Plugin, input string, format core.InputFormat) {
+	response, err := core.InvokePlugin(c.Request.Context(), plugin, input, format)
+	if err != nil {
+		generationFailed(c, err)
+		return
+	}
+
+	files, err := core.PluginFiles(plugin, response)
+	if err != nil {
+		generationFailed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, GenerateResponse{
+		Code:        response.Output(),
+		Files:       files,
+		Diagnostics: response.Diagnostics,
+	})
+}
+
+func generationFailed(c *gin.Context, err error) {
+	response := GenerateResponse{
+		Error: "Generation failed: " + err.Error(),
+	}
+	status := http.StatusInternalServerError
+
+	var pluginErr *plugins.PluginError
+	var protocolErr *plugins.ProtocolError
+	var timeoutErr *plugins.TimeoutError
+	var canceledErr *plugins.CanceledError
+	var outputErr *plugins.OutputLimitError
+	var resourceErr *plugins.ResourceLimitError
+	var integrityErr *plugins.IntegrityError
+	switch {
+	case errors.As(err, &pluginErr):
+		response.ErrorCode = string(pluginErr.Code)
+		response.Location = pluginErr.Location
+		if pluginErr.IsUserError() {
+			status = http.StatusUnprocessableEntity
+		}
+	case errors.As(err, &protocolErr):
+		response.ErrorCode = "protocol_error"
+		status = http.StatusBadGateway
+	case errors.As(err, &timeoutErr):
+		response.ErrorCode = "timeout"
+		status = http.StatusGatewayTimeout
+	case errors.As(err, &canceledErr):
+		response.ErrorCode = "canceled"
+		status = http.StatusServiceUnavailable
+	case errors.As(err, &outputErr):
+		response.ErrorCode = "output_limit_exceeded"
+		status = http.StatusBadGateway
+	case errors.As(err, &resourceErr):
+		response.ErrorCode = "resource_limit_exceeded"
+		status = http.StatusBadGateway
+	case errors.As(err, &integrityErr):
+		response.ErrorCode = "integrity_check_failed"
+		status = http.StatusForbidden
+	}
+
+	c.JSON(status, response)
+}
+
+// ListGenerators lists the default version of every generator, sorted by
+// name, with its other versions and its aliases. The response carries an ETag and is
+// answered with 304 Not Modified while the registry does not change.
 func (h *Handler) ListGenerators(c *gin.Context) {
-	generators := h.registry.List()
+	listing, err := h.generatorListing()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
+	c.Header("ETag", listing.etag)
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if tag = strings.TrimSpace(tag); tag == listing.etag || tag == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", listing.body)
+}
+
+func (h *Handler) generatorListing() (*generatorListing, error) {
+	h.listingMu.Lock()
+	listing, generation := h.listing, h.generation
+	h.listingMu.Unlock()
+	if listing != nil {
+		return listing, nil
+	}
+	
+	registry, release := h.reloader.Acquire()
+	defer release()
+	
+	aliases := map[string][]string{}
+	for alias, target := range registry.Aliases() {
+		aliases[target] = append(aliases[target], alias)
+	}
+	generators := registry.List()
 	generatorInfos := make([]GeneratorInfo, len(generators))
 	
 	for i, gen := range generators {
 		generatorInfos[i] = GeneratorInfo{
 			Name:        gen.GetName(),
 			Description: gen.GetDescription(),
+			Aliases:     aliases[gen.GetName()],
+		}
+		sort.Strings(generatorInfos[i].Aliases)
+		// Other versions are requested as name@version.
+		if versions := registry.Versions(gen.GetName()); len(versions) > 1 {
+			generatorInfos[i].Versions = versions[1:]
+		}
+		
+		if provider, ok := gen.(plugins.ManifestProvider); ok && provider.Manifest() != nil {
+			manifest := provider.Manifest()
+			generatorInfos[i].Version = manifest.Version
+			generatorInfos[i].Author = manifest.Author
+			generatorInfos[i].Language = manifest.Language
+			generatorInfos[i].InputFormats = manifest.InputFormats
+			generatorInfos[i].OutputExtension = manifest.OutputExtension
+			generatorInfos[i].Options = manifest.Options
+			generatorInfos[i].MinVersion = manifest.MinVersion
+		}
+		if pipeline, ok := gen.(*core.Pipeline); ok {
+			generatorInfos[i].Pipeline = pipeline.Steps()
 		}
 	}
-
-	c.JSON(http.StatusOK, ListGeneratorsResponse{
+	
+	body, err := json.Marshal(ListGeneratorsResponse{
 		Generators: generatorInfos,
 	})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	listing = &generatorListing{etag: `"` + hex.EncodeToString(sum[:8]) + `"`, body: body}
+	
+	// A change while the listing was built makes it stale; the next request
+	// builds it again.
+	h.listingMu.Lock()
+	if h.generation == generation {
+		h.listing = listing
+	}
+	h.listingMu.Unlock()
+	return listing, nil
+}
+
+// Reload loads the plugins again, as the watcher does after a change.
+func (h *Handler) Reload(c *gin.Context) {
+	report, err := h.reloader.Reload()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ReloadResponse{
+			Error: err.Error(),
+		})
+		return
+	}
+	
+	response := ReloadResponse{
+		Added:   append([]string{}, report.Added...),
+		Updated: append([]string{}, report.Updated...),
+		Removed: append([]string{}, report.Removed...),
+	}
+	for _, conflict := range report.Discovery.Conflicts {
+		response.Conflicts = append(response.Conflicts, conflict.String())
+	}
+	for _, err := range report.Discovery.Errors {
+		response.Skipped = append(response.Skipped, err.Error())
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) Health(c *gin.Context) {
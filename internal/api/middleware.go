@@ -1,7 +1,12 @@
 package api
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -23,8 +28,16 @@ func Logger() gin.HandlerFunc {
 	})
 }
 
+// CORSMiddleware allows cross-origin requests to the public endpoints. The
+// admin endpoints are left out so that no web page can call them from a
+// browser on the server's machine.
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/admin/") {
+			c.Next()
+			return
+		}
+
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
@@ -38,3 +51,30 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminTokenEnv holds the bearer token required by the admin endpoints.
+const AdminTokenEnv = "DEVTOOLBOX_ADMIN_TOKEN"
+
+// AdminMiddleware guards the admin endpoints. With DEVTOOLBOX_ADMIN_TOKEN set
+// requests need "Authorization: Bearer <token>"; without it only requests
+// from the loopback interface are allowed.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := os.Getenv(AdminTokenEnv); token != "" {
+			given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+				return
+			}
+			c.Next()
+			return
+		}
+
+		host, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are only available from localhost; set " + AdminTokenEnv + " to allow remote access"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,20 +1,50 @@
 package api
 
+import (
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
 type GenerateRequest struct {
-	Template string `json:"template" binding:"required"`
-	Input    string `json:"input" binding:"required"`
+	Template string            `json:"template" binding:"required"`
+	Input    string            `json:"input" binding:"required"`
+	Format   string            `json:"format,omitempty"`
+	Options  map[string]string `json:"options,omitempty"`
 }
 
 type GenerateResponse struct {
-	Code string `json:"code"`
-	Error string `json:"error,omitempty"`
+	Code        string               `json:"code"`
+	Files       []core.GeneratedFile `json:"files,omitempty"`
+	Diagnostics []plugins.Diagnostic `json:"diagnostics,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	ErrorCode   string               `json:"error_code,omitempty"`
+	Location    *plugins.Location    `json:"location,omitempty"`
 }
 
 type GeneratorInfo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name            string                        `json:"name"`
+	Description     string                        `json:"description"`
+	Version         string                        `json:"version,omitempty"`
+	Versions        []string                      `json:"versions,omitempty"`
+	Aliases         []string                      `json:"aliases,omitempty"`
+	Author          string                        `json:"author,omitempty"`
+	Language        string                        `json:"language,omitempty"`
+	InputFormats    []string                      `json:"input_formats,omitempty"`
+	OutputExtension string                        `json:"output_extension,omitempty"`
+	Options         map[string]plugins.OptionSpec `json:"options,omitempty"`
+	MinVersion      string                        `json:"min_devtoolbox_version,omitempty"`
+	Pipeline        []string                      `json:"pipeline,omitempty"`
 }
 
 type ListGeneratorsResponse struct {
 	Generators []GeneratorInfo `json:"generators"`
 }
+
+type ReloadResponse struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Skipped   []string `json:"skipped,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
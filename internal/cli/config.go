@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the configuration",
+	Long: `Inspect the user configuration (~/.devtoolbox/config.yaml) and the project
+configuration (.devtoolbox.yaml in the project root). Project settings
+override user settings.
+
+Example .devtoolbox.yaml:
+
+  plugins:
+    default: disabled        # plugins not listed below; enabled if omitted
+    enabled: [kotlin-data]
+    versions:
+      kotlin-data: ">=1.2.0 <2.0.0"
+  aliases:
+    kt: kotlin-data          # devtoolbox generate kt, or kotlin-data@1.4
+  options:
+    go-struct:
+      package: models
+  pipelines:
+    api-models:              # devtoolbox generate api-models openapi.yaml
+      steps:
+        - processor: redact
+          options: {fields: "password,token"}
+        - generator: go-struct
+        - processor: goimports
+
+Options set here are defaults; --opt and --package on generate override them.
+Pipeline steps run in order. Processors: %s.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long: `Show the effective configuration: the state of every plugin, version
+constraints with the version in use, default generator options and
+pipelines, each with the file it came from.
+
+Examples:
+  devtoolbox config show`,
+	Args: cobra.NoArgs,
+	Run:  runConfigShow,
+}
+
+func init() {
+	configCmd.Long = fmt.Sprintf(configCmd.Long, strings.Join(core.ProcessorNames(), ", "))
+	configCmd.AddCommand(configShowCmd)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		exitWithError(err)
+	}
+	builtins := core.NewGeneratorRegistry()
+	discovery, err := plugins.NewPluginManager().Discover(builtins.GetNames())
+	if err != nil {
+		exitWithError(err)
+	}
+	names := map[string]bool{}
+	for _, plugin := range discovery.Plugins {
+		names[plugin.Name] = true
+	}
+	for _, name := range cfg.Generators() {
+		if _, builtin := builtins.Get(name); !builtin {
+			names[name] = true
+		}
+	}
+	cfg.Apply(discovery, builtins.GetNames())
+
+	fmt.Println("Configuration files:")
+	if len(cfg.Layers) < 2 {
+		fmt.Printf("  %-8s none, not in a project\n", config.ScopeProject)
+	}
+	for _, layer := range cfg.Layers {
+		status := ""
+		if layer.File == nil {
+			status = " (not found)"
+		}
+		fmt.Printf("  %-8s %s%s\n", layer.Scope, layer.Path, status)
+	}
+	fmt.Println()
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SETTING\tVALUE\tSOURCE")
+	state := cfg.DefaultState()
+	fmt.Fprintf(table, "plugins.default\t%s\t%s\n", state.Value, state.Source)
+	for _, name := range sortedKeys(names) {
+		enabled, source := cfg.PluginEnabled(name)
+		fmt.Fprintf(table, "plugins.%s\t%s\t%s\n", name, stateName(enabled), source)
+		if version := cfg.Version(name); version.Value != "" {
+			value := version.Value
+			if plugin := findPlugin(discovery.Plugins, name); plugin != nil && plugin.Manifest != nil {
+				value += fmt.Sprintf(" (using %s)", plugin.Manifest.Version)
+			}
+			fmt.Fprintf(table, "plugins.%s.version\t%s\t%s\n", name, value, version.Source)
+		}
+	}
+	aliases := cfg.Aliases()
+	aliasNames := map[string]bool{}
+	for alias := range aliases {
+		aliasNames[alias] = true
+	}
+	for _, alias := range sortedKeys(aliasNames) {
+		fmt.Fprintf(table, "aliases.%s\t%s\t%s\n", alias, aliases[alias].Value, aliases[alias].Source)
+	}
+	for _, generator := range cfg.Generators() {
+		options := cfg.Options(generator)
+		keys := make([]string, 0, len(options))
+		for key := range options {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(table, "options.%s.%s\t%s\t%s\n", generator, key, options[key].Value, options[key].Source)
+		}
+	}
+	pipelines := cfg.Pipelines()
+	pipelineNames := map[string]bool{}
+	for name := range pipelines {
+		pipelineNames[name] = true
+	}
+	for _, name := range sortedKeys(pipelineNames) {
+		var steps []string
+		for _, step := range pipelines[name].Pipeline.Steps {
+			steps = append(steps, step.Name())
+		}
+		fmt.Fprintf(table, "pipelines.%s\t%s\t%s\n", name, strings.Join(steps, " -> "), pipelines[name].Source)
+	}
+	table.Flush()
+
+	for _, err := range discovery.Errors {
+		fmt.Printf("Problem: %v\n", err)
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/JIIL07/devtoolbox/internal/config"
 	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
 	"github.com/JIIL07/devtoolbox/internal/plugins"
 	"github.com/spf13/cobra"
 )
@@ -15,67 +19,240 @@ var generateCmd = &cobra.Command{
 	Short: "Generate code from JSON schema",
 	Long: `Generate code from a JSON schema file using the specified template.
 
+The input format is detected automatically from the file extension or content;
+use --from to set it explicitly. JSON, YAML, TOML and XML samples are supported,
+as well as CSV and NDJSON files, which are read row by row to infer column types.
+OpenAPI 3.0/3.1 documents (YAML or JSON) produce a type for every schema in
+components.schemas plus request and response types for each operation.
+SQL schemas (CREATE TABLE statements) produce a type for every table.
+
+The template is a generator name or alias; append @version to use another
+installed version of a plugin, e.g. kotlin-data@2 for the newest 2.x.y.
+Pipelines defined in the configuration (see devtoolbox config) are
+templates too. Their options apply to the last generator, or to a step as
+step.key, e.g. --opt redact.fields=password.
+
 Examples:
   devtoolbox generate go-struct schema.json
   devtoolbox generate ts_interface_gen schema.json
-  devtoolbox generate go-struct -i '{"name": "string", "age": "number"}'`,
+  devtoolbox generate ts@1 schema.json
+  devtoolbox generate go-struct -i '{"name": "string", "age": "number"}'
+  devtoolbox generate go-struct config.toml
+  devtoolbox generate ts_interface_gen response.xml --from xml
+  devtoolbox generate py-dataclass export.csv
+  devtoolbox generate sql-postgres schema.json --opt nested=tables
+  devtoolbox generate go-struct schema.json --opt db=tables
+  devtoolbox generate go-struct schema.sql --opt null=sql
+  devtoolbox generate go-struct openapi.yaml --package api -o models.go
+  devtoolbox generate go-struct openapi.yaml --from openapi --out-dir ./models
+  devtoolbox generate api-models openapi.yaml --package api -o models.go`,
 	Args: cobra.RangeArgs(1, 2),
 	Run:  runGenerate,
 }
 
 var inputInline string
+var inputFormat string
+var outputFile string
+var outputDir string
+var packageName string
+var generatorOptions []string
 
 func init() {
 	generateCmd.Flags().StringVarP(&inputInline, "input", "i", "", "JSON input as string")
+	generateCmd.Flags().StringVar(&inputFormat, "from", "auto", fmt.Sprintf("Input format: auto, %s", strings.Join(core.FormatNames(), ", ")))
+	generateCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write generated code to file")
+	generateCmd.Flags().StringVar(&outputDir, "out-dir", "", "Write one file per generated type into directory")
+	generateCmd.Flags().StringVar(&packageName, "package", "", "Package name for generated Go code")
+	generateCmd.Flags().StringArrayVar(&generatorOptions, "opt", nil, "Generator option as key=value (repeatable)")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
 	template := args[0]
 	
-	var input string
+	var inputFile string
 	var err error
 	
-	if inputInline != "" {
-		input = inputInline
-	} else if len(args) > 1 {
-		inputFile := args[1]
+	if inputInline == "" && len(args) > 1 {
+		inputFile = args[1]
 		if !filepath.IsAbs(inputFile) {
 			wd, _ := os.Getwd()
 			inputFile = filepath.Join(wd, inputFile)
 		}
-		
-		data, err := os.ReadFile(inputFile)
+	} else if inputInline == "" {
+		exitWithError(fmt.Errorf("either input file or --input flag is required"))
+	}
+	
+	format, err := core.ParseFormat(inputFormat)
+	if err != nil {
+		exitWithError(err)
+	}
+	if format == core.FormatAuto && inputFile != "" {
+		format = core.FormatFromExtension(inputFile)
+	}
+	
+	registry := loadRegistry()
+	defer registry.Close()
+	
+	generator, exists := registry.Get(template)
+	if source, disabled := registry.Disabled(template); disabled {
+		name, _, _ := strings.Cut(template, core.VersionSeparator)
+		enable := "devtoolbox plugin enable " + name
+		if source != config.UserPath() {
+			enable += " --project"
+		}
+		exitWithError(fmt.Errorf("plugin '%s' is disabled by %s; enable it with %s", name, source, enable))
+	}
+	if !exists {
+		if name, _, versioned := strings.Cut(template, core.VersionSeparator); versioned && len(registry.Versions(name)) > 0 {
+			var available []string
+			name = registry.Resolve(name)
+			for _, version := range registry.Versions(name) {
+				if version == "" {
+					available = append(available, name)
+				} else {
+					available = append(available, name+core.VersionSeparator+version)
+				}
+			}
+			exitWithError(fmt.Errorf("template '%s' not found. Available versions: %v", template, available))
+		}
+		available := registry.GetNames()
+		exitWithError(fmt.Errorf("template '%s' not found. Available templates: %v", template, available))
+	}
+	
+	options, err := core.ParseOptions(generatorOptions)
+	if err != nil {
+		exitWithError(err)
+	}
+	if packageName != "" {
+		options["package"] = packageName
+	}
+	
+	generator, err = core.Configure(generator, registry.Options(template, options))
+	if err != nil {
+		exitWithError(err)
+	}
+	
+	var doc *ir.Document
+	var input string
+	
+	if inputFile != "" && core.IsStreamFormat(format) {
+		doc, err = streamDocument(inputFile, format)
+	} else if inputFile != "" {
+		var data []byte
+		data, err = os.ReadFile(inputFile)
 		if err != nil {
-			exitWithError(fmt.Errorf("failed to read input file: %v", err))
+			err = fmt.Errorf("failed to read input file: %v", err)
 		}
 		input = string(data)
 	} else {
-		exitWithError(fmt.Errorf("either input file or --input flag is required"))
+		input = inputInline
+	}
+	if err != nil {
+		exitWithError(err)
 	}
 	
-	registry := core.NewGeneratorRegistry()
+	if plugin, ok := core.StructuredPlugin(generator); ok {
+		runStructuredPlugin(ctx, plugin, doc, input, format)
+		return
+	}
 	
-	pluginManager := plugins.NewPluginManager()
-	customPlugins, err := pluginManager.LoadPlugins()
-	if err == nil {
-		for _, pluginInfo := range customPlugins {
-			if pluginInfo.Type == "python" {
-				pythonPlugin := plugins.NewPythonPlugin(pluginInfo.Name, pluginInfo.Description, pluginInfo.Path)
-				registry.Register(pythonPlugin)
-			}
+	if outputDir != "" {
+		var files []core.GeneratedFile
+		if doc != nil {
+			files, err = core.GenerateDocumentFiles(ctx, generator, doc)
+		} else {
+			files, err = core.GenerateFiles(ctx, generator, input, format)
+		}
+		if err != nil {
+			exitWithError(fmt.Errorf("generation failed: %v", err))
 		}
+		if err := writeGeneratedFiles(outputDir, files); err != nil {
+			exitWithError(err)
+		}
+		return
 	}
 	
-	generator, exists := registry.Get(template)
-	if !exists {
-		available := registry.GetNames()
-		exitWithError(fmt.Errorf("template '%s' not found. Available templates: %v", template, available))
+	var result string
+	if doc != nil {
+		result, err = core.GenerateFromDocument(ctx, generator, doc)
+	} else {
+		result, err = core.GenerateWithFormat(ctx, generator, input, format)
+	}
+	if err != nil {
+		exitWithError(fmt.Errorf("generation failed: %v", err))
 	}
 	
-	result, err := generator.Generate(input)
+	writeResult(result)
+}
+
+func runStructuredPlugin(ctx context.Context, plugin plugins.Plugin, doc *ir.Document, input string, format core.InputFormat) {
+	var response *plugins.Response
+	var err error
+	if doc != nil {
+		response, err = core.InvokePluginDocument(ctx, plugin, doc)
+	} else {
+		response, err = core.InvokePlugin(ctx, plugin, input, format)
+	}
 	if err != nil {
 		exitWithError(fmt.Errorf("generation failed: %v", err))
 	}
 	
+	for _, diagnostic := range response.Diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic.String())
+	}
+	
+	if outputDir != "" {
+		files, err := core.PluginFiles(plugin, response)
+		if err != nil {
+			exitWithError(fmt.Errorf("generation failed: %v", err))
+		}
+		if err := writeGeneratedFiles(outputDir, files); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	
+	writeResult(response.Output())
+}
+
+func writeResult(result string) {
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(result+"\n"), 0644); err != nil {
+			exitWithError(fmt.Errorf("failed to write output file: %v", err))
+		}
+		fmt.Printf("Generated: %s\n", outputFile)
+		return
+	}
+	
 	fmt.Println(result)
 }
+
+func streamDocument(path string, format core.InputFormat) (*ir.Document, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+	defer file.Close()
+	
+	return core.ParseReader(file, format)
+}
+
+func writeGeneratedFiles(dir string, files []core.GeneratedFile) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+	
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		fmt.Printf("Generated: %s\n", path)
+	}
+	
+	return nil
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/core"
 	"github.com/JIIL07/devtoolbox/internal/plugins"
 	"github.com/spf13/cobra"
 )
@@ -12,7 +15,7 @@ import (
 var pluginCmd = &cobra.Command{
 	Use:   "plugin",
 	Short: "Manage plugins",
-	Long:  "Add, remove, and list custom plugins for code generation",
+	Long:  "Add, install, update, remove, verify, enable, disable, and list custom plugins for code generation",
 }
 
 var pluginAddCmd = &cobra.Command{
@@ -20,9 +23,30 @@ var pluginAddCmd = &cobra.Command{
 	Short: "Add a new plugin",
 	Long: `Add a new plugin to the system.
 
+The path may be a plugin script, a manifest (plugin.yaml or plugin.json) or a
+directory containing one. A manifest next to the script (plugin.yaml or
+<script>.plugin.yaml) is picked up automatically and validated.
+
+Plugins are not limited to Python: with "language: exec" in the manifest any
+executable works, either run directly or through the manifest "interpreter"
+(node, ruby, sh and friends are chosen by extension when it is omitted).
+WASI modules (.wasm, or "language: wasm") run in-process without any
+interpreter. A manifest "build" command (e.g. "go build -o bin/gen ." for
+plugins written with the Go SDK) is run in the plugin directory first.
+
+Custom plugins are untrusted by default and always run in the sandbox on
+Linux: no network, a read-only view of the system and a private /tmp. Use
+--trust trusted for plugins you wrote yourself; they may then opt out with
+"sandbox: none" in their manifest.
+
+The checksums of the plugin and its manifest are recorded and verified before
+every run; a signature (plugin.sig) is verified against the trusted keys.
+
 Examples:
   devtoolbox plugin add ./plugins/custom/my_plugin.py
-  devtoolbox plugin add /path/to/plugin.py`,
+  devtoolbox plugin add ./plugins/custom/my_plugin/
+  devtoolbox plugin add ./plugins/custom/my_node_plugin/plugin.yaml
+  devtoolbox plugin add /path/to/plugin.yaml --trust trusted`,
 	Args: cobra.ExactArgs(1),
 	Run:  runPluginAdd,
 }
@@ -30,7 +54,23 @@ Examples:
 var pluginListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all plugins",
-	Long:  "List all available plugins (official and custom)",
+	Long: `List the built-in generators and all discovered plugins.
+
+Plugins are searched in these scopes; a plugin in a later scope replaces one
+with the same name from an earlier scope:
+
+  system   plugins/official next to the executable,
+           /usr/local/share/devtoolbox/plugins, /usr/share/devtoolbox/plugins
+  user     ~/.devtoolbox/plugins and plugins registered with plugin add
+  project  .devtoolbox/plugins in the working directory or a parent, and
+           plugins/official in the working directory
+  env      the directories in DEVTOOLBOX_PLUGIN_PATH
+
+Built-in generator names are reserved. Name clashes and plugins that were
+skipped, e.g. for an invalid manifest, are reported at the end.
+
+Plugins disabled with plugin disable or in .devtoolbox.yaml are listed with
+their status; see devtoolbox config show.`,
 	Run:   runPluginList,
 }
 
@@ -46,7 +86,11 @@ Examples:
 	Run:  runPluginRemove,
 }
 
+var pluginTrust string
+
 func init() {
+	pluginAddCmd.Flags().StringVar(&pluginTrust, "trust", string(plugins.TrustUntrusted), "Trust level: untrusted or trusted")
+	
 	pluginCmd.AddCommand(pluginAddCmd)
 	pluginCmd.AddCommand(pluginListCmd)
 	pluginCmd.AddCommand(pluginRemoveCmd)
@@ -64,37 +108,152 @@ func runPluginAdd(cmd *cobra.Command, args []string) {
 		exitWithError(fmt.Errorf("plugin file not found: %s", pluginPath))
 	}
 	
+	trust, err := plugins.ParseTrust(pluginTrust)
+	if err != nil || trust == plugins.TrustOfficial {
+		exitWithError(fmt.Errorf("invalid --trust %q, expected %s or %s", pluginTrust, plugins.TrustUntrusted, plugins.TrustTrusted))
+	}
+	
+	manifest, err := plugins.ResolveManifest(pluginPath)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to add plugin: %v", err))
+	}
+	if manifest != nil {
+		for _, name := range manifest.InputFormats {
+			if _, err := core.ParseFormat(name); err != nil || name == "auto" {
+				exitWithError(fmt.Errorf("failed to add plugin: invalid manifest %s: unknown input format %q", manifest.Path, name))
+			}
+		}
+		if len(manifest.Build) > 0 {
+			fmt.Printf("Building plugin: %s\n", strings.Join(manifest.Build, " "))
+		}
+	}
+	
 	manager := plugins.NewPluginManager()
-	err := manager.AddPlugin(pluginPath)
+	err = manager.AddPlugin(pluginPath, trust)
 	if err != nil {
 		exitWithError(fmt.Errorf("failed to add plugin: %v", err))
 	}
 	
+	if manifest != nil {
+		fmt.Printf("Plugin added successfully: %s %s\n", manifest.Name, manifest.Version)
+		return
+	}
 	fmt.Printf("Plugin added successfully: %s\n", filepath.Base(pluginPath))
 }
 
 func runPluginList(cmd *cobra.Command, args []string) {
+	builtins := core.NewGeneratorRegistry()
 	manager := plugins.NewPluginManager()
-	pluginList, err := manager.ListPlugins()
+	discovery, err := manager.Discover(builtins.GetNames())
 	if err != nil {
 		exitWithError(fmt.Errorf("failed to list plugins: %v", err))
 	}
-	
-	if len(pluginList) == 0 {
-		fmt.Println("No plugins found.")
-		return
+	cfg, err := config.Load()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to list plugins: %v", err))
 	}
+	// Disabled plugins are listed too; version constraints pick which of the
+	// plugins with the same name is shown.
+	found := discovery.Plugins
+	disabled := cfg.Apply(discovery, builtins.GetNames())
 	
 	fmt.Println("Available plugins:")
 	fmt.Println("==================")
 	
-	for _, plugin := range pluginList {
+	for _, name := range builtins.GetNames() {
+		generator, _ := builtins.Get(name)
+		fmt.Printf("Name: %s\n", name)
+		fmt.Printf("Description: %s\n", generator.GetDescription())
+		fmt.Printf("Type: go\n")
+		fmt.Printf("Path: builtin\n")
+		fmt.Println("---")
+	}
+	
+	for _, plugin := range found {
+		status := "enabled"
+		if source, ok := disabled[plugin.Name]; ok {
+			status = "disabled by " + source
+		} else if used := findPlugin(discovery.Plugins, plugin.Name); used != nil {
+			plugin = *used
+		} else {
+			continue
+		}
 		fmt.Printf("Name: %s\n", plugin.Name)
 		fmt.Printf("Description: %s\n", plugin.Description)
 		fmt.Printf("Type: %s\n", plugin.Type)
 		fmt.Printf("Path: %s\n", plugin.Path)
+		fmt.Printf("Scope: %s\n", plugin.Scope)
+		fmt.Printf("Status: %s\n", status)
+		if plugin.Trust != plugins.TrustOfficial {
+			if _, err := os.Stat(plugin.Path); err != nil {
+				fmt.Println("Warning: plugin files are missing; add the plugin again or use plugin install")
+			}
+		}
+		if plugin.Source != nil {
+			fmt.Printf("Source: %s\n", plugin.Source)
+			if plugin.Source.Resolved != "" {
+				fmt.Printf("Resolved: %s\n", plugin.Source.Resolved)
+			}
+		}
+		if plugin.Integrity != nil {
+			printIntegrity(plugin)
+		}
+		printSandbox(plugin)
+		if plugin.Manifest != nil {
+			printManifest(plugin.Manifest)
+		}
 		fmt.Println("---")
 	}
+	
+	for _, conflict := range discovery.Conflicts {
+		fmt.Printf("Conflict: %s\n", conflict)
+	}
+	for _, err := range discovery.Errors {
+		fmt.Printf("Skipped: %v\n", err)
+	}
+}
+
+func printManifest(manifest *plugins.Manifest) {
+	fmt.Printf("Version: %s\n", manifest.Version)
+	if manifest.Author != "" {
+		fmt.Printf("Author: %s\n", manifest.Author)
+	}
+	if len(manifest.Aliases) > 0 {
+		fmt.Printf("Aliases: %s\n", strings.Join(manifest.Aliases, ", "))
+	}
+	fmt.Printf("Language: %s\n", manifest.Language)
+	if len(manifest.Interpreter) > 0 {
+		fmt.Printf("Interpreter: %s\n", strings.Join(manifest.Interpreter, " "))
+	}
+	if len(manifest.Build) > 0 {
+		fmt.Printf("Build: %s\n", strings.Join(manifest.Build, " "))
+	}
+	if len(manifest.Requirements) > 0 {
+		fmt.Printf("Requirements: %s\n", strings.Join(manifest.Requirements, ", "))
+	}
+	if len(manifest.InputFormats) > 0 {
+		fmt.Printf("Input formats: %s\n", strings.Join(manifest.InputFormats, ", "))
+	}
+	if manifest.OutputExtension != "" {
+		fmt.Printf("Output extension: %s\n", manifest.OutputExtension)
+	}
+	if manifest.MinVersion != "" {
+		fmt.Printf("Requires DevToolBox: >= %s\n", manifest.MinVersion)
+	}
+	for _, name := range manifest.OptionNames() {
+		option := manifest.Options[name]
+		line := fmt.Sprintf("Option: %s (%s)", name, option.Type)
+		if len(option.Enum) > 0 {
+			line += fmt.Sprintf(" one of %s", strings.Join(option.Enum, "|"))
+		}
+		if option.Default != "" {
+			line += fmt.Sprintf(", default %s", option.Default)
+		}
+		if option.Description != "" {
+			line += " - " + option.Description
+		}
+		fmt.Println(line)
+	}
 }
 
 func runPluginRemove(cmd *cobra.Command, args []string) {
@@ -108,3 +267,23 @@ func runPluginRemove(cmd *cobra.Command, args []string) {
 	
 	fmt.Printf("Plugin removed successfully: %s\n", pluginName)
 }
+
+func printSandbox(plugin plugins.PluginInfo) {
+	trust, err := plugins.ParseTrust(string(plugin.Trust))
+	if err != nil {
+		trust = plugins.TrustUntrusted
+	}
+	
+	sandbox := "none"
+	if plugin.Type == plugins.LanguageWasm {
+		sandbox = "wasm (in-process)"
+	} else if plugin.Type == plugins.LanguageTemplate {
+		sandbox = "template (in-process)"
+	} else if plugins.Sandboxed(trust, plugin.Manifest) {
+		sandbox = "strict"
+		if !plugins.SandboxSupported() {
+			sandbox += " (unavailable on this system)"
+		}
+	}
+	fmt.Printf("Trust: %s, sandbox: %s\n", trust, sandbox)
+}
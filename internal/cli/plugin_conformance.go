@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/conformance"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var pluginTestCmd = &cobra.Command{
+	Use:   "test <plugin-name|path>",
+	Short: "Run the conformance tests of a plugin",
+	Long: `Run a plugin against a suite of fixture inputs and check that it is ready
+to publish:
+
+  - it answers every fixture within its timeout (and --timeout, if set)
+  - JSON protocol responses are valid: file names stay in the output
+    directory and diagnostics have a known severity
+  - its output is identical in every run (--runs)
+  - its output matches the golden files, recorded with --update
+  - it rejects malformed JSON with an error (invalid_input for the JSON
+    protocol) instead of generating code
+
+The fixtures are the JSON files in examples/ (in the working directory or
+next to the executable, or --examples) and the files matched by the
+"fixtures" patterns of the plugin manifest, e.g. fixtures: [testdata/*.json].
+Golden files are kept in testdata/golden in the plugin directory, one per
+fixture; plugins sharing a directory use testdata/golden/<name>.
+
+The plugin is a registered plugin, with name@version and aliases as for
+generate, or the path of a plugin directory, manifest or script that has not
+been added yet. Such a plugin is built first and runs as an untrusted plugin
+unless --trust trusted is given.
+
+Examples:
+  devtoolbox plugin test ts_interface_gen
+  devtoolbox plugin test ./kotlin-gen --update
+  devtoolbox plugin test kotlin-data@2 --runs 5 --timeout 2s`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginTest,
+}
+
+var (
+	pluginTestUpdate   bool
+	pluginTestRuns     int
+	pluginTestTimeout  time.Duration
+	pluginTestGolden   string
+	pluginTestExamples string
+	pluginTestTrust    string
+)
+
+func init() {
+	pluginTestCmd.Flags().BoolVar(&pluginTestUpdate, "update", false, "Record the outputs as the golden files")
+	pluginTestCmd.Flags().IntVar(&pluginTestRuns, "runs", conformance.DefaultRuns, "Runs per fixture to check that the output is deterministic")
+	pluginTestCmd.Flags().DurationVar(&pluginTestTimeout, "timeout", 0, "Maximum duration of every call (default: the plugin's own limit)")
+	pluginTestCmd.Flags().StringVar(&pluginTestGolden, "golden", "", "Directory of the golden files (default testdata/golden in the plugin directory)")
+	pluginTestCmd.Flags().StringVar(&pluginTestExamples, "examples", "", "Directory of the sample inputs (default ./examples)")
+	pluginTestCmd.Flags().StringVar(&pluginTestTrust, "trust", string(plugins.TrustUntrusted), "Trust level of a plugin given by path: untrusted or trusted")
+
+	pluginCmd.AddCommand(pluginTestCmd)
+}
+
+func runPluginTest(cmd *cobra.Command, args []string) {
+	if pluginTestRuns < 1 {
+		exitWithError(fmt.Errorf("--runs must be at least 1"))
+	}
+
+	plugin, goldenDir, closePlugin := loadTestPlugin(args[0])
+	defer closePlugin()
+	if pluginTestGolden != "" {
+		goldenDir = pluginTestGolden
+	}
+
+	examples := pluginTestExamples
+	if examples == "" {
+		examples = conformance.FindExamples()
+	}
+	fixtures, err := conformance.Fixtures(examples, plugin.Manifest())
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to collect fixtures: %v", err))
+	}
+
+	fmt.Printf("Testing %s against %d fixtures (%d runs each)\n", plugin.GetName(), len(fixtures), pluginTestRuns)
+	if goldenDir != "" {
+		fmt.Printf("Golden files: %s\n", goldenDir)
+	}
+	report, err := conformance.Run(cmd.Context(), plugin, conformance.Options{
+		Fixtures:  fixtures,
+		GoldenDir: goldenDir,
+		Update:    pluginTestUpdate,
+		Runs:      pluginTestRuns,
+		Timeout:   pluginTestTimeout,
+	})
+	if report != nil {
+		printReport(report)
+	}
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to write golden files: %v", err))
+	}
+
+	fmt.Printf("\nSummary: %d passed, %d warned, %d skipped, %d failed\n",
+		report.Count(conformance.StatusPass), report.Count(conformance.StatusWarn),
+		report.Count(conformance.StatusSkip), report.Count(conformance.StatusFail))
+	if report.Failed() {
+		exitWithError(fmt.Errorf("plugin %s failed %d conformance checks", plugin.GetName(), report.Count(conformance.StatusFail)))
+	}
+}
+
+// loadTestPlugin returns the plugin to test, its default golden directory
+// and a function releasing it. Existing paths are loaded directly, anything
+// else is looked up like a generate template.
+func loadTestPlugin(target string) (plugins.Plugin, string, func()) {
+	if _, err := os.Stat(target); err == nil && strings.ContainsAny(target, `./\`) {
+		return loadPluginPath(target)
+	}
+
+	registry := loadRegistry()
+	if source, disabled := registry.Disabled(target); disabled {
+		registry.Close()
+		exitWithError(fmt.Errorf("plugin '%s' is disabled by %s", registry.Resolve(target), source))
+	}
+	generator, ok := registry.Get(target)
+	if !ok {
+		registry.Close()
+		exitWithError(fmt.Errorf("plugin '%s' not found", target))
+	}
+	plugin, ok := generator.(plugins.Plugin)
+	if !ok {
+		registry.Close()
+		exitWithError(fmt.Errorf("'%s' is a built-in generator; plugin test checks external plugins", target))
+	}
+	configured, err := core.Configure(plugin, registry.Options(target, nil))
+	if err != nil {
+		registry.Close()
+		exitWithError(err)
+	}
+
+	name := registry.Resolve(target)
+	entrypoint := ""
+	if manifest := plugin.Manifest(); manifest != nil {
+		entrypoint = manifest.EntrypointPath()
+	} else if discovery, err := plugins.NewPluginManager().Discover(core.NewGeneratorRegistry().GetNames()); err == nil {
+		if info := findPlugin(discovery.Plugins, name); info != nil {
+			entrypoint = info.Path
+		}
+	}
+	goldenDir := ""
+	if entrypoint != "" || plugin.Manifest() != nil {
+		goldenDir = conformance.DefaultGoldenDir(name, entrypoint, plugin.Manifest())
+	}
+	return configured.(plugins.Plugin), goldenDir, func() { registry.Close() }
+}
+
+func loadPluginPath(path string) (plugins.Plugin, string, func()) {
+	trust, err := plugins.ParseTrust(pluginTestTrust)
+	if err != nil || trust == plugins.TrustOfficial {
+		exitWithError(fmt.Errorf("invalid --trust %q, expected %s or %s", pluginTestTrust, plugins.TrustUntrusted, plugins.TrustTrusted))
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	manifest, err := plugins.ResolveManifest(path)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to load plugin: %v", err))
+	}
+
+	var plugin plugins.ExternalPlugin
+	if manifest != nil {
+		if len(manifest.Build) > 0 {
+			fmt.Printf("Building plugin: %s\n", strings.Join(manifest.Build, " "))
+		}
+		if err := plugins.BuildPlugin(manifest); err != nil {
+			exitWithError(err)
+		}
+		plugin = plugins.NewPluginFromManifest(manifest)
+		path = manifest.EntrypointPath()
+	} else {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		plugin, err = plugins.NewExternalPlugin(plugins.LanguageForPath(path), name, "", path)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to load plugin: %v", err))
+		}
+	}
+	plugin.SetTrust(trust)
+	return plugin, conformance.DefaultGoldenDir(plugin.GetName(), path, manifest), func() { plugin.Close() }
+}
+
+func printReport(report *conformance.Report) {
+	fixture := "-"
+	for _, check := range report.Checks {
+		if check.Fixture != fixture {
+			fixture = check.Fixture
+			fmt.Println()
+			if fixture != "" {
+				fmt.Println(fixture)
+			}
+		}
+		fmt.Printf("  %-4s  %-16s %s\n", check.Status, check.Name, check.Detail)
+	}
+}
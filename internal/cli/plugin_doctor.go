@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var pluginDoctorCmd = &cobra.Command{
+	Use:   "doctor [plugin-name...]",
+	Short: "Check that plugins can run",
+	Long: `Check what plugins need to run: the entrypoint, the interpreter, the Python
+SDK, the virtual environment and installed version of every requirement, the
+sandbox and the recorded checksums.
+
+Python plugins that declare requirements in their manifest run in their own
+virtual environment, created on plugin add with python3 -m venv. Requirements
+are installed from the wheel directory in DEVTOOLBOX_WHEELS or
+~/.devtoolbox/wheels if there is one, so no network is needed; otherwise pip
+uses its configured index.
+
+Without names all discovered plugins are checked; built-in generators need
+nothing.
+
+Examples:
+  devtoolbox plugin doctor
+  devtoolbox plugin doctor kotlin-data`,
+	Run: runPluginDoctor,
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginDoctorCmd)
+}
+
+func runPluginDoctor(cmd *cobra.Command, args []string) {
+	pluginList, err := plugins.NewPluginManager().ListPlugins()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to check plugins: %v", err))
+	}
+
+	selected := pluginList
+	if len(args) > 0 {
+		selected = nil
+	}
+	for _, name := range args {
+		if _, builtin := core.NewGeneratorRegistry().Get(name); builtin {
+			fmt.Printf("%s: built-in, nothing to check\n", name)
+			continue
+		}
+		plugin := findPlugin(pluginList, name)
+		if plugin == nil {
+			exitWithError(fmt.Errorf("plugin '%s' not found", name))
+		}
+		selected = append(selected, *plugin)
+	}
+
+	failed := false
+	for i, plugin := range selected {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s (%s, %s)\n", plugin.Name, plugin.Type, plugin.Scope)
+		for _, check := range plugins.Diagnose(plugin) {
+			status := "ok"
+			if !check.OK {
+				status = "FAIL"
+				failed = true
+			}
+			fmt.Printf("  %-4s  %-12s %s\n", status, check.Name, check.Detail)
+		}
+	}
+	if failed {
+		exitWithError(fmt.Errorf("some plugins cannot run"))
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var pluginEnableCmd = &cobra.Command{
+	Use:   "enable <plugin-name...>",
+	Short: "Enable plugins",
+	Long: `Enable plugins in the user configuration (~/.devtoolbox/config.yaml), or
+with --project in the project configuration (.devtoolbox.yaml in the project
+root, or in the working directory outside a project).
+
+Project settings override user settings; see devtoolbox config show.
+
+Examples:
+  devtoolbox plugin enable kotlin-data
+  devtoolbox plugin enable kotlin-data --project`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setPluginsEnabled(args, true)
+	},
+}
+
+var pluginDisableCmd = &cobra.Command{
+	Use:   "disable <plugin-name...>",
+	Short: "Disable plugins",
+	Long: `Disable plugins in the user configuration (~/.devtoolbox/config.yaml), or
+with --project in the project configuration (.devtoolbox.yaml). Disabled
+plugins are not loaded by generate or the server; built-in generators cannot
+be disabled.
+
+Examples:
+  devtoolbox plugin disable legacy-gen
+  devtoolbox plugin disable legacy-gen --project`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setPluginsEnabled(args, false)
+	},
+}
+
+var pluginConfigProject bool
+
+func init() {
+	pluginEnableCmd.Flags().BoolVar(&pluginConfigProject, "project", false, "Change the project configuration")
+	pluginDisableCmd.Flags().BoolVar(&pluginConfigProject, "project", false, "Change the project configuration")
+
+	pluginCmd.AddCommand(pluginEnableCmd)
+	pluginCmd.AddCommand(pluginDisableCmd)
+}
+
+func setPluginsEnabled(names []string, enabled bool) {
+	builtins := core.NewGeneratorRegistry()
+	discovery, err := plugins.NewPluginManager().Discover(builtins.GetNames())
+	if err != nil {
+		exitWithError(err)
+	}
+	for _, name := range names {
+		if _, builtin := builtins.Get(name); builtin {
+			exitWithError(fmt.Errorf("'%s' is a built-in generator, which is always enabled", name))
+		}
+		if findPlugin(discovery.Plugins, name) == nil {
+			exitWithError(fmt.Errorf("plugin '%s' not found", name))
+		}
+	}
+
+	path := config.UserPath()
+	if pluginConfigProject {
+		path = config.ProjectPath()
+	}
+	for _, name := range names {
+		if err := config.SetPluginEnabled(path, name, enabled); err != nil {
+			exitWithError(fmt.Errorf("failed to change %s: %v", path, err))
+		}
+		fmt.Printf("Plugin %s: %s in %s\n", stateName(enabled), name, path)
+	}
+
+	// A project setting may still override the user configuration.
+	cfg, err := config.Load()
+	if err != nil {
+		exitWithError(err)
+	}
+	for _, name := range names {
+		if effective, source := cfg.PluginEnabled(name); effective != enabled {
+			fmt.Printf("Note: %s stays %s, as set by %s\n", name, stateName(effective), source)
+		}
+	}
+}
+
+func stateName(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var pluginInstallCmd = &cobra.Command{
+	Use:   "install <source>",
+	Short: "Install a plugin from git, an archive or an index",
+	Long: `Install a plugin into ~/.devtoolbox/plugins/<name>.
+
+Unlike plugin add, which registers files where they are, install copies the
+plugin into a managed directory, so it keeps working when the source moves and
+can be updated later. The source may be:
+
+  a git repository   git+https://host/repo, https://host/repo.git, git@host:repo.git
+  an archive         ./plugin.tar.gz, https://host/plugin.zip (.tar.gz, .tgz, .tar, .zip)
+  a directory        ./plugins/custom/kotlin_data
+  a plugin name      kotlin-data, looked up in the plugin index
+
+Git sources may be pinned to a tag, branch or commit with @<ref>, index
+sources to a version with @<version>. The index is a YAML or JSON file or URL
+given with --index, DEVTOOLBOX_PLUGIN_INDEX, or ~/.devtoolbox/index.yaml.
+
+Examples:
+  devtoolbox plugin install https://github.com/acme/kotlin-data.git@v1.2.0
+  devtoolbox plugin install ./dist/kotlin-data.tar.gz
+  devtoolbox plugin install kotlin-data@1.2.0 --index https://plugins.acme.dev/index.yaml
+  devtoolbox plugin install git+https://github.com/acme/generators --subdir kotlin`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginInstall,
+}
+
+var pluginUpdateCmd = &cobra.Command{
+	Use:   "update [plugin-name...]",
+	Short: "Update installed plugins",
+	Long: `Fetch installed plugins again from their source and replace them.
+
+Pinned plugins stay on their ref or version unless --version pins a new one;
+unpinned plugins follow the default branch or the latest indexed version.
+Without names all installed plugins are updated.
+
+Examples:
+  devtoolbox plugin update
+  devtoolbox plugin update kotlin-data --version 1.3.0`,
+	Run: runPluginUpdate,
+}
+
+var pluginUninstallCmd = &cobra.Command{
+	Use:   "uninstall <plugin-name>",
+	Short: "Uninstall a plugin and delete its files",
+	Long: `Unregister a plugin and delete its files if it was installed with
+plugin install. Plugins registered with plugin add keep their files.
+
+Examples:
+  devtoolbox plugin uninstall kotlin-data`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginUninstall,
+}
+
+var (
+	pluginInstallTrust string
+	pluginIndex        string
+	pluginSubdir       string
+	pluginVersion      string
+)
+
+func init() {
+	pluginInstallCmd.Flags().StringVar(&pluginInstallTrust, "trust", string(plugins.TrustUntrusted), "Trust level: untrusted or trusted")
+	pluginInstallCmd.Flags().StringVar(&pluginIndex, "index", "", "Plugin index file or URL for plugin names")
+	pluginInstallCmd.Flags().StringVar(&pluginSubdir, "subdir", "", "Plugin directory inside the repository or archive")
+	pluginUpdateCmd.Flags().StringVar(&pluginVersion, "version", "", "Pin a git ref or index version")
+
+	pluginCmd.AddCommand(pluginInstallCmd)
+	pluginCmd.AddCommand(pluginUpdateCmd)
+	pluginCmd.AddCommand(pluginUninstallCmd)
+}
+
+func runPluginInstall(cmd *cobra.Command, args []string) {
+	trust, err := plugins.ParseTrust(pluginInstallTrust)
+	if err != nil || trust == plugins.TrustOfficial {
+		exitWithError(fmt.Errorf("invalid --trust %q, expected %s or %s", pluginInstallTrust, plugins.TrustUntrusted, plugins.TrustTrusted))
+	}
+
+	source, err := plugins.ParseSource(args[0])
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to install plugin: %v", err))
+	}
+
+	fmt.Printf("Installing plugin from %s\n", source)
+	info, err := plugins.NewPluginManager().InstallPlugin(source, plugins.InstallOptions{
+		Trust:  trust,
+		Index:  pluginIndex,
+		Subdir: pluginSubdir,
+	})
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to install plugin: %v", err))
+	}
+	fmt.Printf("Plugin installed successfully: %s %s\n", info.Name, info.Manifest.Version)
+}
+
+func runPluginUpdate(cmd *cobra.Command, args []string) {
+	manager := plugins.NewPluginManager()
+	names := args
+	if len(names) == 0 {
+		if pluginVersion != "" {
+			exitWithError(fmt.Errorf("--version needs a plugin name"))
+		}
+		installed, err := manager.LoadPlugins()
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to update plugins: %v", err))
+		}
+		for _, plugin := range installed {
+			if plugin.Source != nil {
+				names = append(names, plugin.Name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Println("No installed plugins to update.")
+			return
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		old, updated, err := manager.UpdatePlugin(name, pluginVersion)
+		if err != nil {
+			fmt.Printf("Failed to update %s: %v\n", name, err)
+			failed = true
+			continue
+		}
+		if old.Manifest != nil && old.Manifest.Version != updated.Manifest.Version {
+			fmt.Printf("Plugin updated: %s %s -> %s\n", name, old.Manifest.Version, updated.Manifest.Version)
+		} else {
+			fmt.Printf("Plugin up to date: %s %s\n", name, updated.Manifest.Version)
+		}
+	}
+	if failed {
+		exitWithError(fmt.Errorf("some plugins were not updated"))
+	}
+}
+
+func runPluginUninstall(cmd *cobra.Command, args []string) {
+	info, err := plugins.NewPluginManager().UninstallPlugin(args[0])
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to uninstall plugin: %v", err))
+	}
+	if info.Source == nil {
+		fmt.Printf("Plugin removed successfully: %s (files kept at %s)\n", info.Name, info.Path)
+		return
+	}
+	fmt.Printf("Plugin uninstalled successfully: %s\n", info.Name)
+}
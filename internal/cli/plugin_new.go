@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var pluginNewCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create a plugin project",
+	Long: `Create a working plugin project in a new directory: the manifest, an
+entrypoint that speaks the plugin protocol, a sample input, golden-file tests
+for it and a README. The project is ready for plugin add.
+
+Languages:
+  python  a script using the Python SDK (devtoolbox_sdk), in worker mode
+  go      a binary using the Go SDK, built by plugin add, in worker mode
+  wasm    the Go plugin built as a WebAssembly module, no runtime needed
+  exec    a shell script speaking the text protocol
+
+Go and WebAssembly projects get a go.mod requiring the SDK unless they are
+created inside the DevToolBox source tree; --module sets its module path. The
+SDK has no release yet, so the go.mod replaces it with a DevToolBox checkout:
+--sdk-dir, by default the source tree this binary was built from.
+--sample uses your own input as the test fixture; the expected output is
+generated from it.
+
+Examples:
+  devtoolbox plugin new kotlin-gen
+  devtoolbox plugin new proto-gen --lang go --dir plugins/proto_gen
+  devtoolbox plugin new docs-gen --lang wasm --module example.com/docs-gen
+  devtoolbox plugin new js-gen --lang exec --sample user.json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginNew,
+}
+
+var (
+	pluginNewLang        string
+	pluginNewDir         string
+	pluginNewDescription string
+	pluginNewSample      string
+	pluginNewModule      string
+	pluginNewSDKDir      string
+)
+
+func init() {
+	pluginNewCmd.Flags().StringVar(&pluginNewLang, "lang", "python", fmt.Sprintf("Plugin language: %s", strings.Join(scaffold.Languages, ", ")))
+	pluginNewCmd.Flags().StringVar(&pluginNewDir, "dir", "", "Project directory (default ./<name>)")
+	pluginNewCmd.Flags().StringVar(&pluginNewDescription, "description", "", "Plugin description for the manifest")
+	pluginNewCmd.Flags().StringVar(&pluginNewSample, "sample", "", "Sample input file for the tests")
+	pluginNewCmd.Flags().StringVar(&pluginNewModule, "module", "", "Go module path (go and wasm)")
+	pluginNewCmd.Flags().StringVar(&pluginNewSDKDir, "sdk-dir", "", "DevToolBox checkout the go.mod replaces the SDK with (go and wasm)")
+
+	pluginCmd.AddCommand(pluginNewCmd)
+}
+
+func runPluginNew(cmd *cobra.Command, args []string) {
+	name := args[0]
+	if _, builtin := core.NewGeneratorRegistry().Get(name); builtin {
+		exitWithError(fmt.Errorf("'%s' is the name of a built-in generator; choose another name", name))
+	}
+
+	options := scaffold.Options{
+		Name:        name,
+		Language:    pluginNewLang,
+		Dir:         pluginNewDir,
+		Description: pluginNewDescription,
+		Module:      pluginNewModule,
+		SDKDir:      pluginNewSDKDir,
+	}
+	if pluginNewSample != "" {
+		data, err := os.ReadFile(pluginNewSample)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to read sample: %v", err))
+		}
+		options.Sample, options.SampleName = string(data), pluginNewSample
+	}
+
+	result, err := scaffold.New(options)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to create plugin: %v", err))
+	}
+
+	fmt.Printf("Created %s plugin %s in %s:\n", pluginNewLang, name, result.Dir)
+	for _, file := range result.Files {
+		fmt.Printf("  %s\n", filepath.ToSlash(file))
+	}
+	fmt.Println()
+	fmt.Println("Next steps:")
+	fmt.Printf("  cd %s\n", result.Dir)
+	fmt.Printf("  %s\n", result.Test)
+	fmt.Println("  devtoolbox plugin add .")
+	fmt.Printf("  devtoolbox generate %s testdata/%s\n", name, filepath.Base(sampleName(options)))
+}
+
+func sampleName(options scaffold.Options) string {
+	if options.SampleName != "" {
+		return options.SampleName
+	}
+	return "input.json"
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/spf13/cobra"
+)
+
+var pluginVerifyCmd = &cobra.Command{
+	Use:   "verify [plugin-name...]",
+	Short: "Verify plugin checksums and signatures",
+	Long: `Check that plugins still match the checksums recorded when they were added
+or installed, and that signed plugins are still signed by a trusted key.
+Plugins that fail are refused before every run.
+
+After changing a plugin on purpose, record its checksums again with --update.
+Without names all custom plugins are verified.
+
+Examples:
+  devtoolbox plugin verify
+  devtoolbox plugin verify kotlin-data --update`,
+	Run: runPluginVerify,
+}
+
+var pluginKeygenCmd = &cobra.Command{
+	Use:   "keygen <name>",
+	Short: "Create a key pair for signing plugins",
+	Long: `Create an ed25519 key pair: <name>.key, the private key for plugin sign,
+and <name>.pub, a line for the trusted keys file of everyone who runs your
+plugins (DEVTOOLBOX_TRUSTED_KEYS or ~/.devtoolbox/trusted_keys).
+
+Examples:
+  devtoolbox plugin keygen acme
+  cat acme.pub >> ~/.devtoolbox/trusted_keys`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginKeygen,
+}
+
+var pluginSignCmd = &cobra.Command{
+	Use:   "sign <plugin-path>",
+	Short: "Sign a plugin",
+	Long: `Write a detached signature of a plugin with a key from plugin keygen.
+
+The signature covers the checksums of the entrypoint and the manifest. It is
+written to plugin.sig next to plugin.yaml, or to <script>.sig for scripts
+without their own directory. Plugins with a build step cannot be signed, since
+their entrypoint is only produced on add.
+
+Examples:
+  devtoolbox plugin sign ./plugins/custom/kotlin_data --key acme.key`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPluginSign,
+}
+
+var (
+	pluginVerifyUpdate bool
+	pluginSignKey      string
+)
+
+func init() {
+	pluginVerifyCmd.Flags().BoolVar(&pluginVerifyUpdate, "update", false, "Record the checksums again")
+	pluginSignCmd.Flags().StringVar(&pluginSignKey, "key", "", "Private key file from plugin keygen")
+	pluginSignCmd.MarkFlagRequired("key")
+
+	pluginCmd.AddCommand(pluginVerifyCmd)
+	pluginCmd.AddCommand(pluginKeygenCmd)
+	pluginCmd.AddCommand(pluginSignCmd)
+}
+
+func runPluginVerify(cmd *cobra.Command, args []string) {
+	manager := plugins.NewPluginManager()
+	installed, err := manager.LoadPlugins()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to verify plugins: %v", err))
+	}
+
+	names := args
+	if len(names) == 0 {
+		for _, plugin := range installed {
+			names = append(names, plugin.Name)
+		}
+		if len(names) == 0 {
+			fmt.Println("No custom plugins to verify.")
+			return
+		}
+	}
+
+	failed := false
+	for _, name := range names {
+		if pluginVerifyUpdate {
+			if _, err := manager.RecordIntegrity(name); err != nil {
+				fmt.Printf("Failed to update %s: %v\n", name, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Checksums recorded: %s\n", name)
+			continue
+		}
+
+		plugin := findPlugin(installed, name)
+		if plugin == nil {
+			fmt.Printf("%s: plugin not found\n", name)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: %s\n", name, integrityStatus(*plugin))
+		if plugin.Integrity.Verify() != nil {
+			failed = true
+		}
+	}
+	if failed {
+		exitWithError(fmt.Errorf("some plugins failed verification"))
+	}
+}
+
+func runPluginKeygen(cmd *cobra.Command, args []string) {
+	name := args[0]
+	privatePath, publicPath := name+".key", name+".pub"
+	for _, path := range []string{privatePath, publicPath} {
+		if _, err := os.Stat(path); err == nil {
+			exitWithError(fmt.Errorf("%s already exists", path))
+		}
+	}
+
+	privateKey, publicKey, err := plugins.GenerateKey(filepath.Base(name))
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to generate key: %v", err))
+	}
+	if err := os.WriteFile(privatePath, []byte(privateKey+"\n"), 0600); err != nil {
+		exitWithError(fmt.Errorf("failed to write private key: %v", err))
+	}
+	if err := os.WriteFile(publicPath, []byte(publicKey+"\n"), 0644); err != nil {
+		exitWithError(fmt.Errorf("failed to write public key: %v", err))
+	}
+	fmt.Printf("Private key: %s (keep it secret)\n", privatePath)
+	fmt.Printf("Public key: %s (add it to the trusted keys file)\n", publicPath)
+}
+
+func runPluginSign(cmd *cobra.Command, args []string) {
+	signature, err := plugins.SignPlugin(args[0], pluginSignKey)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to sign plugin: %v", err))
+	}
+	fmt.Printf("Plugin signed: %s\n", signature)
+}
+
+func findPlugin(installed []plugins.PluginInfo, name string) *plugins.PluginInfo {
+	for i := range installed {
+		if installed[i].Name == name {
+			return &installed[i]
+		}
+	}
+	return nil
+}
+
+func integrityStatus(plugin plugins.PluginInfo) string {
+	if plugin.Integrity == nil {
+		return "not recorded; run plugin verify --update"
+	}
+	if err := plugin.Integrity.Verify(); err != nil {
+		return fmt.Sprintf("FAILED: %v", err)
+	}
+	if plugin.Integrity.Signer != "" {
+		return "ok, signed by " + plugin.Integrity.Signer
+	}
+	return "ok"
+}
+
+func printIntegrity(plugin plugins.PluginInfo) {
+	fmt.Printf("Integrity: %s\n", integrityStatus(plugin))
+}
@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -19,19 +24,43 @@ Features:
 - Plugin system for custom generators
 - Web interface for easy usage
 - CLI for advanced users`,
+	Version: version.Version,
 }
 
+// Execute runs the CLI. Ctrl+C cancels the command context, which stops
+// running plugins.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(pluginCmd)
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
 func exitWithError(err error) {
 	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	os.Exit(1)
 }
+
+// loadRegistry loads the built-in generators and the discovered plugins as
+// selected by the user and project configuration, and warns about plugins
+// that clash or were skipped. An invalid configuration is an error, since
+// generating without it would silently produce different code.
+func loadRegistry() *core.GeneratorRegistry {
+	registry, discovery, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to load plugins: %v", err))
+	}
+	for _, conflict := range discovery.Conflicts {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", conflict)
+	}
+	for _, err := range discovery.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: skipped plugin: %v\n", err)
+	}
+	return registry
+}
@@ -1,12 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/JIIL07/devtoolbox/internal/api"
 	"github.com/JIIL07/devtoolbox/internal/core"
@@ -21,19 +23,31 @@ var serverCmd = &cobra.Command{
 
 The server provides a web interface for code generation and exposes REST API endpoints.
 
+Plugins are reloaded while the server runs: the plugin directories,
+plugins.json and the configuration files are checked every --watch-interval,
+and changed plugins are replaced without interrupting requests that already
+use them. POST /admin/reload reloads them immediately; it is only available
+from localhost unless DEVTOOLBOX_ADMIN_TOKEN is set, in which case it needs
+"Authorization: Bearer <token>".
+
 Examples:
   devtoolbox server
   devtoolbox server --port 3000
-  devtoolbox server --host 0.0.0.0 --port 8080`,
+  devtoolbox server --host 0.0.0.0 --port 8080
+  devtoolbox server --watch=false`,
 	Run: runServer,
 }
 
 var serverHost string
 var serverPort string
+var serverWatch bool
+var serverWatchInterval time.Duration
 
 func init() {
 	serverCmd.Flags().StringVar(&serverHost, "host", "localhost", "Server host")
 	serverCmd.Flags().StringVar(&serverPort, "port", "8080", "Server port")
+	serverCmd.Flags().BoolVar(&serverWatch, "watch", true, "Reload plugins when they change")
+	serverCmd.Flags().DurationVar(&serverWatchInterval, "watch-interval", 2*time.Second, "How often to check plugins for changes")
 }
 
 func runServer(cmd *cobra.Command, args []string) {
@@ -43,12 +57,19 @@ func runServer(cmd *cobra.Command, args []string) {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	
-	registry := core.NewGeneratorRegistry()
-	handler := api.NewHandler(registry)
+	reloader := core.NewReloader(loadRegistry(), core.LoadGeneratorRegistry)
+	handler := api.NewReloadableHandler(reloader)
+	
+	ctx, stop := context.WithCancel(cmd.Context())
+	defer stop()
+	if serverWatch {
+		go reloader.Watch(ctx, serverWatchInterval, logReload)
+	}
 	
 	router.GET("/health", handler.Health)
 	router.GET("/generators", handler.ListGenerators)
 	router.POST("/generate", handler.Generate)
+	router.POST("/admin/reload", api.AdminMiddleware(), handler.Reload)
 	
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
@@ -76,6 +97,7 @@ func runServer(cmd *cobra.Command, args []string) {
 		fmt.Printf("   GET  /health     - Health check\n")
 		fmt.Printf("   GET  /generators - List available generators\n")
 		fmt.Printf("   POST /generate   - Generate code\n")
+		fmt.Printf("   POST /admin/reload - Reload plugins\n")
 		fmt.Printf("\nPress Ctrl+C to stop the server\n")
 		
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -88,4 +110,25 @@ func runServer(cmd *cobra.Command, args []string) {
 	<-quit
 	
 	fmt.Println("\n🛑 Shutting down server...")
+	stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
+	}
+	reloader.Close()
+}
+
+func logReload(report *core.ReloadReport, err error) {
+	if err != nil {
+		log.Printf("Failed to reload plugins: %v", err)
+		return
+	}
+	log.Printf("Reloaded plugins: %s", report)
+	for _, conflict := range report.Discovery.Conflicts {
+		log.Printf("Plugin conflict: %s", conflict)
+	}
+	for _, err := range report.Discovery.Errors {
+		log.Printf("Skipped plugin: %v", err)
+	}
 }
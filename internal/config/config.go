@@ -0,0 +1,493 @@
+// Package config loads the user and project configuration: which plugins are
+// enabled, which plugin versions a project needs, default generator options
+// and generator pipelines.
+//
+// The user configuration is ~/.devtoolbox/config.yaml; the project
+// configuration is .devtoolbox.yaml in the project root, the working
+// directory or its nearest parent with a .devtoolbox directory or a
+// .devtoolbox.yaml. Project settings override user settings:
+//
+//	plugins:
+//	  default: disabled        # plugins not listed below; enabled if omitted
+//	  enabled: [kotlin-data]
+//	  disabled: [legacy-gen]
+//	  versions:
+//	    kotlin-data: ">=1.2.0 <2.0.0"
+//	aliases:
+//	  ts: ts_interface_gen
+//	options:
+//	  go-struct:
+//	    package: models
+//	pipelines:
+//	  api-models:
+//	    description: Go models for the API
+//	    steps:
+//	      - processor: redact
+//	        options: {fields: "password,token"}
+//	      - generator: go-struct
+//	        options: {package: api}
+//	      - processor: goimports
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of configuration files, from the lowest to the highest precedence.
+const (
+	ScopeUser    = "user"
+	ScopeProject = "project"
+)
+
+// SourceDefault is the source of settings that no file sets.
+const SourceDefault = "default"
+
+const (
+	stateEnabled  = "enabled"
+	stateDisabled = "disabled"
+)
+
+// File is one configuration file.
+type File struct {
+	Plugins PluginSettings `yaml:"plugins,omitempty"`
+	// Aliases map short names to generator names, e.g. ts: ts_interface_gen.
+	Aliases map[string]string            `yaml:"aliases,omitempty"`
+	Options map[string]map[string]string `yaml:"options,omitempty"`
+	// Pipelines are generators made of other generators and processors, run
+	// like any generator by name.
+	Pipelines map[string]Pipeline `yaml:"pipelines,omitempty"`
+}
+
+// Pipeline chains generators: the output of every generator is the input of
+// the next one. Processors before a generator change the type model it gets,
+// processors after it change the code it produced.
+type Pipeline struct {
+	Description string `yaml:"description,omitempty"`
+	Steps       []Step `yaml:"steps"`
+}
+
+// Step is a generator or a processor. Format is the input format of a
+// generator that reads the output of the previous one; it is detected from
+// the content if omitted.
+type Step struct {
+	Generator string            `yaml:"generator,omitempty"`
+	Processor string            `yaml:"processor,omitempty"`
+	Format    string            `yaml:"format,omitempty"`
+	Options   map[string]string `yaml:"options,omitempty"`
+}
+
+// Name returns the generator or processor of the step.
+func (s Step) Name() string {
+	if s.Generator != "" {
+		return s.Generator
+	}
+	return s.Processor
+}
+
+// PipelineValue is a pipeline and the file it came from.
+type PipelineValue struct {
+	Pipeline Pipeline
+	Source   string
+}
+
+// PluginSettings select the plugins to use. Default applies to plugins that
+// are in neither list. Versions maps plugin names to version constraints, see
+// plugins.MatchVersion.
+type PluginSettings struct {
+	Default  string            `yaml:"default,omitempty"`
+	Enabled  []string          `yaml:"enabled,omitempty"`
+	Disabled []string          `yaml:"disabled,omitempty"`
+	Versions map[string]string `yaml:"versions,omitempty"`
+}
+
+// Layer is a configuration file in a scope. File is nil if the file does not
+// exist.
+type Layer struct {
+	Scope string
+	Path  string
+	File  *File
+}
+
+// Config is the effective configuration.
+type Config struct {
+	// Layers are ordered from the lowest to the highest precedence.
+	Layers []Layer
+}
+
+// Value is a setting and the file it came from, or SourceDefault.
+type Value struct {
+	Value  string
+	Source string
+}
+
+// UserPath returns the user configuration file.
+func UserPath() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".devtoolbox", "config.yaml")
+}
+
+// ProjectPath returns the configuration file of the current project, or of
+// the working directory if it is not in a project.
+func ProjectPath() string {
+	dir := plugins.FindProjectDir()
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return filepath.Join(dir, plugins.ProjectConfig)
+}
+
+// Load reads the user configuration and the configuration of the current
+// project. Missing files are not an error.
+func Load() (*Config, error) {
+	config := &Config{}
+	paths := []Layer{{Scope: ScopeUser, Path: UserPath()}}
+	if dir := plugins.FindProjectDir(); dir != "" {
+		paths = append(paths, Layer{Scope: ScopeProject, Path: filepath.Join(dir, plugins.ProjectConfig)})
+	}
+	for _, layer := range paths {
+		file, err := LoadFile(layer.Path)
+		if err != nil {
+			return nil, err
+		}
+		layer.File = file
+		config.Layers = append(config.Layers, layer)
+	}
+	return config, nil
+}
+
+// LoadFile reads and validates a configuration file. It returns nil if the
+// file does not exist.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	file := &File{}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Validate checks the plugin states, version constraints, aliases and
+// pipelines.
+func (f *File) Validate() error {
+	switch f.Plugins.Default {
+	case "", stateEnabled, stateDisabled:
+	default:
+		return fmt.Errorf("plugins.default must be %s or %s, got %q", stateEnabled, stateDisabled, f.Plugins.Default)
+	}
+	for _, name := range f.Plugins.Enabled {
+		if containsString(f.Plugins.Disabled, name) {
+			return fmt.Errorf("plugin %s is both enabled and disabled", name)
+		}
+	}
+	for name, constraint := range f.Plugins.Versions {
+		if _, err := plugins.MatchVersion("0.0.0", constraint); err != nil {
+			return fmt.Errorf("plugins.versions.%s: %w", name, err)
+		}
+	}
+	for alias, target := range f.Aliases {
+		if alias == "" || target == "" || strings.Contains(alias, "@") {
+			return fmt.Errorf("aliases.%s: invalid alias of %q", alias, target)
+		}
+	}
+	for name, pipeline := range f.Pipelines {
+		if err := pipeline.validate(name); err != nil {
+			return fmt.Errorf("pipelines.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p Pipeline) validate(name string) error {
+	if name == "" || strings.Contains(name, "@") {
+		return fmt.Errorf("invalid pipeline name %q", name)
+	}
+	generators := 0
+	for i, step := range p.Steps {
+		switch {
+		case (step.Generator == "") == (step.Processor == ""):
+			return fmt.Errorf("step %d must have either a generator or a processor", i+1)
+		case step.Processor != "" && step.Format != "":
+			return fmt.Errorf("step %d: format applies to generators only", i+1)
+		case step.Generator != "":
+			generators++
+		}
+	}
+	if generators == 0 {
+		return errors.New("a pipeline needs at least one generator step")
+	}
+	return nil
+}
+
+// PluginEnabled reports whether a plugin is enabled and which file decided it.
+func (c *Config) PluginEnabled(name string) (bool, string) {
+	enabled, source := true, SourceDefault
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		settings := layer.File.Plugins
+		if settings.Default != "" {
+			enabled, source = settings.Default == stateEnabled, layer.Path
+		}
+		if containsString(settings.Enabled, name) {
+			enabled, source = true, layer.Path
+		}
+		if containsString(settings.Disabled, name) {
+			enabled, source = false, layer.Path
+		}
+	}
+	return enabled, source
+}
+
+// DefaultState returns the state of plugins that are not listed and the file
+// that set it.
+func (c *Config) DefaultState() Value {
+	state := Value{Value: stateEnabled, Source: SourceDefault}
+	for _, layer := range c.Layers {
+		if layer.File != nil && layer.File.Plugins.Default != "" {
+			state = Value{Value: layer.File.Plugins.Default, Source: layer.Path}
+		}
+	}
+	return state
+}
+
+// Version returns the version constraint of a plugin, or an empty Value if
+// there is none.
+func (c *Config) Version(name string) Value {
+	var version Value
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		if constraint, ok := layer.File.Plugins.Versions[name]; ok {
+			version = Value{Value: constraint, Source: layer.Path}
+		}
+	}
+	return version
+}
+
+// Aliases returns the generator names by alias.
+func (c *Config) Aliases() map[string]Value {
+	aliases := map[string]Value{}
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		for alias, target := range layer.File.Aliases {
+			aliases[alias] = Value{Value: target, Source: layer.Path}
+		}
+	}
+	return aliases
+}
+
+// Options returns the default options of a generator by key.
+func (c *Config) Options(generator string) map[string]Value {
+	options := map[string]Value{}
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		for key, value := range layer.File.Options[generator] {
+			options[key] = Value{Value: value, Source: layer.Path}
+		}
+	}
+	return options
+}
+
+// Pipelines returns the pipelines by name. A pipeline in a file of higher
+// precedence replaces the pipeline with the same name as a whole.
+func (c *Config) Pipelines() map[string]PipelineValue {
+	pipelines := map[string]PipelineValue{}
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		for name, pipeline := range layer.File.Pipelines {
+			pipelines[name] = PipelineValue{Pipeline: pipeline, Source: layer.Path}
+		}
+	}
+	return pipelines
+}
+
+// OptionValues returns the default options of every generator that has any.
+func (c *Config) OptionValues() map[string]map[string]string {
+	values := map[string]map[string]string{}
+	for _, generator := range c.Generators() {
+		options := c.Options(generator)
+		if len(options) == 0 {
+			continue
+		}
+		values[generator] = make(map[string]string, len(options))
+		for key, option := range options {
+			values[generator][key] = option.Value
+		}
+	}
+	return values
+}
+
+// Generators returns the sorted names of the generators and plugins the
+// configuration mentions.
+func (c *Config) Generators() []string {
+	seen := map[string]bool{}
+	for _, layer := range c.Layers {
+		if layer.File == nil {
+			continue
+		}
+		for _, name := range append(append([]string{}, layer.File.Plugins.Enabled...), layer.File.Plugins.Disabled...) {
+			seen[name] = true
+		}
+		for name := range layer.File.Plugins.Versions {
+			seen[name] = true
+		}
+		for name := range layer.File.Options {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply removes disabled plugins from a discovery and, for plugins with a
+// version constraint, uses the candidate with the highest precedence that
+// satisfies it, updating the conflicts to match; plugins that are not used
+// do not conflict. Constraints that no
+// candidate satisfies and enabled plugins that were not found are added to
+// the discovery errors. Names in builtins are built-in generators, which are
+// always enabled. Apply returns the disabled plugins with the file that
+// disabled them.
+func (c *Config) Apply(discovery *plugins.Discovery, builtins []string) map[string]string {
+	disabled := map[string]string{}
+	found := map[string]bool{}
+	unused := map[string]bool{}
+	var used []plugins.PluginInfo
+
+	for _, plugin := range discovery.Plugins {
+		found[plugin.Name] = true
+		if enabled, source := c.PluginEnabled(plugin.Name); !enabled {
+			disabled[plugin.Name] = source
+			unused[plugin.Name] = true
+			continue
+		}
+
+		version := c.Version(plugin.Name)
+		if version.Value == "" {
+			used = append(used, plugin)
+			continue
+		}
+		candidates := []plugins.PluginInfo{plugin}
+		conflict := -1
+		for i := range discovery.Conflicts {
+			if discovery.Conflicts[i].Name == plugin.Name {
+				conflict = i
+				candidates = append(candidates, discovery.Conflicts[i].Shadowed...)
+			}
+		}
+		chosen := -1
+		for i, candidate := range candidates {
+			if ok, _ := plugins.MatchVersion(pluginVersion(candidate), version.Value); ok {
+				chosen = i
+				break
+			}
+		}
+		if chosen < 0 {
+			discovery.Errors = append(discovery.Errors, versionError(plugin.Name, version, candidates))
+			unused[plugin.Name] = true
+			continue
+		}
+		used = append(used, candidates[chosen])
+		if chosen > 0 {
+			shadowed := append(append([]plugins.PluginInfo{}, candidates[:chosen]...), candidates[chosen+1:]...)
+			discovery.Conflicts[conflict].Used = candidates[chosen]
+			discovery.Conflicts[conflict].Shadowed = shadowed
+		}
+	}
+	discovery.Plugins = used
+
+	conflicts := discovery.Conflicts[:0]
+	for _, conflict := range discovery.Conflicts {
+		if !unused[conflict.Name] {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	discovery.Conflicts = conflicts
+
+	for _, name := range c.Generators() {
+		if found[name] || containsString(builtins, name) {
+			continue
+		}
+		enabled, source := c.PluginEnabled(name)
+		if !enabled {
+			continue
+		}
+		if version := c.Version(name); version.Value != "" {
+			discovery.Errors = append(discovery.Errors, fmt.Errorf("plugin %s %s required by %s is not installed", name, version.Value, version.Source))
+		} else if c.listed(name) {
+			discovery.Errors = append(discovery.Errors, fmt.Errorf("plugin %s enabled by %s is not installed", name, source))
+		}
+	}
+	return disabled
+}
+
+// listed reports whether a plugin is named in an enabled list, as opposed to
+// being enabled by a default.
+func (c *Config) listed(name string) bool {
+	for _, layer := range c.Layers {
+		if layer.File != nil && containsString(layer.File.Plugins.Enabled, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func pluginVersion(plugin plugins.PluginInfo) string {
+	if plugin.Manifest == nil {
+		return ""
+	}
+	return plugin.Manifest.Version
+}
+
+func versionError(name string, version Value, candidates []plugins.PluginInfo) error {
+	found := ""
+	for i, candidate := range candidates {
+		if i > 0 {
+			found += ", "
+		}
+		v := pluginVersion(candidate)
+		if v == "" {
+			v = "no version"
+		}
+		found += fmt.Sprintf("%s in %s %s", v, candidate.Scope, candidate.Path)
+	}
+	return fmt.Errorf("plugin %s: no version matches %s required by %s (found %s)", name, version.Value, version.Source, found)
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
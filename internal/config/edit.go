@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetPluginEnabled adds a plugin to the enabled or disabled list of a
+// configuration file and removes it from the other one, creating the file if
+// needed. Comments and all other settings are kept.
+func SetPluginEnabled(path, name string, enabled bool) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid config %s: expected a mapping", path)
+	}
+
+	add, remove := stateEnabled, stateDisabled
+	if !enabled {
+		add, remove = stateDisabled, stateEnabled
+	}
+	plugins := mappingValue(root, "plugins", yaml.MappingNode)
+	if list := mappingValue(plugins, remove, 0); list != nil {
+		removeScalar(list, name)
+		if len(list.Content) == 0 {
+			deleteKey(plugins, remove)
+		}
+	}
+	list := mappingValue(plugins, add, yaml.SequenceNode)
+	if !hasScalar(list, name) {
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name})
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	var file File
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if err := file.Validate(); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// mappingValue returns the value of a key in a mapping node. A missing key is
+// added with an empty node of the given kind, unless kind is 0.
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			if kind != 0 && value.Kind != kind && value.Tag == "!!null" {
+				*value = yaml.Node{Kind: kind}
+			}
+			return value
+		}
+	}
+	if kind == 0 {
+		return nil
+	}
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+func deleteKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func hasScalar(sequence *yaml.Node, value string) bool {
+	for _, item := range sequence.Content {
+		if item.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeScalar(sequence *yaml.Node, value string) {
+	content := sequence.Content[:0]
+	for _, item := range sequence.Content {
+		if item.Value != value {
+			content = append(content, item)
+		}
+	}
+	sequence.Content = content
+}
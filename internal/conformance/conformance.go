@@ -0,0 +1,410 @@
+// Package conformance runs a plugin against a suite of fixture inputs for
+// devtoolbox plugin test: the sample inputs in examples/ and the fixtures
+// declared in the plugin manifest.
+//
+// Every fixture is generated several times to check that the plugin answers
+// within its timeout, speaks the protocol, returns the same output on every
+// run and matches the golden output recorded with Options.Update. The plugin
+// must also reject malformed JSON with an error instead of generating code.
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// MalformedInput is the truncated JSON document every plugin must reject.
+const MalformedInput = `{"id": 1, "name": "broken`
+
+// DefaultRuns is how many times every fixture is generated by default.
+const DefaultRuns = 2
+
+// GoldenExtension is appended to the fixture name to name its golden file.
+const GoldenExtension = ".golden"
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check is one result of a conformance run. Fixture is empty for checks of
+// the plugin as a whole.
+type Check struct {
+	Fixture string
+	Name    string
+	Status  Status
+	Detail  string
+}
+
+// Fixture is a sample input. Name is unique within a run, e.g.
+// "examples/user-schema.json", and names the golden file. FormatAuto means
+// the format is detected from the content.
+type Fixture struct {
+	Name   string
+	Path   string
+	Format core.InputFormat
+}
+
+// Options control a conformance run.
+type Options struct {
+	Fixtures []Fixture
+	// GoldenDir holds the golden outputs, one file per fixture. Without it
+	// outputs are not compared.
+	GoldenDir string
+	// Update rewrites the golden files instead of comparing them.
+	Update bool
+	// Runs is how many times every fixture is generated, DefaultRuns if zero.
+	Runs int
+	// Timeout bounds every call on top of the plugin's own limit.
+	Timeout time.Duration
+}
+
+// Report is the outcome of a conformance run.
+type Report struct {
+	Plugin string
+	Checks []Check
+}
+
+// Failed reports whether any check failed.
+func (r *Report) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns how many checks have the given status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Report) add(fixture, name string, status Status, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Fixture: fixture, Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// FindExamples returns the examples directory in the working directory or
+// next to the executable, or "" if there is none.
+func FindExamples() string {
+	candidates := []string{"examples"}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), "examples"))
+	}
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if abs, err := filepath.Abs(dir); err == nil {
+				return abs
+			}
+			return dir
+		}
+	}
+	return ""
+}
+
+// Fixtures collects the JSON samples in examplesDir, named examples/<file>,
+// and the fixtures declared in the manifest, named fixtures/<path relative
+// to the plugin directory>. Either may be empty or nil.
+func Fixtures(examplesDir string, manifest *plugins.Manifest) ([]Fixture, error) {
+	var fixtures []Fixture
+	if examplesDir != "" {
+		paths, err := filepath.Glob(filepath.Join(examplesDir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			fixtures = append(fixtures, Fixture{Name: "examples/" + filepath.Base(path), Path: path, Format: core.FormatFromExtension(path)})
+		}
+	}
+
+	if manifest != nil {
+		root := filepath.Dir(manifest.Path)
+		for _, path := range manifest.FixturePaths() {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil, err
+			}
+			fixtures = append(fixtures, Fixture{Name: "fixtures/" + filepath.ToSlash(rel), Path: path, Format: core.FormatFromExtension(path)})
+		}
+	}
+	return fixtures, nil
+}
+
+// DefaultGoldenDir returns testdata/golden in the plugin directory. Plugins
+// sharing a directory, scripts and script-specific manifests, get their own
+// subdirectory named after the plugin.
+func DefaultGoldenDir(name, entrypoint string, manifest *plugins.Manifest) string {
+	if manifest != nil {
+		dir := filepath.Join(filepath.Dir(manifest.Path), "testdata", "golden")
+		for _, base := range plugins.ManifestNames {
+			if filepath.Base(manifest.Path) == base {
+				return dir
+			}
+		}
+		return filepath.Join(dir, name)
+	}
+	return filepath.Join(filepath.Dir(entrypoint), "testdata", "golden", name)
+}
+
+// GoldenPath returns the golden file of a fixture.
+func GoldenPath(goldenDir string, fixture Fixture) string {
+	return filepath.Join(goldenDir, filepath.FromSlash(fixture.Name)+GoldenExtension)
+}
+
+// Run checks the plugin against the fixtures. Failures of the plugin are
+// reported as checks; Run itself only fails if golden files cannot be
+// written.
+func Run(ctx context.Context, plugin plugins.Plugin, options Options) (*Report, error) {
+	if options.Runs <= 0 {
+		options.Runs = DefaultRuns
+	}
+	report := &Report{Plugin: plugin.GetName()}
+
+	checkManifest(report, plugin)
+	checkMalformed(ctx, report, plugin, options.Timeout)
+	if len(options.Fixtures) == 0 {
+		report.add("", "fixtures", StatusWarn, "no fixtures; declare them with fixtures in the manifest or run from a directory with examples/")
+	}
+
+	for _, fixture := range options.Fixtures {
+		if err := checkFixture(ctx, report, plugin, fixture, options); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func checkManifest(report *Report, plugin plugins.Plugin) {
+	manifest := plugin.Manifest()
+	if manifest == nil {
+		report.add("", "manifest", StatusWarn, "none; the plugin speaks the text protocol and accepts no options")
+		return
+	}
+	report.add("", "manifest", StatusPass, "%s %s, %s protocol", manifest.Name, manifest.Version, plugins.Protocol(plugin))
+}
+
+// checkMalformed sends a truncated JSON document without a type model. JSON
+// protocol plugins must report invalid_input; text protocol plugins should
+// fail in any way, "Error: ..." output included.
+func checkMalformed(ctx context.Context, report *Report, plugin plugins.Plugin, timeout time.Duration) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := plugin.Invoke(ctx, &plugins.Request{Input: MalformedInput, Format: string(core.FormatJSON)})
+	var pluginErr *plugins.PluginError
+	switch {
+	case err == nil && plugins.Protocol(plugin) == plugins.ProtocolJSON:
+		report.add("", "malformed input", StatusFail, "generated code from malformed JSON instead of reporting %s", plugins.CodeInvalidInput)
+	case err == nil:
+		report.add("", "malformed input", StatusWarn, "generated code from malformed JSON; print \"Error: ...\" or exit with code %d", plugins.ExitInvalidInput)
+	case errors.As(err, &pluginErr) && (pluginErr.Code == plugins.CodeInvalidInput || plugins.Protocol(plugin) == plugins.ProtocolText):
+		report.add("", "malformed input", StatusPass, "rejected with %s", pluginErr.Code)
+	case errors.As(err, &pluginErr):
+		report.add("", "malformed input", StatusWarn, "rejected with %s, expected %s: %v", pluginErr.Code, plugins.CodeInvalidInput, err)
+	default:
+		report.add("", "malformed input", StatusFail, "%v", err)
+	}
+}
+
+func checkFixture(ctx context.Context, report *Report, plugin plugins.Plugin, fixture Fixture, options Options) error {
+	data, err := os.ReadFile(fixture.Path)
+	if err != nil {
+		report.add(fixture.Name, "generate", StatusFail, "%v", err)
+		return nil
+	}
+	format := fixture.Format
+	if format == core.FormatAuto {
+		format = core.DetectFormat(string(data))
+	}
+	if !accepts(plugin, format) {
+		report.add(fixture.Name, "generate", StatusSkip, "the plugin does not accept %s input", format)
+		return nil
+	}
+
+	var outputs, problems []string
+	var slowest time.Duration
+	for run := 0; run < options.Runs; run++ {
+		started := time.Now()
+		output, runProblems, err := generate(ctx, plugin, string(data), format, options.Timeout)
+		if elapsed := time.Since(started); elapsed > slowest {
+			slowest = elapsed
+		}
+		if err != nil {
+			report.add(fixture.Name, "generate", StatusFail, "run %d: %v", run+1, err)
+			return nil
+		}
+		if run == 0 {
+			problems = runProblems
+		}
+		outputs = append(outputs, output)
+	}
+	report.add(fixture.Name, "generate", StatusPass, "%d runs, slowest %s", options.Runs, slowest.Round(time.Millisecond))
+	switch {
+	case len(problems) > 0:
+		report.add(fixture.Name, "protocol", StatusFail, "%s", strings.Join(problems, "; "))
+	case strings.TrimSpace(outputs[0]) == "":
+		report.add(fixture.Name, "protocol", StatusFail, "empty output")
+	default:
+		report.add(fixture.Name, "protocol", StatusPass, "valid %s protocol response", plugins.Protocol(plugin))
+	}
+
+	deterministic := true
+	for run := 1; run < len(outputs); run++ {
+		if outputs[run] != outputs[0] {
+			report.add(fixture.Name, "deterministic", StatusFail, "run %d differs from run 1 at %s", run+1, firstDifference(outputs[0], outputs[run]))
+			deterministic = false
+			break
+		}
+	}
+	if deterministic && len(outputs) > 1 {
+		report.add(fixture.Name, "deterministic", StatusPass, "identical output in %d runs", len(outputs))
+	}
+
+	return checkGolden(report, fixture, outputs[0], options)
+}
+
+func checkGolden(report *Report, fixture Fixture, output string, options Options) error {
+	if options.GoldenDir == "" {
+		report.add(fixture.Name, "golden", StatusSkip, "no golden directory")
+		return nil
+	}
+	path := GoldenPath(options.GoldenDir, fixture)
+
+	if options.Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			return err
+		}
+		report.add(fixture.Name, "golden", StatusPass, "recorded")
+		return nil
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		report.add(fixture.Name, "golden", StatusSkip, "not recorded yet; record it with --update")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(expected) != output {
+		report.add(fixture.Name, "golden", StatusFail, "output differs at %s", firstDifference(string(expected), output))
+		return nil
+	}
+	report.add(fixture.Name, "golden", StatusPass, "matches")
+	return nil
+}
+
+// generate runs the plugin the way devtoolbox generate does and renders all
+// returned files. For JSON protocol plugins it also lists the problems of the
+// response.
+func generate(ctx context.Context, plugin plugins.Plugin, input string, format core.InputFormat, timeout time.Duration) (string, []string, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	structured, ok := core.StructuredPlugin(plugin)
+	if !ok {
+		output, err := core.GenerateWithFormat(ctx, plugin, input, format)
+		return output, nil, err
+	}
+
+	response, err := core.InvokePlugin(ctx, structured, input, format)
+	if err != nil {
+		return "", nil, err
+	}
+	var problems []string
+	for _, diagnostic := range response.Diagnostics {
+		if diagnostic.Severity != plugins.SeverityWarning && diagnostic.Severity != plugins.SeverityInfo {
+			problems = append(problems, fmt.Sprintf("diagnostic %q has severity %q, expected %s or %s", diagnostic.Message, diagnostic.Severity, plugins.SeverityWarning, plugins.SeverityInfo))
+		}
+	}
+	files, err := core.PluginFiles(structured, response)
+	if err != nil {
+		return "", append(problems, err.Error()), nil
+	}
+	names := map[string]bool{}
+	for _, file := range files {
+		if names[file.Name] {
+			problems = append(problems, fmt.Sprintf("file %s is returned twice", file.Name))
+		}
+		names[file.Name] = true
+	}
+	return render(files), problems, nil
+}
+
+// render joins the files of a response; a single file is its content, several
+// are each preceded by a header line with the file name.
+func render(files []core.GeneratedFile) string {
+	if len(files) == 1 {
+		return files[0].Content
+	}
+	var builder strings.Builder
+	for i, file := range files {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		fmt.Fprintf(&builder, "==> %s <==\n%s\n", filepath.ToSlash(file.Name), file.Content)
+	}
+	return builder.String()
+}
+
+func accepts(plugin plugins.Plugin, format core.InputFormat) bool {
+	manifest := plugin.Manifest()
+	if manifest == nil || len(manifest.InputFormats) == 0 {
+		return true
+	}
+	for _, name := range manifest.InputFormats {
+		if strings.EqualFold(name, string(format)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstDifference describes where two outputs start to differ.
+func firstDifference(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var want, got string
+		if i < len(expectedLines) {
+			want = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if i >= len(expectedLines) || i >= len(actualLines) || want != got {
+			return fmt.Sprintf("line %d: expected %q, got %q", i+1, want, got)
+		}
+	}
+	return "the end"
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,496 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const (
+	ddlWord   = 'w'
+	ddlQuoted = 'q'
+	ddlString = 's'
+	ddlPunct  = 'p'
+)
+
+type ddlToken struct {
+	kind byte
+	text string
+}
+
+func (t ddlToken) is(word string) bool {
+	return t.kind == ddlWord && strings.EqualFold(t.text, word)
+}
+
+func (t ddlToken) isPunct(punct string) bool {
+	return t.kind == ddlPunct && t.text == punct
+}
+
+// ddlColumnStops — слова, с которых после типа колонки начинаются ее ограничения.
+var ddlColumnStops = map[string]bool{
+	"not": true, "null": true, "primary": true, "references": true, "default": true,
+	"unique": true, "check": true, "constraint": true, "collate": true, "generated": true,
+	"auto_increment": true, "autoincrement": true, "comment": true, "on": true, "identity": true,
+}
+
+var ddlTableConstraints = map[string]bool{
+	"constraint": true, "primary": true, "foreign": true, "unique": true, "check": true,
+	"key": true, "index": true, "fulltext": true, "spatial": true, "exclude": true,
+}
+
+var ddlParameterizedTypes = map[string]bool{
+	"char": true, "varchar": true, "binary": true, "varbinary": true, "decimal": true, "numeric": true,
+	"enum": true, "set": true, "int": true, "tinyint": true, "smallint": true, "mediumint": true,
+	"bigint": true, "float": true, "double": true, "bit": true,
+}
+
+type ddlParser struct {
+	doc   *ir.Document
+	enums map[string][]string
+}
+
+// ParseSQL строит модель типов по операторам CREATE TABLE в диалектах
+// PostgreSQL, MySQL и SQLite. Каждая таблица становится моделью, колонки —
+// обязательными полями, колонки без NOT NULL — nullable. Перечисления из
+// CREATE TYPE ... AS ENUM учитываются, остальные операторы пропускаются.
+func ParseSQL(input string) (*ir.Document, error) {
+	tokens, err := tokenizeDDL(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &ddlParser{doc: ir.NewDocument(), enums: make(map[string][]string)}
+	for _, statement := range splitDDLStatements(tokens) {
+		if err := parser.statement(statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(parser.doc.Models) == 0 {
+		return nil, fmt.Errorf("ошибка парсинга SQL: не найдено ни одного CREATE TABLE")
+	}
+
+	parser.doc.Format = string(FormatSQL)
+	return parser.doc, nil
+}
+
+// isDDL сообщает, начинается ли текст (после комментариев) с оператора CREATE.
+func isDDL(input string) bool {
+	tokens, err := tokenizeDDL(input)
+	return err == nil && len(tokens) > 1 && tokens[0].is("create") && tokens[1].kind == ddlWord
+}
+
+func (p *ddlParser) statement(tokens []ddlToken) error {
+	if len(tokens) == 0 || !tokens[0].is("create") {
+		return nil
+	}
+
+	i := 1
+	for i < len(tokens) && (tokens[i].is("or") || tokens[i].is("replace") || tokens[i].is("temp") ||
+		tokens[i].is("temporary") || tokens[i].is("unlogged") || tokens[i].is("global") || tokens[i].is("local")) {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil
+	}
+
+	switch {
+	case tokens[i].is("table"):
+		return p.table(tokens[i+1:])
+	case tokens[i].is("type"):
+		p.enum(tokens[i+1:])
+	}
+	return nil
+}
+
+func (p *ddlParser) enum(tokens []ddlToken) {
+	name, rest := ddlQualifiedName(tokens)
+	if len(rest) < 3 || !rest[0].is("as") || !rest[1].is("enum") {
+		return
+	}
+	p.enums[strings.ToLower(name)] = ddlStrings(rest[2:])
+}
+
+func (p *ddlParser) table(tokens []ddlToken) error {
+	if len(tokens) >= 3 && tokens[0].is("if") && tokens[1].is("not") && tokens[2].is("exists") {
+		tokens = tokens[3:]
+	}
+
+	name, rest := ddlQualifiedName(tokens)
+	if name == "" {
+		return fmt.Errorf("ошибка парсинга SQL: не указано имя таблицы")
+	}
+	if len(rest) == 0 || !rest[0].isPunct("(") {
+		return fmt.Errorf("ошибка парсинга SQL: у таблицы %s нет списка колонок", name)
+	}
+
+	items, ok := ddlSplitList(rest)
+	if !ok {
+		return fmt.Errorf("ошибка парсинга SQL: не закрыта скобка в таблице %s", name)
+	}
+
+	modelType := &ir.Type{Kind: ir.KindObject}
+	var primary []string
+	for _, item := range items {
+		if len(item) == 0 {
+			continue
+		}
+		if ddlIsTableConstraint(item) {
+			primary = append(primary, ddlPrimaryKey(item)...)
+			continue
+		}
+
+		field, err := p.column(item)
+		if err != nil {
+			return fmt.Errorf("ошибка парсинга SQL: таблица %s: %w", name, err)
+		}
+		modelType.Fields = append(modelType.Fields, field)
+	}
+
+	for _, column := range primary {
+		for _, field := range modelType.Fields {
+			if strings.EqualFold(field.Name, column) {
+				field.Type.Nullable = false
+			}
+		}
+	}
+
+	p.doc.Add(&ir.Model{
+		Name: uniqueModelName(p.doc, ToPascalCase(name)),
+		Type: modelType,
+	})
+	return nil
+}
+
+func (p *ddlParser) column(tokens []ddlToken) (*ir.Field, error) {
+	name := tokens[0].text
+	if tokens[0].kind != ddlWord && tokens[0].kind != ddlQuoted {
+		return nil, fmt.Errorf("ожидалось имя колонки, получено %q", name)
+	}
+
+	i := 1
+	depth := 0
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		if depth == 0 && token.kind == ddlWord {
+			word := strings.ToLower(token.text)
+			if ddlColumnStops[word] {
+				break
+			}
+			if word == "character" && i+1 < len(tokens) && tokens[i+1].is("set") {
+				break
+			}
+		}
+		if token.isPunct("(") {
+			depth++
+		} else if token.isPunct(")") {
+			depth--
+		}
+	}
+	if i == 1 {
+		return nil, fmt.Errorf("у колонки %s не указан тип", name)
+	}
+
+	columnType := p.columnType(tokens[1:i])
+	field := &ir.Field{Name: name, Type: columnType, Required: true}
+
+	nullable := true
+	constraints := tokens[i:]
+	depth = 0
+	for j := 0; j < len(constraints); j++ {
+		token := constraints[j]
+		switch {
+		case token.isPunct("("):
+			depth++
+		case token.isPunct(")"):
+			depth--
+		case depth > 0:
+		case token.is("not") && j+1 < len(constraints) && constraints[j+1].is("null"):
+			nullable = false
+			j++
+		case token.is("primary") && j+1 < len(constraints) && constraints[j+1].is("key"):
+			nullable = false
+			j++
+		case token.is("comment") && j+1 < len(constraints) && constraints[j+1].kind == ddlString:
+			field.Description = constraints[j+1].text
+			j++
+		}
+	}
+	columnType.Nullable = nullable
+
+	return field, nil
+}
+
+// columnType переводит SQL-тип в тип модели. Неизвестные типы разбираются
+// по правилам родства типов SQLite.
+func (p *ddlParser) columnType(tokens []ddlToken) *ir.Type {
+	var words, args []string
+	arrays := 0
+	depth := 0
+	for _, token := range tokens {
+		switch {
+		case token.isPunct("("):
+			depth++
+		case token.isPunct(")"):
+			depth--
+		case depth > 0:
+			if token.kind != ddlPunct {
+				args = append(args, token.text)
+			}
+		case token.isPunct("["):
+			arrays++
+		case token.is("array"):
+			arrays++
+		case token.kind == ddlWord || token.kind == ddlQuoted:
+			words = append(words, strings.ToLower(token.text))
+		}
+	}
+
+	result := p.scalarType(words, args)
+	for ; arrays > 0; arrays-- {
+		result = ir.ArrayOf(result)
+	}
+	return result
+}
+
+func (p *ddlParser) scalarType(words, args []string) *ir.Type {
+	if len(words) == 0 {
+		return &ir.Type{Kind: ir.KindAny}
+	}
+	if values, ok := p.enums[words[len(words)-1]]; ok {
+		return &ir.Type{Kind: ir.KindString, Enum: values}
+	}
+
+	switch words[0] {
+	case "smallint", "int2", "smallserial", "serial2", "mediumint", "int", "integer", "int4", "serial", "serial4":
+		return &ir.Type{Kind: ir.KindInteger, Format: "int32"}
+	case "tinyint":
+		if len(args) == 1 && args[0] == "1" {
+			return &ir.Type{Kind: ir.KindBoolean}
+		}
+		return &ir.Type{Kind: ir.KindInteger, Format: "int32"}
+	case "bigint", "int8", "bigserial", "serial8":
+		return &ir.Type{Kind: ir.KindInteger, Format: "int64"}
+	case "real", "float4":
+		return &ir.Type{Kind: ir.KindNumber, Format: "float"}
+	case "float", "double", "float8":
+		return &ir.Type{Kind: ir.KindNumber, Format: "double"}
+	case "numeric", "decimal", "dec", "fixed", "money":
+		return &ir.Type{Kind: ir.KindString, Format: "decimal"}
+	case "bool", "boolean":
+		return &ir.Type{Kind: ir.KindBoolean}
+	case "bit":
+		if len(args) == 0 || args[0] == "1" {
+			return &ir.Type{Kind: ir.KindBoolean}
+		}
+		return &ir.Type{Kind: ir.KindString}
+	case "year":
+		return &ir.Type{Kind: ir.KindInteger, Format: "int32"}
+	case "date":
+		return &ir.Type{Kind: ir.KindTime, Format: "date"}
+	case "time", "timetz", "interval", "point":
+		return &ir.Type{Kind: ir.KindString}
+	case "timestamp", "timestamptz", "datetime":
+		return &ir.Type{Kind: ir.KindTime, Format: "date-time"}
+	case "uuid", "uniqueidentifier":
+		return &ir.Type{Kind: ir.KindString, Format: "uuid"}
+	case "json", "jsonb":
+		return &ir.Type{Kind: ir.KindAny, Format: "json"}
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return &ir.Type{Kind: ir.KindString, Format: "binary"}
+	case "enum":
+		return &ir.Type{Kind: ir.KindString, Enum: args}
+	}
+
+	name := strings.Join(words, " ")
+	switch {
+	case strings.Contains(name, "int"):
+		return &ir.Type{Kind: ir.KindInteger, Format: "int64"}
+	case strings.Contains(name, "blob"):
+		return &ir.Type{Kind: ir.KindString, Format: "binary"}
+	case strings.Contains(name, "real"), strings.Contains(name, "floa"), strings.Contains(name, "doub"):
+		return &ir.Type{Kind: ir.KindNumber, Format: "double"}
+	default:
+		return &ir.Type{Kind: ir.KindString}
+	}
+}
+
+// ddlIsTableConstraint отличает ограничения уровня таблицы от колонок.
+// Колонка может называться key или index, поэтому KEY и INDEX считаются
+// ограничением, только если за ними идет список колонок, а не тип с параметрами.
+func ddlIsTableConstraint(tokens []ddlToken) bool {
+	if tokens[0].kind != ddlWord || !ddlTableConstraints[strings.ToLower(tokens[0].text)] {
+		return false
+	}
+	if !tokens[0].is("key") && !tokens[0].is("index") {
+		return true
+	}
+	if len(tokens) > 1 && tokens[1].isPunct("(") {
+		return true
+	}
+	return len(tokens) > 2 && tokens[2].isPunct("(") && !ddlParameterizedTypes[strings.ToLower(tokens[1].text)]
+}
+
+// ddlPrimaryKey возвращает колонки ограничения PRIMARY KEY (...) уровня таблицы.
+func ddlPrimaryKey(tokens []ddlToken) []string {
+	if tokens[0].is("constraint") && len(tokens) > 2 {
+		tokens = tokens[2:]
+	}
+	if len(tokens) < 3 || !tokens[0].is("primary") || !tokens[1].is("key") {
+		return nil
+	}
+
+	var columns []string
+	for _, token := range tokens[2:] {
+		if token.isPunct(")") {
+			break
+		}
+		if token.kind == ddlWord || token.kind == ddlQuoted {
+			columns = append(columns, token.text)
+		}
+	}
+	return columns
+}
+
+// ddlQualifiedName читает имя вида schema.table и возвращает последнюю часть.
+func ddlQualifiedName(tokens []ddlToken) (string, []ddlToken) {
+	name := ""
+	i := 0
+	for i < len(tokens) {
+		if tokens[i].kind != ddlWord && tokens[i].kind != ddlQuoted {
+			break
+		}
+		name = tokens[i].text
+		i++
+		if i < len(tokens) && tokens[i].isPunct(".") {
+			i++
+			continue
+		}
+		break
+	}
+	return name, tokens[i:]
+}
+
+// ddlSplitList делит содержимое скобок, с которых начинаются tokens, по
+// запятым верхнего уровня.
+func ddlSplitList(tokens []ddlToken) ([][]ddlToken, bool) {
+	var items [][]ddlToken
+	var current []ddlToken
+	depth := 0
+
+	for _, token := range tokens {
+		switch {
+		case token.isPunct("("):
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case token.isPunct(")"):
+			depth--
+			if depth == 0 {
+				return append(items, current), true
+			}
+		case token.isPunct(",") && depth == 1:
+			items = append(items, current)
+			current = nil
+			continue
+		}
+		current = append(current, token)
+	}
+	return nil, false
+}
+
+func ddlStrings(tokens []ddlToken) []string {
+	var values []string
+	for _, token := range tokens {
+		if token.kind == ddlString {
+			values = append(values, token.text)
+		}
+	}
+	return values
+}
+
+func splitDDLStatements(tokens []ddlToken) [][]ddlToken {
+	var statements [][]ddlToken
+	start := 0
+	for i, token := range tokens {
+		if token.isPunct(";") {
+			statements = append(statements, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		statements = append(statements, tokens[start:])
+	}
+	return statements
+}
+
+func tokenizeDDL(input string) ([]ddlToken, error) {
+	var tokens []ddlToken
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-', r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("ошибка парсинга SQL: не закрыт комментарий")
+			}
+			i += 2
+		case r == '\'' || r == '"' || r == '`':
+			text, next, ok := ddlQuotedText(runes, i)
+			if !ok {
+				return nil, fmt.Errorf("ошибка парсинга SQL: не закрыта кавычка %c", r)
+			}
+			kind := byte(ddlQuoted)
+			if r == '\'' {
+				kind = ddlString
+			}
+			tokens = append(tokens, ddlToken{kind: kind, text: text})
+			i = next
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, ddlToken{kind: ddlWord, text: string(runes[start:i])})
+		default:
+			tokens = append(tokens, ddlToken{kind: ddlPunct, text: string(r)})
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// ddlQuotedText читает текст в кавычках, начиная с позиции кавычки.
+// Удвоенная кавычка внутри означает саму кавычку.
+func ddlQuotedText(runes []rune, start int) (string, int, bool) {
+	quote := runes[start]
+	var text strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] != quote {
+			text.WriteRune(runes[i])
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			text.WriteRune(quote)
+			i++
+			continue
+		}
+		return text.String(), i + 1, true
+	}
+	return "", 0, false
+}
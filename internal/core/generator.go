@@ -1,13 +1,11 @@
 package core
 
 import (
-	"encoding/json"
 	"fmt"
 	"reflect"
-	"sort"
 	"strings"
 
-	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/JIIL07/devtoolbox/internal/ir"
 )
 
 type CodeGenerator interface {
@@ -16,9 +14,19 @@ type CodeGenerator interface {
 	GetDescription() string
 }
 
+const defaultGoPackage = "models"
+
+const (
+	nullPointer = "pointer"
+	nullSQL     = "sql"
+)
+
 type GoStructGenerator struct {
 	name        string
 	description string
+	packageName string
+	dbTags      string
+	nullTypes   string
 }
 
 func NewGoStructGenerator() *GoStructGenerator {
@@ -36,123 +44,345 @@ func (g *GoStructGenerator) GetDescription() string {
 	return g.description
 }
 
-func (g *GoStructGenerator) Generate(input string) (string, error) {
-	var data interface{}
-	if err := json.Unmarshal([]byte(input), &data); err != nil {
-		return "", fmt.Errorf("ошибка парсинга JSON: %w", err)
+func (g *GoStructGenerator) SetPackageName(name string) {
+	g.packageName = name
+}
+
+// Configure поддерживает настройки package, db и null. db=json добавляет теги db
+// для DDL из sql-* генераторов с nested=json, db=tables — с nested=tables.
+// null=sql описывает nullable поля типами sql.Null* вместо указателей.
+func (g *GoStructGenerator) Configure(options map[string]string) (CodeGenerator, error) {
+	configured := *g
+	for key, value := range options {
+		switch key {
+		case "package":
+			configured.packageName = value
+		case "db":
+			switch value {
+			case "", "false":
+				configured.dbTags = ""
+			case "true", NestedJSON:
+				configured.dbTags = NestedJSON
+			case NestedTables:
+				configured.dbTags = NestedTables
+			default:
+				return nil, invalidOption(key, value, "true", "false", NestedJSON, NestedTables)
+			}
+		case "null":
+			switch value {
+			case "", nullPointer:
+				configured.nullTypes = ""
+			case nullSQL:
+				configured.nullTypes = nullSQL
+			default:
+				return nil, invalidOption(key, value, nullPointer, nullSQL)
+			}
+		default:
+			return nil, unknownOption(g, key, "package", "db", "null")
+		}
 	}
+	return &configured, nil
+}
 
-	structName := "GeneratedStruct"
-	code, err := g.generateStruct(structName, data)
+func (g *GoStructGenerator) Generate(input string) (string, error) {
+	doc, err := ParseInput(input, FormatAuto)
 	if err != nil {
-		return "", fmt.Errorf("ошибка генерации структуры: %w", err)
+		return "", err
 	}
 
-	return code, nil
+	return g.GenerateDocument(doc)
 }
 
-func (g *GoStructGenerator) generateStruct(structName string, data interface{}) (string, error) {
+func (g *GoStructGenerator) GenerateDocument(doc *ir.Document) (string, error) {
 	var builder strings.Builder
-	var nestedStructs []string
+	tables := g.dbTables(doc)
 
-	builder.WriteString(fmt.Sprintf("type %s struct {\n", structName))
+	if g.packageName != "" {
+		builder.WriteString(g.fileHeader(g.packageName, doc.Models, tables))
+	}
 
-	switch v := data.(type) {
-	case map[string]interface{}:
-		keys := make([]string, 0, len(v))
-		for k := range v {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-
-		for _, key := range keys {
-			value := v[key]
-			fieldName := g.ToPascalCase(key)
-			fieldType := g.GetGoType(value)
-			jsonTag := fmt.Sprintf("`json:\"%s\"`", key)
-
-			if nestedMap, ok := value.(map[string]interface{}); ok {
-				nestedStructName := g.ToPascalCase(key)
-				builder.WriteString(fmt.Sprintf("\t%s %s %s\n", fieldName, nestedStructName, jsonTag))
-
-				nestedStruct, err := g.generateStruct(nestedStructName, nestedMap)
-				if err != nil {
-					return "", err
-				}
-				nestedStructs = append(nestedStructs, nestedStruct)
-			} else if slice, ok := value.([]interface{}); ok && len(slice) > 0 {
-				elementType := g.GetGoType(slice[0])
-				if nestedMap, ok := slice[0].(map[string]interface{}); ok {
-					nestedStructName := g.ToPascalCase(key)
-					builder.WriteString(fmt.Sprintf("\t%s []%s %s\n", fieldName, nestedStructName, jsonTag))
-
-					nestedStruct, err := g.generateStruct(nestedStructName, nestedMap)
-					if err != nil {
-						return "", err
-					}
-					nestedStructs = append(nestedStructs, nestedStruct)
-				} else {
-					builder.WriteString(fmt.Sprintf("\t%s []%s %s\n", fieldName, elementType, jsonTag))
-				}
-			} else {
-				builder.WriteString(fmt.Sprintf("\t%s %s %s\n", fieldName, fieldType, jsonTag))
+	for i, model := range doc.Models {
+		code, err := g.generateModel(doc, model, tables)
+		if err != nil {
+			return "", fmt.Errorf("ошибка генерации структуры: %w", err)
+		}
+		if i > 0 {
+			builder.WriteString("\n\n")
+		}
+		builder.WriteString(code)
+	}
+
+	return builder.String(), nil
+}
+
+func (g *GoStructGenerator) GenerateFiles(doc *ir.Document) ([]GeneratedFile, error) {
+	packageName := g.packageName
+	if packageName == "" {
+		packageName = defaultGoPackage
+	}
+
+	tables := g.dbTables(doc)
+	files := make([]GeneratedFile, 0, len(doc.Models))
+	for _, model := range doc.Models {
+		code, err := g.generateModel(doc, model, tables)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка генерации структуры: %w", err)
+		}
+		files = append(files, GeneratedFile{
+			Name:    ToSnakeCase(model.Name) + ".go",
+			Content: g.fileHeader(packageName, []*ir.Model{model}, tables) + code + "\n",
+		})
+	}
+
+	return files, nil
+}
+
+func (g *GoStructGenerator) fileHeader(packageName string, models []*ir.Model, tables map[string]*sqlTable) string {
+	header := fmt.Sprintf("package %s\n\n", packageName)
+
+	imports := make(map[string]bool)
+	for _, model := range models {
+		g.collectImports(model.Type, imports)
+		if table := tables[model.Name]; table != nil {
+			for _, column := range table.keyColumns() {
+				g.collectImports(column.key, imports)
 			}
 		}
+	}
+
+	var packages []string
+	for _, path := range []string{"database/sql", "encoding/json", "time"} {
+		if imports[path] {
+			packages = append(packages, path)
+		}
+	}
+
+	switch len(packages) {
+	case 0:
+		return header
+	case 1:
+		return header + fmt.Sprintf("import %q\n\n", packages[0])
 	default:
-		return "", fmt.Errorf("неподдерживаемый тип данных: %T", data)
+		var builder strings.Builder
+		builder.WriteString("import (\n")
+		for _, path := range packages {
+			builder.WriteString(fmt.Sprintf("\t%q\n", path))
+		}
+		builder.WriteString(")\n\n")
+		return header + builder.String()
 	}
+}
 
-	builder.WriteString("}")
+func (g *GoStructGenerator) collectImports(t *ir.Type, imports map[string]bool) {
+	if t == nil {
+		return
+	}
+
+	goType := g.goType(t)
+	switch {
+	case strings.Contains(goType, "sql."):
+		imports["database/sql"] = true
+	case strings.Contains(goType, "json."):
+		imports["encoding/json"] = true
+	case strings.Contains(goType, "time."):
+		imports["time"] = true
+	}
+
+	g.collectImports(t.Elem, imports)
+	for _, field := range t.Fields {
+		g.collectImports(field.Type, imports)
+	}
+}
+
+func (g *GoStructGenerator) generateModel(doc *ir.Document, model *ir.Model, tables map[string]*sqlTable) (string, error) {
+	var builder strings.Builder
+
+	if model.Description != "" {
+		builder.WriteString(fmt.Sprintf("// %s %s\n", model.Name, strings.Join(strings.Fields(model.Description), " ")))
+	}
+
+	if model.Type == nil {
+		return "", fmt.Errorf("у модели %s не задан тип", model.Name)
+	}
+
+	if model.Type.Kind != ir.KindObject {
+		builder.WriteString(fmt.Sprintf("type %s %s", model.Name, g.goType(model.Type)))
+		return builder.String(), nil
+	}
 
-	if len(nestedStructs) > 0 {
-		builder.WriteString("\n\n")
-		for _, nested := range nestedStructs {
-			builder.WriteString(nested)
-			builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf("type %s struct {\n", model.Name))
+
+	fieldNames := make(map[string]bool)
+	for _, field := range model.Type.Fields {
+		fieldName := g.ToPascalCase(field.Name)
+		if fieldName == "" {
+			fieldName = "Field"
+		}
+		fieldNames[fieldName] = true
+
+		fieldType := g.goType(field.Type)
+		if field.Type.Kind == ir.KindRef && field.Type.Ref == model.Name && !strings.HasPrefix(fieldType, "*") {
+			fieldType = "*" + fieldType
+		}
+
+		tag := field.Name
+		if !field.Required {
+			tag += ",omitempty"
+		}
+		tags := fmt.Sprintf("json:\"%s\"", tag)
+		switch InputFormat(doc.Format) {
+		case FormatXML:
+			tags += fmt.Sprintf(" xml:\"%s\"", xmlTag(field))
+		case FormatCSV:
+			tags += fmt.Sprintf(" csv:\"%s\"", field.Name)
+		}
+		if InputFormat(doc.Format) == FormatSQL {
+			tags += fmt.Sprintf(" db:\"%s\"", field.Name)
+		} else if g.dbTags != "" {
+			tags += fmt.Sprintf(" db:\"%s\"", dbTag(tables[model.Name], field))
 		}
+
+		builder.WriteString(fmt.Sprintf("\t%s %s `%s`\n", fieldName, fieldType, tags))
 	}
 
+	// Колонки ключей есть только в DDL, в JSON они не попадают.
+	if table := tables[model.Name]; table != nil {
+		for _, column := range table.keyColumns() {
+			fieldName := g.ToPascalCase(column.name)
+			if fieldNames[fieldName] {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\t%s %s `json:\"-\" db:\"%s\"`\n", fieldName, g.goType(column.key), column.name))
+		}
+	}
+
+	builder.WriteString("}")
+
 	return builder.String(), nil
 }
 
-func (g *GoStructGenerator) ToPascalCase(s string) string {
-	if s == "" {
-		return ""
-	}
-
-	var parts []string
-	var current strings.Builder
-	
-	for i, r := range s {
-		if r == '_' || r == '-' || r == ' ' {
-			if current.Len() > 0 {
-				parts = append(parts, current.String())
-				current.Reset()
-			}
-		} else if i > 0 && r >= 'A' && r <= 'Z' {
-			if current.Len() > 0 {
-				parts = append(parts, current.String())
-				current.Reset()
-			}
-			current.WriteRune(r)
+// dbTables раскладывает модели по таблицам так же, как sql-* генераторы с
+// nested, равным настройке db, чтобы теги db совпадали с колонками DDL.
+func (g *GoStructGenerator) dbTables(doc *ir.Document) map[string]*sqlTable {
+	if g.dbTags == "" || InputFormat(doc.Format) == FormatSQL {
+		return nil
+	}
+	planner := &sqlPlanner{
+		generator: &SQLGenerator{dialect: DialectPostgres, nested: g.dbTags},
+		doc:       doc,
+		tables:    make(map[string]*sqlTable),
+	}
+	if _, err := planner.plan(); err != nil {
+		return nil
+	}
+	return planner.tables
+}
+
+// dbTag возвращает колонку поля в таблице модели. Поля, которые хранятся в
+// других таблицах, например вложенные объекты при nested=tables, получают
+// тег "-". Модели без своей таблицы сохраняют имена колонок по полям.
+func dbTag(table *sqlTable, field *ir.Field) string {
+	if table == nil {
+		return SQLColumnName(field.Name)
+	}
+	if column, ok := table.fields[field.Name]; ok {
+		return column
+	}
+	return "-"
+}
+
+func xmlTag(field *ir.Field) string {
+	switch field.XML {
+	case ir.XMLAttr:
+		return field.Name + ",attr"
+	case ir.XMLCharData:
+		return ",chardata"
+	default:
+		return field.Name
+	}
+}
+
+func (g *GoStructGenerator) goType(t *ir.Type) string {
+	if t == nil {
+		return "interface{}"
+	}
+
+	if t.Nullable && g.nullTypes == nullSQL {
+		if nullType := sqlNullType(t); nullType != "" {
+			return nullType
+		}
+	}
+
+	var goType string
+	switch t.Kind {
+	case ir.KindString:
+		if t.Format == "binary" {
+			return "[]byte"
+		}
+		goType = "string"
+	case ir.KindInteger:
+		switch t.Format {
+		case "int32", "int64":
+			goType = t.Format
+		default:
+			goType = "int"
+		}
+	case ir.KindNumber:
+		if t.Format == "float" {
+			goType = "float32"
 		} else {
-			current.WriteRune(r)
+			goType = "float64"
 		}
+	case ir.KindBoolean:
+		goType = "bool"
+	case ir.KindTime:
+		goType = "time.Time"
+	case ir.KindArray:
+		return "[]" + g.goType(t.Elem)
+	case ir.KindMap:
+		return "map[string]" + g.goType(t.Elem)
+	case ir.KindObject:
+		return "map[string]interface{}"
+	case ir.KindRef:
+		goType = t.Ref
+	default:
+		if t.Format == "json" {
+			return "json.RawMessage"
+		}
+		return "interface{}"
 	}
-	
-	if current.Len() > 0 {
-		parts = append(parts, current.String())
+
+	if t.Nullable {
+		return "*" + goType
 	}
+	return goType
+}
 
-	var result strings.Builder
-	for _, part := range parts {
-		if part == "" {
-			continue
+// sqlNullType возвращает подходящий тип sql.Null* или пустую строку, если
+// такого нет и поле остается указателем.
+func sqlNullType(t *ir.Type) string {
+	switch t.Kind {
+	case ir.KindString:
+		if t.Format != "binary" {
+			return "sql.NullString"
 		}
-		result.WriteString(strings.ToUpper(part[:1]) + strings.ToLower(part[1:]))
+	case ir.KindInteger:
+		if t.Format == "int32" {
+			return "sql.NullInt32"
+		}
+		return "sql.NullInt64"
+	case ir.KindNumber:
+		return "sql.NullFloat64"
+	case ir.KindBoolean:
+		return "sql.NullBool"
+	case ir.KindTime:
+		return "sql.NullTime"
 	}
+	return ""
+}
 
-	return result.String()
+func (g *GoStructGenerator) ToPascalCase(s string) string {
+	return ToPascalCase(s)
 }
 
 func (g *GoStructGenerator) GetGoType(value interface{}) string {
@@ -182,50 +412,3 @@ func (g *GoStructGenerator) GetGoType(value interface{}) string {
 		return reflect.TypeOf(value).String()
 	}
 }
-
-type GeneratorRegistry struct {
-	generators map[string]CodeGenerator
-}
-
-func NewGeneratorRegistry() *GeneratorRegistry {
-	registry := &GeneratorRegistry{
-		generators: make(map[string]CodeGenerator),
-	}
-	
-	registry.Register(NewGoStructGenerator())
-	
-	loader := plugins.NewPythonPluginLoader("plugins")
-	pythonPlugins, err := loader.LoadOfficialPlugins()
-	if err == nil {
-		for _, plugin := range pythonPlugins {
-			registry.Register(plugin)
-		}
-	}
-	
-	return registry
-}
-
-func (r *GeneratorRegistry) Register(generator CodeGenerator) {
-	r.generators[generator.GetName()] = generator
-}
-
-func (r *GeneratorRegistry) Get(name string) (CodeGenerator, bool) {
-	generator, exists := r.generators[name]
-	return generator, exists
-}
-
-func (r *GeneratorRegistry) List() []CodeGenerator {
-	generators := make([]CodeGenerator, 0, len(r.generators))
-	for _, generator := range r.generators {
-		generators = append(generators, generator)
-	}
-	return generators
-}
-
-func (r *GeneratorRegistry) GetNames() []string {
-	names := make([]string, 0, len(r.generators))
-	for name := range r.generators {
-		names = append(names, name)
-	}
-	return names
-}
\ No newline at end of file
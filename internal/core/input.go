@@ -0,0 +1,453 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+type InputFormat string
+
+const (
+	FormatAuto    InputFormat = ""
+	FormatJSON    InputFormat = "json"
+	FormatYAML    InputFormat = "yaml"
+	FormatTOML    InputFormat = "toml"
+	FormatXML     InputFormat = "xml"
+	FormatCSV     InputFormat = "csv"
+	FormatNDJSON  InputFormat = "ndjson"
+	FormatOpenAPI InputFormat = "openapi"
+	FormatSQL     InputFormat = "sql"
+)
+
+var inputFormats = []InputFormat{FormatJSON, FormatYAML, FormatTOML, FormatXML, FormatCSV, FormatNDJSON, FormatOpenAPI, FormatSQL}
+
+type GeneratedFile struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// DocumentGenerator реализуют генераторы, умеющие работать с моделью типов
+// напрямую, а не только с JSON примером.
+type DocumentGenerator interface {
+	CodeGenerator
+	GenerateDocument(doc *ir.Document) (string, error)
+}
+
+// FileGenerator реализуют генераторы, умеющие раскладывать модели по файлам.
+type FileGenerator interface {
+	DocumentGenerator
+	GenerateFiles(doc *ir.Document) ([]GeneratedFile, error)
+}
+
+// FormatRestricted реализуют генераторы, принимающие только часть форматов,
+// например плагины, перечислившие input_formats в манифесте.
+type FormatRestricted interface {
+	InputFormats() []string
+}
+
+// ContextGenerator реализуют генераторы, работу которых можно прервать,
+// например внешние плагины.
+type ContextGenerator interface {
+	CodeGenerator
+	GenerateContext(ctx context.Context, input string) (string, error)
+}
+
+func ParseFormat(name string) (InputFormat, error) {
+	if name == "" || name == "auto" {
+		return FormatAuto, nil
+	}
+	for _, format := range inputFormats {
+		if string(format) == strings.ToLower(name) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("неизвестный формат входных данных: %s", name)
+}
+
+func FormatNames() []string {
+	names := make([]string, len(inputFormats))
+	for i, format := range inputFormats {
+		names[i] = string(format)
+	}
+	return names
+}
+
+func FormatFromExtension(path string) InputFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".xml", ".wsdl", ".xsd":
+		return FormatXML
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".sql", ".ddl":
+		return FormatSQL
+	default:
+		return FormatAuto
+	}
+}
+
+// DetectFormat определяет формат по содержимому. Текст, начинающийся с
+// { или [, не проверяется парсером YAML, чтобы ошибки синтаксиса JSON не
+// маскировались более снисходительным разбором.
+func DetectFormat(input string) InputFormat {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return FormatJSON
+	}
+	if strings.HasPrefix(trimmed, "<") {
+		return FormatXML
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		var probe map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &probe); err == nil {
+			if _, ok := probe["openapi"]; ok {
+				return FormatOpenAPI
+			}
+			return FormatJSON
+		}
+		if firstLine, rest, ok := strings.Cut(trimmed, "\n"); ok && json.Valid([]byte(firstLine)) && strings.TrimSpace(rest) != "" {
+			return FormatNDJSON
+		}
+		return FormatJSON
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		var tomlProbe map[string]interface{}
+		if !json.Valid([]byte(trimmed)) && toml.Unmarshal([]byte(input), &tomlProbe) == nil {
+			return FormatTOML
+		}
+		return FormatJSON
+	}
+
+	if isDDL(input) {
+		return FormatSQL
+	}
+
+	var probe interface{}
+	if err := yaml.Unmarshal([]byte(input), &probe); err == nil {
+		if object, ok := probe.(map[string]interface{}); ok {
+			if _, ok := object["openapi"]; ok {
+				return FormatOpenAPI
+			}
+			return FormatYAML
+		}
+		if _, ok := probe.([]interface{}); ok {
+			return FormatYAML
+		}
+	}
+
+	var tomlProbe map[string]interface{}
+	if err := toml.Unmarshal([]byte(input), &tomlProbe); err == nil {
+		return FormatTOML
+	}
+
+	return FormatJSON
+}
+
+// DecodeSample разбирает пример данных в одном из форматов-образцов и
+// возвращает значение в том виде, который дает json.Unmarshal.
+func DecodeSample(input string, format InputFormat) (interface{}, error) {
+	var data interface{}
+
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal([]byte(input), &data); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга JSON: %w", err)
+		}
+		return data, nil
+	case FormatYAML:
+		if err := yaml.Unmarshal([]byte(input), &data); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга YAML: %w", err)
+		}
+		return normalizeValue(data), nil
+	case FormatTOML:
+		var table map[string]interface{}
+		if err := toml.Unmarshal([]byte(input), &table); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга TOML: %w", err)
+		}
+		return normalizeValue(table), nil
+	case FormatXML:
+		_, data, err := decodeXML(input)
+		return data, err
+	default:
+		return nil, fmt.Errorf("формат %s не является примером данных", format)
+	}
+}
+
+// ConvertToJSON переводит пример данных в JSON, чтобы его могли принять
+// генераторы, работающие только с JSON. Для табличных форматов строится
+// одна запись-образец по выведенным типам колонок.
+func ConvertToJSON(input string, format InputFormat) (string, error) {
+	if format == FormatAuto {
+		format = DetectFormat(input)
+	}
+	if format == FormatJSON {
+		return input, nil
+	}
+
+	if IsStreamFormat(format) {
+		doc, err := ParseReader(strings.NewReader(input), format)
+		if err != nil {
+			return "", err
+		}
+		return sampleJSON(doc)
+	}
+
+	data, err := DecodeSample(input, format)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("ошибка преобразования в JSON: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func ParseInput(input string, format InputFormat) (*ir.Document, error) {
+	if format == FormatAuto {
+		format = DetectFormat(input)
+	}
+
+	switch format {
+	case FormatJSON, FormatYAML, FormatTOML:
+		data, err := DecodeSample(input, format)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := DocumentFromSample(defaultModelName, data)
+		if err != nil {
+			return nil, err
+		}
+		doc.Format = string(format)
+		return doc, nil
+	case FormatXML:
+		return documentFromXML(input)
+	case FormatCSV, FormatNDJSON:
+		return ParseReader(strings.NewReader(input), format)
+	case FormatOpenAPI:
+		return ParseOpenAPI(input)
+	case FormatSQL:
+		return ParseSQL(input)
+	default:
+		return nil, fmt.Errorf("неизвестный формат входных данных: %s", format)
+	}
+}
+
+// IsStreamFormat сообщает, можно ли читать формат потоково через ParseReader.
+func IsStreamFormat(format InputFormat) bool {
+	return format == FormatCSV || format == FormatNDJSON
+}
+
+func ParseReader(r io.Reader, format InputFormat) (*ir.Document, error) {
+	switch format {
+	case FormatCSV:
+		return InferCSV(r)
+	case FormatNDJSON:
+		return InferNDJSON(r)
+	default:
+		return nil, fmt.Errorf("формат %s не поддерживает потоковое чтение", format)
+	}
+}
+
+// GenerateWithFormat передает JSON генератору как есть. Остальные форматы
+// генераторы моделей получают в виде модели типов, а JSON-генераторы —
+// примеры данных, переведенные в JSON.
+func GenerateWithFormat(ctx context.Context, generator CodeGenerator, input string, format InputFormat) (string, error) {
+	if pipeline, ok := generator.(*Pipeline); ok {
+		output, _, err := pipeline.run(ctx, input, nil, format, false)
+		return output, err
+	}
+	if format == FormatAuto {
+		format = DetectFormat(input)
+	}
+	if err := checkInputFormat(generator, format); err != nil {
+		return "", err
+	}
+	if plugin, ok := StructuredPlugin(generator); ok {
+		response, err := InvokePlugin(ctx, plugin, input, format)
+		if err != nil {
+			return "", err
+		}
+		return response.Output(), nil
+	}
+	if format == FormatJSON {
+		return generate(ctx, generator, input)
+	}
+
+	if _, ok := generator.(DocumentGenerator); !ok {
+		if format == FormatOpenAPI || format == FormatSQL {
+			return "", fmt.Errorf("генератор %s не поддерживает формат %s", generator.GetName(), format)
+		}
+		converted, err := ConvertToJSON(input, format)
+		if err != nil {
+			return "", err
+		}
+		return generate(ctx, generator, converted)
+	}
+
+	doc, err := ParseInput(input, format)
+	if err != nil {
+		return "", err
+	}
+	return GenerateFromDocument(ctx, generator, doc)
+}
+
+// GenerateFromDocument передает модель типов генератору моделей, а
+// JSON-генераторам — запись-образец, построенную по модели.
+func GenerateFromDocument(ctx context.Context, generator CodeGenerator, doc *ir.Document) (string, error) {
+	if pipeline, ok := generator.(*Pipeline); ok {
+		output, _, err := pipeline.run(ctx, "", doc, FormatAuto, false)
+		return output, err
+	}
+	if err := checkInputFormat(generator, InputFormat(doc.Format)); err != nil {
+		return "", err
+	}
+	if plugin, ok := StructuredPlugin(generator); ok {
+		response, err := InvokePluginDocument(ctx, plugin, doc)
+		if err != nil {
+			return "", err
+		}
+		return response.Output(), nil
+	}
+
+	if docGenerator, ok := generator.(DocumentGenerator); ok {
+		return docGenerator.GenerateDocument(doc)
+	}
+
+	sample, err := sampleJSON(doc)
+	if err != nil {
+		return "", err
+	}
+	return generate(ctx, generator, sample)
+}
+
+func generate(ctx context.Context, generator CodeGenerator, input string) (string, error) {
+	if contextGenerator, ok := generator.(ContextGenerator); ok {
+		return contextGenerator.GenerateContext(ctx, input)
+	}
+	return generator.Generate(input)
+}
+
+func GenerateFiles(ctx context.Context, generator CodeGenerator, input string, format InputFormat) ([]GeneratedFile, error) {
+	if pipeline, ok := generator.(*Pipeline); ok {
+		_, files, err := pipeline.run(ctx, input, nil, format, true)
+		return files, err
+	}
+	if plugin, ok := StructuredPlugin(generator); ok {
+		response, err := InvokePlugin(ctx, plugin, input, format)
+		if err != nil {
+			return nil, err
+		}
+		return PluginFiles(plugin, response)
+	}
+
+	if _, ok := generator.(FileGenerator); !ok {
+		return nil, fmt.Errorf("генератор %s не поддерживает вывод в несколько файлов", generator.GetName())
+	}
+
+	doc, err := ParseInput(input, format)
+	if err != nil {
+		return nil, err
+	}
+	return GenerateDocumentFiles(ctx, generator, doc)
+}
+
+func GenerateDocumentFiles(ctx context.Context, generator CodeGenerator, doc *ir.Document) ([]GeneratedFile, error) {
+	if pipeline, ok := generator.(*Pipeline); ok {
+		_, files, err := pipeline.run(ctx, "", doc, FormatAuto, true)
+		return files, err
+	}
+	if plugin, ok := StructuredPlugin(generator); ok {
+		response, err := InvokePluginDocument(ctx, plugin, doc)
+		if err != nil {
+			return nil, err
+		}
+		return PluginFiles(plugin, response)
+	}
+
+	fileGenerator, ok := generator.(FileGenerator)
+	if !ok {
+		return nil, fmt.Errorf("генератор %s не поддерживает вывод в несколько файлов", generator.GetName())
+	}
+	return fileGenerator.GenerateFiles(doc)
+}
+
+func checkInputFormat(generator CodeGenerator, format InputFormat) error {
+	restricted, ok := generator.(FormatRestricted)
+	if !ok || format == FormatAuto {
+		return nil
+	}
+
+	formats := restricted.InputFormats()
+	if len(formats) == 0 {
+		return nil
+	}
+	for _, name := range formats {
+		if strings.EqualFold(name, string(format)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("генератор %s не поддерживает формат %s, поддерживаются: %s", generator.GetName(), format, strings.Join(formats, ", "))
+}
+
+func sampleJSON(doc *ir.Document) (string, error) {
+	sample, err := SampleFromDocument(doc)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.MarshalIndent(sample, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("ошибка преобразования в JSON: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// normalizeValue приводит результат yaml.Unmarshal и toml.Unmarshal к виду,
+// который дает json.Unmarshal: ключи-строки, числа float64, даты строками.
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return result
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
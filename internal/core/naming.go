@@ -0,0 +1,15 @@
+package core
+
+import "github.com/JIIL07/devtoolbox/internal/naming"
+
+func ToPascalCase(s string) string {
+	return naming.ToPascalCase(s)
+}
+
+func ToCamelCase(s string) string {
+	return naming.ToCamelCase(s)
+}
+
+func ToSnakeCase(s string) string {
+	return naming.ToSnakeCase(s)
+}
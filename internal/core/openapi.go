@@ -0,0 +1,526 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"gopkg.in/yaml.v3"
+)
+
+const componentSchemasPrefix = "#/components/schemas/"
+
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type openAPIConverter struct {
+	root      map[string]interface{}
+	schemas   map[string]interface{}
+	doc       *ir.Document
+	reserved  map[string]bool
+	resolving map[string]bool
+}
+
+// ParseOpenAPI строит модель типов по OpenAPI 3.0/3.1 документу в YAML или JSON:
+// по модели на каждую схему из components.schemas и типы запросов и ответов
+// для каждой операции.
+func ParseOpenAPI(input string) (*ir.Document, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(input), &raw); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга OpenAPI документа: %w", err)
+	}
+
+	root, ok := normalizeValue(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("OpenAPI документ должен быть объектом")
+	}
+
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("поддерживается только OpenAPI 3.x, получено: %q", version)
+	}
+
+	components, _ := root["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+
+	c := &openAPIConverter{
+		root:      root,
+		schemas:   schemas,
+		doc:       ir.NewDocument(),
+		reserved:  make(map[string]bool),
+		resolving: make(map[string]bool),
+	}
+
+	if err := c.convertSchemas(); err != nil {
+		return nil, err
+	}
+	if err := c.convertOperations(); err != nil {
+		return nil, err
+	}
+
+	return c.doc, nil
+}
+
+func (c *openAPIConverter) convertSchemas() error {
+	names := sortedKeys(c.schemas)
+	for _, name := range names {
+		c.reserved[ToPascalCase(name)] = true
+	}
+
+	for _, name := range names {
+		schema, ok := c.schemas[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("схема %s должна быть объектом", name)
+		}
+		if _, err := c.addModel(ToPascalCase(name), schema, stringValue(schema, "description")); err != nil {
+			return fmt.Errorf("схема %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *openAPIConverter) convertOperations() error {
+	paths, _ := c.root["paths"].(map[string]interface{})
+
+	for _, path := range sortedKeys(paths) {
+		item, err := c.resolveObject(paths[path])
+		if err != nil {
+			return fmt.Errorf("путь %s: %w", path, err)
+		}
+		shared, _ := item["parameters"].([]interface{})
+
+		for _, method := range operationMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			baseName := ToPascalCase(stringValue(operation, "operationId"))
+			if baseName == "" {
+				baseName = ToPascalCase(method + " " + path)
+			}
+
+			if err := c.convertOperation(baseName, operation, shared); err != nil {
+				return fmt.Errorf("операция %s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *openAPIConverter) convertOperation(baseName string, operation map[string]interface{}, shared []interface{}) error {
+	summary := stringValue(operation, "summary")
+	parameters := append(append([]interface{}{}, shared...), sliceValue(operation, "parameters")...)
+
+	if len(parameters) > 0 {
+		name := c.reserve(baseName + "Params")
+		object := &ir.Type{Kind: ir.KindObject}
+		c.doc.Add(&ir.Model{Name: name, Description: summary, Role: ir.RoleParams, Type: object})
+		seen := make(map[string]bool)
+
+		for _, rawParameter := range parameters {
+			parameter, err := c.resolveObject(rawParameter)
+			if err != nil {
+				return err
+			}
+
+			paramName := stringValue(parameter, "name")
+			if paramName == "" || seen[paramName] {
+				continue
+			}
+			seen[paramName] = true
+
+			schema, _ := parameter["schema"].(map[string]interface{})
+			paramType, err := c.schemaType(schema, name+ToPascalCase(paramName))
+			if err != nil {
+				return fmt.Errorf("параметр %s: %w", paramName, err)
+			}
+
+			required, _ := parameter["required"].(bool)
+			object.Fields = append(object.Fields, &ir.Field{
+				Name:        paramName,
+				Type:        paramType,
+				Required:    required || parameter["in"] == "path",
+				Description: stringValue(parameter, "description"),
+			})
+		}
+	}
+
+	if rawBody, ok := operation["requestBody"]; ok {
+		body, err := c.resolveObject(rawBody)
+		if err != nil {
+			return err
+		}
+		if schema := contentSchema(body); schema != nil {
+			model, err := c.addModel(c.reserve(baseName+"Request"), schema, summary)
+			if err != nil {
+				return fmt.Errorf("тело запроса: %w", err)
+			}
+			model.Role = ir.RoleRequest
+		}
+	}
+
+	responses, _ := operation["responses"].(map[string]interface{})
+	if code, ok := successResponseCode(responses); ok {
+		response, err := c.resolveObject(responses[code])
+		if err != nil {
+			return err
+		}
+		if schema := contentSchema(response); schema != nil {
+			model, err := c.addModel(c.reserve(baseName+"Response"), schema, summary)
+			if err != nil {
+				return fmt.Errorf("ответ %s: %w", code, err)
+			}
+			model.Role = ir.RoleResponse
+		}
+	}
+
+	return nil
+}
+
+func (c *openAPIConverter) addModel(name string, schema map[string]interface{}, description string) (*ir.Model, error) {
+	model := &ir.Model{Name: name, Description: description}
+	c.doc.Add(model)
+
+	var err error
+	if isObjectSchema(schema) && schema["$ref"] == nil {
+		model.Type, err = c.objectType(schema, name)
+	} else {
+		model.Type, err = c.schemaType(schema, name)
+	}
+	return model, err
+}
+
+func (c *openAPIConverter) schemaType(schema map[string]interface{}, hint string) (*ir.Type, error) {
+	if schema == nil {
+		return ir.Primitive(ir.KindAny), nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		return c.refType(ref, hint)
+	}
+
+	if allOf := sliceValue(schema, "allOf"); len(allOf) > 0 {
+		if len(allOf) == 1 {
+			member, _ := allOf[0].(map[string]interface{})
+			return c.schemaType(member, hint)
+		}
+		return c.hoist(schema, hint)
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants := sliceValue(schema, key); len(variants) > 0 {
+			return c.variantType(variants, hint)
+		}
+	}
+
+	kind, nullable := schemaKind(schema)
+
+	var result *ir.Type
+	switch kind {
+	case "string":
+		format := stringValue(schema, "format")
+		if format == "date-time" {
+			result = &ir.Type{Kind: ir.KindTime, Format: format}
+		} else {
+			result = &ir.Type{Kind: ir.KindString, Format: format}
+		}
+	case "integer":
+		result = &ir.Type{Kind: ir.KindInteger, Format: stringValue(schema, "format")}
+	case "number":
+		result = &ir.Type{Kind: ir.KindNumber, Format: stringValue(schema, "format")}
+	case "boolean":
+		result = ir.Primitive(ir.KindBoolean)
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		elem, err := c.schemaType(items, hint+"Item")
+		if err != nil {
+			return nil, err
+		}
+		result = ir.ArrayOf(elem)
+	case "object":
+		if _, ok := schema["properties"]; ok {
+			return c.hoist(schema, hint)
+		}
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		elem, err := c.schemaType(additional, hint+"Value")
+		if err != nil {
+			return nil, err
+		}
+		result = ir.MapOf(elem)
+	default:
+		result = ir.Primitive(ir.KindAny)
+	}
+
+	result.Nullable = nullable
+	for _, value := range sliceValue(schema, "enum") {
+		if value != nil {
+			result.Enum = append(result.Enum, fmt.Sprint(value))
+		}
+	}
+
+	return result, nil
+}
+
+func (c *openAPIConverter) objectType(schema map[string]interface{}, name string) (*ir.Type, error) {
+	object := &ir.Type{Kind: ir.KindObject}
+	properties := make(map[string]interface{})
+	required := make(map[string]bool)
+
+	if err := c.collectProperties(schema, properties, required); err != nil {
+		return nil, err
+	}
+
+	for _, key := range sortedKeys(properties) {
+		property, _ := properties[key].(map[string]interface{})
+		fieldType, err := c.schemaType(property, name+ToPascalCase(key))
+		if err != nil {
+			return nil, fmt.Errorf("свойство %s: %w", key, err)
+		}
+		object.Fields = append(object.Fields, &ir.Field{
+			Name:        key,
+			Type:        fieldType,
+			Required:    required[key],
+			Description: stringValue(property, "description"),
+		})
+	}
+
+	_, object.Nullable = schemaKind(schema)
+	return object, nil
+}
+
+func (c *openAPIConverter) collectProperties(schema map[string]interface{}, properties map[string]interface{}, required map[string]bool) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		if c.resolving[ref] {
+			return fmt.Errorf("циклическая ссылка в allOf: %s", ref)
+		}
+		target, err := c.resolveRef(ref)
+		if err != nil {
+			return err
+		}
+		c.resolving[ref] = true
+		defer delete(c.resolving, ref)
+		return c.collectProperties(target, properties, required)
+	}
+
+	for _, rawMember := range sliceValue(schema, "allOf") {
+		member, _ := rawMember.(map[string]interface{})
+		if err := c.collectProperties(member, properties, required); err != nil {
+			return err
+		}
+	}
+
+	own, _ := schema["properties"].(map[string]interface{})
+	for key, property := range own {
+		properties[key] = property
+	}
+	for _, key := range sliceValue(schema, "required") {
+		if name, ok := key.(string); ok {
+			required[name] = true
+		}
+	}
+
+	return nil
+}
+
+func (c *openAPIConverter) variantType(variants []interface{}, hint string) (*ir.Type, error) {
+	var members []map[string]interface{}
+	nullable := false
+
+	for _, rawVariant := range variants {
+		variant, _ := rawVariant.(map[string]interface{})
+		if kind, _ := schemaKind(variant); kind == "null" {
+			nullable = true
+			continue
+		}
+		members = append(members, variant)
+	}
+
+	if len(members) != 1 {
+		return &ir.Type{Kind: ir.KindAny, Nullable: nullable}, nil
+	}
+
+	result, err := c.schemaType(members[0], hint)
+	if err != nil {
+		return nil, err
+	}
+	result.Nullable = result.Nullable || nullable
+	return result, nil
+}
+
+func (c *openAPIConverter) hoist(schema map[string]interface{}, hint string) (*ir.Type, error) {
+	name := c.reserve(hint)
+	if _, err := c.addModel(name, schema, stringValue(schema, "description")); err != nil {
+		return nil, err
+	}
+	_, nullable := schemaKind(schema)
+	return &ir.Type{Kind: ir.KindRef, Ref: name, Nullable: nullable}, nil
+}
+
+func (c *openAPIConverter) refType(ref, hint string) (*ir.Type, error) {
+	if strings.HasPrefix(ref, componentSchemasPrefix) {
+		name := unescapePointer(strings.TrimPrefix(ref, componentSchemasPrefix))
+		if !strings.Contains(name, "/") {
+			if _, ok := c.schemas[name]; !ok {
+				return nil, fmt.Errorf("схема не найдена: %s", ref)
+			}
+			return ir.Ref(ToPascalCase(name)), nil
+		}
+	}
+
+	if c.resolving[ref] {
+		return nil, fmt.Errorf("циклическая ссылка: %s", ref)
+	}
+	target, err := c.resolveRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	c.resolving[ref] = true
+	defer delete(c.resolving, ref)
+	return c.schemaType(target, hint)
+}
+
+func (c *openAPIConverter) resolveObject(value interface{}) (map[string]interface{}, error) {
+	object, _ := value.(map[string]interface{})
+	for depth := 0; object != nil; depth++ {
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object, nil
+		}
+		if depth > 32 {
+			return nil, fmt.Errorf("слишком глубокая цепочка ссылок: %s", ref)
+		}
+		target, err := c.resolveRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		object = target
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (c *openAPIConverter) resolveRef(ref string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("внешние ссылки не поддерживаются: %s", ref)
+	}
+
+	var current interface{} = c.root
+	for _, segment := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("не удалось разрешить ссылку: %s", ref)
+		}
+		if current, ok = object[unescapePointer(segment)]; !ok {
+			return nil, fmt.Errorf("не удалось разрешить ссылку: %s", ref)
+		}
+	}
+
+	target, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ссылка указывает не на объект: %s", ref)
+	}
+	return target, nil
+}
+
+func (c *openAPIConverter) reserve(base string) string {
+	name := base
+	for i := 2; c.reserved[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	c.reserved[name] = true
+	return name
+}
+
+func schemaKind(schema map[string]interface{}) (string, bool) {
+	nullable, _ := schema["nullable"].(bool)
+
+	switch t := schema["type"].(type) {
+	case string:
+		return t, nullable
+	case []interface{}:
+		kind := ""
+		for _, item := range t {
+			if item == "null" {
+				nullable = true
+			} else if s, ok := item.(string); ok && kind == "" {
+				kind = s
+			}
+		}
+		if kind == "" && nullable {
+			return "null", false
+		}
+		return kind, nullable
+	}
+
+	if _, ok := schema["properties"]; ok {
+		return "object", nullable
+	}
+	return "", nullable
+}
+
+func isObjectSchema(schema map[string]interface{}) bool {
+	kind, _ := schemaKind(schema)
+	_, hasProperties := schema["properties"]
+	return (kind == "object" && hasProperties) || len(sliceValue(schema, "allOf")) > 1
+}
+
+func contentSchema(object map[string]interface{}) map[string]interface{} {
+	content, _ := object["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return nil
+	}
+
+	mediaType, ok := content["application/json"].(map[string]interface{})
+	if !ok {
+		for _, key := range sortedKeys(content) {
+			if strings.HasSuffix(key, "json") {
+				mediaType, _ = content[key].(map[string]interface{})
+				break
+			}
+		}
+	}
+	if mediaType == nil {
+		mediaType, _ = content[sortedKeys(content)[0]].(map[string]interface{})
+	}
+
+	schema, _ := mediaType["schema"].(map[string]interface{})
+	return schema
+}
+
+func successResponseCode(responses map[string]interface{}) (string, bool) {
+	for _, code := range sortedKeys(responses) {
+		if strings.HasPrefix(code, "2") {
+			return code, true
+		}
+	}
+	if _, ok := responses["default"]; ok {
+		return "default", true
+	}
+	return "", false
+}
+
+func unescapePointer(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
+
+func stringValue(object map[string]interface{}, key string) string {
+	value, _ := object[key].(string)
+	return value
+}
+
+func sliceValue(object map[string]interface{}, key string) []interface{} {
+	value, _ := object[key].([]interface{})
+	return value
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// Configurable реализуют генераторы с настройками. Configure возвращает
+// настроенную копию, поэтому экземпляр из реестра можно безопасно
+// использовать из нескольких запросов одновременно.
+type Configurable interface {
+	Configure(options map[string]string) (CodeGenerator, error)
+}
+
+func Configure(generator CodeGenerator, options map[string]string) (CodeGenerator, error) {
+	if len(options) == 0 {
+		return generator, nil
+	}
+
+	if plugin, ok := generator.(plugins.Plugin); ok {
+		return plugin.WithOptions(options)
+	}
+
+	configurable, ok := generator.(Configurable)
+	if !ok {
+		return nil, fmt.Errorf("генератор %s не поддерживает настройки", generator.GetName())
+	}
+	return configurable.Configure(options)
+}
+
+// ParseOptions разбирает настройки вида key=value.
+func ParseOptions(pairs []string) (map[string]string, error) {
+	options := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("настройка должна иметь вид key=value: %s", pair)
+		}
+		options[key] = strings.TrimSpace(value)
+	}
+	return options, nil
+}
+
+func unknownOption(generator CodeGenerator, key string, known ...string) error {
+	sort.Strings(known)
+	return fmt.Errorf("неизвестная настройка %s для генератора %s, доступные: %s", key, generator.GetName(), strings.Join(known, ", "))
+}
+
+func invalidOption(key, value string, allowed ...string) error {
+	return fmt.Errorf("недопустимое значение %q для настройки %s, ожидается одно из: %s", value, key, strings.Join(allowed, ", "))
+}
@@ -0,0 +1,362 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// Pipeline — генератор из конфигурации, составленный из других генераторов
+// и обработчиков. Выход каждого генератора становится входом следующего,
+// обработчики перед генератором меняют модель типов, после него — код.
+// Генераторы берутся из реестра при каждом вызове, поэтому конвейер
+// использует текущие версии плагинов и их настройки по умолчанию.
+type Pipeline struct {
+	name        string
+	description string
+	steps       []pipelineStep
+	registry    *GeneratorRegistry
+}
+
+type pipelineStep struct {
+	// generator — имя, псевдоним или имя@версия генератора; у обработчика
+	// пустое.
+	generator string
+	// format — формат выхода предыдущего генератора.
+	format    InputFormat
+	processor string
+	options   map[string]string
+	document  DocumentProcessor
+	code      CodeProcessor
+}
+
+func (s pipelineStep) name() string {
+	if s.generator != "" {
+		return s.generator
+	}
+	return s.processor
+}
+
+// NewPipeline создаёт конвейер name из описания в конфигурации. Генераторы
+// шагов ищутся в registry. Обработчики модели типов должны стоять перед
+// последним генератором, обработчики кода — после первого.
+func NewPipeline(registry *GeneratorRegistry, name string, spec config.Pipeline) (*Pipeline, error) {
+	pipeline := &Pipeline{name: name, description: spec.Description, registry: registry}
+	for i, specStep := range spec.Steps {
+		step := pipelineStep{generator: specStep.Generator, processor: specStep.Processor, options: specStep.Options}
+		if (step.generator == "") == (step.processor == "") {
+			return nil, fmt.Errorf("шаг %d: нужен либо генератор, либо обработчик", i+1)
+		}
+		if specStep.Format != "" {
+			format, err := ParseFormat(specStep.Format)
+			if err != nil {
+				return nil, fmt.Errorf("шаг %d: %w", i+1, err)
+			}
+			step.format = format
+		}
+		if step.processor != "" {
+			if err := step.newProcessor(); err != nil {
+				return nil, fmt.Errorf("шаг %d: %w", i+1, err)
+			}
+		}
+		pipeline.steps = append(pipeline.steps, step)
+	}
+
+	first, last := pipeline.firstGenerator(), pipeline.lastGenerator()
+	if last < 0 {
+		return nil, fmt.Errorf("в конвейере нет ни одного генератора")
+	}
+	for i, step := range pipeline.steps {
+		if step.document != nil && i > last {
+			return nil, fmt.Errorf("шаг %d: обработчик %s меняет модель типов и должен стоять перед генератором", i+1, step.processor)
+		}
+		if step.document == nil && step.code != nil && i < first {
+			return nil, fmt.Errorf("шаг %d: обработчик %s меняет код и должен стоять после генератора", i+1, step.processor)
+		}
+		if i == first && step.format != FormatAuto {
+			return nil, fmt.Errorf("шаг %d: format задаёт формат выхода предыдущего генератора, а у первого генератора его нет", i+1)
+		}
+	}
+	return pipeline, nil
+}
+
+func (s *pipelineStep) newProcessor() error {
+	processor, err := NewProcessor(s.processor, s.options)
+	if err != nil {
+		return err
+	}
+	s.document, _ = processor.(DocumentProcessor)
+	s.code, _ = processor.(CodeProcessor)
+	return nil
+}
+
+func (p *Pipeline) GetName() string {
+	return p.name
+}
+
+func (p *Pipeline) GetDescription() string {
+	if p.description != "" {
+		return p.description
+	}
+	return "Конвейер " + strings.Join(p.Steps(), " -> ")
+}
+
+// Steps возвращает имена генераторов и обработчиков конвейера по порядку.
+func (p *Pipeline) Steps() []string {
+	names := make([]string, len(p.steps))
+	for i, step := range p.steps {
+		names[i] = step.name()
+	}
+	return names
+}
+
+// Configure возвращает копию конвейера с настройками шагов. Настройка
+// шаг.ключ, например go-struct.package или redact.fields, относится к шагам с
+// этим именем, остальные — к последнему генератору, поэтому --package
+// работает и для конвейера, который заканчивается go-struct.
+func (p *Pipeline) Configure(options map[string]string) (CodeGenerator, error) {
+	configured := *p
+	configured.steps = append([]pipelineStep(nil), p.steps...)
+
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	updated := map[int]bool{}
+	for _, key := range keys {
+		targets := []int{configured.lastGenerator()}
+		option := key
+		if name, rest, ok := strings.Cut(key, "."); ok {
+			if matched := configured.stepsNamed(name); len(matched) > 0 {
+				targets, option = matched, rest
+			}
+		}
+		for _, i := range targets {
+			step := &configured.steps[i]
+			if !updated[i] {
+				step.options = copyOptions(step.options)
+				updated[i] = true
+			}
+			step.options[option] = options[key]
+		}
+	}
+
+	for i := range configured.steps {
+		step := &configured.steps[i]
+		if updated[i] && step.processor != "" {
+			if err := step.newProcessor(); err != nil {
+				return nil, fmt.Errorf("конвейер %s, шаг %d: %w", p.name, i+1, err)
+			}
+		}
+	}
+	return &configured, nil
+}
+
+func (p *Pipeline) stepsNamed(name string) []int {
+	var indexes []int
+	for i, step := range p.steps {
+		if step.name() == name {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func copyOptions(options map[string]string) map[string]string {
+	copied := make(map[string]string, len(options)+1)
+	for key, value := range options {
+		copied[key] = value
+	}
+	return copied
+}
+
+func (p *Pipeline) firstGenerator() int {
+	for i, step := range p.steps {
+		if step.generator != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Pipeline) lastGenerator() int {
+	for i := len(p.steps) - 1; i >= 0; i-- {
+		if p.steps[i].generator != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextFormat возвращает формат, в котором следующий генератор начиная с шага
+// from читает выход предыдущего.
+func (p *Pipeline) nextFormat(from int) InputFormat {
+	for _, step := range p.steps[from:] {
+		if step.generator != "" {
+			return step.format
+		}
+	}
+	return FormatAuto
+}
+
+// resolve возвращает настроенный генератор шага из реестра.
+func (p *Pipeline) resolve(step pipelineStep) (CodeGenerator, error) {
+	if source, disabled := p.registry.Disabled(step.generator); disabled {
+		return nil, fmt.Errorf("плагин %s отключён в %s", step.generator, source)
+	}
+	generator, ok := p.registry.Get(step.generator)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrGeneratorNotFound, step.generator)
+	}
+	return Configure(generator, p.registry.Options(step.generator, step.options))
+}
+
+// validate проверяет, что генераторы всех шагов есть в реестре и что
+// конвейер не вызывает сам себя через другие конвейеры.
+func (p *Pipeline) validate(path []string) error {
+	for _, name := range path {
+		if name == p.name {
+			return fmt.Errorf("конвейер вызывает сам себя: %s -> %s", strings.Join(path, " -> "), p.name)
+		}
+	}
+	path = append(append([]string(nil), path...), p.name)
+	for i, step := range p.steps {
+		if step.generator == "" {
+			continue
+		}
+		if source, disabled := p.registry.Disabled(step.generator); disabled {
+			return fmt.Errorf("шаг %d: плагин %s отключён в %s", i+1, step.generator, source)
+		}
+		generator, ok := p.registry.Get(step.generator)
+		if !ok {
+			return fmt.Errorf("шаг %d: %w: %s", i+1, ErrGeneratorNotFound, step.generator)
+		}
+		if nested, ok := generator.(*Pipeline); ok {
+			if err := nested.validate(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) Generate(input string) (string, error) {
+	return p.GenerateContext(context.Background(), input)
+}
+
+func (p *Pipeline) GenerateContext(ctx context.Context, input string) (string, error) {
+	output, _, err := p.run(ctx, input, nil, FormatAuto, false)
+	return output, err
+}
+
+func (p *Pipeline) GenerateDocument(doc *ir.Document) (string, error) {
+	output, _, err := p.run(context.Background(), "", doc, FormatAuto, false)
+	return output, err
+}
+
+func (p *Pipeline) GenerateFiles(doc *ir.Document) ([]GeneratedFile, error) {
+	_, files, err := p.run(context.Background(), "", doc, FormatAuto, true)
+	return files, err
+}
+
+// run выполняет шаги конвейера над input в формате format или над готовой
+// моделью типов doc. С files последний генератор раскладывает код по файлам,
+// и обработчики кода применяются к каждому файлу.
+func (p *Pipeline) run(ctx context.Context, input string, doc *ir.Document, format InputFormat, files bool) (string, []GeneratedFile, error) {
+	last := p.lastGenerator()
+	var output, outputName string
+	var generated []GeneratedFile
+	chained := false
+
+	for i, step := range p.steps {
+		if chained && step.code == nil {
+			input, doc, format = output, nil, p.nextFormat(i)
+			chained = false
+		}
+
+		var err error
+		switch {
+		case step.document != nil:
+			if doc == nil {
+				doc, err = ParseInput(input, format)
+			}
+			if err == nil {
+				doc, err = step.document.ProcessDocument(doc)
+			}
+		case step.code != nil && generated != nil:
+			for j := range generated {
+				if generated[j].Content, err = step.code.ProcessCode(generated[j].Content, generated[j].Name); err != nil {
+					break
+				}
+			}
+		case step.code != nil:
+			output, err = step.code.ProcessCode(output, outputName)
+		default:
+			var generator CodeGenerator
+			generator, err = p.resolve(step)
+			if err != nil {
+				break
+			}
+			outputName = ""
+			if extension := outputExtension(generator); extension != "" {
+				outputName = "output" + extension
+			}
+			switch {
+			case i == last && files && doc != nil:
+				generated, err = GenerateDocumentFiles(ctx, generator, doc)
+			case i == last && files:
+				generated, err = GenerateFiles(ctx, generator, input, format)
+			case doc != nil:
+				output, err = GenerateFromDocument(ctx, generator, doc)
+			default:
+				output, err = GenerateWithFormat(ctx, generator, input, format)
+			}
+			chained = true
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("конвейер %s, шаг %d (%s): %w", p.name, i+1, step.name(), err)
+		}
+	}
+	return output, generated, nil
+}
+
+// outputExtension возвращает расширение файлов, которые создаёт генератор,
+// или пустую строку, если оно неизвестно.
+func outputExtension(generator CodeGenerator) string {
+	switch g := generator.(type) {
+	case plugins.ManifestProvider:
+		if g.Manifest() != nil {
+			return g.Manifest().OutputExtension
+		}
+	case *GoStructGenerator:
+		return ".go"
+	case *PythonDataclassGenerator:
+		return ".py"
+	case *SQLGenerator:
+		return ".sql"
+	case *Pipeline:
+		if last := g.lastGenerator(); last >= 0 {
+			if generator, err := g.resolve(g.steps[last]); err == nil {
+				return outputExtension(generator)
+			}
+		}
+	}
+	return ""
+}
+
+// pipelineFingerprint меняется вместе с описанием конвейера, чтобы
+// перезагрузка сообщала об изменённых конвейерах.
+func pipelineFingerprint(spec config.Pipeline) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return "pipeline:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// StructuredPlugin возвращает плагин, если генератор говорит по JSON протоколу
+// и может получать модель типов, настройки и возвращать несколько файлов.
+func StructuredPlugin(generator CodeGenerator) (plugins.Plugin, bool) {
+	plugin, ok := generator.(plugins.Plugin)
+	if !ok || plugins.Protocol(plugin) != plugins.ProtocolJSON {
+		return nil, false
+	}
+	return plugin, true
+}
+
+// InvokePlugin передает плагину исходный текст вместе с моделью типов.
+func InvokePlugin(ctx context.Context, plugin plugins.Plugin, input string, format InputFormat) (*plugins.Response, error) {
+	if format == FormatAuto {
+		format = DetectFormat(input)
+	}
+	if err := checkInputFormat(plugin, format); err != nil {
+		return nil, err
+	}
+
+	doc, err := ParseInput(input, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.Invoke(ctx, &plugins.Request{
+		Input:  input,
+		Format: string(format),
+		IR:     doc,
+	})
+}
+
+// InvokePluginDocument передает плагину только модель типов, например для
+// CSV и NDJSON, прочитанных потоково.
+func InvokePluginDocument(ctx context.Context, plugin plugins.Plugin, doc *ir.Document) (*plugins.Response, error) {
+	if err := checkInputFormat(plugin, InputFormat(doc.Format)); err != nil {
+		return nil, err
+	}
+
+	return plugin.Invoke(ctx, &plugins.Request{
+		Format: doc.Format,
+		IR:     doc,
+	})
+}
+
+// PluginFiles переводит файлы из ответа плагина в GeneratedFile. Файлы без
+// имени называются по плагину, пути за пределами каталога вывода запрещены.
+func PluginFiles(plugin plugins.Plugin, response *plugins.Response) ([]GeneratedFile, error) {
+	extension := ""
+	if manifest := plugin.Manifest(); manifest != nil {
+		extension = manifest.OutputExtension
+	}
+
+	files := make([]GeneratedFile, 0, len(response.Files))
+	for i, file := range response.Files {
+		name := file.Name
+		if name == "" {
+			name = plugin.GetName() + extension
+			if len(response.Files) > 1 {
+				name = fmt.Sprintf("%s_%d%s", plugin.GetName(), i+1, extension)
+			}
+		}
+
+		clean := filepath.Clean(filepath.FromSlash(name))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("плагин %s вернул недопустимое имя файла: %s", plugin.GetName(), file.Name)
+		}
+
+		files = append(files, GeneratedFile{Name: clean, Content: file.Content})
+	}
+	return files, nil
+}
@@ -0,0 +1,456 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/naming"
+)
+
+// DocumentProcessor изменяет модель типов перед генератором конвейера,
+// например убирает поля или переименовывает их.
+type DocumentProcessor interface {
+	ProcessDocument(doc *ir.Document) (*ir.Document, error)
+}
+
+// CodeProcessor изменяет код, созданный генератором конвейера. name — имя
+// файла; для вывода одной строкой это "output" с расширением генератора или
+// пустая строка, если расширение неизвестно.
+type CodeProcessor interface {
+	ProcessCode(code, name string) (string, error)
+}
+
+// ProcessorFactory создаёт обработчик с настройками шага конвейера. Результат
+// должен реализовывать DocumentProcessor или CodeProcessor.
+type ProcessorFactory func(options map[string]string) (interface{}, error)
+
+var (
+	processorsMu sync.RWMutex
+	processors   = map[string]ProcessorFactory{
+		"redact":         newRedactProcessor,
+		"normalize-keys": newNormalizeKeysProcessor,
+		"license-header": newLicenseHeaderProcessor,
+		"gofmt":          newGofmtProcessor,
+		"goimports":      newGoimportsProcessor,
+	}
+)
+
+// RegisterProcessor добавляет обработчик, доступный конвейерам по имени.
+func RegisterProcessor(name string, factory ProcessorFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("недопустимый обработчик %q", name)
+	}
+	processorsMu.Lock()
+	defer processorsMu.Unlock()
+	if _, exists := processors[name]; exists {
+		return fmt.Errorf("обработчик %s уже зарегистрирован", name)
+	}
+	processors[name] = factory
+	return nil
+}
+
+// ProcessorNames возвращает отсортированные имена обработчиков.
+func ProcessorNames() []string {
+	processorsMu.RLock()
+	defer processorsMu.RUnlock()
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProcessor создаёт обработчик по имени.
+func NewProcessor(name string, options map[string]string) (interface{}, error) {
+	processorsMu.RLock()
+	factory, ok := processors[name]
+	processorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("неизвестный обработчик %s, доступные: %s", name, strings.Join(ProcessorNames(), ", "))
+	}
+
+	processor, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("обработчик %s: %w", name, err)
+	}
+	_, document := processor.(DocumentProcessor)
+	_, code := processor.(CodeProcessor)
+	if !document && !code {
+		return nil, fmt.Errorf("обработчик %s не изменяет ни модель типов, ни код", name)
+	}
+	return processor, nil
+}
+
+func checkProcessorOptions(options map[string]string, known ...string) error {
+	for key := range options {
+		found := false
+		for _, name := range known {
+			found = found || key == name
+		}
+		if !found {
+			sort.Strings(known)
+			return fmt.Errorf("неизвестная настройка %s, доступные: %s", key, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// cloneDocument копирует модель типов, чтобы обработчик не менял документ,
+// которым ещё пользуется вызывающий код.
+func cloneDocument(doc *ir.Document) (*ir.Document, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	clone := &ir.Document{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// rewriteFields вызывает fn для полей каждого объекта внутри типа.
+func rewriteFields(t *ir.Type, fn func([]*ir.Field) ([]*ir.Field, error)) error {
+	if t == nil {
+		return nil
+	}
+	if t.Kind == ir.KindObject {
+		fields, err := fn(t.Fields)
+		if err != nil {
+			return err
+		}
+		t.Fields = fields
+	}
+	if err := rewriteFields(t.Elem, fn); err != nil {
+		return err
+	}
+	for _, field := range t.Fields {
+		if err := rewriteFields(field.Type, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rewriteDocument(doc *ir.Document, fn func([]*ir.Field) ([]*ir.Field, error)) (*ir.Document, error) {
+	clone, err := cloneDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range clone.Models {
+		if err := rewriteFields(model.Type, fn); err != nil {
+			return nil, fmt.Errorf("модель %s: %w", model.Name, err)
+		}
+	}
+	return clone, nil
+}
+
+// redactProcessor убирает поля с секретами. Имена сравниваются без учёта
+// регистра и стиля: apiKey совпадает с api_key.
+type redactProcessor struct {
+	fields map[string]bool
+}
+
+func newRedactProcessor(options map[string]string) (interface{}, error) {
+	if err := checkProcessorOptions(options, "fields"); err != nil {
+		return nil, err
+	}
+	processor := &redactProcessor{fields: map[string]bool{}}
+	for _, name := range strings.Split(options["fields"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			processor.fields[ToSnakeCase(name)] = true
+		}
+	}
+	if len(processor.fields) == 0 {
+		return nil, fmt.Errorf("не указаны поля: fields=password,token")
+	}
+	return processor, nil
+}
+
+func (p *redactProcessor) ProcessDocument(doc *ir.Document) (*ir.Document, error) {
+	return rewriteDocument(doc, func(fields []*ir.Field) ([]*ir.Field, error) {
+		kept := fields[:0]
+		for _, field := range fields {
+			if !p.fields[ToSnakeCase(field.Name)] {
+				kept = append(kept, field)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// normalizeKeysProcessor приводит имена полей к одному стилю.
+type normalizeKeysProcessor struct {
+	convert func(string) string
+}
+
+func newNormalizeKeysProcessor(options map[string]string) (interface{}, error) {
+	if err := checkProcessorOptions(options, "case"); err != nil {
+		return nil, err
+	}
+	convert := map[string]func(string) string{
+		"snake":  ToSnakeCase,
+		"camel":  ToCamelCase,
+		"pascal": ToPascalCase,
+		"kebab":  naming.ToKebabCase,
+	}
+	style := options["case"]
+	if style == "" {
+		style = "snake"
+	}
+	if convert[style] == nil {
+		return nil, invalidOption("case", style, "snake", "camel", "pascal", "kebab")
+	}
+	return &normalizeKeysProcessor{convert: convert[style]}, nil
+}
+
+func (p *normalizeKeysProcessor) ProcessDocument(doc *ir.Document) (*ir.Document, error) {
+	return rewriteDocument(doc, func(fields []*ir.Field) ([]*ir.Field, error) {
+		seen := map[string]string{}
+		for _, field := range fields {
+			name := p.convert(field.Name)
+			if name == "" {
+				continue
+			}
+			if previous, ok := seen[name]; ok {
+				return nil, fmt.Errorf("поля %s и %s получают одно имя %s", previous, field.Name, name)
+			}
+			seen[name] = field.Name
+			field.Name = name
+		}
+		return fields, nil
+	})
+}
+
+// licenseHeaderProcessor добавляет в начало кода заголовок с лицензией в
+// комментарии, подходящем по расширению файла. Код, который уже начинается
+// с заголовка, не меняется.
+type licenseHeaderProcessor struct {
+	text    string
+	comment string
+}
+
+func newLicenseHeaderProcessor(options map[string]string) (interface{}, error) {
+	if err := checkProcessorOptions(options, "text", "comment"); err != nil {
+		return nil, err
+	}
+	text := strings.TrimRight(options["text"], "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("не указан текст заголовка: text=...")
+	}
+	return &licenseHeaderProcessor{text: text, comment: options["comment"]}, nil
+}
+
+func (p *licenseHeaderProcessor) ProcessCode(code, name string) (string, error) {
+	comment := p.comment
+	if comment == "" {
+		comment = lineComment(filepath.Ext(name))
+	}
+	var header strings.Builder
+	for _, line := range strings.Split(p.text, "\n") {
+		header.WriteString(strings.TrimRight(comment+" "+line, " ") + "\n")
+	}
+
+	shebang := ""
+	if strings.HasPrefix(code, "#!") {
+		end := strings.IndexByte(code, '\n') + 1
+		if end == 0 {
+			end = len(code)
+		}
+		shebang, code = code[:end], code[end:]
+	}
+	if strings.HasPrefix(code, header.String()) {
+		return shebang + code, nil
+	}
+	return shebang + header.String() + "\n" + code, nil
+}
+
+// lineComment возвращает начало однострочного комментария языка по
+// расширению файла.
+func lineComment(extension string) string {
+	switch strings.ToLower(extension) {
+	case ".py", ".rb", ".sh", ".yaml", ".yml", ".toml", ".r", ".pl", ".ex", ".exs":
+		return "#"
+	case ".sql", ".lua", ".hs", ".elm":
+		return "--"
+	default:
+		return "//"
+	}
+}
+
+// gofmtProcessor форматирует Go код. Файлы с другим расширением не меняются.
+type gofmtProcessor struct{}
+
+func newGofmtProcessor(options map[string]string) (interface{}, error) {
+	if err := checkProcessorOptions(options); err != nil {
+		return nil, err
+	}
+	return gofmtProcessor{}, nil
+}
+
+func (gofmtProcessor) ProcessCode(code, name string) (string, error) {
+	if !isGoFile(name) {
+		return code, nil
+	}
+	return formatGo(code)
+}
+
+func isGoFile(name string) bool {
+	return name == "" || filepath.Ext(name) == ".go"
+}
+
+// formatGo форматирует файл или список объявлений без package.
+func formatGo(code string) (string, error) {
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		return "", fmt.Errorf("ошибка форматирования Go кода: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// goimportsProcessor, как goimports, убирает неиспользуемые импорты,
+// добавляет недостающие импорты стандартной библиотеки и форматирует код.
+type goimportsProcessor struct{}
+
+func newGoimportsProcessor(options map[string]string) (interface{}, error) {
+	if err := checkProcessorOptions(options); err != nil {
+		return nil, err
+	}
+	return goimportsProcessor{}, nil
+}
+
+// standardImports — пакеты стандартной библиотеки, которые goimports
+// добавляет по имени.
+var standardImports = map[string]string{}
+
+func init() {
+	for _, importPath := range []string{
+		"bytes", "context", "database/sql", "encoding/base64", "encoding/json",
+		"encoding/xml", "errors", "fmt", "io", "math", "math/big", "net/http",
+		"net/url", "os", "regexp", "sort", "strconv", "strings", "sync", "time",
+	} {
+		standardImports[path.Base(importPath)] = importPath
+	}
+}
+
+func (goimportsProcessor) ProcessCode(code, name string) (string, error) {
+	if !isGoFile(name) {
+		return code, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, code, parser.ParseComments)
+	if err != nil {
+		// Без package импорты некуда добавить, остаётся только форматирование.
+		return formatGo(code)
+	}
+
+	used := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	changed := false
+	imported := map[string]bool{}
+	var imports []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := importName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." && !used[name] {
+			changed = true
+			continue
+		}
+		imported[name] = true
+		imports = append(imports, spec)
+	}
+	for name := range used {
+		if importPath, ok := standardImports[name]; ok && !imported[name] {
+			imports = append(imports, &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(importPath)}})
+			changed = true
+		}
+	}
+	if !changed {
+		return formatGo(code)
+	}
+
+	// Объявления импортов заменяются одним блоком сразу после package.
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	source := code
+	for i := len(file.Decls) - 1; i >= 0; i-- {
+		decl, ok := file.Decls[i].(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		start := offset(decl.Pos())
+		if decl.Doc != nil {
+			start = offset(decl.Doc.Pos())
+		}
+		source = source[:start] + source[offset(decl.End()):]
+	}
+	position := offset(file.Name.End())
+	return formatGo(source[:position] + renderImports(imports) + source[position:])
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName возвращает имя пакета по пути импорта, как его обычно
+// называют: gopkg.in/yaml.v3 — yaml, github.com/a/go-toml/v2 — toml.
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// renderImports возвращает блок импортов: сначала стандартная библиотека,
+// затем остальные пакеты.
+func renderImports(imports []*ast.ImportSpec) string {
+	if len(imports) == 0 {
+		return ""
+	}
+	var standard, external []string
+	for _, spec := range imports {
+		line := "\t" + spec.Path.Value
+		if spec.Name != nil {
+			line = "\t" + spec.Name.Name + " " + spec.Path.Value
+		}
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		first, _, _ := strings.Cut(importPath, "/")
+		if strings.Contains(first, ".") {
+			external = append(external, line)
+		} else {
+			standard = append(standard, line)
+		}
+	}
+	sort.Strings(standard)
+	sort.Strings(external)
+	groups := standard
+	if len(standard) > 0 && len(external) > 0 {
+		groups = append(groups, "")
+	}
+	groups = append(groups, external...)
+	return "\n\nimport (\n" + strings.Join(groups, "\n") + "\n)\n"
+}
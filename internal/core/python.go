@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true,
+	"async": true, "await": true, "break": true, "class": true, "continue": true, "def": true,
+	"del": true, "elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true,
+	"return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+type PythonDataclassGenerator struct {
+	name        string
+	description string
+}
+
+func NewPythonDataclassGenerator() *PythonDataclassGenerator {
+	return &PythonDataclassGenerator{
+		name:        "py-dataclass",
+		description: "Генерирует Python dataclass модели из JSON схемы",
+	}
+}
+
+func (g *PythonDataclassGenerator) GetName() string {
+	return g.name
+}
+
+func (g *PythonDataclassGenerator) GetDescription() string {
+	return g.description
+}
+
+func (g *PythonDataclassGenerator) Generate(input string) (string, error) {
+	doc, err := ParseInput(input, FormatAuto)
+	if err != nil {
+		return "", err
+	}
+
+	return g.GenerateDocument(doc)
+}
+
+func (g *PythonDataclassGenerator) GenerateDocument(doc *ir.Document) (string, error) {
+	typing := make(map[string]bool)
+	usesDatetime := false
+
+	blocks := make([]string, 0, len(doc.Models))
+	for _, model := range doc.Models {
+		if model.Type == nil {
+			return "", fmt.Errorf("у модели %s не задан тип", model.Name)
+		}
+		if model.Type.Uses(ir.KindTime) {
+			usesDatetime = true
+		}
+		blocks = append(blocks, g.generateModel(model, typing))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("from __future__ import annotations\n\n")
+	builder.WriteString("from dataclasses import dataclass\n")
+	if usesDatetime {
+		builder.WriteString("from datetime import datetime\n")
+	}
+	if len(typing) > 0 {
+		names := make([]string, 0, len(typing))
+		for name := range typing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		builder.WriteString(fmt.Sprintf("from typing import %s\n", strings.Join(names, ", ")))
+	}
+
+	builder.WriteString("\n\n")
+	builder.WriteString(strings.Join(blocks, "\n\n\n"))
+
+	return builder.String(), nil
+}
+
+func (g *PythonDataclassGenerator) generateModel(model *ir.Model, typing map[string]bool) string {
+	var builder strings.Builder
+
+	if model.Type.Kind != ir.KindObject {
+		return fmt.Sprintf("%s = %s", model.Name, g.pythonType(model.Type, typing))
+	}
+
+	builder.WriteString(fmt.Sprintf("@dataclass\nclass %s:\n", model.Name))
+	if model.Description != "" {
+		builder.WriteString(fmt.Sprintf("    \"\"\"%s\"\"\"\n\n", strings.Join(strings.Fields(model.Description), " ")))
+	}
+
+	if len(model.Type.Fields) == 0 {
+		builder.WriteString("    pass")
+		return builder.String()
+	}
+
+	var required, optional []string
+	for _, field := range model.Type.Fields {
+		fieldName := ToSnakeCase(field.Name)
+		if fieldName == "" {
+			fieldName = "field"
+		}
+		if pythonKeywords[fieldName] {
+			fieldName += "_"
+		}
+
+		fieldType := g.pythonType(field.Type, typing)
+		if field.Required && !field.Type.Nullable {
+			required = append(required, fmt.Sprintf("    %s: %s", fieldName, fieldType))
+			continue
+		}
+
+		if !field.Type.Nullable {
+			typing["Optional"] = true
+			fieldType = fmt.Sprintf("Optional[%s]", fieldType)
+		}
+		optional = append(optional, fmt.Sprintf("    %s: %s = None", fieldName, fieldType))
+	}
+
+	builder.WriteString(strings.Join(append(required, optional...), "\n"))
+
+	return builder.String()
+}
+
+func (g *PythonDataclassGenerator) pythonType(t *ir.Type, typing map[string]bool) string {
+	if t == nil {
+		typing["Any"] = true
+		return "Any"
+	}
+
+	var pythonType string
+	switch t.Kind {
+	case ir.KindString:
+		if t.Format == "binary" {
+			pythonType = "bytes"
+		} else {
+			pythonType = "str"
+		}
+	case ir.KindInteger:
+		pythonType = "int"
+	case ir.KindNumber:
+		pythonType = "float"
+	case ir.KindBoolean:
+		pythonType = "bool"
+	case ir.KindTime:
+		pythonType = "datetime"
+	case ir.KindArray:
+		typing["List"] = true
+		pythonType = fmt.Sprintf("List[%s]", g.pythonType(t.Elem, typing))
+	case ir.KindMap:
+		typing["Dict"] = true
+		pythonType = fmt.Sprintf("Dict[str, %s]", g.pythonType(t.Elem, typing))
+	case ir.KindObject:
+		typing["Any"] = true
+		typing["Dict"] = true
+		pythonType = "Dict[str, Any]"
+	case ir.KindRef:
+		pythonType = t.Ref
+	default:
+		typing["Any"] = true
+		return "Any"
+	}
+
+	if t.Nullable {
+		typing["Optional"] = true
+		return fmt.Sprintf("Optional[%s]", pythonType)
+	}
+	return pythonType
+}
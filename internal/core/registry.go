@@ -0,0 +1,578 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// VersionSeparator отделяет версию генератора от имени: go-struct@2.
+const VersionSeparator = "@"
+
+var (
+	// ErrGeneratorExists возвращается при повторной регистрации генератора
+	// с тем же именем и версией. Заменить генератор можно через Replace.
+	ErrGeneratorExists = errors.New("генератор уже зарегистрирован")
+	// ErrGeneratorNotFound возвращается, если генератора нет в реестре.
+	ErrGeneratorNotFound = errors.New("генератор не найден")
+)
+
+// ChangeKind — вид изменения реестра.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeRemoved ChangeKind = "removed"
+)
+
+// Change описывает изменение реестра. Для псевдонима Name — сам псевдоним, а
+// Target — имя генератора, на который он указывает.
+type Change struct {
+	Kind    ChangeKind
+	Name    string
+	Version string
+	Target  string
+}
+
+func (c Change) String() string {
+	name := c.Name
+	if c.Version != "" {
+		name += VersionSeparator + c.Version
+	}
+	if c.Target != "" {
+		name += " -> " + c.Target
+	}
+	return fmt.Sprintf("%s %s", c.Kind, name)
+}
+
+// registryEntry — одна версия генератора.
+type registryEntry struct {
+	generator CodeGenerator
+	version   string
+	// fingerprint — отпечаток плагина из RegisterPlugins, по нему Reloader
+	// находит изменившиеся плагины. У встроенных генераторов пустой.
+	fingerprint string
+	// runtime — генератор добавлен через Register или Replace, а не найден
+	// при загрузке. Reloader переносит такие генераторы в новый реестр.
+	runtime bool
+}
+
+// GeneratorRegistry — потокобезопасный реестр генераторов. Под одним именем
+// может быть несколько версий генератора: первая зарегистрированная
+// используется по умолчанию, остальные доступны как имя@версия. Псевдонимы
+// ведут на имя генератора. List и GetNames возвращают генераторы по
+// алфавиту, а подписчики Subscribe узнают о каждом изменении.
+type GeneratorRegistry struct {
+	mu      sync.RWMutex
+	entries map[string][]*registryEntry
+	aliases map[string]string
+	// runtimeAliases — псевдонимы, добавленные через Alias, а не при загрузке.
+	runtimeAliases map[string]bool
+	// defaults — настройки генераторов по умолчанию из конфигурации.
+	defaults map[string]map[string]string
+	// disabled — отключённые в конфигурации плагины и файлы, где это сделано.
+	disabled map[string]string
+
+	subscribers    map[int]func(Change)
+	nextSubscriber int
+}
+
+// NewGeneratorRegistry создаёт реестр со встроенными генераторами.
+func NewGeneratorRegistry() *GeneratorRegistry {
+	registry := &GeneratorRegistry{
+		entries:        make(map[string][]*registryEntry),
+		aliases:        make(map[string]string),
+		runtimeAliases: make(map[string]bool),
+		subscribers:    make(map[int]func(Change)),
+	}
+	for _, generator := range []CodeGenerator{
+		NewGoStructGenerator(),
+		NewPythonDataclassGenerator(),
+		NewSQLGenerator(DialectPostgres),
+		NewSQLGenerator(DialectMySQL),
+		NewSQLGenerator(DialectSQLite),
+	} {
+		registry.add(&registryEntry{generator: generator}, false)
+	}
+	return registry
+}
+
+// LoadGeneratorRegistry создаёт реестр со встроенными генераторами и всеми
+// плагинами, найденными plugins.PluginManager.Discover, с учётом
+// конфигурации пользователя и проекта (config.Load): отключённые плагины
+// пропускаются, для плагинов с ограничением версии выбирается подходящая, а
+// настройки генераторов становятся настройками по умолчанию. Остальные
+// версии плагина, в том числе плагина с именем встроенного генератора,
+// доступны как имя@версия. Псевдонимы берутся из манифестов плагинов и
+// конфигурации, а конвейеры из конфигурации регистрируются как генераторы
+// (см. Pipeline). Его используют CLI, cmd/web и server, поэтому набор
+// генераторов везде одинаковый. Конфликты имён, пропущенные плагины и
+// псевдонимы возвращаются в Discovery.
+func LoadGeneratorRegistry() (*GeneratorRegistry, *plugins.Discovery, error) {
+	registry := NewGeneratorRegistry()
+	cfg, err := config.Load()
+	if err != nil {
+		return registry, nil, err
+	}
+	discovery, err := plugins.NewPluginManager().Discover(registry.GetNames())
+	if err != nil {
+		return registry, nil, err
+	}
+	registry.disabled = cfg.Apply(discovery, registry.GetNames())
+	registry.defaults = cfg.OptionValues()
+
+	infos := append([]plugins.PluginInfo{}, discovery.Plugins...)
+	for _, conflict := range discovery.Conflicts {
+		infos = append(infos, conflict.Shadowed...)
+	}
+	registry.RegisterPlugins(infos)
+
+	for _, plugin := range discovery.Plugins {
+		if plugin.Manifest == nil {
+			continue
+		}
+		for _, alias := range plugin.Manifest.Aliases {
+			if err := registry.alias(alias, plugin.Name, false); err != nil {
+				discovery.Errors = append(discovery.Errors, fmt.Errorf("plugin %s: %w", plugin.Name, err))
+			}
+		}
+	}
+	specs := cfg.Pipelines()
+	pipelines := registry.registerPipelines(specs, discovery)
+	aliases := cfg.Aliases()
+	for _, alias := range sortedAliases(aliases) {
+		target := aliases[alias]
+		if _, disabled := registry.disabled[target.Value]; disabled {
+			continue
+		}
+		if err := registry.alias(alias, target.Value, false); err != nil {
+			discovery.Errors = append(discovery.Errors, fmt.Errorf("%s: %w", target.Source, err))
+		}
+	}
+
+	// Шаги проверяются, когда известны все генераторы, конвейеры и псевдонимы.
+	var invalid []string
+	for _, pipeline := range pipelines {
+		if err := pipeline.validate(nil); err != nil {
+			invalid = append(invalid, pipeline.name)
+			discovery.Errors = append(discovery.Errors, fmt.Errorf("%s: конвейер %s: %w", specs[pipeline.name].Source, pipeline.name, err))
+		}
+	}
+	for _, name := range invalid {
+		registry.Unregister(name)
+	}
+	return registry, discovery, nil
+}
+
+// registerPipelines добавляет конвейеры из конфигурации. Конвейер не может
+// называться как генератор или псевдоним.
+func (r *GeneratorRegistry) registerPipelines(values map[string]config.PipelineValue, discovery *plugins.Discovery) []*Pipeline {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pipelines []*Pipeline
+	for _, name := range names {
+		value := values[name]
+		pipeline, err := NewPipeline(r, name, value.Pipeline)
+		if err == nil && len(r.Versions(name)) > 0 {
+			err = fmt.Errorf("%w: %s", ErrGeneratorExists, name)
+		}
+		if err == nil {
+			err = r.add(&registryEntry{generator: pipeline, fingerprint: pipelineFingerprint(value.Pipeline)}, false)
+		}
+		if err != nil {
+			discovery.Errors = append(discovery.Errors, fmt.Errorf("%s: конвейер %s: %w", value.Source, name, err))
+			continue
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	return pipelines
+}
+
+func sortedAliases(aliases map[string]config.Value) []string {
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterPlugins добавляет найденные или установленные плагины. Первый
+// плагин с данным именем становится версией по умолчанию, плагины с другой
+// версией добавляются как имя@версия, а плагины с уже зарегистрированной
+// версией пропускаются.
+func (r *GeneratorRegistry) RegisterPlugins(infos []plugins.PluginInfo) {
+	for _, pluginInfo := range infos {
+		plugin, err := plugins.NewExternalPlugin(pluginInfo.Type, pluginInfo.Name, pluginInfo.Description, pluginInfo.Path)
+		if err != nil {
+			continue
+		}
+		plugin.SetManifest(pluginInfo.Manifest)
+		trust, err := plugins.ParseTrust(string(pluginInfo.Trust))
+		if err != nil {
+			trust = plugins.TrustUntrusted
+		}
+		plugin.SetTrust(trust)
+		plugin.SetIntegrity(pluginInfo.Integrity)
+		if err := r.add(&registryEntry{generator: plugin, fingerprint: pluginFingerprint(pluginInfo)}, false); err != nil {
+			plugin.Close()
+		}
+	}
+}
+
+// Register добавляет генератор. Если генератор с тем же именем и версией уже
+// есть, возвращается ошибка ErrGeneratorExists.
+func (r *GeneratorRegistry) Register(generator CodeGenerator) error {
+	return r.add(&registryEntry{generator: generator, runtime: true}, false)
+}
+
+// Replace добавляет генератор или заменяет уже зарегистрированный генератор
+// с тем же именем и версией. Заменённый генератор не останавливается.
+// Имя не может быть недопустимым или занятым псевдонимом.
+func (r *GeneratorRegistry) Replace(generator CodeGenerator) error {
+	return r.add(&registryEntry{generator: generator, runtime: true}, true)
+}
+
+func (r *GeneratorRegistry) add(entry *registryEntry, replace bool) error {
+	name := entry.generator.GetName()
+	entry.version = generatorVersion(entry.generator)
+	if name == "" || strings.Contains(name, VersionSeparator) {
+		return fmt.Errorf("недопустимое имя генератора %q", name)
+	}
+
+	r.mu.Lock()
+	if _, alias := r.aliases[name]; alias {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s уже используется как псевдоним", ErrGeneratorExists, name)
+	}
+	kind := ChangeAdded
+	versions := r.entries[name]
+	index := findVersion(versions, entry.version)
+	switch {
+	case index < 0:
+		r.entries[name] = append(versions, entry)
+	case replace:
+		versions[index] = entry
+		kind = ChangeUpdated
+	default:
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrGeneratorExists, entryKey(name, entry.version))
+	}
+	subscribers := r.subscribersLocked()
+	r.mu.Unlock()
+
+	notify(subscribers, Change{Kind: kind, Name: name, Version: entry.version})
+	return nil
+}
+
+// Unregister удаляет псевдоним или генератор: по имени — все его версии и
+// псевдонимы, по имени@версии — одну версию. Генераторы не останавливаются.
+func (r *GeneratorRegistry) Unregister(key string) error {
+	r.mu.Lock()
+	if target, ok := r.aliases[key]; ok {
+		delete(r.aliases, key)
+		delete(r.runtimeAliases, key)
+		subscribers := r.subscribersLocked()
+		r.mu.Unlock()
+		notify(subscribers, Change{Kind: ChangeRemoved, Name: key, Target: target})
+		return nil
+	}
+	name, version, exact := r.parseKey(key)
+	versions := r.entries[name]
+	var removed []Change
+	switch index := findVersion(versions, version); {
+	case len(versions) == 0 || exact && index < 0:
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrGeneratorNotFound, key)
+	case exact && len(versions) > 1:
+		r.entries[name] = append(versions[:index:index], versions[index+1:]...)
+		removed = append(removed, Change{Kind: ChangeRemoved, Name: name, Version: version})
+	default:
+		delete(r.entries, name)
+		for _, entry := range versions {
+			removed = append(removed, Change{Kind: ChangeRemoved, Name: name, Version: entry.version})
+		}
+		for alias, target := range r.aliases {
+			if target == name {
+				delete(r.aliases, alias)
+				delete(r.runtimeAliases, alias)
+				removed = append(removed, Change{Kind: ChangeRemoved, Name: alias, Target: name})
+			}
+		}
+	}
+	subscribers := r.subscribersLocked()
+	r.mu.Unlock()
+
+	for _, change := range removed {
+		notify(subscribers, change)
+	}
+	return nil
+}
+
+// Alias добавляет псевдоним alias для генератора target или переназначает
+// существующий псевдоним. Псевдоним не может совпадать с именем генератора.
+func (r *GeneratorRegistry) Alias(alias, target string) error {
+	return r.alias(alias, target, true)
+}
+
+func (r *GeneratorRegistry) alias(alias, target string, runtime bool) error {
+	if alias == "" || strings.Contains(alias, VersionSeparator) {
+		return fmt.Errorf("недопустимый псевдоним %q", alias)
+	}
+
+	r.mu.Lock()
+	if _, exists := r.entries[alias]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("псевдоним %s совпадает с именем генератора", alias)
+	}
+	if resolved, ok := r.aliases[target]; ok {
+		target = resolved
+	}
+	if _, exists := r.entries[target]; !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("псевдоним %s: %w: %s", alias, ErrGeneratorNotFound, target)
+	}
+	kind := ChangeAdded
+	if previous, exists := r.aliases[alias]; exists {
+		if previous == target {
+			r.runtimeAliases[alias] = runtime
+			r.mu.Unlock()
+			return nil
+		}
+		kind = ChangeUpdated
+	}
+	r.aliases[alias] = target
+	r.runtimeAliases[alias] = runtime
+	subscribers := r.subscribersLocked()
+	r.mu.Unlock()
+
+	notify(subscribers, Change{Kind: kind, Name: alias, Target: target})
+	return nil
+}
+
+// Aliases возвращает псевдонимы и имена генераторов, на которые они ведут.
+func (r *GeneratorRegistry) Aliases() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	aliases := make(map[string]string, len(r.aliases))
+	for alias, target := range r.aliases {
+		aliases[alias] = target
+	}
+	return aliases
+}
+
+// Resolve возвращает имя генератора для имени, псевдонима или имени@версии.
+func (r *GeneratorRegistry) Resolve(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, _, _ := r.parseKey(key)
+	return name
+}
+
+// Get возвращает генератор по имени или псевдониму. С суффиксом @версия
+// выбирается старшая версия, которая начинается с указанной: go-struct@2
+// находит 2.3.1, а go-struct@2.1 — только 2.1.x.
+func (r *GeneratorRegistry) Get(key string) (CodeGenerator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.lookup(key)
+	if entry == nil {
+		return nil, false
+	}
+	return entry.generator, true
+}
+
+func (r *GeneratorRegistry) lookup(key string) *registryEntry {
+	name, version, exact := r.parseKey(key)
+	versions := r.entries[name]
+	if len(versions) == 0 {
+		return nil
+	}
+	if !exact {
+		return versions[0]
+	}
+	var best *registryEntry
+	for _, entry := range versions {
+		if !versionPrefix(entry.version, version) {
+			continue
+		}
+		if best == nil || plugins.CompareVersions(entry.version, best.version) > 0 {
+			best = entry
+		}
+	}
+	return best
+}
+
+// parseKey разбирает имя@версию и заменяет псевдоним именем генератора.
+func (r *GeneratorRegistry) parseKey(key string) (name, version string, exact bool) {
+	name, version, exact = strings.Cut(key, VersionSeparator)
+	if target, ok := r.aliases[name]; ok {
+		name = target
+	}
+	return name, version, exact
+}
+
+// Versions возвращает версии генератора; первая используется по умолчанию.
+// У встроенных генераторов версия пустая.
+func (r *GeneratorRegistry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, _, _ = r.parseKey(name)
+	versions := make([]string, len(r.entries[name]))
+	for i, entry := range r.entries[name] {
+		versions[i] = entry.version
+	}
+	return versions
+}
+
+// List возвращает генераторы по умолчанию, отсортированные по имени.
+func (r *GeneratorRegistry) List() []CodeGenerator {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := r.namesLocked()
+	generators := make([]CodeGenerator, len(names))
+	for i, name := range names {
+		generators[i] = r.entries[name][0].generator
+	}
+	return generators
+}
+
+// GetNames возвращает отсортированные имена генераторов.
+func (r *GeneratorRegistry) GetNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+func (r *GeneratorRegistry) namesLocked() []string {
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Subscribe вызывает fn при каждом изменении реестра, пока не будет вызвана
+// возвращённая функция отмены. fn вызывается вне блокировки реестра, поэтому
+// может обращаться к нему.
+func (r *GeneratorRegistry) Subscribe(fn func(Change)) func() {
+	r.mu.Lock()
+	id := r.nextSubscriber
+	r.nextSubscriber++
+	r.subscribers[id] = fn
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		delete(r.subscribers, id)
+		r.mu.Unlock()
+	}
+}
+
+func (r *GeneratorRegistry) subscribersLocked() []func(Change) {
+	ids := make([]int, 0, len(r.subscribers))
+	for id := range r.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscribers := make([]func(Change), len(ids))
+	for i, id := range ids {
+		subscribers[i] = r.subscribers[id]
+	}
+	return subscribers
+}
+
+func notify(subscribers []func(Change), change Change) {
+	for _, subscriber := range subscribers {
+		subscriber(change)
+	}
+}
+
+// Options возвращает настройки генератора по умолчанию из конфигурации,
+// дополненные и переопределённые options. name может быть псевдонимом или
+// именем@версией.
+func (r *GeneratorRegistry) Options(name string, options map[string]string) map[string]string {
+	r.mu.RLock()
+	name, _, _ = r.parseKey(name)
+	defaults := r.defaults[name]
+	r.mu.RUnlock()
+	if len(defaults) == 0 {
+		return options
+	}
+	merged := make(map[string]string, len(defaults)+len(options))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range options {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Disabled сообщает, отключён ли плагин в конфигурации, и возвращает файл,
+// в котором это сделано.
+func (r *GeneratorRegistry) Disabled(name string) (string, bool) {
+	name, _, _ = strings.Cut(name, VersionSeparator)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	source, disabled := r.disabled[name]
+	return source, disabled
+}
+
+// Close останавливает рабочие процессы плагинов.
+func (r *GeneratorRegistry) Close() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, versions := range r.entries {
+		for _, entry := range versions {
+			if closer, ok := entry.generator.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+}
+
+// generatorVersion возвращает версию плагина из манифеста; у встроенных
+// генераторов версии нет.
+func generatorVersion(generator CodeGenerator) string {
+	if provider, ok := generator.(plugins.ManifestProvider); ok && provider.Manifest() != nil {
+		return provider.Manifest().Version
+	}
+	return ""
+}
+
+func findVersion(versions []*registryEntry, version string) int {
+	for i, entry := range versions {
+		if entry.version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// versionPrefix сообщает, начинается ли version с компонентов prefix.
+func versionPrefix(version, prefix string) bool {
+	version = strings.TrimPrefix(version, "v")
+	prefix = strings.TrimPrefix(prefix, "v")
+	return version == prefix || strings.HasPrefix(version, prefix+".")
+}
+
+// entryKey — имя версии в отчётах: имя@версия или просто имя.
+func entryKey(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + VersionSeparator + version
+}
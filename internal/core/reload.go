@@ -0,0 +1,456 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// watchDepth ограничивает глубину обхода каталогов плагинов при поиске
+// изменений: плагин лежит в каталоге пути поиска, а его сборка — не глубже
+// пары уровней.
+const watchDepth = 3
+
+// ReloadReport описывает результат перезагрузки плагинов.
+type ReloadReport struct {
+	Added     []string
+	Updated   []string
+	Removed   []string
+	Discovery *plugins.Discovery
+}
+
+// Changed сообщает, изменился ли набор плагинов.
+func (r *ReloadReport) Changed() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Removed) > 0
+}
+
+func (r *ReloadReport) String() string {
+	var parts []string
+	for _, part := range []struct {
+		label string
+		names []string
+	}{{"added", r.Added}, {"updated", r.Updated}, {"removed", r.Removed}} {
+		if len(part.names) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", part.label, strings.Join(part.names, ", ")))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Reloader хранит текущий реестр и заменяет его целиком при перезагрузке.
+// Запросы берут реестр через Acquire и возвращают его функцией release, поэтому
+// плагины старого реестра останавливаются только после завершения всех
+// запросов, которые их используют. Неизменившиеся плагины переходят в новый
+// реестр вместе с рабочими процессами, как и генераторы и псевдонимы,
+// добавленные через Register, Replace и Alias. Подписчики Subscribe узнают об
+// изменениях текущего реестра, в том числе при его замене.
+type Reloader struct {
+	load func() (*GeneratorRegistry, *plugins.Discovery, error)
+
+	mu      sync.Mutex
+	current *registrySnapshot
+	// reloadMu не даёт двум перезагрузкам идти одновременно.
+	reloadMu sync.Mutex
+	state    string
+
+	subscribersMu  sync.Mutex
+	subscribers    map[int]func(Change)
+	nextSubscriber int
+	// unsubscribe отменяет подписку на изменения текущего реестра.
+	unsubscribe func()
+}
+
+type registrySnapshot struct {
+	registry *GeneratorRegistry
+	refs     int
+	retired  bool
+	drained  chan struct{}
+}
+
+// NewReloader создаёт Reloader с реестром registry. load загружает новый
+// реестр, обычно это LoadGeneratorRegistry; без него Reload возвращает ошибку.
+func NewReloader(registry *GeneratorRegistry, load func() (*GeneratorRegistry, *plugins.Discovery, error)) *Reloader {
+	reloader := &Reloader{
+		load:        load,
+		current:     &registrySnapshot{registry: registry, drained: make(chan struct{})},
+		subscribers: make(map[int]func(Change)),
+	}
+	reloader.unsubscribe = registry.Subscribe(reloader.notify)
+	if load != nil {
+		reloader.state = watchState()
+	}
+	return reloader
+}
+
+// Acquire возвращает текущий реестр. release нужно вызвать, когда запрос
+// закончит работу с реестром и полученными из него генераторами.
+func (r *Reloader) Acquire() (*GeneratorRegistry, func()) {
+	r.mu.Lock()
+	snapshot := r.current
+	snapshot.refs++
+	r.mu.Unlock()
+
+	var once sync.Once
+	return snapshot.registry, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			snapshot.refs--
+			if snapshot.retired && snapshot.refs == 0 {
+				close(snapshot.drained)
+			}
+			r.mu.Unlock()
+		})
+	}
+}
+
+// Reload загружает плагины заново и атомарно заменяет реестр. Если загрузка
+// не удалась, остаётся прежний реестр.
+func (r *Reloader) Reload() (*ReloadReport, error) {
+	if r.load == nil {
+		return nil, errors.New("перезагрузка плагинов не поддерживается")
+	}
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	state := watchState()
+	registry, discovery, err := r.load()
+	if err != nil {
+		if registry != nil {
+			registry.Close()
+		}
+		return nil, err
+	}
+
+	r.mu.Lock()
+	old := r.current
+	r.mu.Unlock()
+
+	report := &ReloadReport{Discovery: discovery}
+	stale, changes := registry.reuse(old.registry, report)
+
+	r.mu.Lock()
+	r.current = &registrySnapshot{registry: registry, drained: make(chan struct{})}
+	old.retired = true
+	if old.refs == 0 {
+		close(old.drained)
+	}
+	r.mu.Unlock()
+	r.state = state
+
+	r.unsubscribe()
+	r.unsubscribe = registry.Subscribe(r.notify)
+	for _, change := range changes {
+		r.notify(change)
+	}
+
+	go func() {
+		<-old.drained
+		for _, generator := range stale {
+			if closer, ok := generator.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}()
+	return report, nil
+}
+
+// Subscribe вызывает fn при каждом изменении текущего реестра и при каждом
+// изменении, внесённом перезагрузкой, пока не будет вызвана возвращённая
+// функция отмены.
+func (r *Reloader) Subscribe(fn func(Change)) func() {
+	r.subscribersMu.Lock()
+	id := r.nextSubscriber
+	r.nextSubscriber++
+	r.subscribers[id] = fn
+	r.subscribersMu.Unlock()
+
+	return func() {
+		r.subscribersMu.Lock()
+		delete(r.subscribers, id)
+		r.subscribersMu.Unlock()
+	}
+}
+
+func (r *Reloader) notify(change Change) {
+	r.subscribersMu.Lock()
+	ids := make([]int, 0, len(r.subscribers))
+	for id := range r.subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscribers := make([]func(Change), len(ids))
+	for i, id := range ids {
+		subscribers[i] = r.subscribers[id]
+	}
+	r.subscribersMu.Unlock()
+	notify(subscribers, change)
+}
+
+// Watch проверяет каталоги плагинов, plugins.json и файлы конфигурации каждые
+// interval и перезагружает плагины после изменений. Изменение применяется,
+// когда файлы перестают меняться, чтобы не загрузить наполовину
+// скопированный плагин. Результат каждой перезагрузки передаётся в report.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, report func(*ReloadReport, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	pending := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state := watchState()
+		r.reloadMu.Lock()
+		unchanged := state == r.state
+		r.reloadMu.Unlock()
+		if unchanged || state != pending {
+			pending = state
+			continue
+		}
+		pending = ""
+		result, err := r.Reload()
+		if report != nil && (err != nil || result.Changed()) {
+			report(result, err)
+		}
+		if err != nil {
+			// Повторять неудачную загрузку до следующего изменения незачем.
+			r.reloadMu.Lock()
+			r.state = state
+			r.reloadMu.Unlock()
+		}
+	}
+}
+
+// Close останавливает плагины текущего реестра.
+func (r *Reloader) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current.registry.Close()
+}
+
+// reuse переносит из old плагины, которые не изменились, заполняет report и
+// возвращает генераторы old, которые больше не используются, и изменения
+// реестра. Плагин узнаётся по отпечатку, поэтому он сохраняет рабочие
+// процессы, даже если перестал быть версией по умолчанию. Конвейеры не
+// переносятся: они ищут генераторы в своём реестре. Генераторы и псевдонимы,
+// добавленные во время работы, тоже переносятся, если при загрузке не
+// появились генератор или псевдоним с тем же именем.
+func (r *GeneratorRegistry) reuse(old *GeneratorRegistry, report *ReloadReport) ([]CodeGenerator, []Change) {
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := old.pluginEntries()
+	byFingerprint := map[string]*registryEntry{}
+	for _, entry := range previous {
+		byFingerprint[entry.fingerprint] = entry
+	}
+	current := r.pluginEntries()
+	reused := map[*registryEntry]bool{}
+	for _, entry := range current {
+		if _, pipeline := entry.generator.(*Pipeline); pipeline {
+			continue
+		}
+		if kept, ok := byFingerprint[entry.fingerprint]; ok && !reused[kept] {
+			if closer, ok := entry.generator.(io.Closer); ok {
+				closer.Close()
+			}
+			entry.generator = kept.generator
+			reused[kept] = true
+		}
+	}
+
+	var stale []CodeGenerator
+	for _, entry := range previous {
+		if !reused[entry] {
+			stale = append(stale, entry.generator)
+		}
+	}
+	stale = append(stale, r.carryRuntime(old)...)
+
+	var changes []Change
+	for _, key := range sortedEntryKeys(current) {
+		entry := current[key]
+		change := Change{Name: entry.generator.GetName(), Version: entry.version}
+		switch kept, ok := previous[key]; {
+		case !ok:
+			report.Added = append(report.Added, key)
+			change.Kind = ChangeAdded
+		case kept.fingerprint != entry.fingerprint:
+			report.Updated = append(report.Updated, key)
+			change.Kind = ChangeUpdated
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for _, key := range sortedEntryKeys(previous) {
+		if _, ok := current[key]; !ok {
+			entry := previous[key]
+			report.Removed = append(report.Removed, key)
+			changes = append(changes, Change{Kind: ChangeRemoved, Name: entry.generator.GetName(), Version: entry.version})
+		}
+	}
+
+	for _, alias := range sortedAliasNames(r.aliases) {
+		target := r.aliases[alias]
+		switch previous, ok := old.aliases[alias]; {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdded, Name: alias, Target: target})
+		case previous != target:
+			changes = append(changes, Change{Kind: ChangeUpdated, Name: alias, Target: target})
+		}
+	}
+	for _, alias := range sortedAliasNames(old.aliases) {
+		if _, ok := r.aliases[alias]; !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, Name: alias, Target: old.aliases[alias]})
+		}
+	}
+	return stale, changes
+}
+
+// carryRuntime добавляет генераторы и псевдонимы old, добавленные во время
+// работы, и возвращает генераторы, для которых не нашлось места.
+func (r *GeneratorRegistry) carryRuntime(old *GeneratorRegistry) []CodeGenerator {
+	var stale []CodeGenerator
+	for _, name := range old.namesLocked() {
+		for _, entry := range old.entries[name] {
+			if !entry.runtime {
+				continue
+			}
+			if _, alias := r.aliases[name]; alias || findVersion(r.entries[name], entry.version) >= 0 {
+				stale = append(stale, entry.generator)
+				continue
+			}
+			carried := *entry
+			r.entries[name] = append(r.entries[name], &carried)
+		}
+	}
+
+	for _, alias := range sortedAliasNames(old.aliases) {
+		target := old.aliases[alias]
+		if !old.runtimeAliases[alias] || len(r.entries[target]) == 0 {
+			continue
+		}
+		if _, exists := r.aliases[alias]; exists {
+			continue
+		}
+		if _, exists := r.entries[alias]; exists {
+			continue
+		}
+		r.aliases[alias] = target
+		r.runtimeAliases[alias] = true
+	}
+	return stale
+}
+
+// pluginEntries возвращает версии плагинов и конвейеры по имени в отчёте:
+// версия по умолчанию — по имени плагина, остальные — по имени@версии.
+func (r *GeneratorRegistry) pluginEntries() map[string]*registryEntry {
+	entries := map[string]*registryEntry{}
+	for name, versions := range r.entries {
+		for i, entry := range versions {
+			if entry.fingerprint == "" {
+				continue
+			}
+			key := name
+			if i > 0 || versions[0].fingerprint == "" {
+				key = entryKey(name, entry.version)
+			}
+			entries[key] = entry
+		}
+	}
+	return entries
+}
+
+func sortedEntryKeys(entries map[string]*registryEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedAliasNames(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pluginFingerprint меняется вместе с описанием плагина и его файлами.
+func pluginFingerprint(info plugins.PluginInfo) string {
+	data, _ := json.Marshal(info)
+	hash := sha256.New()
+	hash.Write(data)
+	fmt.Fprintf(hash, "\n%s\n", info.Scope)
+	paths := []string{info.Path}
+	if info.Manifest != nil && info.Manifest.Path != "" {
+		paths = append(paths, info.Manifest.Path)
+	}
+	for _, path := range paths {
+		if stat, err := os.Stat(path); err == nil {
+			fmt.Fprintf(hash, "%s %d %d\n", path, stat.Size(), stat.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// watchState описывает состояние всех файлов, от которых зависит набор
+// плагинов.
+func watchState() string {
+	paths := plugins.NewPluginManager().WatchPaths()
+	paths = append(paths, config.UserPath())
+	if dir := plugins.FindProjectDir(); dir != "" {
+		paths = append(paths, filepath.Join(dir, plugins.ProjectConfig))
+	}
+
+	hash := sha256.New()
+	for _, path := range paths {
+		root := filepath.Clean(path)
+		filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(hash, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+			if entry.IsDir() && strings.Count(strings.TrimPrefix(path, root), string(filepath.Separator)) >= watchDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
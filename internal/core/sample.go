@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const defaultModelName = "GeneratedStruct"
+
+// DocumentFromSample строит модель типов по примеру данных в форме,
+// которую возвращает json.Unmarshal в interface{}.
+func DocumentFromSample(rootName string, data interface{}) (*ir.Document, error) {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("неподдерживаемый тип данных: %T", data)
+	}
+
+	doc := ir.NewDocument()
+	inferModel(doc, rootName, object)
+	return doc, nil
+}
+
+func inferModel(doc *ir.Document, name string, object map[string]interface{}) string {
+	model := &ir.Model{
+		Name: name,
+		Type: &ir.Type{Kind: ir.KindObject},
+	}
+	doc.Add(model)
+
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		model.Type.Fields = append(model.Type.Fields, &ir.Field{
+			Name:     key,
+			Type:     inferType(doc, key, object[key]),
+			Required: true,
+		})
+	}
+
+	return name
+}
+
+func inferType(doc *ir.Document, key string, value interface{}) *ir.Type {
+	switch v := value.(type) {
+	case nil:
+		return ir.Primitive(ir.KindAny)
+	case bool:
+		return ir.Primitive(ir.KindBoolean)
+	case float64:
+		if v == float64(int64(v)) {
+			return ir.Primitive(ir.KindInteger)
+		}
+		return ir.Primitive(ir.KindNumber)
+	case string:
+		return ir.Primitive(ir.KindString)
+	case []interface{}:
+		if len(v) == 0 {
+			return ir.ArrayOf(ir.Primitive(ir.KindAny))
+		}
+		return ir.ArrayOf(inferType(doc, key, v[0]))
+	case map[string]interface{}:
+		return ir.Ref(inferModel(doc, uniqueModelName(doc, ToPascalCase(key)), v))
+	default:
+		return ir.Primitive(ir.KindAny)
+	}
+}
+
+func uniqueModelName(doc *ir.Document, base string) string {
+	if base == "" {
+		base = "Item"
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, exists := doc.Model(name); !exists {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// SampleFromDocument строит по первой модели документа значение-образец,
+// из которого JSON-генераторы выведут те же типы полей.
+func SampleFromDocument(doc *ir.Document) (interface{}, error) {
+	if len(doc.Models) == 0 {
+		return nil, fmt.Errorf("документ не содержит моделей")
+	}
+	return sampleValue(doc, doc.Models[0].Type, map[string]bool{doc.Models[0].Name: true}), nil
+}
+
+func sampleValue(doc *ir.Document, t *ir.Type, visiting map[string]bool) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	switch t.Kind {
+	case ir.KindString:
+		return "string"
+	case ir.KindInteger:
+		return float64(0)
+	case ir.KindNumber:
+		return 0.5
+	case ir.KindBoolean:
+		return false
+	case ir.KindTime:
+		return "1970-01-01T00:00:00Z"
+	case ir.KindArray:
+		return []interface{}{sampleValue(doc, t.Elem, visiting)}
+	case ir.KindMap:
+		return map[string]interface{}{}
+	case ir.KindObject:
+		object := make(map[string]interface{}, len(t.Fields))
+		for _, field := range t.Fields {
+			object[field.Name] = sampleValue(doc, field.Type, visiting)
+		}
+		return object
+	case ir.KindRef:
+		model, ok := doc.Model(t.Ref)
+		if !ok || visiting[t.Ref] {
+			return map[string]interface{}{}
+		}
+		visiting[t.Ref] = true
+		defer delete(visiting, t.Ref)
+		return sampleValue(doc, model.Type, visiting)
+	default:
+		return nil
+	}
+}
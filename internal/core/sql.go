@@ -0,0 +1,551 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+type SQLDialect string
+
+const (
+	DialectPostgres SQLDialect = "postgres"
+	DialectMySQL    SQLDialect = "mysql"
+	DialectSQLite   SQLDialect = "sqlite"
+)
+
+const (
+	NestedJSON   = "json"
+	NestedTables = "tables"
+)
+
+var sqlDialectTitles = map[SQLDialect]string{
+	DialectPostgres: "PostgreSQL",
+	DialectMySQL:    "MySQL",
+	DialectSQLite:   "SQLite",
+}
+
+var sqlReservedWords = map[string]bool{
+	"all": true, "and": true, "as": true, "asc": true, "by": true, "case": true, "check": true,
+	"column": true, "constraint": true, "create": true, "default": true, "delete": true, "desc": true,
+	"else": true, "end": true, "foreign": true, "from": true, "grant": true, "group": true, "in": true,
+	"index": true, "insert": true, "is": true, "join": true, "key": true, "limit": true, "not": true,
+	"null": true, "on": true, "or": true, "order": true, "primary": true, "references": true,
+	"select": true, "table": true, "then": true, "to": true, "union": true, "unique": true,
+	"update": true, "user": true, "values": true, "when": true, "where": true,
+}
+
+type SQLGenerator struct {
+	name        string
+	description string
+	dialect     SQLDialect
+	nested      string
+}
+
+type sqlTable struct {
+	name        string
+	columns     []*sqlColumn
+	primaryKey  []string
+	foreignKeys []sqlForeignKey
+	// fields сопоставляет полям модели их колонки. Полей, которые хранятся в
+	// других таблицах, здесь нет.
+	fields map[string]string
+}
+
+type sqlColumn struct {
+	name          string
+	sqlType       string
+	notNull       bool
+	autoIncrement bool
+	check         []string
+	// key — тип первичного или внешнего ключа в модели типов, по нему
+	// GoStructGenerator добавляет в структуру поля для колонок-ключей.
+	key *ir.Type
+}
+
+type sqlForeignKey struct {
+	column    string
+	table     string
+	refColumn string
+}
+
+func NewSQLGenerator(dialect SQLDialect) *SQLGenerator {
+	return &SQLGenerator{
+		name:        "sql-" + string(dialect),
+		description: fmt.Sprintf("Генерирует CREATE TABLE для %s из JSON схемы", sqlDialectTitles[dialect]),
+		dialect:     dialect,
+		nested:      NestedJSON,
+	}
+}
+
+func (g *SQLGenerator) GetName() string {
+	return g.name
+}
+
+func (g *SQLGenerator) GetDescription() string {
+	return g.description
+}
+
+func (g *SQLGenerator) Configure(options map[string]string) (CodeGenerator, error) {
+	configured := *g
+	for key, value := range options {
+		switch key {
+		case "nested":
+			if value != NestedJSON && value != NestedTables {
+				return nil, invalidOption(key, value, NestedJSON, NestedTables)
+			}
+			configured.nested = value
+		default:
+			return nil, unknownOption(g, key, "nested")
+		}
+	}
+	return &configured, nil
+}
+
+func (g *SQLGenerator) Generate(input string) (string, error) {
+	doc, err := ParseInput(input, FormatAuto)
+	if err != nil {
+		return "", err
+	}
+
+	return g.GenerateDocument(doc)
+}
+
+func (g *SQLGenerator) GenerateDocument(doc *ir.Document) (string, error) {
+	planner := &sqlPlanner{generator: g, doc: doc, tables: make(map[string]*sqlTable)}
+	tables, err := planner.plan()
+	if err != nil {
+		return "", err
+	}
+
+	statements := make([]string, 0, len(tables))
+	for _, table := range tables {
+		statements = append(statements, g.createTable(table))
+	}
+
+	return strings.Join(statements, "\n\n"), nil
+}
+
+func (g *SQLGenerator) createTable(table *sqlTable) string {
+	var lines []string
+
+	for _, column := range table.columns {
+		line := fmt.Sprintf("    %s %s", g.quote(column.name), column.sqlType)
+		if len(table.primaryKey) == 1 && table.primaryKey[0] == column.name {
+			switch {
+			case column.autoIncrement && g.dialect == DialectMySQL:
+				line += " AUTO_INCREMENT PRIMARY KEY"
+			case column.autoIncrement && g.dialect == DialectSQLite:
+				line += " PRIMARY KEY AUTOINCREMENT"
+			default:
+				line += " PRIMARY KEY"
+			}
+		} else if column.notNull {
+			line += " NOT NULL"
+		}
+		if len(column.check) > 0 {
+			values := make([]string, len(column.check))
+			for i, value := range column.check {
+				values[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+			}
+			line += fmt.Sprintf(" CHECK (%s IN (%s))", g.quote(column.name), strings.Join(values, ", "))
+		}
+		lines = append(lines, line)
+	}
+
+	if len(table.primaryKey) > 1 {
+		columns := make([]string, len(table.primaryKey))
+		for i, column := range table.primaryKey {
+			columns[i] = g.quote(column)
+		}
+		lines = append(lines, fmt.Sprintf("    PRIMARY KEY (%s)", strings.Join(columns, ", ")))
+	}
+
+	for _, fk := range table.foreignKeys {
+		lines = append(lines, fmt.Sprintf("    FOREIGN KEY (%s) REFERENCES %s (%s)", g.quote(fk.column), g.quote(fk.table), g.quote(fk.refColumn)))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", g.quote(table.name), strings.Join(lines, ",\n"))
+}
+
+func (g *SQLGenerator) quote(name string) string {
+	if !sqlReservedWords[strings.ToLower(name)] {
+		return name
+	}
+	if g.dialect == DialectMySQL {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+func (g *SQLGenerator) columnType(t *ir.Type, primary bool) string {
+	switch t.Kind {
+	case ir.KindString:
+		switch {
+		case t.Format == "uuid" && g.dialect == DialectPostgres:
+			return "UUID"
+		case t.Format == "uuid" && g.dialect == DialectMySQL:
+			return "CHAR(36)"
+		case t.Format == "date" && g.dialect != DialectSQLite:
+			return "DATE"
+		case t.Format == "decimal" && g.dialect == DialectMySQL:
+			return "DECIMAL(65, 30)"
+		case t.Format == "decimal":
+			return "NUMERIC"
+		case t.Format == "binary" && g.dialect == DialectPostgres:
+			return "BYTEA"
+		case t.Format == "binary":
+			return "BLOB"
+		case g.dialect == DialectMySQL:
+			return "VARCHAR(255)"
+		default:
+			return "TEXT"
+		}
+	case ir.KindInteger:
+		if g.dialect == DialectSQLite {
+			return "INTEGER"
+		}
+		if t.Format == "int64" || primary {
+			return "BIGINT"
+		}
+		if g.dialect == DialectMySQL {
+			return "INT"
+		}
+		return "INTEGER"
+	case ir.KindNumber:
+		switch g.dialect {
+		case DialectPostgres:
+			if t.Format == "float" {
+				return "REAL"
+			}
+			return "DOUBLE PRECISION"
+		case DialectMySQL:
+			if t.Format == "float" {
+				return "FLOAT"
+			}
+			return "DOUBLE"
+		default:
+			return "REAL"
+		}
+	case ir.KindBoolean:
+		if g.dialect == DialectSQLite {
+			return "INTEGER"
+		}
+		return "BOOLEAN"
+	case ir.KindTime:
+		switch g.dialect {
+		case DialectPostgres:
+			return "TIMESTAMPTZ"
+		case DialectMySQL:
+			return "DATETIME"
+		default:
+			return "TEXT"
+		}
+	default:
+		return g.jsonType()
+	}
+}
+
+func (g *SQLGenerator) jsonType() string {
+	switch g.dialect {
+	case DialectPostgres:
+		return "JSONB"
+	case DialectMySQL:
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
+
+func (g *SQLGenerator) serialType() string {
+	switch g.dialect {
+	case DialectPostgres:
+		return "BIGSERIAL"
+	case DialectMySQL:
+		return "BIGINT"
+	default:
+		return "INTEGER"
+	}
+}
+
+type sqlPlanner struct {
+	generator *SQLGenerator
+	doc       *ir.Document
+	tables    map[string]*sqlTable
+	order     []string
+	deps      map[string][]string
+}
+
+func (p *sqlPlanner) plan() ([]*sqlTable, error) {
+	models := p.tableModels()
+	if len(models) == 0 {
+		return nil, fmt.Errorf("документ не содержит объектных моделей для таблиц")
+	}
+
+	p.deps = make(map[string][]string)
+	for _, model := range models {
+		p.addTable(model)
+	}
+	for _, model := range models {
+		p.addColumns(model)
+	}
+
+	return p.sorted(), nil
+}
+
+// tableModels выбирает модели, которые станут таблицами. При хранении
+// вложенных объектов в JSON таблицами становятся только модели, на которые
+// не ссылаются другие объекты.
+func (p *sqlPlanner) tableModels() []*ir.Model {
+	var candidates []*ir.Model
+	for _, model := range p.doc.Models {
+		if model.Type != nil && model.Type.Kind == ir.KindObject && model.Role == "" {
+			candidates = append(candidates, model)
+		}
+	}
+
+	if p.generator.nested == NestedTables {
+		return candidates
+	}
+
+	referenced := make(map[string]bool)
+	for _, model := range candidates {
+		for _, field := range model.Type.Fields {
+			for _, ref := range field.Type.Refs() {
+				if ref != model.Name {
+					referenced[ref] = true
+				}
+			}
+		}
+	}
+
+	var roots []*ir.Model
+	for _, model := range candidates {
+		if !referenced[model.Name] {
+			roots = append(roots, model)
+		}
+	}
+	if len(roots) == 0 {
+		return candidates
+	}
+	return roots
+}
+
+func (p *sqlPlanner) addTable(model *ir.Model) {
+	table := &sqlTable{name: SQLTableName(model.Name), fields: make(map[string]string)}
+
+	primary := &sqlColumn{name: "id", sqlType: p.generator.serialType(), notNull: true, autoIncrement: true,
+		key: &ir.Type{Kind: ir.KindInteger, Format: "int64"}}
+	for _, field := range model.Type.Fields {
+		if SQLColumnName(field.Name) == "id" {
+			if t := p.resolve(field.Type); isScalarKind(t.Kind) {
+				key := *t
+				key.Nullable = false
+				primary = &sqlColumn{name: "id", sqlType: p.generator.columnType(t, true), notNull: true, key: &key}
+				table.fields[field.Name] = "id"
+			}
+		}
+	}
+
+	table.columns = append(table.columns, primary)
+	table.primaryKey = []string{primary.name}
+	p.tables[model.Name] = table
+	p.order = append(p.order, model.Name)
+}
+
+func (p *sqlPlanner) addColumns(model *ir.Model) {
+	table := p.tables[model.Name]
+
+	for _, field := range model.Type.Fields {
+		name := SQLColumnName(field.Name)
+		if name == "id" && table.columns[0].name == "id" && !table.columns[0].autoIncrement {
+			continue
+		}
+		if name == "id" {
+			name = "source_id"
+		}
+
+		t := p.resolve(field.Type)
+		notNull := field.Required && !nullableColumn(t)
+
+		switch {
+		case isScalarKind(t.Kind):
+			column := &sqlColumn{name: name, sqlType: p.generator.columnType(t, false), notNull: notNull, check: t.Enum}
+			table.columns = append(table.columns, column)
+			table.fields[field.Name] = name
+
+		case t.Kind == ir.KindRef && p.nestedTables() && p.tables[t.Ref] != nil:
+			child := p.tables[t.Ref]
+			column := name + "_id"
+			table.columns = append(table.columns, &sqlColumn{name: column, sqlType: p.keyType(child), notNull: notNull, key: child.keyRef(!notNull)})
+			table.foreignKeys = append(table.foreignKeys, sqlForeignKey{column: column, table: child.name, refColumn: child.primaryKey[0]})
+			p.deps[model.Name] = append(p.deps[model.Name], t.Ref)
+
+		case t.Kind == ir.KindArray && p.nestedTables():
+			p.addArray(model, name, field, p.resolve(t.Elem))
+
+		case t.Kind == ir.KindArray:
+			sqlType := p.generator.jsonType()
+			if elem := p.resolve(t.Elem); p.generator.dialect == DialectPostgres && isScalarKind(elem.Kind) {
+				sqlType = p.generator.columnType(elem, false) + "[]"
+			}
+			table.columns = append(table.columns, &sqlColumn{name: name, sqlType: sqlType, notNull: notNull})
+			table.fields[field.Name] = name
+
+		default:
+			table.columns = append(table.columns, &sqlColumn{name: name, sqlType: p.generator.jsonType(), notNull: notNull})
+			table.fields[field.Name] = name
+		}
+	}
+}
+
+// addArray хранит массив в отдельной таблице: объекты — в таблице их модели
+// со ссылкой на родителя, остальные значения — в таблице parent_field.
+func (p *sqlPlanner) addArray(model *ir.Model, name string, field *ir.Field, elem *ir.Type) {
+	table := p.tables[model.Name]
+	parentColumn := table.name + "_id"
+
+	if elem.Kind == ir.KindRef && p.tables[elem.Ref] != nil {
+		child := p.tables[elem.Ref]
+		if child.column(parentColumn) == nil {
+			child.columns = append(child.columns, &sqlColumn{name: parentColumn, sqlType: p.keyType(table), key: table.keyRef(true)})
+			child.foreignKeys = append(child.foreignKeys, sqlForeignKey{column: parentColumn, table: table.name, refColumn: table.primaryKey[0]})
+			p.deps[elem.Ref] = append(p.deps[elem.Ref], model.Name)
+		}
+		return
+	}
+
+	valueType := p.generator.jsonType()
+	if isScalarKind(elem.Kind) {
+		valueType = p.generator.columnType(elem, false)
+	}
+
+	joinName := table.name + "_" + name
+	join := &sqlTable{
+		name: joinName,
+		columns: []*sqlColumn{
+			{name: parentColumn, sqlType: p.keyType(table), notNull: true},
+			{name: "position", sqlType: p.generator.columnType(ir.Primitive(ir.KindInteger), false), notNull: true},
+			{name: "value", sqlType: valueType, notNull: !nullableColumn(elem), check: elem.Enum},
+		},
+		primaryKey:  []string{parentColumn, "position"},
+		foreignKeys: []sqlForeignKey{{column: parentColumn, table: table.name, refColumn: table.primaryKey[0]}},
+	}
+
+	key := model.Name + "." + field.Name
+	p.tables[key] = join
+	p.order = append(p.order, key)
+	p.deps[key] = append(p.deps[key], model.Name)
+}
+
+// nullableColumn сообщает, может ли колонка содержать NULL. Тип any получают
+// значения, которые в образце были null, и схемы без типа, поэтому такие
+// колонки всегда допускают NULL.
+func nullableColumn(t *ir.Type) bool {
+	return t.Nullable || t.Kind == ir.KindAny
+}
+
+func (p *sqlPlanner) nestedTables() bool {
+	return p.generator.nested == NestedTables
+}
+
+func (p *sqlPlanner) keyType(table *sqlTable) string {
+	primary := table.column(table.primaryKey[0])
+	if primary.autoIncrement {
+		if p.generator.dialect == DialectSQLite {
+			return "INTEGER"
+		}
+		return "BIGINT"
+	}
+	return primary.sqlType
+}
+
+// resolve разворачивает ссылки на необъектные модели (псевдонимы типов),
+// сохраняя признак nullable.
+func (p *sqlPlanner) resolve(t *ir.Type) *ir.Type {
+	if t == nil {
+		return ir.Primitive(ir.KindAny)
+	}
+	for depth := 0; t.Kind == ir.KindRef && depth < 16; depth++ {
+		model, ok := p.doc.Model(t.Ref)
+		if !ok || model.Type == nil || model.Type.Kind == ir.KindObject {
+			return t
+		}
+		resolved := *model.Type
+		resolved.Nullable = resolved.Nullable || t.Nullable
+		t = &resolved
+	}
+	return t
+}
+
+// sorted упорядочивает таблицы так, чтобы таблица создавалась после тех,
+// на которые она ссылается. Циклические зависимости оставляются в исходном порядке.
+func (p *sqlPlanner) sorted() []*sqlTable {
+	visited := make(map[string]int)
+	var result []*sqlTable
+
+	var visit func(key string)
+	visit = func(key string) {
+		if visited[key] != 0 {
+			return
+		}
+		visited[key] = 1
+		for _, dep := range p.deps[key] {
+			if dep != key {
+				visit(dep)
+			}
+		}
+		visited[key] = 2
+		result = append(result, p.tables[key])
+	}
+
+	for _, key := range p.order {
+		visit(key)
+	}
+	return result
+}
+
+// keyRef возвращает тип колонки, которая ссылается на первичный ключ таблицы.
+func (t *sqlTable) keyRef(nullable bool) *ir.Type {
+	key := *t.column(t.primaryKey[0]).key
+	key.Nullable = nullable
+	return &key
+}
+
+// keyColumns возвращает колонки-ключи, которым не соответствует ни одно поле
+// модели: суррогатный id и ссылки на другие таблицы.
+func (t *sqlTable) keyColumns() []*sqlColumn {
+	mapped := make(map[string]bool, len(t.fields))
+	for _, column := range t.fields {
+		mapped[column] = true
+	}
+	var columns []*sqlColumn
+	for _, column := range t.columns {
+		if !mapped[column.name] && column.key != nil {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func (t *sqlTable) column(name string) *sqlColumn {
+	for _, column := range t.columns {
+		if column.name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+func SQLTableName(modelName string) string {
+	return ToSnakeCase(modelName)
+}
+
+func SQLColumnName(fieldName string) string {
+	name := ToSnakeCase(fieldName)
+	if name == "" {
+		return "field"
+	}
+	return name
+}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const recordModelName = "Record"
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// columnType накапливает сведения о типе колонки или поля по всем строкам,
+// не храня сами значения.
+type columnType struct {
+	kind     ir.Kind
+	nullable bool
+	missing  bool
+	fields   map[string]*columnType
+	order    []string
+	elem     *columnType
+	// objects — число объектов, уже учтенных в fields. Поле, которого не
+	// было в одном из них, необязательное.
+	objects int
+}
+
+func newColumnType() *columnType {
+	return &columnType{fields: make(map[string]*columnType)}
+}
+
+func (c *columnType) field(name string) *columnType {
+	column, ok := c.fields[name]
+	if !ok {
+		column = newColumnType()
+		c.fields[name] = column
+		c.order = append(c.order, name)
+	}
+	return column
+}
+
+func (c *columnType) observeKind(kind ir.Kind) {
+	c.kind = mergeKinds(c.kind, kind)
+}
+
+func (c *columnType) observeText(text string) {
+	if strings.TrimSpace(text) == "" {
+		c.nullable = true
+		return
+	}
+	c.observeKind(textKind(text))
+}
+
+func (c *columnType) observeValue(value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		c.nullable = true
+	case bool:
+		c.observeKind(ir.KindBoolean)
+	case float64:
+		if v == float64(int64(v)) {
+			c.observeKind(ir.KindInteger)
+		} else {
+			c.observeKind(ir.KindNumber)
+		}
+	case string:
+		if isTime(v) {
+			c.observeKind(ir.KindTime)
+		} else {
+			c.observeKind(ir.KindString)
+		}
+	case []interface{}:
+		c.observeKind(ir.KindArray)
+		if c.elem == nil {
+			c.elem = newColumnType()
+		}
+		for _, item := range v {
+			c.elem.observeValue(item)
+		}
+	case map[string]interface{}:
+		c.observeKind(ir.KindObject)
+		c.observeObject(v)
+	default:
+		c.observeKind(ir.KindAny)
+	}
+}
+
+func (c *columnType) observeObject(object map[string]interface{}) {
+	for _, name := range c.order {
+		if _, ok := object[name]; !ok {
+			c.fields[name].missing = true
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, exists := c.fields[key]; !exists && c.objects > 0 {
+			c.field(key).missing = true
+		}
+		c.field(key).observeValue(object[key])
+	}
+	c.objects++
+}
+
+func mergeKinds(current, next ir.Kind) ir.Kind {
+	switch {
+	case current == "" || current == next:
+		return next
+	case isNumericKind(current) && isNumericKind(next):
+		return ir.KindNumber
+	case isScalarKind(current) && isScalarKind(next):
+		return ir.KindString
+	default:
+		return ir.KindAny
+	}
+}
+
+func isNumericKind(kind ir.Kind) bool {
+	return kind == ir.KindInteger || kind == ir.KindNumber
+}
+
+func isScalarKind(kind ir.Kind) bool {
+	switch kind {
+	case ir.KindString, ir.KindInteger, ir.KindNumber, ir.KindBoolean, ir.KindTime:
+		return true
+	}
+	return false
+}
+
+func textKind(text string) ir.Kind {
+	text = strings.TrimSpace(text)
+	if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return ir.KindInteger
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return ir.KindNumber
+	}
+	if strings.EqualFold(text, "true") || strings.EqualFold(text, "false") {
+		return ir.KindBoolean
+	}
+	if isTime(text) {
+		return ir.KindTime
+	}
+	return ir.KindString
+}
+
+func isTime(text string) bool {
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// InferCSV читает CSV построчно: первая строка задает поля, тип каждой колонки
+// выводится по всем остальным строкам, пустые значения делают поле nullable.
+func InferCSV(r io.Reader) (*ir.Document, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("ошибка парсинга CSV: нет строки заголовка")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга CSV: %w", err)
+	}
+
+	names := append([]string(nil), header...)
+	if len(names) > 0 {
+		names[0] = strings.TrimPrefix(names[0], "\ufeff")
+	}
+
+	columns := make([]*columnType, len(names))
+	for i := range columns {
+		columns[i] = newColumnType()
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка парсинга CSV: %w", err)
+		}
+		for i, column := range columns {
+			if i < len(record) {
+				column.observeText(record[i])
+			} else {
+				column.nullable = true
+			}
+		}
+	}
+
+	model := &ir.Model{Name: recordModelName, Type: &ir.Type{Kind: ir.KindObject}}
+	for i, name := range names {
+		model.Type.Fields = append(model.Type.Fields, &ir.Field{
+			Name:     name,
+			Type:     columns[i].irType(nil, name),
+			Required: true,
+		})
+	}
+
+	doc := ir.NewDocument()
+	doc.Format = string(FormatCSV)
+	doc.Add(model)
+	return doc, nil
+}
+
+// InferNDJSON читает по одному JSON объекту на строку и объединяет их типы:
+// поля, отсутствующие хотя бы в одной записи, становятся необязательными.
+func InferNDJSON(r io.Reader) (*ir.Document, error) {
+	decoder := json.NewDecoder(r)
+	root := newColumnType()
+	root.kind = ir.KindObject
+
+	for count := 1; ; count++ {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			if count == 1 {
+				return nil, fmt.Errorf("ошибка парсинга NDJSON: нет ни одной записи")
+			}
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка парсинга NDJSON: запись %d: %w", count, err)
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("ошибка парсинга NDJSON: запись %d не является объектом", count)
+		}
+		root.observeObject(object)
+	}
+
+	doc := ir.NewDocument()
+	doc.Format = string(FormatNDJSON)
+	root.addModel(doc, recordModelName)
+	return doc, nil
+}
+
+func (c *columnType) addModel(doc *ir.Document, name string) string {
+	model := &ir.Model{Name: name, Type: &ir.Type{Kind: ir.KindObject}}
+	doc.Add(model)
+
+	names := append([]string(nil), c.order...)
+	sort.Strings(names)
+
+	for _, fieldName := range names {
+		column := c.fields[fieldName]
+		model.Type.Fields = append(model.Type.Fields, &ir.Field{
+			Name:     fieldName,
+			Type:     column.irType(doc, fieldName),
+			Required: !column.missing,
+		})
+	}
+
+	return name
+}
+
+// irType строит тип поля. Колонки CSV передают doc == nil: вложенных моделей
+// у них не бывает, а колонка из одних пустых значений остается строкой.
+func (c *columnType) irType(doc *ir.Document, name string) *ir.Type {
+	var result *ir.Type
+
+	switch c.kind {
+	case "":
+		if doc == nil {
+			result = ir.Primitive(ir.KindString)
+		} else {
+			result = ir.Primitive(ir.KindAny)
+		}
+	case ir.KindArray:
+		elem := ir.Primitive(ir.KindAny)
+		if c.elem != nil && c.elem.kind != "" {
+			elem = c.elem.irType(doc, name)
+			elem.Nullable = false
+		}
+		result = ir.ArrayOf(elem)
+	case ir.KindObject:
+		if doc == nil {
+			result = ir.MapOf(ir.Primitive(ir.KindAny))
+		} else {
+			result = ir.Ref(c.addModel(doc, uniqueModelName(doc, ToPascalCase(name))))
+		}
+	default:
+		result = ir.Primitive(c.kind)
+	}
+
+	result.Nullable = c.nullable && result.Kind != ir.KindAny
+	return result
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const (
+	xmlAttributePrefix = "@"
+	xmlTextKey         = "#text"
+)
+
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlElement
+	text     strings.Builder
+}
+
+// decodeXML возвращает имя корневого элемента и его содержимое в JSON-совместимом
+// виде: атрибуты хранятся под ключами "@имя", текст элемента со структурой — под
+// "#text", повторяющиеся элементы собираются в массив.
+func decodeXML(input string) (string, interface{}, error) {
+	decoder := xml.NewDecoder(strings.NewReader(input))
+	var stack []*xmlElement
+	var root *xmlElement
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("ошибка парсинга XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, element)
+			} else if root == nil {
+				root = element
+			} else {
+				return "", nil, fmt.Errorf("ошибка парсинга XML: несколько корневых элементов")
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return "", nil, fmt.Errorf("ошибка парсинга XML: документ не содержит элементов")
+	}
+
+	return root.name, root.value(), nil
+}
+
+func (e *xmlElement) value() interface{} {
+	text := strings.TrimSpace(e.text.String())
+
+	var attrs []xml.Attr
+	for _, attr := range e.attrs {
+		if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	if len(attrs) == 0 && len(e.children) == 0 {
+		return xmlScalar(text)
+	}
+
+	object := make(map[string]interface{})
+	for _, attr := range attrs {
+		object[xmlAttributePrefix+attr.Name.Local] = xmlScalar(attr.Value)
+	}
+
+	for _, child := range e.children {
+		value := child.value()
+		existing, ok := object[child.name]
+		if !ok {
+			object[child.name] = value
+			continue
+		}
+		if list, ok := existing.([]interface{}); ok {
+			object[child.name] = append(list, value)
+		} else {
+			object[child.name] = []interface{}{existing, value}
+		}
+	}
+
+	if text != "" {
+		object[xmlTextKey] = xmlScalar(text)
+	}
+
+	return object
+}
+
+func xmlScalar(text string) interface{} {
+	if text == "" {
+		return ""
+	}
+	if text == "true" || text == "false" {
+		return text == "true"
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return text
+}
+
+func documentFromXML(input string) (*ir.Document, error) {
+	rootName, value, err := decodeXML(input)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := DocumentFromSample(ToPascalCase(rootName), value)
+	if err != nil {
+		return nil, err
+	}
+	doc.Format = string(FormatXML)
+
+	for _, model := range doc.Models {
+		for _, field := range model.Type.Fields {
+			switch {
+			case strings.HasPrefix(field.Name, xmlAttributePrefix):
+				field.Name = strings.TrimPrefix(field.Name, xmlAttributePrefix)
+				field.XML = ir.XMLAttr
+			case field.Name == xmlTextKey:
+				field.Name = "value"
+				field.XML = ir.XMLCharData
+			}
+		}
+	}
+
+	return doc, nil
+}
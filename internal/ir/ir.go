@@ -0,0 +1,133 @@
+package ir
+
+type Kind string
+
+const (
+	KindAny     Kind = "any"
+	KindString  Kind = "string"
+	KindInteger Kind = "integer"
+	KindNumber  Kind = "number"
+	KindBoolean Kind = "boolean"
+	KindTime    Kind = "time"
+	KindObject  Kind = "object"
+	KindArray   Kind = "array"
+	KindMap     Kind = "map"
+	KindRef     Kind = "ref"
+)
+
+type Type struct {
+	Kind     Kind     `json:"kind"`
+	Format   string   `json:"format,omitempty"`
+	Ref      string   `json:"ref,omitempty"`
+	Elem     *Type    `json:"elem,omitempty"`
+	Fields   []*Field `json:"fields,omitempty"`
+	Nullable bool     `json:"nullable,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+}
+
+const (
+	XMLAttr     = "attr"
+	XMLCharData = "chardata"
+)
+
+type Field struct {
+	Name        string `json:"name"`
+	Type        *Type  `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+	XML         string `json:"xml,omitempty"`
+}
+
+const (
+	RoleParams   = "params"
+	RoleRequest  = "request"
+	RoleResponse = "response"
+)
+
+type Model struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role,omitempty"`
+	Type        *Type  `json:"type"`
+}
+
+type Document struct {
+	Format string   `json:"format,omitempty"`
+	Models []*Model `json:"models"`
+}
+
+func NewDocument() *Document {
+	return &Document{
+		Models: []*Model{},
+	}
+}
+
+func (d *Document) Add(model *Model) {
+	d.Models = append(d.Models, model)
+}
+
+func (d *Document) Model(name string) (*Model, bool) {
+	for _, model := range d.Models {
+		if model.Name == name {
+			return model, true
+		}
+	}
+	return nil, false
+}
+
+func (d *Document) Names() []string {
+	names := make([]string, 0, len(d.Models))
+	for _, model := range d.Models {
+		names = append(names, model.Name)
+	}
+	return names
+}
+
+func Ref(name string) *Type {
+	return &Type{Kind: KindRef, Ref: name}
+}
+
+func ArrayOf(elem *Type) *Type {
+	return &Type{Kind: KindArray, Elem: elem}
+}
+
+func MapOf(elem *Type) *Type {
+	return &Type{Kind: KindMap, Elem: elem}
+}
+
+func Primitive(kind Kind) *Type {
+	return &Type{Kind: kind}
+}
+
+// Refs возвращает имена моделей, на которые ссылается тип, в порядке обхода.
+func (t *Type) Refs() []string {
+	var refs []string
+	t.walk(func(tt *Type) {
+		if tt.Kind == KindRef {
+			refs = append(refs, tt.Ref)
+		}
+	})
+	return refs
+}
+
+// Uses сообщает, встречается ли где-либо внутри типа указанный вид.
+func (t *Type) Uses(kind Kind) bool {
+	found := false
+	t.walk(func(tt *Type) {
+		if tt.Kind == kind {
+			found = true
+		}
+	})
+	return found
+}
+
+func (t *Type) walk(fn func(*Type)) {
+	if t == nil {
+		return
+	}
+	fn(t)
+	t.Elem.walk(fn)
+	for _, field := range t.Fields {
+		field.Type.walk(fn)
+	}
+}
@@ -0,0 +1,74 @@
+// Package naming переводит имена полей и моделей между стилями написания.
+// Им пользуются встроенные генераторы, обработчики конвейеров и шаблонные
+// плагины.
+package naming
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func ToPascalCase(s string) string {
+	var result strings.Builder
+	for _, part := range splitWords(s) {
+		result.WriteString(capitalize(strings.ToLower(part)))
+	}
+	return result.String()
+}
+
+func ToCamelCase(s string) string {
+	pascal := ToPascalCase(s)
+	if pascal == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(pascal)
+	return string(unicode.ToLower(first)) + pascal[size:]
+}
+
+func ToSnakeCase(s string) string {
+	parts := splitWords(s)
+	for i, part := range parts {
+		parts[i] = strings.ToLower(part)
+	}
+	return strings.Join(parts, "_")
+}
+
+func ToKebabCase(s string) string {
+	return strings.ReplaceAll(ToSnakeCase(s), "_", "-")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(first)) + s[size:]
+}
+
+func splitWords(s string) []string {
+	var parts []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+
+	for i, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case i > 0 && r >= 'A' && r <= 'Z':
+			flush()
+			current.WriteRune(r)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return parts
+}
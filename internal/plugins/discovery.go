@@ -0,0 +1,356 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Scopes of plugin search paths, from the lowest to the highest precedence.
+// A plugin found in a later scope replaces one with the same name from an
+// earlier scope.
+const (
+	ScopeSystem  = "system"
+	ScopeUser    = "user"
+	ScopeProject = "project"
+	ScopeEnv     = "env"
+)
+
+// ScopeBuiltin marks the generators compiled into DevToolBox. Their names are
+// reserved; plugins cannot replace them.
+const ScopeBuiltin = "builtin"
+
+// PluginPathEnv lists extra plugin directories, separated like PATH. They
+// take precedence over all other search paths.
+const PluginPathEnv = "DEVTOOLBOX_PLUGIN_PATH"
+
+// ProjectDir is the directory of project settings, looked up from the working
+// directory upwards. Project plugins live in its plugins subdirectory.
+const ProjectDir = ".devtoolbox"
+
+// ProjectConfig is the project configuration file. Like ProjectDir it marks
+// the project root.
+const ProjectConfig = ".devtoolbox.yaml"
+
+var scopeRank = map[string]int{ScopeSystem: 0, ScopeUser: 1, ScopeProject: 2, ScopeEnv: 3}
+
+// SearchPath is a directory searched for plugins: Python scripts, manifests
+// (<script>.plugin.yaml) and plugin directories with a plugin.yaml. A
+// directory that has a plugin.yaml itself is a single plugin.
+type SearchPath struct {
+	Scope string
+	Dir   string
+}
+
+// Discovery is the result of searching for plugins.
+type Discovery struct {
+	// Plugins are the plugins to use, sorted by name.
+	Plugins   []PluginInfo
+	Conflicts []Conflict
+	// Errors are plugins that were skipped, e.g. for an invalid manifest.
+	Errors []error
+}
+
+// Conflict reports plugins with the same name. Used is the one with the
+// highest precedence, or the built-in generator.
+type Conflict struct {
+	Name     string
+	Used     PluginInfo
+	Shadowed []PluginInfo
+}
+
+func (c Conflict) String() string {
+	shadowed := make([]string, len(c.Shadowed))
+	for i, plugin := range c.Shadowed {
+		shadowed[i] = pluginLocation(plugin)
+	}
+	return fmt.Sprintf("plugin %s: using %s, ignoring %s", c.Name, pluginLocation(c.Used), strings.Join(shadowed, ", "))
+}
+
+func pluginLocation(plugin PluginInfo) string {
+	if plugin.Scope == ScopeBuiltin {
+		return "the built-in generator"
+	}
+	return fmt.Sprintf("%s %s", plugin.Scope, plugin.Path)
+}
+
+// DefaultSearchPaths returns the search paths from the lowest to the highest
+// precedence:
+//
+//   - system: plugins/official next to the executable, as in the Docker
+//     image, and /usr/local/share/devtoolbox/plugins and
+//     /usr/share/devtoolbox/plugins
+//   - user: ~/.devtoolbox/plugins, where plugin install puts plugins
+//   - project: .devtoolbox/plugins in the working directory or the nearest
+//     parent that has a .devtoolbox directory or a .devtoolbox.yaml, and
+//     plugins/official in the working directory, as in the source tree. Any
+//     repository can ship the latter, so it is not official.
+//   - env: the directories in DEVTOOLBOX_PLUGIN_PATH
+//
+// Plugins registered with plugin add belong to the user scope wherever their
+// files are. Directories that do not exist are skipped.
+func (pm *PluginManager) DefaultSearchPaths() []SearchPath {
+	var paths []SearchPath
+	add := func(scope, dir string) {
+		if dir == "" {
+			return
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		for _, path := range paths {
+			if path.Dir == dir {
+				return
+			}
+		}
+		paths = append(paths, SearchPath{Scope: scope, Dir: dir})
+	}
+
+	if executable, err := os.Executable(); err == nil {
+		add(ScopeSystem, filepath.Join(filepath.Dir(executable), "plugins", "official"))
+	}
+	if runtime.GOOS != "windows" {
+		add(ScopeSystem, "/usr/local/share/devtoolbox/plugins")
+		add(ScopeSystem, "/usr/share/devtoolbox/plugins")
+	}
+	add(ScopeUser, pm.PluginsDir())
+	if project := FindProjectDir(); project != "" {
+		add(ScopeProject, filepath.Join(project, ProjectDir, "plugins"))
+	}
+	add(ScopeProject, filepath.Join("plugins", "official"))
+	for _, dir := range filepath.SplitList(os.Getenv(PluginPathEnv)) {
+		add(ScopeEnv, dir)
+	}
+	return paths
+}
+
+// FindProjectDir returns the working directory or its nearest parent that has
+// a .devtoolbox directory or a .devtoolbox.yaml, or "" if there is none. The
+// user's own ~/.devtoolbox is not a project.
+func FindProjectDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	homeDir, _ := os.UserHomeDir()
+	for {
+		if dir == homeDir {
+			return ""
+		}
+		if info, err := os.Stat(filepath.Join(dir, ProjectDir)); err == nil && info.IsDir() {
+			return dir
+		}
+		if _, err := os.Stat(filepath.Join(dir, ProjectConfig)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Discover finds the plugins in the search paths and the plugins registered
+// with plugin add. Names in reserved belong to built-in generators; plugins
+// with those names are reported as conflicts and skipped.
+func (pm *PluginManager) Discover(reserved []string) (*Discovery, error) {
+	registered, err := pm.LoadPlugins()
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := &Discovery{}
+	var found []PluginInfo
+	for _, path := range pm.SearchPaths() {
+		plugins, errs := scanSearchPath(path)
+		found = append(found, plugins...)
+		discovery.Errors = append(discovery.Errors, errs...)
+	}
+
+	// Registered plugins carry their trust and checksums, so they replace
+	// the copy found by scanning, e.g. installed plugins in the user scope.
+	byPath := map[string]int{}
+	for i, plugin := range found {
+		byPath[plugin.Path] = i
+	}
+	for _, plugin := range registered {
+		plugin.Scope = ScopeUser
+		if i, ok := byPath[plugin.Path]; ok {
+			found[i] = plugin
+			continue
+		}
+		found = append(found, plugin)
+	}
+
+	byName := map[string][]PluginInfo{}
+	for _, plugin := range found {
+		byName[plugin.Name] = append(byName[plugin.Name], plugin)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		candidates := byName[name]
+		// Stable, so that within a scope the first search path wins.
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return scopeRank[candidates[i].Scope] > scopeRank[candidates[j].Scope]
+		})
+		if containsString(reserved, name) {
+			discovery.Conflicts = append(discovery.Conflicts, Conflict{
+				Name:     name,
+				Used:     PluginInfo{Name: name, Path: "builtin", Scope: ScopeBuiltin},
+				Shadowed: candidates,
+			})
+			continue
+		}
+		if len(candidates) > 1 {
+			discovery.Conflicts = append(discovery.Conflicts, Conflict{Name: name, Used: candidates[0], Shadowed: candidates[1:]})
+		}
+		discovery.Plugins = append(discovery.Plugins, candidates[0])
+	}
+	return discovery, nil
+}
+
+// SearchPaths returns the directories Discover searches.
+func (pm *PluginManager) SearchPaths() []SearchPath {
+	if pm.searchPaths != nil {
+		return pm.searchPaths
+	}
+	return pm.DefaultSearchPaths()
+}
+
+// SetSearchPaths replaces the default search paths.
+func (pm *PluginManager) SetSearchPaths(paths []SearchPath) {
+	pm.searchPaths = paths
+}
+
+// WatchPaths returns the files and directories whose changes can change what
+// Discover finds: plugins.json, the search paths and the directories of
+// registered plugins.
+func (pm *PluginManager) WatchPaths() []string {
+	paths := []string{pm.configPath}
+	for _, path := range pm.SearchPaths() {
+		paths = append(paths, path.Dir)
+	}
+	registered, _ := pm.LoadPlugins()
+	for _, plugin := range registered {
+		dir := filepath.Dir(plugin.manifestPath())
+		if !containsString(paths, dir) {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+// scanSearchPath lists the plugins in a search path. Plugins from the system
+// scope are official; all others are untrusted until registered with
+// plugin add.
+func scanSearchPath(path SearchPath) ([]PluginInfo, []error) {
+	entries, err := os.ReadDir(path.Dir)
+	if err != nil {
+		return nil, nil
+	}
+	trust := TrustUntrusted
+	if path.Scope == ScopeSystem {
+		trust = TrustOfficial
+	}
+
+	for _, name := range ManifestNames {
+		manifestPath := filepath.Join(path.Dir, name)
+		if _, err := os.Stat(manifestPath); err == nil {
+			plugin, err := manifestPlugin(manifestPath, path.Scope, trust)
+			if err != nil {
+				return nil, []error{err}
+			}
+			return []PluginInfo{plugin}, nil
+		}
+	}
+
+	var plugins []PluginInfo
+	var errs []error
+	covered := map[string]bool{}
+	addManifest := func(manifestPath string) {
+		plugin, err := manifestPlugin(manifestPath, path.Scope, trust)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if !covered[plugin.Path] {
+			covered[plugin.Path] = true
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		entryPath := filepath.Join(path.Dir, entry.Name())
+		if entry.IsDir() {
+			for _, name := range ManifestNames {
+				if _, err := os.Stat(filepath.Join(entryPath, name)); err == nil {
+					addManifest(filepath.Join(entryPath, name))
+					break
+				}
+			}
+			continue
+		}
+		for _, name := range ManifestNames {
+			if strings.HasSuffix(entry.Name(), "."+name) {
+				addManifest(entryPath)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path.Dir, entry.Name())
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || covered[entryPath] {
+			continue
+		}
+		language := LanguageForPath(entryPath)
+		if language == LanguageExec {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		plugins = append(plugins, PluginInfo{
+			Name:        name,
+			Description: fmt.Sprintf("Custom plugin: %s", name),
+			Type:        language,
+			Path:        entryPath,
+			Trust:       trust,
+			Scope:       path.Scope,
+		})
+	}
+	return plugins, errs
+}
+
+func manifestPlugin(manifestPath, scope string, trust Trust) (PluginInfo, error) {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return PluginInfo{}, err
+	}
+	if _, err := os.Stat(manifest.EntrypointPath()); err != nil && len(manifest.Build) > 0 {
+		return PluginInfo{}, fmt.Errorf("plugin %s in %s is not built; register it with plugin add, which runs its build command", manifest.Name, manifestPath)
+	}
+
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("Custom plugin: %s", manifest.Name)
+	}
+	return PluginInfo{
+		Name:        manifest.Name,
+		Description: description,
+		Type:        manifest.Language,
+		Path:        manifest.EntrypointPath(),
+		Manifest:    manifest,
+		Trust:       trust,
+		Scope:       scope,
+	}, nil
+}
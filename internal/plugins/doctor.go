@@ -0,0 +1,173 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Check is one finding of plugin doctor.
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Diagnose checks what a registered plugin needs to run: its entrypoint, the
+// interpreter or the templates, the Python SDK and requirements, the sandbox
+// and the recorded checksums. It does not create missing environments.
+func Diagnose(info PluginInfo) []Check {
+	checks := []Check{entrypointCheck(info.Path)}
+
+	switch info.Type {
+	case LanguageWasm:
+		checks = append(checks, Check{Name: "interpreter", OK: true, Detail: "in-process WebAssembly runtime"})
+	case LanguageTemplate:
+		checks = append(checks, templateCheck(info.Path, info.Manifest, info.Integrity))
+	case LanguageExec:
+		checks = append(checks, execInterpreterCheck(info.Path, info.Manifest))
+	case LanguagePython:
+		checks = append(checks, pythonInterpreterCheck(info.Manifest))
+		if dir, err := PythonSDKPath(); err != nil {
+			checks = append(checks, Check{Name: "sdk", Detail: err.Error()})
+		} else {
+			checks = append(checks, Check{Name: "sdk", OK: true, Detail: dir})
+		}
+		checks = append(checks, requirementChecks(info.Manifest)...)
+	}
+
+	trust, err := ParseTrust(string(info.Trust))
+	if err != nil {
+		trust = TrustUntrusted
+	}
+	checks = append(checks, sandboxCheck(info.Type, trust, info.Manifest))
+
+	if trust != TrustOfficial {
+		checks = append(checks, integrityCheck(info.Integrity))
+	}
+	return checks
+}
+
+func entrypointCheck(path string) Check {
+	if _, err := os.Stat(path); err != nil {
+		return Check{Name: "entrypoint", Detail: fmt.Sprintf("%s is missing", path)}
+	}
+	return Check{Name: "entrypoint", OK: true, Detail: path}
+}
+
+func execInterpreterCheck(entrypoint string, manifest *Manifest) Check {
+	interpreter := DefaultInterpreters[strings.ToLower(filepath.Ext(entrypoint))]
+	if manifest != nil && len(manifest.Interpreter) > 0 {
+		interpreter = manifest.Interpreter
+	}
+	if len(interpreter) == 0 {
+		return Check{Name: "interpreter", OK: true, Detail: "none, the entrypoint is executed directly"}
+	}
+	path, err := exec.LookPath(interpreter[0])
+	if err != nil {
+		return Check{Name: "interpreter", Detail: fmt.Sprintf("%s not found in PATH", interpreter[0])}
+	}
+	return Check{Name: "interpreter", OK: true, Detail: path}
+}
+
+func pythonInterpreterCheck(manifest *Manifest) Check {
+	var python string
+	var err error
+	if manifest != nil && len(manifest.Interpreter) > 0 {
+		python, err = exec.LookPath(manifest.Interpreter[0])
+	} else {
+		python, err = pythonExecutable()
+	}
+	if err != nil {
+		return Check{Name: "interpreter", Detail: err.Error()}
+	}
+
+	output, err := exec.Command(python, "-c", "import sys; print(sys.version.split()[0]); print(sys.executable)").Output()
+	if err != nil {
+		return Check{Name: "interpreter", Detail: fmt.Sprintf("%s does not run: %v", python, err)}
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(output)), "\n", 2)
+	if len(lines) != 2 {
+		return Check{Name: "interpreter", Detail: fmt.Sprintf("%s printed unexpected output %q", python, output)}
+	}
+	return Check{Name: "interpreter", OK: true, Detail: fmt.Sprintf("python %s (%s)", lines[0], lines[1])}
+}
+
+// templateCheck parses the templates of a template plugin that would be used
+// to run it.
+func templateCheck(entrypoint string, manifest *Manifest, integrity *Integrity) Check {
+	var spec *TemplateSpec
+	if manifest != nil {
+		spec = manifest.Template
+	}
+	if _, err := parseTemplates(entrypoint, spec, integrity.partials(entrypoint)); err != nil {
+		return Check{Name: "templates", Detail: err.Error()}
+	}
+	return Check{Name: "templates", OK: true, Detail: "in-process text/template engine"}
+}
+
+// requirementChecks reports the environment of the plugin and the installed
+// version of every requirement.
+func requirementChecks(manifest *Manifest) []Check {
+	if manifest == nil || len(manifest.Requirements) == 0 {
+		return []Check{{Name: "requirements", OK: true, Detail: "none"}}
+	}
+
+	dir, err := VenvDir(manifest)
+	if err != nil {
+		return []Check{{Name: "environment", Detail: err.Error()}}
+	}
+	installed, err := InstalledRequirements(manifest)
+	if err != nil {
+		return []Check{{Name: "environment", Detail: err.Error()}}
+	}
+	if installed == nil {
+		from := "the package index"
+		if wheels := DefaultWheels(); wheels != "" {
+			from = wheels
+		}
+		return []Check{{Name: "environment", OK: true, Detail: fmt.Sprintf("not created yet; it is created on the next run from %s", from)}}
+	}
+
+	checks := []Check{{Name: "environment", OK: true, Detail: dir}}
+	for _, requirement := range manifest.Requirements {
+		version, ok := installed[requirementName(requirement)]
+		if !ok {
+			checks = append(checks, Check{Name: "requirement", Detail: requirement + ": missing"})
+			continue
+		}
+		checks = append(checks, Check{Name: "requirement", OK: true, Detail: requirement + ": " + version})
+	}
+	return checks
+}
+
+func sandboxCheck(language string, trust Trust, manifest *Manifest) Check {
+	switch {
+	case language == LanguageWasm:
+		return Check{Name: "sandbox", OK: true, Detail: "wasm (in-process)"}
+	case language == LanguageTemplate:
+		return Check{Name: "sandbox", OK: true, Detail: "template (in-process)"}
+	case !Sandboxed(trust, manifest):
+		return Check{Name: "sandbox", OK: true, Detail: "none"}
+	case SandboxSupported():
+		return Check{Name: "sandbox", OK: true, Detail: "strict"}
+	case trust == TrustUntrusted:
+		return Check{Name: "sandbox", Detail: "unavailable on this system; untrusted plugins cannot run"}
+	}
+	return Check{Name: "sandbox", OK: true, Detail: "unavailable on this system; the plugin runs without isolation"}
+}
+
+func integrityCheck(integrity *Integrity) Check {
+	if integrity == nil {
+		return Check{Name: "integrity", OK: true, Detail: "not recorded"}
+	}
+	if err := integrity.Verify(); err != nil {
+		return Check{Name: "integrity", Detail: err.Error()}
+	}
+	if integrity.Signer != "" {
+		return Check{Name: "integrity", OK: true, Detail: "signed by " + integrity.Signer}
+	}
+	return Check{Name: "integrity", OK: true, Detail: "checksums match"}
+}
@@ -0,0 +1,176 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ErrorCode string
+
+const (
+	CodeInvalidInput   ErrorCode = "invalid_input"
+	CodeInvalidOptions ErrorCode = "invalid_options"
+	CodeUnsupported    ErrorCode = "unsupported"
+	CodeInternal       ErrorCode = "internal"
+)
+
+// Exit codes with a fixed meaning. Any other non-zero code is reported as
+// CodeInternal.
+const (
+	ExitInvalidInput   = 2
+	ExitInvalidOptions = 3
+	ExitUnsupported    = 4
+)
+
+// PluginError is a failure reported by the plugin itself, either as an error
+// in a JSON protocol response or through its exit code and stderr.
+type PluginError struct {
+	Plugin   string
+	Code     ErrorCode
+	Message  string
+	Location *Location
+	ExitCode int
+	Stderr   string
+}
+
+func (e *PluginError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = strings.TrimSpace(e.Stderr)
+	}
+	if location := e.Location.String(); location != "" {
+		message = location + ": " + message
+	}
+	if e.ExitCode != 0 {
+		return fmt.Sprintf("plugin %s failed (%s, exit code %d): %s", e.Plugin, e.Code, e.ExitCode, message)
+	}
+	return fmt.Sprintf("plugin %s failed (%s): %s", e.Plugin, e.Code, message)
+}
+
+// IsUserError reports whether the failure was caused by the input or options
+// rather than by the plugin.
+func (e *PluginError) IsUserError() bool {
+	return e.Code == CodeInvalidInput || e.Code == CodeInvalidOptions || e.Code == CodeUnsupported
+}
+
+// ProtocolError means the plugin answered with something that is not a valid
+// protocol response.
+type ProtocolError struct {
+	Plugin  string
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("plugin %s protocol error: %s", e.Plugin, e.Message)
+}
+
+// ExecError means the plugin process could not be started.
+type ExecError struct {
+	Plugin string
+	Err    error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("plugin %s could not be started: %v", e.Plugin, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError means the plugin did not finish within its timeout or the
+// caller's deadline. The process group was killed.
+type TimeoutError struct {
+	Plugin  string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout == 0 {
+		return fmt.Sprintf("plugin %s exceeded the request deadline", e.Plugin)
+	}
+	return fmt.Sprintf("plugin %s timed out after %s", e.Plugin, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// CanceledError means the caller gave up, e.g. the HTTP client disconnected
+// or the user pressed Ctrl+C. The process group was killed.
+type CanceledError struct {
+	Plugin string
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("plugin %s was canceled", e.Plugin)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return context.Canceled
+}
+
+// OutputLimitError means the plugin wrote more than its output limit.
+type OutputLimitError struct {
+	Plugin string
+	Limit  int64
+}
+
+func (e *OutputLimitError) Error() string {
+	return fmt.Sprintf("plugin %s output exceeded the limit of %s", e.Plugin, formatSize(e.Limit))
+}
+
+// ResourceLimitError means the plugin was stopped by its CPU or memory limit.
+type ResourceLimitError struct {
+	Plugin   string
+	Resource string
+	Limit    string
+	Stderr   string
+}
+
+func (e *ResourceLimitError) Error() string {
+	return fmt.Sprintf("plugin %s exceeded its %s limit of %s", e.Plugin, e.Resource, e.Limit)
+}
+
+// IntegrityError means the plugin files no longer match the checksums or the
+// signature recorded when the plugin was added. The plugin was not run.
+type IntegrityError struct {
+	Plugin string
+	Err    error
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("plugin %s failed its integrity check: %v", e.Plugin, e.Err)
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return e.Err
+}
+
+// contextError explains why a call bounded by timeout on top of parent was
+// stopped: the caller canceled it, the caller's deadline passed, or the
+// plugin's own timeout expired.
+func contextError(parent context.Context, plugin string, timeout time.Duration) error {
+	switch parent.Err() {
+	case context.Canceled:
+		return &CanceledError{Plugin: plugin}
+	case context.DeadlineExceeded:
+		return &TimeoutError{Plugin: plugin}
+	}
+	return &TimeoutError{Plugin: plugin, Timeout: timeout}
+}
+
+func exitCodeError(exitCode int) ErrorCode {
+	switch exitCode {
+	case ExitInvalidInput:
+		return CodeInvalidInput
+	case ExitInvalidOptions:
+		return CodeInvalidOptions
+	case ExitUnsupported:
+		return CodeUnsupported
+	default:
+		return CodeInternal
+	}
+}
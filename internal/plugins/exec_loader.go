@@ -0,0 +1,41 @@
+package plugins
+
+import "fmt"
+
+// ExecPlugin runs any executable that speaks the plugin protocol over
+// stdin/stdout: a compiled binary, or a Node, Ruby or shell script started
+// through the interpreter from the manifest or DefaultInterpreters.
+type ExecPlugin struct {
+	processPlugin
+}
+
+func NewExecPlugin(name, description, path string) *ExecPlugin {
+	return &ExecPlugin{processPlugin{
+		language:    LanguageExec,
+		name:        name,
+		description: description,
+		entrypoint:  path,
+		trust:       TrustTrusted,
+	}}
+}
+
+func NewExecPluginFromManifest(manifest *Manifest) *ExecPlugin {
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("Executable plugin: %s", manifest.Name)
+	}
+
+	plugin := NewExecPlugin(manifest.Name, description, manifest.EntrypointPath())
+	plugin.SetManifest(manifest)
+	return plugin
+}
+
+// WithOptions returns a copy of the plugin that sends the given options with
+// every request.
+func (p *ExecPlugin) WithOptions(options map[string]string) (Plugin, error) {
+	configured := *p
+	if err := configured.withOptions(options); err != nil {
+		return nil, err
+	}
+	return &configured, nil
+}
@@ -0,0 +1,572 @@
+package plugins
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// InstallOptions configure plugin install.
+type InstallOptions struct {
+	Trust Trust
+	// Index is the index to look plugin names up in; DefaultIndex is used
+	// when it is empty.
+	Index string
+	// Subdir is the plugin directory inside a repository or archive.
+	Subdir string
+}
+
+// InstallPlugin fetches a plugin into ~/.devtoolbox/plugins/<name> and
+// registers it. Installed plugins need a manifest.
+func (pm *PluginManager) InstallPlugin(source *Source, options InstallOptions) (*PluginInfo, error) {
+	plugins, err := pm.LoadPlugins()
+	if err != nil {
+		return nil, err
+	}
+
+	source = cloneSource(source)
+	if options.Subdir != "" {
+		source.Subdir = options.Subdir
+	}
+	if source.Kind == SourceIndex && source.Index == "" {
+		source.Index = options.Index
+		if source.Index == "" {
+			source.Index = DefaultIndex()
+		}
+	}
+
+	staging, root, err := pm.fetch(source)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := ResolveManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, plugin := range plugins {
+		if plugin.Name == manifest.Name {
+			return nil, fmt.Errorf("plugin '%s' already exists", manifest.Name)
+		}
+	}
+	if source.Kind == SourceIndex && source.Ref != "" && CompareVersions(manifest.Version, source.Ref) != 0 {
+		return nil, fmt.Errorf("index entry %s@%s contains version %s", source.Location, source.Ref, manifest.Version)
+	}
+
+	target := pm.pluginDir(manifest.Name)
+	if err := os.RemoveAll(target); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(root, target); err != nil {
+		return nil, fmt.Errorf("failed to install plugin: %w", err)
+	}
+
+	info, err := installedPlugin(target, source, options.Trust)
+	if err != nil {
+		os.RemoveAll(target)
+		return nil, err
+	}
+	if err := pm.savePlugins(append(plugins, *info)); err != nil {
+		os.RemoveAll(target)
+		return nil, err
+	}
+	return info, nil
+}
+
+// UpdatePlugin fetches an installed plugin again and replaces it. A non-empty
+// ref pins a new git ref or index version; otherwise the recorded pin is kept.
+// It returns the plugin before and after the update.
+func (pm *PluginManager) UpdatePlugin(name, ref string) (*PluginInfo, *PluginInfo, error) {
+	plugins, err := pm.LoadPlugins()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	i := indexOfPlugin(plugins, name)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("plugin '%s' not found", name)
+	}
+	old := plugins[i]
+	if old.Source == nil {
+		return nil, nil, fmt.Errorf("plugin '%s' was added from a local path; install it with plugin install to enable updates", name)
+	}
+
+	source := cloneSource(old.Source)
+	if ref != "" {
+		if source.Kind != SourceGit && source.Kind != SourceIndex {
+			return nil, nil, fmt.Errorf("plugin '%s' comes from %s and cannot be pinned", name, source.Kind)
+		}
+		source.Ref = ref
+	}
+
+	staging, root, err := pm.fetch(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := ResolveManifest(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest.Name != name {
+		return nil, nil, fmt.Errorf("source of plugin '%s' now contains plugin '%s'", name, manifest.Name)
+	}
+
+	target := pm.pluginDir(name)
+	backup := filepath.Join(staging, "previous")
+	if err := os.Rename(target, backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	restore := func() {
+		os.RemoveAll(target)
+		os.Rename(backup, target)
+	}
+	if err := os.Rename(root, target); err != nil {
+		restore()
+		return nil, nil, fmt.Errorf("failed to update plugin: %w", err)
+	}
+
+	updated, err := installedPlugin(target, source, old.Trust)
+	if err != nil {
+		restore()
+		return nil, nil, err
+	}
+	plugins[i] = *updated
+	if err := pm.savePlugins(plugins); err != nil {
+		restore()
+		return nil, nil, err
+	}
+	return &old, updated, nil
+}
+
+// UninstallPlugin unregisters a plugin and deletes the files of installed
+// plugins. Plugins registered with plugin add keep their files.
+func (pm *PluginManager) UninstallPlugin(name string) (*PluginInfo, error) {
+	plugins, err := pm.LoadPlugins()
+	if err != nil {
+		return nil, err
+	}
+
+	i := indexOfPlugin(plugins, name)
+	if i < 0 {
+		return nil, fmt.Errorf("plugin '%s' not found", name)
+	}
+	info := plugins[i]
+	if err := pm.savePlugins(append(plugins[:i:i], plugins[i+1:]...)); err != nil {
+		return nil, err
+	}
+	if info.Source != nil {
+		if err := os.RemoveAll(pm.pluginDir(name)); err != nil {
+			return &info, fmt.Errorf("plugin '%s' was removed but its files were not: %w", name, err)
+		}
+	}
+	return &info, nil
+}
+
+// PluginsDir is where installed plugins live, one directory per plugin.
+func (pm *PluginManager) PluginsDir() string {
+	return filepath.Join(filepath.Dir(pm.configPath), "plugins")
+}
+
+func (pm *PluginManager) pluginDir(name string) string {
+	return filepath.Join(pm.PluginsDir(), name)
+}
+
+func indexOfPlugin(plugins []PluginInfo, name string) int {
+	for i, plugin := range plugins {
+		if plugin.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func cloneSource(source *Source) *Source {
+	clone := *source
+	clone.Resolved = ""
+	return &clone
+}
+
+// installedPlugin builds the plugin in dir, records its checksums and
+// describes it for plugins.json. The build command and pip run outside the
+// sandbox, so they are refused for plugins that are not trusted.
+func installedPlugin(dir string, source *Source, trust Trust) (*PluginInfo, error) {
+	manifest, err := ResolveManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if trust != TrustTrusted && trust != TrustOfficial {
+		if err := untrustedBuildError(manifest); err != nil {
+			return nil, err
+		}
+	}
+	if err := BuildPlugin(manifest); err != nil {
+		return nil, err
+	}
+	integrity, err := NewIntegrity(manifest.EntrypointPath(), manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("Custom plugin: %s", manifest.Name)
+	}
+	return &PluginInfo{
+		Name:        manifest.Name,
+		Description: description,
+		Type:        manifest.Language,
+		Path:        manifest.EntrypointPath(),
+		Manifest:    manifest,
+		Trust:       trust,
+		Source:      source,
+		Integrity:   integrity,
+	}, nil
+}
+
+// untrustedBuildError refuses what an untrusted plugin would run on the
+// host when it is installed: a build command or pip installing requirements.
+func untrustedBuildError(manifest *Manifest) error {
+	var steps []string
+	if len(manifest.Build) > 0 {
+		steps = append(steps, "a build command")
+	}
+	if len(manifest.Requirements) > 0 {
+		steps = append(steps, "requirements")
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+	return fmt.Errorf("plugin %s has %s, which would run unsandboxed on install; install it with --trust trusted if you trust its source", manifest.Name, strings.Join(steps, " and "))
+}
+
+// fetch puts the plugin into a new staging directory under the plugins
+// directory, so it can be moved into place with a rename. It returns the
+// staging directory, to be removed by the caller, and the plugin root in it.
+func (pm *PluginManager) fetch(source *Source) (string, string, error) {
+	if err := os.MkdirAll(pm.PluginsDir(), 0755); err != nil {
+		return "", "", err
+	}
+	staging, err := os.MkdirTemp(pm.PluginsDir(), ".staging-")
+	if err != nil {
+		return "", "", err
+	}
+
+	root, err := fetchInto(source, filepath.Join(staging, "src"))
+	if err != nil {
+		os.RemoveAll(staging)
+		return "", "", err
+	}
+	return staging, root, nil
+}
+
+func fetchInto(source *Source, dir string) (string, error) {
+	var err error
+	switch source.Kind {
+	case SourceGit:
+		source.Resolved, err = gitClone(source.Location, source.Ref, dir)
+	case SourceArchive:
+		err = fetchArchive(source.Location, dir)
+	case SourcePath:
+		err = copyTree(source.Location, dir)
+	case SourceIndex:
+		return fetchIndexed(source, dir)
+	default:
+		err = fmt.Errorf("unknown plugin source kind %q", source.Kind)
+	}
+	if err != nil {
+		return "", err
+	}
+	return pluginRoot(dir, source.Subdir)
+}
+
+func fetchIndexed(source *Source, dir string) (string, error) {
+	if source.Index == "" {
+		return "", fmt.Errorf("no plugin index configured: pass --index, set %s or create ~/.devtoolbox/index.yaml", IndexEnv)
+	}
+	index, err := LoadIndex(source.Index)
+	if err != nil {
+		return "", err
+	}
+	entry, err := index.Find(source.Location, source.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	location := index.resolve(entry.Source)
+	inner, err := ParseSource(location)
+	if err != nil {
+		return "", err
+	}
+	if inner.Kind == SourceIndex {
+		return "", fmt.Errorf("index entry %s@%s must point to a git URL, an archive or a directory", entry.Name, entry.Version)
+	}
+	inner.Subdir = entry.Subdir
+	if source.Subdir != "" {
+		inner.Subdir = source.Subdir
+	}
+
+	root, err := fetchInto(inner, dir)
+	if err != nil {
+		return "", err
+	}
+	source.Resolved = inner.String()
+	return root, nil
+}
+
+// pluginRoot finds the directory with the manifest: subdir if given, else
+// dir itself or its only subdirectory, as in archives of a single folder.
+func pluginRoot(dir, subdir string) (string, error) {
+	if subdir != "" {
+		root := filepath.Join(dir, filepath.FromSlash(subdir))
+		if !withinDir(dir, root) {
+			return "", fmt.Errorf("invalid subdir %q", subdir)
+		}
+		return root, nil
+	}
+	if hasManifest(dir) {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() && hasManifest(filepath.Join(dir, entries[0].Name())) {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+func hasManifest(dir string) bool {
+	for _, name := range ManifestNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func gitClone(location, ref, dir string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is required to install plugins from git repositories")
+	}
+
+	args := []string{"clone", "--quiet"}
+	if ref == "" {
+		args = append(args, "--depth", "1")
+	}
+	if err := runGit(append(args, "--", location, dir)...); err != nil {
+		return "", err
+	}
+	if ref != "" {
+		if err := runGit("-C", dir, "checkout", "--quiet", ref, "--"); err != nil {
+			return "", err
+		}
+	}
+
+	cmd := exec.Command("git", "-C", dir, "rev-parse", "HEAD")
+	commit, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(commit)), nil
+}
+
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s failed: %v\n%s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func fetchArchive(location, dir string) error {
+	var data []byte
+	var err error
+	if isURL(location) {
+		data, err = download(location)
+	} else {
+		data, err = os.ReadFile(location)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	lower := strings.ToLower(location)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		err = extractZip(data, dir)
+	case strings.HasSuffix(lower, ".tar"):
+		err = extractTar(bytes.NewReader(data), dir)
+	default:
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(bytes.NewReader(data))
+		if err == nil {
+			err = extractTar(gz, dir)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", location, err)
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archivePath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeArchiveFile(target, reader, header.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = symlinkError(header.Name)
+		default:
+			// Hard links, devices and PAX records carry no plugin files.
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(data []byte, dir string) error {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		target, err := archivePath(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		if mode&fs.ModeSymlink != 0 {
+			return symlinkError(file.Name)
+		}
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(target, content, mode)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePath rejects entries that would land outside dir.
+func archivePath(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if filepath.IsAbs(filepath.FromSlash(name)) || !withinDir(dir, target) {
+		return "", fmt.Errorf("archive entry %q escapes the plugin directory", name)
+	}
+	return target, nil
+}
+
+// symlinkError rejects symlinks in plugin sources. A checked link target is
+// not enough: later entries are written through links created by earlier
+// ones, so a chain of relative links can still escape the plugin directory.
+func symlinkError(name string) error {
+	return fmt.Errorf("%s is a symlink; plugins must not contain symlinks", name)
+}
+
+func writeArchiveFile(target string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()&0755|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, io.LimitReader(r, maxDownloadSize)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyTree copies a local plugin directory, skipping version control data.
+// Like archives, the directory must not contain symlinks.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case entry.IsDir():
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			return symlinkError(rel)
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return writeArchiveFile(target, file, info.Mode())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,407 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TrustedKeysEnv names the trusted keys file; without it
+// ~/.devtoolbox/trusted_keys is used if it exists.
+const TrustedKeysEnv = "DEVTOOLBOX_TRUSTED_KEYS"
+
+// SignatureFile is the detached signature of a plugin directory, next to its
+// plugin.yaml. Scripts without their own directory are signed in <script>.sig.
+const SignatureFile = "plugin.sig"
+
+const signatureHeader = "devtoolbox plugin signature v1\n"
+
+// Integrity pins the content of a plugin's files as they were when the plugin
+// was added. Plugins are refused if the files change.
+type Integrity struct {
+	// Files maps absolute paths to SHA-256 hex digests.
+	Files map[string]string `json:"files"`
+	// Root is the directory the signed paths are relative to.
+	Root string `json:"root"`
+	// Manifest is the manifest the plugin was added with, if any.
+	Manifest string `json:"manifest,omitempty"`
+	// Signer names the trusted key whose signature was verified, if any. The
+	// Signature file is then checked again before every execution.
+	Signer    string `json:"signer,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// TrustedKey is an ed25519 public key from the trusted keys file.
+type TrustedKey struct {
+	Name string
+	Key  ed25519.PublicKey
+}
+
+// NewIntegrity records the checksums of a plugin's files and verifies its
+// signature, if the plugin is signed and trusted keys are configured.
+func NewIntegrity(entrypoint string, manifest *Manifest) (*Integrity, error) {
+	root, files, signature, err := integrityFiles(entrypoint, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum plugin: %w", err)
+	}
+	integrity := &Integrity{Files: make(map[string]string, len(files)), Root: root}
+	if manifest != nil {
+		integrity.Manifest = manifest.Path
+	}
+	for _, path := range files {
+		sum, err := fileChecksum(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum plugin: %w", err)
+		}
+		integrity.Files[path] = sum
+	}
+
+	if _, err := os.Stat(signature); err != nil {
+		return integrity, nil
+	}
+	if manifest != nil && len(manifest.Build) > 0 {
+		return nil, fmt.Errorf("plugin %s is signed, but plugins with a build step cannot be verified", manifest.Name)
+	}
+	keys, err := LoadTrustedKeys(DefaultTrustedKeys())
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return integrity, nil
+	}
+	signer, err := verifySignature(signature, integrity.message(), keys)
+	if err != nil {
+		return nil, err
+	}
+	integrity.Signer = signer
+	integrity.Signature = signature
+	return integrity, nil
+}
+
+// Verify checks that the plugin files still match their checksums, that no
+// file was added to the directory of a plugin with its own plugin.yaml and,
+// for signed plugins, that the signature is still valid for a trusted key.
+func (i *Integrity) Verify() error {
+	if i == nil {
+		return nil
+	}
+
+	for _, path := range i.paths() {
+		sum, err := fileChecksum(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s is missing", path)
+		}
+		if err != nil {
+			return err
+		}
+		if sum != i.Files[path] {
+			return fmt.Errorf("%s was modified after the plugin was added", path)
+		}
+	}
+	if err := i.checkAdded(); err != nil {
+		return err
+	}
+
+	if i.Signer == "" {
+		return nil
+	}
+	keys, err := LoadTrustedKeys(DefaultTrustedKeys())
+	if err != nil {
+		return err
+	}
+	_, err = verifySignature(i.Signature, i.message(), keys)
+	return err
+}
+
+// checkAdded fails on files that appeared in the plugin directory after the
+// plugin was added, e.g. a module shadowing one the entrypoint imports.
+func (i *Integrity) checkAdded() error {
+	if i.Manifest == "" || !containsString(ManifestNames, filepath.Base(i.Manifest)) {
+		return nil
+	}
+	files, err := treeFiles(i.Root, filepath.Join(i.Root, SignatureFile))
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if _, ok := i.Files[path]; !ok {
+			return fmt.Errorf("%s appeared after the plugin was added", path)
+		}
+	}
+	return nil
+}
+
+func (i *Integrity) paths() []string {
+	paths := make([]string, 0, len(i.Files))
+	for path := range i.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// message is what a signature covers: the checksums of the plugin files
+// relative to the plugin root, one per line as printed by sha256sum.
+func (i *Integrity) message() []byte {
+	lines := make([]string, 0, len(i.Files))
+	for path, sum := range i.Files {
+		rel, err := filepath.Rel(i.Root, path)
+		if err != nil {
+			rel = filepath.Base(path)
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(rel)+"\n")
+	}
+	sort.Strings(lines)
+	return []byte(signatureHeader + strings.Join(lines, ""))
+}
+
+// integrityFiles returns the plugin root, the files to checksum and where the
+// signature lives. A plugin with its own plugin.yaml owns its directory, so
+// every file below it is covered except the signature and local .git and
+// .venv directories. Scripts, possibly with a <script>.plugin.yaml, share their
+// directory and only cover their own files.
+func integrityFiles(entrypoint string, manifest *Manifest) (string, []string, string, error) {
+	if manifest == nil || manifest.Path == "" {
+		return filepath.Dir(entrypoint), []string{entrypoint}, entrypoint + ".sig", nil
+	}
+	root := filepath.Dir(manifest.Path)
+	if containsString(ManifestNames, filepath.Base(manifest.Path)) {
+		signature := filepath.Join(root, SignatureFile)
+		files, err := treeFiles(root, signature)
+		return root, files, signature, err
+	}
+	files := []string{manifest.Path, entrypoint}
+	if manifest.Language == LanguageTemplate {
+		files = append(files, partialTemplates(entrypoint)...)
+	}
+	return root, files, entrypoint + ".sig", nil
+}
+
+// treeFiles lists the files below root other than the signature. Symlinks
+// are followed to their files; linked directories are not entered.
+func treeFiles(root, signature string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && (entry.Name() == ".git" || entry.Name() == ".venv") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == signature {
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return nil
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func verifySignature(path string, message []byte, keys []TrustedKey) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("signature %s is missing", path)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return "", fmt.Errorf("signature %s is malformed", path)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key.Key, message, signature) {
+			return key.Name, nil
+		}
+	}
+	return "", fmt.Errorf("signature %s does not match the plugin files or any trusted key", path)
+}
+
+// DefaultTrustedKeys returns the trusted keys file from DEVTOOLBOX_TRUSTED_KEYS
+// or ~/.devtoolbox/trusted_keys.
+func DefaultTrustedKeys() string {
+	if path := os.Getenv(TrustedKeysEnv); path != "" {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".devtoolbox", "trusted_keys")
+}
+
+// LoadTrustedKeys reads one base64 ed25519 public key per line, optionally
+// followed by a name; blank lines and lines starting with # are skipped. A
+// missing file means no keys.
+func LoadTrustedKeys(path string) ([]TrustedKey, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+
+	var keys []TrustedKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid trusted key in %s line %d", path, line)
+		}
+		name := fmt.Sprintf("%s:%d", filepath.Base(path), line)
+		if len(fields) > 1 {
+			name = strings.Join(fields[1:], " ")
+		}
+		keys = append(keys, TrustedKey{Name: name, Key: ed25519.PublicKey(key)})
+	}
+	return keys, nil
+}
+
+// GenerateKey creates a signing key pair. The private key and the trusted
+// keys line for the public key are both base64 encoded.
+func GenerateKey(name string) (privateKey string, publicKey string, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey = base64.StdEncoding.EncodeToString(public)
+	if name != "" {
+		publicKey += " " + name
+	}
+	return base64.StdEncoding.EncodeToString(private), publicKey, nil
+}
+
+// SignPlugin writes the detached signature of the plugin at path (a script,
+// a manifest or a plugin directory) with a base64 private key from keyPath.
+// It returns the signature file.
+func SignPlugin(path, keyPath string) (string, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", fmt.Errorf("%s is not an ed25519 private key", keyPath)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	manifest, err := ResolveManifest(absPath)
+	if err != nil {
+		return "", err
+	}
+	entrypoint := absPath
+	if manifest != nil {
+		if len(manifest.Build) > 0 {
+			return "", fmt.Errorf("plugins with a build step cannot be signed")
+		}
+		entrypoint = manifest.EntrypointPath()
+	}
+
+	root, files, signaturePath, err := integrityFiles(entrypoint, manifest)
+	if err != nil {
+		return "", err
+	}
+	integrity := &Integrity{Files: make(map[string]string, len(files)), Root: root}
+	for _, file := range files {
+		if integrity.Files[file], err = fileChecksum(file); err != nil {
+			return "", err
+		}
+	}
+	signature := ed25519.Sign(ed25519.PrivateKey(key), integrity.message())
+	if err := os.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return signaturePath, nil
+}
+
+// RecordIntegrity records the checksums of a registered plugin again, after
+// its files were changed on purpose, e.g. edited or rebuilt. The manifest is
+// read again as well.
+func (pm *PluginManager) RecordIntegrity(name string) (*PluginInfo, error) {
+	plugins, err := pm.LoadPlugins()
+	if err != nil {
+		return nil, err
+	}
+	for i := range plugins {
+		info := &plugins[i]
+		if info.Name != name {
+			continue
+		}
+
+		manifest, err := ResolveManifest(info.manifestPath())
+		if err != nil {
+			return nil, err
+		}
+		if manifest != nil {
+			info.Manifest = manifest
+			info.Path = manifest.EntrypointPath()
+		}
+		if info.Integrity, err = NewIntegrity(info.Path, manifest); err != nil {
+			return nil, err
+		}
+		return info, pm.savePlugins(plugins)
+	}
+	return nil, fmt.Errorf("plugin '%s' not found", name)
+}
+
+// manifestPath returns the manifest recorded in the integrity of the plugin,
+// or the entrypoint to look the manifest up from. Plugins recorded before the
+// manifest was stored fall back to the checksummed file named like one.
+func (info *PluginInfo) manifestPath() string {
+	if info.Integrity == nil {
+		return info.Path
+	}
+	if info.Integrity.Manifest != "" {
+		return info.Integrity.Manifest
+	}
+	for _, path := range info.Integrity.paths() {
+		for _, name := range ManifestNames {
+			if path != info.Path && strings.HasSuffix(filepath.Base(path), name) {
+				return path
+			}
+		}
+	}
+	return info.Path
+}
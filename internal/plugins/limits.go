@@ -0,0 +1,173 @@
+package plugins
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout   = 30 * time.Second
+	DefaultMaxOutput = 10 << 20
+)
+
+// Limits bound a single plugin call. Zero CPU and Memory mean no limit.
+// CPU and Memory are enforced with rlimits and only on Linux.
+type Limits struct {
+	Timeout   time.Duration
+	MaxOutput int64
+	CPU       time.Duration
+	Memory    int64
+}
+
+// LimitsSpec is the limits section of a manifest. Durations use Go syntax
+// ("30s"), sizes accept KB, MB and GB suffixes ("512MB").
+type LimitsSpec struct {
+	Timeout   string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	MaxOutput string `json:"max_output,omitempty" yaml:"max_output,omitempty"`
+	CPU       string `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Memory    string `json:"memory,omitempty" yaml:"memory,omitempty"`
+}
+
+// Limits converts the spec, filling defaults for timeout and output size.
+func (s *LimitsSpec) Limits() (Limits, error) {
+	limits := Limits{Timeout: DefaultTimeout, MaxOutput: DefaultMaxOutput}
+	if s == nil {
+		return limits, nil
+	}
+
+	var err error
+	if s.Timeout != "" {
+		if limits.Timeout, err = parsePositiveDuration(s.Timeout); err != nil {
+			return limits, fmt.Errorf("limits.timeout: %w", err)
+		}
+	}
+	if s.CPU != "" {
+		if limits.CPU, err = parsePositiveDuration(s.CPU); err != nil {
+			return limits, fmt.Errorf("limits.cpu: %w", err)
+		}
+	}
+	if s.MaxOutput != "" {
+		if limits.MaxOutput, err = parseSize(s.MaxOutput); err != nil {
+			return limits, fmt.Errorf("limits.max_output: %w", err)
+		}
+	}
+	if s.Memory != "" {
+		if limits.Memory, err = parseSize(s.Memory); err != nil {
+			return limits, fmt.Errorf("limits.memory: %w", err)
+		}
+	}
+	return limits, nil
+}
+
+// pluginLimits returns the limits declared in the manifest, or the defaults.
+func pluginLimits(manifest *Manifest) Limits {
+	var spec *LimitsSpec
+	if manifest != nil {
+		spec = manifest.Limits
+	}
+	limits, err := spec.Limits()
+	if err != nil {
+		// Manifests are validated on load; fall back to the defaults.
+		limits, _ = (*LimitsSpec)(nil).Limits()
+	}
+	return limits
+}
+
+func parsePositiveDuration(value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("must be positive, got %s", value)
+	}
+	return duration, nil
+}
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func parseSize(value string) (int64, error) {
+	text := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return number * multiplier, nil
+}
+
+func formatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.multiplier && size%unit.multiplier == 0 {
+			return fmt.Sprintf("%d%s", size/unit.multiplier, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+// resourceLimitError recognizes a process stopped by its CPU or memory limit.
+func resourceLimitError(plugin string, state *os.ProcessState, stderr string, limits Limits) error {
+	// A sandboxed plugin runs as PID 1 of its namespace and ignores SIGXCPU
+	// until the hard limit kills it, so the CPU time used is checked instead
+	// of the signal.
+	if limits.CPU > 0 && state != nil && !state.Success() && state.UserTime()+state.SystemTime() >= limits.CPU {
+		return &ResourceLimitError{Plugin: plugin, Resource: "cpu", Limit: limits.CPU.String(), Stderr: stderr}
+	}
+	// Python raises MemoryError; Go and C runtimes report "out of memory".
+	if limits.Memory > 0 && (strings.Contains(stderr, "MemoryError") || strings.Contains(stderr, "out of memory")) {
+		return &ResourceLimitError{Plugin: plugin, Resource: "memory", Limit: formatSize(limits.Memory), Stderr: stderr}
+	}
+	return nil
+}
+
+var errOutputLimit = errors.New("output limit exceeded")
+
+// cappedBuffer collects plugin output and calls onExceed once more than limit
+// bytes were written, so the caller can kill the process. The buffer is not
+// embedded: its ReadFrom would let io.Copy bypass the limit.
+type cappedBuffer struct {
+	buffer   bytes.Buffer
+	limit    int64
+	exceeded bool
+	onExceed func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && int64(b.buffer.Len()+len(p)) > b.limit {
+		if !b.exceeded {
+			b.exceeded = true
+			b.onExceed()
+		}
+		return 0, errOutputLimit
+	}
+	return b.buffer.Write(p)
+}
+
+func (b *cappedBuffer) Bytes() []byte {
+	return b.buffer.Bytes()
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buffer.String()
+}
@@ -1,21 +1,34 @@
 package plugins
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 type PluginInfo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Path        string `json:"path"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Path        string    `json:"path"`
+	Manifest    *Manifest `json:"manifest,omitempty"`
+	Trust       Trust     `json:"trust,omitempty"`
+	// Source is set for plugins installed with plugin install, whose files
+	// are managed in PluginsDir.
+	Source *Source `json:"source,omitempty"`
+	// Integrity pins the plugin files as they were when the plugin was added.
+	Integrity *Integrity `json:"integrity,omitempty"`
+	// Scope is the search path scope the plugin was discovered in.
+	Scope string `json:"-"`
 }
 
 type PluginManager struct {
-	configPath string
+	configPath  string
+	searchPaths []SearchPath
 }
 
 func NewPluginManager() *PluginManager {
@@ -27,14 +40,33 @@ func NewPluginManager() *PluginManager {
 	}
 }
 
-func (pm *PluginManager) AddPlugin(pluginPath string) error {
+// AddPlugin registers a custom plugin and records the checksums of its files.
+// Untrusted plugins always run in the sandbox; trusted ones may opt out in
+// their manifest.
+func (pm *PluginManager) AddPlugin(pluginPath string, trust Trust) error {
 	plugins, err := pm.LoadPlugins()
 	if err != nil {
 		return err
 	}
 	
+	manifest, err := ResolveManifest(pluginPath)
+	if err != nil {
+		return err
+	}
+	
 	pluginName := filepath.Base(pluginPath)
 	pluginName = pluginName[:len(pluginName)-len(filepath.Ext(pluginName))]
+	description := ""
+	language := LanguageForPath(pluginPath)
+	if manifest != nil {
+		pluginName = manifest.Name
+		description = manifest.Description
+		pluginPath = manifest.EntrypointPath()
+		language = manifest.Language
+	}
+	if description == "" {
+		description = fmt.Sprintf("Custom plugin: %s", pluginName)
+	}
 	
 	for _, plugin := range plugins {
 		if plugin.Name == pluginName {
@@ -42,11 +74,25 @@ func (pm *PluginManager) AddPlugin(pluginPath string) error {
 		}
 	}
 	
+	if manifest != nil {
+		if err := BuildPlugin(manifest); err != nil {
+			return err
+		}
+	}
+	
+	integrity, err := NewIntegrity(pluginPath, manifest)
+	if err != nil {
+		return err
+	}
+	
 	newPlugin := PluginInfo{
 		Name:        pluginName,
-		Description: fmt.Sprintf("Custom plugin: %s", pluginName),
-		Type:        "python",
+		Description: description,
+		Type:        language,
 		Path:        pluginPath,
+		Manifest:    manifest,
+		Trust:       trust,
+		Integrity:   integrity,
 	}
 	
 	plugins = append(plugins, newPlugin)
@@ -54,6 +100,33 @@ func (pm *PluginManager) AddPlugin(pluginPath string) error {
 	return pm.savePlugins(plugins)
 }
 
+// BuildPlugin runs the build command of the manifest, if any, in the plugin
+// directory, e.g. "go build -o bin/gen ." for plugins written with the Go SDK,
+// and creates the virtual environment for the requirements of Python plugins.
+func BuildPlugin(manifest *Manifest) error {
+	if _, err := EnsureVenv(context.Background(), manifest); err != nil {
+		return err
+	}
+	if len(manifest.Build) == 0 {
+		return nil
+	}
+	
+	cmd := exec.Command(manifest.Build[0], manifest.Build[1:]...)
+	cmd.Dir = filepath.Dir(manifest.Path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to build plugin %s: %v\n%s", manifest.Name, err, strings.TrimSpace(string(output)))
+	}
+	
+	info, err := os.Stat(manifest.EntrypointPath())
+	if err != nil {
+		return fmt.Errorf("build of plugin %s did not produce entrypoint %s", manifest.Name, manifest.Entrypoint)
+	}
+	if manifest.Language == LanguageExec && !manifest.hasInterpreter() && !isExecutable(info) {
+		return fmt.Errorf("build of plugin %s produced entrypoint %s that is not executable", manifest.Name, manifest.Entrypoint)
+	}
+	return nil
+}
+
 func (pm *PluginManager) RemovePlugin(pluginName string) error {
 	plugins, err := pm.LoadPlugins()
 	if err != nil {
@@ -76,29 +149,14 @@ func (pm *PluginManager) RemovePlugin(pluginName string) error {
 	return pm.savePlugins(plugins)
 }
 
+// ListPlugins returns the plugins found by Discover; built-in generators are
+// not included.
 func (pm *PluginManager) ListPlugins() ([]PluginInfo, error) {
-	plugins, err := pm.LoadPlugins()
+	discovery, err := pm.Discover(nil)
 	if err != nil {
 		return nil, err
 	}
-	
-	officialPlugins := []PluginInfo{
-		{
-			Name:        "go-struct",
-			Description: "Генерирует Go структуры с JSON тегами из JSON схемы",
-			Type:        "go",
-			Path:        "builtin",
-		},
-		{
-			Name:        "ts_interface_gen",
-			Description: "Python plugin: ts_interface_gen",
-			Type:        "python",
-			Path:        "plugins/official/ts_interface_gen.py",
-		},
-	}
-	
-	allPlugins := append(officialPlugins, plugins...)
-	return allPlugins, nil
+	return discovery.Plugins, nil
 }
 
 func (pm *PluginManager) LoadPlugins() ([]PluginInfo, error) {
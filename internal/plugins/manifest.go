@@ -0,0 +1,520 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/version"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestNames are the file names looked up next to a plugin script.
+// A script-specific manifest (<script>.plugin.yaml) takes precedence, so
+// several plugins can share one directory.
+var ManifestNames = []string{"plugin.yaml", "plugin.yml", "plugin.json"}
+
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var optionTypes = []string{"string", "boolean", "integer", "number"}
+
+type OptionSpec struct {
+	Type        string   `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Default     string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Required    bool     `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// WorkerSpec enables the long-lived worker mode. Durations use Go syntax,
+// e.g. "90s" or "5m".
+type WorkerSpec struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	PoolSize       int    `json:"pool_size,omitempty" yaml:"pool_size,omitempty"`
+	IdleTimeout    string `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`
+	MaxRequests    int    `json:"max_requests,omitempty" yaml:"max_requests,omitempty"`
+	HealthInterval string `json:"health_interval,omitempty" yaml:"health_interval,omitempty"`
+}
+
+// Config converts the spec into a WorkerConfig; zero values mean defaults.
+func (w *WorkerSpec) Config() (WorkerConfig, error) {
+	config := WorkerConfig{PoolSize: w.PoolSize, MaxRequests: w.MaxRequests}
+
+	var err error
+	if w.IdleTimeout != "" {
+		if config.IdleTimeout, err = time.ParseDuration(w.IdleTimeout); err != nil {
+			return config, fmt.Errorf("worker.idle_timeout: %w", err)
+		}
+	}
+	if w.HealthInterval != "" {
+		if config.HealthInterval, err = time.ParseDuration(w.HealthInterval); err != nil {
+			return config, fmt.Errorf("worker.health_interval: %w", err)
+		}
+	}
+	if config.PoolSize < 0 || config.MaxRequests < 0 {
+		return config, fmt.Errorf("worker.pool_size and worker.max_requests must not be negative")
+	}
+	return config, nil
+}
+
+// Command is an interpreter or build command line. Manifests may give it as a list
+// (["node", "--no-warnings"]) or as one string split on spaces.
+type Command []string
+
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var line string
+	if err := json.Unmarshal(data, &line); err == nil {
+		*c = strings.Fields(line)
+		return nil
+	}
+	var args []string
+	if err := json.Unmarshal(data, &args); err != nil {
+		return fmt.Errorf("command must be a string or a list of strings")
+	}
+	*c = args
+	return nil
+}
+
+func (c *Command) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*c = strings.Fields(value.Value)
+		return nil
+	}
+	var args []string
+	if err := value.Decode(&args); err != nil {
+		return fmt.Errorf("command must be a string or a list of strings")
+	}
+	*c = args
+	return nil
+}
+
+type Manifest struct {
+	Name            string                `json:"name" yaml:"name"`
+	Version         string                `json:"version" yaml:"version"`
+	Description     string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Aliases         []string              `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Author          string                `json:"author,omitempty" yaml:"author,omitempty"`
+	Language        string                `json:"language" yaml:"language"`
+	Entrypoint      string                `json:"entrypoint" yaml:"entrypoint"`
+	Interpreter     Command               `json:"interpreter,omitempty" yaml:"interpreter,omitempty"`
+	Build           Command               `json:"build,omitempty" yaml:"build,omitempty"`
+	Requirements    []string              `json:"requirements,omitempty" yaml:"requirements,omitempty"`
+	Protocol        string                `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	InputFormats    []string              `json:"input_formats,omitempty" yaml:"input_formats,omitempty"`
+	OutputExtension string                `json:"output_extension,omitempty" yaml:"output_extension,omitempty"`
+	Fixtures        []string              `json:"fixtures,omitempty" yaml:"fixtures,omitempty"`
+	Options         map[string]OptionSpec `json:"options,omitempty" yaml:"options,omitempty"`
+	MinVersion      string                `json:"min_devtoolbox_version,omitempty" yaml:"min_devtoolbox_version,omitempty"`
+	Worker          *WorkerSpec           `json:"worker,omitempty" yaml:"worker,omitempty"`
+	Limits          *LimitsSpec           `json:"limits,omitempty" yaml:"limits,omitempty"`
+	Sandbox         string                `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	Template        *TemplateSpec         `json:"template,omitempty" yaml:"template,omitempty"`
+
+	// Path is the manifest file the plugin was loaded from.
+	Path string `json:"-" yaml:"-"`
+}
+
+// LoadManifest reads and validates a plugin manifest. The entrypoint is
+// resolved relative to the manifest directory.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &manifest)
+	} else {
+		err = yaml.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	manifest.Path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+// FindManifest returns the manifest describing the given script, or nil if
+// the script has none.
+func FindManifest(scriptPath string) (*Manifest, error) {
+	absPath, err := filepath.Abs(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	dir := filepath.Dir(absPath)
+	base := strings.TrimSuffix(filepath.Base(absPath), filepath.Ext(absPath))
+
+	for _, name := range ManifestNames {
+		path := filepath.Join(dir, base+"."+name)
+		if _, err := os.Stat(path); err == nil {
+			return LoadManifest(path)
+		}
+	}
+
+	for _, name := range ManifestNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		manifest, err := LoadManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.EntrypointPath() == absPath {
+			return manifest, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ResolveManifest accepts a manifest file, a directory containing one, or a
+// plugin script and returns the manifest for it, or nil for a bare script.
+func ResolveManifest(path string) (*Manifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		for _, name := range ManifestNames {
+			manifestPath := filepath.Join(path, name)
+			if _, err := os.Stat(manifestPath); err == nil {
+				return LoadManifest(manifestPath)
+			}
+		}
+		return nil, fmt.Errorf("no plugin manifest found in %s", path)
+	}
+
+	for _, name := range ManifestNames {
+		if strings.HasSuffix(filepath.Base(path), name) {
+			return LoadManifest(path)
+		}
+	}
+
+	return FindManifest(path)
+}
+
+func (m *Manifest) Validate() error {
+	var problems []string
+
+	if err := ValidateName(m.Name); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	for _, alias := range m.Aliases {
+		if !pluginNamePattern.MatchString(alias) || alias == m.Name {
+			problems = append(problems, fmt.Sprintf("alias %q must differ from the name and contain only lowercase letters, digits, '-' and '_'", alias))
+		}
+	}
+
+	if m.Version == "" {
+		problems = append(problems, "version is required")
+	} else if _, err := parseVersion(m.Version); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if m.Language == "" {
+		m.Language = LanguagePython
+	}
+	switch m.Language {
+	case LanguagePython:
+		if len(m.Requirements) > 0 && len(m.Interpreter) > 0 {
+			problems = append(problems, "requirements cannot be combined with an interpreter; they are installed into a virtual environment of the plugin")
+		}
+		for _, requirement := range m.Requirements {
+			if !validRequirement(requirement) {
+				problems = append(problems, fmt.Sprintf("invalid requirement %q", requirement))
+			}
+		}
+	case LanguageExec:
+	case LanguageWasm:
+		if len(m.Interpreter) > 0 {
+			problems = append(problems, "interpreter is not supported for wasm plugins")
+		}
+		if m.Worker != nil && m.Worker.Enabled {
+			problems = append(problems, "worker mode is not supported for wasm plugins")
+		}
+	case LanguageTemplate:
+		if len(m.Interpreter) > 0 || len(m.Build) > 0 {
+			problems = append(problems, "interpreter and build are not supported for template plugins")
+		}
+		if m.Worker != nil && m.Worker.Enabled {
+			problems = append(problems, "worker mode is not supported for template plugins")
+		}
+		if m.Protocol == "" {
+			m.Protocol = ProtocolJSON
+		} else if m.Protocol != ProtocolJSON {
+			problems = append(problems, "template plugins require protocol: json")
+		}
+		problems = append(problems, m.Template.validate()...)
+	default:
+		problems = append(problems, fmt.Sprintf("unsupported language %q, expected %s, %s, %s or %s", m.Language, LanguagePython, LanguageExec, LanguageWasm, LanguageTemplate))
+	}
+	if m.Language != LanguageTemplate && m.Template != nil {
+		problems = append(problems, "template is only supported for template plugins")
+	}
+	if m.Language == LanguageTemplate && m.Entrypoint != "" {
+		if _, err := os.Stat(m.EntrypointPath()); err == nil {
+			if _, err := parseTemplates(m.EntrypointPath(), m.Template, partialTemplates(m.EntrypointPath())); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+	if m.Language != LanguagePython && len(m.Requirements) > 0 {
+		problems = append(problems, "requirements are only supported for python plugins")
+	}
+
+	if m.Entrypoint == "" {
+		problems = append(problems, "entrypoint is required")
+	} else if !m.entrypointInside() {
+		problems = append(problems, fmt.Sprintf("entrypoint %s must be inside the plugin directory", m.Entrypoint))
+	} else if len(m.Build) > 0 {
+		// The entrypoint is produced by the build command on plugin add.
+	} else if info, err := os.Stat(m.EntrypointPath()); err != nil {
+		problems = append(problems, fmt.Sprintf("entrypoint %s not found", m.Entrypoint))
+	} else if m.Language == LanguageExec && !m.hasInterpreter() && !isExecutable(info) {
+		problems = append(problems, fmt.Sprintf("entrypoint %s is not executable and no interpreter is set", m.Entrypoint))
+	}
+
+	if m.Protocol == "" {
+		m.Protocol = ProtocolText
+	}
+	if m.Protocol != ProtocolText && m.Protocol != ProtocolJSON {
+		problems = append(problems, fmt.Sprintf("unsupported protocol %q, expected %s or %s", m.Protocol, ProtocolText, ProtocolJSON))
+	}
+
+	if m.Worker != nil && m.Worker.Enabled {
+		if m.Protocol != ProtocolJSON {
+			problems = append(problems, "worker mode requires protocol: json")
+		}
+		if _, err := m.Worker.Config(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if m.Sandbox != "" && m.Sandbox != SandboxStrict && m.Sandbox != SandboxNone {
+		problems = append(problems, fmt.Sprintf("unsupported sandbox %q, expected %s or %s", m.Sandbox, SandboxStrict, SandboxNone))
+	}
+
+	if _, err := m.Limits.Limits(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if m.OutputExtension != "" && !strings.HasPrefix(m.OutputExtension, ".") {
+		m.OutputExtension = "." + m.OutputExtension
+	}
+
+	for _, pattern := range m.Fixtures {
+		clean := filepath.ToSlash(filepath.Clean(pattern))
+		if filepath.IsAbs(pattern) || clean == ".." || strings.HasPrefix(clean, "../") {
+			problems = append(problems, fmt.Sprintf("fixture %q must be inside the plugin directory", pattern))
+		} else if _, err := filepath.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid fixture pattern %q", pattern))
+		}
+	}
+
+	for _, name := range m.OptionNames() {
+		option := m.Options[name]
+		if option.Type == "" {
+			option.Type = "string"
+			m.Options[name] = option
+		}
+		if !containsString(optionTypes, option.Type) {
+			problems = append(problems, fmt.Sprintf("option %s has unsupported type %q", name, option.Type))
+		}
+		if option.Default != "" && len(option.Enum) > 0 && !containsString(option.Enum, option.Default) {
+			problems = append(problems, fmt.Sprintf("option %s default %q is not one of %s", name, option.Default, strings.Join(option.Enum, ", ")))
+		}
+	}
+
+	if m.MinVersion != "" {
+		if _, err := parseVersion(m.MinVersion); err != nil {
+			problems = append(problems, fmt.Sprintf("min_devtoolbox_version: %v", err))
+		} else if CompareVersions(version.Version, m.MinVersion) < 0 {
+			problems = append(problems, fmt.Sprintf("requires DevToolBox %s or newer, running %s", m.MinVersion, version.Version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// hasInterpreter reports whether an exec plugin runs through an interpreter.
+func (m *Manifest) hasInterpreter() bool {
+	_, ok := DefaultInterpreters[strings.ToLower(filepath.Ext(m.Entrypoint))]
+	return len(m.Interpreter) > 0 || ok
+}
+
+// FixturePaths returns the sorted files matched by the fixture patterns, the
+// sample inputs plugin test runs the plugin against.
+func (m *Manifest) FixturePaths() []string {
+	var paths []string
+	for _, pattern := range m.Fixtures {
+		matches, _ := filepath.Glob(filepath.Join(filepath.Dir(m.Path), filepath.FromSlash(pattern)))
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && !containsString(paths, match) {
+				paths = append(paths, match)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// entrypointInside reports whether the entrypoint stays in the manifest
+// directory, also after resolving symlinks: checksums and the sandbox only
+// cover that directory.
+func (m *Manifest) entrypointInside() bool {
+	clean := filepath.Clean(filepath.FromSlash(m.Entrypoint))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return false
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(m.Path))
+	if err != nil {
+		return true
+	}
+	resolved, err := filepath.EvalSymlinks(m.EntrypointPath())
+	return err != nil || withinDir(dir, resolved)
+}
+
+// EntrypointPath returns the absolute path of the plugin script.
+func (m *Manifest) EntrypointPath() string {
+	if filepath.IsAbs(m.Entrypoint) {
+		return filepath.Clean(m.Entrypoint)
+	}
+	return filepath.Join(filepath.Dir(m.Path), m.Entrypoint)
+}
+
+func (m *Manifest) OptionNames() []string {
+	names := make([]string, 0, len(m.Options))
+	for name := range m.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateName checks a plugin name.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if !pluginNamePattern.MatchString(name) {
+		return fmt.Errorf("name %q must contain only lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// CompareVersions compares two "major.minor.patch" versions. A leading "v"
+// and any pre-release or build suffix are ignored.
+func CompareVersions(a, b string) int {
+	left, _ := parseVersion(a)
+	right, _ := parseVersion(b)
+	for i := range left {
+		if left[i] != right[i] {
+			if left[i] < right[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// MatchVersion reports whether a version satisfies a constraint: clauses such
+// as ">=1.2.0 <2.0.0", separated by spaces or commas, that must all hold. The
+// operators are =, !=, >, >=, < and <=; a bare version must match exactly.
+func MatchVersion(version, constraint string) (bool, error) {
+	clauses := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(clauses) == 0 {
+		return false, fmt.Errorf("empty version constraint")
+	}
+
+	_, versionErr := parseVersion(version)
+	matched := versionErr == nil
+	for _, clause := range clauses {
+		target := strings.TrimLeft(clause, "=!<>")
+		operator := strings.TrimSuffix(clause, target)
+		if operator == "" {
+			operator = "="
+		}
+		if _, err := parseVersion(target); err != nil {
+			return false, fmt.Errorf("invalid version constraint %q: %v", constraint, err)
+		}
+
+		var ok bool
+		comparison := CompareVersions(version, target)
+		switch operator {
+		case "=", "==":
+			ok = comparison == 0
+		case "!=":
+			ok = comparison != 0
+		case ">":
+			ok = comparison > 0
+		case ">=":
+			ok = comparison >= 0
+		case "<":
+			ok = comparison < 0
+		case "<=":
+			ok = comparison <= 0
+		default:
+			return false, fmt.Errorf("invalid version constraint %q: unknown operator %q", constraint, operator)
+		}
+		matched = matched && ok
+	}
+	return matched, nil
+}
+
+func parseVersion(value string) ([3]int, error) {
+	var parts [3]int
+
+	trimmed := strings.TrimPrefix(value, "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+
+	fields := strings.Split(trimmed, ".")
+	if len(fields) > 3 || trimmed == "" {
+		return parts, fmt.Errorf("invalid version %q", value)
+	}
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return parts, fmt.Errorf("invalid version %q", value)
+		}
+		parts[i] = number
+	}
+	return parts, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Plugin languages. Python plugins run a script with the Python interpreter,
+// exec plugins run any executable, optionally through an interpreter, wasm
+// plugins are WASI modules executed in-process and template plugins render
+// Go text/template files in-process.
+const (
+	LanguagePython   = "python"
+	LanguageExec     = "exec"
+	LanguageWasm     = "wasm"
+	LanguageTemplate = "template"
+)
+
+// Plugin is an external generator. Generate keeps the plain JSON-in,
+// code-out contract of built-in generators; Invoke exposes the full protocol
+// and stops the plugin when ctx is done.
+type Plugin interface {
+	GetName() string
+	GetDescription() string
+	Generate(input string) (string, error)
+	Invoke(ctx context.Context, request *Request) (*Response, error)
+	Manifest() *Manifest
+	WithOptions(options map[string]string) (Plugin, error)
+}
+
+// ExternalPlugin is a plugin loaded from outside the binary: a Python script,
+// any executable speaking the plugin protocol over stdin/stdout, or a
+// WebAssembly module.
+type ExternalPlugin interface {
+	Plugin
+	SetManifest(manifest *Manifest)
+	Trust() Trust
+	SetTrust(trust Trust)
+	SetIntegrity(integrity *Integrity)
+	Workers() *WorkerPool
+	Close() error
+}
+
+// NewExternalPlugin creates a plugin of the given language for the entrypoint
+// at path.
+func NewExternalPlugin(language, name, description, path string) (ExternalPlugin, error) {
+	switch language {
+	case LanguagePython, "":
+		return NewPythonPlugin(name, description, path), nil
+	case LanguageExec:
+		return NewExecPlugin(name, description, path), nil
+	case LanguageWasm:
+		return NewWasmPlugin(name, description, path), nil
+	case LanguageTemplate:
+		return NewTemplatePlugin(name, description, path), nil
+	default:
+		return nil, fmt.Errorf("unsupported plugin type %q", language)
+	}
+}
+
+// NewPluginFromManifest creates the plugin described by a validated manifest.
+func NewPluginFromManifest(manifest *Manifest) ExternalPlugin {
+	switch manifest.Language {
+	case LanguageExec:
+		return NewExecPluginFromManifest(manifest)
+	case LanguageWasm:
+		return NewWasmPluginFromManifest(manifest)
+	case LanguageTemplate:
+		return NewTemplatePluginFromManifest(manifest)
+	default:
+		return NewPythonPluginFromManifest(manifest)
+	}
+}
+
+// LanguageForPath guesses the language of a plugin without a manifest.
+func LanguageForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".py":
+		return LanguagePython
+	case ".wasm":
+		return LanguageWasm
+	default:
+		return LanguageExec
+	}
+}
+
+// Protocol returns the protocol spoken by the plugin.
+func Protocol(plugin ManifestProvider) string {
+	if manifest := plugin.Manifest(); manifest != nil && manifest.Protocol != "" {
+		return manifest.Protocol
+	}
+	return ProtocolText
+}
+
+// ValidateOptions checks options against the manifest options schema.
+func ValidateOptions(name string, manifest *Manifest, options map[string]string) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if manifest == nil || manifest.Protocol != ProtocolJSON {
+		return &PluginError{Plugin: name, Code: CodeInvalidOptions, Message: "plugin does not accept options"}
+	}
+
+	for key, value := range options {
+		spec, ok := manifest.Options[key]
+		if !ok {
+			return &PluginError{Plugin: name, Code: CodeInvalidOptions, Message: fmt.Sprintf("unknown option %s, available: %s", key, strings.Join(manifest.OptionNames(), ", "))}
+		}
+		if err := spec.check(value); err != nil {
+			return &PluginError{Plugin: name, Code: CodeInvalidOptions, Message: fmt.Sprintf("option %s: %v", key, err)}
+		}
+	}
+	return nil
+}
+
+// optionsWithDefaults adds manifest defaults for options that were not set
+// and reports required options that are missing.
+func optionsWithDefaults(name string, manifest *Manifest, options map[string]string) (map[string]string, error) {
+	if manifest == nil || len(manifest.Options) == 0 {
+		return options, nil
+	}
+
+	result := make(map[string]string, len(manifest.Options))
+	for key, value := range options {
+		result[key] = value
+	}
+	for _, key := range manifest.OptionNames() {
+		spec := manifest.Options[key]
+		if _, ok := result[key]; ok {
+			continue
+		}
+		if spec.Default != "" {
+			result[key] = spec.Default
+		} else if spec.Required {
+			return nil, &PluginError{Plugin: name, Code: CodeInvalidOptions, Message: fmt.Sprintf("option %s is required", key)}
+		}
+	}
+	return result, nil
+}
+
+func (s OptionSpec) check(value string) error {
+	if len(s.Enum) > 0 && !containsString(s.Enum, value) {
+		return fmt.Errorf("expected one of %s, got %q", strings.Join(s.Enum, ", "), value)
+	}
+
+	var err error
+	switch s.Type {
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	case "integer":
+		_, err = strconv.Atoi(value)
+	case "number":
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("expected %s, got %q", s.Type, value)
+	}
+	return nil
+}
@@ -0,0 +1,331 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultInterpreters maps entrypoint extensions of exec plugins to the
+// interpreter used when the manifest does not set one. Entrypoints with other
+// extensions are executed directly.
+var DefaultInterpreters = map[string][]string{
+	".js":   {"node"},
+	".mjs":  {"node"},
+	".cjs":  {"node"},
+	".rb":   {"ruby"},
+	".pl":   {"perl"},
+	".php":  {"php"},
+	".sh":   {"sh"},
+	".bash": {"bash"},
+	".py":   {"python3"},
+}
+
+// processPlugin implements the protocol, worker mode, limits and sandboxing
+// shared by all plugins that run as separate processes.
+type processPlugin struct {
+	language    string
+	name        string
+	description string
+	entrypoint  string
+	manifest    *Manifest
+	options     map[string]string
+	pool        *WorkerPool
+	trust       Trust
+	integrity   *Integrity
+}
+
+func (p *processPlugin) GetName() string {
+	return p.name
+}
+
+func (p *processPlugin) GetDescription() string {
+	return p.description
+}
+
+func (p *processPlugin) Manifest() *Manifest {
+	return p.manifest
+}
+
+// SetManifest attaches a manifest and, if it enables worker mode, a pool of
+// long-lived plugin processes.
+func (p *processPlugin) SetManifest(manifest *Manifest) {
+	p.manifest = manifest
+	p.pool = nil
+	if manifest == nil || manifest.Worker == nil || !manifest.Worker.Enabled {
+		return
+	}
+
+	config, err := manifest.Worker.Config()
+	if err != nil {
+		return
+	}
+	limits := pluginLimits(manifest)
+	config.MaxOutput = limits.MaxOutput
+	config.Memory = limits.Memory
+	p.pool = NewWorkerPool(p.name, config, func() (*exec.Cmd, error) {
+		cmd, err := p.command(context.Background(), "--worker")
+		if err != nil {
+			return nil, err
+		}
+		return cmd, p.isolate(cmd, Limits{Memory: config.Memory})
+	})
+}
+
+func (p *processPlugin) Trust() Trust {
+	return p.trust
+}
+
+// SetTrust sets the trust level, which decides whether the plugin runs in
+// the sandbox. Call it before the first Invoke.
+func (p *processPlugin) SetTrust(trust Trust) {
+	p.trust = trust
+}
+
+// SetIntegrity attaches the checksums recorded when the plugin was added;
+// they are verified before every call.
+func (p *processPlugin) SetIntegrity(integrity *Integrity) {
+	p.integrity = integrity
+}
+
+// Workers returns the worker pool, or nil if the plugin runs one process per
+// call.
+func (p *processPlugin) Workers() *WorkerPool {
+	return p.pool
+}
+
+// Close stops the plugin's worker processes.
+func (p *processPlugin) Close() error {
+	if p.pool == nil {
+		return nil
+	}
+	return p.pool.Close()
+}
+
+// InputFormats returns the input formats declared in the manifest; nil means
+// the plugin accepts any format.
+func (p *processPlugin) InputFormats() []string {
+	if p.manifest == nil {
+		return nil
+	}
+	return p.manifest.InputFormats
+}
+
+func (p *processPlugin) Generate(input string) (string, error) {
+	return p.GenerateContext(context.Background(), input)
+}
+
+// GenerateContext is Generate bounded by ctx.
+func (p *processPlugin) GenerateContext(ctx context.Context, input string) (string, error) {
+	response, err := p.Invoke(ctx, &Request{Input: input, Format: "json"})
+	if err != nil {
+		return "", err
+	}
+	return response.Output(), nil
+}
+
+// withOptions validates options for a copy of the plugin.
+func (p *processPlugin) withOptions(options map[string]string) error {
+	if err := ValidateOptions(p.name, p.manifest, options); err != nil {
+		return err
+	}
+	p.options = options
+	return nil
+}
+
+// interpreter returns the command that runs the entrypoint, or nil if the
+// entrypoint is executed directly.
+func (p *processPlugin) interpreter(ctx context.Context) ([]string, error) {
+	if p.manifest != nil && len(p.manifest.Interpreter) > 0 {
+		return p.manifest.Interpreter, nil
+	}
+	if p.language == LanguageExec {
+		return DefaultInterpreters[strings.ToLower(filepath.Ext(p.entrypoint))], nil
+	}
+	// The environment is normally created on plugin add; this recreates it
+	// if the cache was cleaned since.
+	venv, err := EnsureVenv(ctx, p.manifest)
+	if err != nil {
+		return nil, err
+	}
+	if venv != "" {
+		return []string{venv}, nil
+	}
+	python, err := pythonExecutable()
+	if err != nil {
+		return nil, err
+	}
+	return []string{python}, nil
+}
+
+// command builds the command that runs the plugin with extra arguments.
+func (p *processPlugin) command(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	interpreter, err := p.interpreter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if len(interpreter) == 0 {
+		cmd = exec.CommandContext(ctx, p.entrypoint, args...)
+	} else {
+		argv := append(append(append([]string{}, interpreter[1:]...), p.entrypoint), args...)
+		cmd = exec.CommandContext(ctx, interpreter[0], argv...)
+	}
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	// Without the SDK only plugins importing devtoolbox_sdk fail, with an
+	// ImportError on stderr. Bytecode caches would add files to the plugin
+	// directory and fail its integrity check.
+	if p.language == LanguagePython {
+		cmd.Env = append(os.Environ(), "PYTHONDONTWRITEBYTECODE=1")
+		if path, err := pythonPath(); err == nil {
+			cmd.Env = append(cmd.Env, "PYTHONPATH="+path)
+		}
+	}
+	return cmd, nil
+}
+
+// isolate moves cmd into the sandbox if the plugin must be sandboxed; the
+// sandbox then applies the rlimits itself. Where the sandbox is unavailable
+// only untrusted plugins fail; the others run without isolation.
+func (p *processPlugin) isolate(cmd *exec.Cmd, limits Limits) error {
+	if !Sandboxed(p.trust, p.manifest) {
+		return nil
+	}
+
+	dirs := sandboxDirs(p.entrypoint, p.manifest)
+	var err error
+	if p.language == LanguagePython {
+		err = sandboxPython(cmd, dirs, limits)
+	} else {
+		err = sandbox(cmd, append(dirs, executableDir(cmd.Path)), limits)
+	}
+	if errors.Is(err, errSandboxUnsupported) && p.trust != TrustUntrusted {
+		return nil
+	}
+	return err
+}
+
+// Invoke runs the plugin once, or hands the request to a worker in worker
+// mode. In the text protocol only request.Input is passed to the plugin.
+// The call is bounded by ctx and by the manifest limits; on cancellation the
+// whole process group is killed.
+func (p *processPlugin) Invoke(ctx context.Context, request *Request) (*Response, error) {
+	if err := p.integrity.Verify(); err != nil {
+		return nil, &IntegrityError{Plugin: p.name, Err: err}
+	}
+
+	limits := pluginLimits(p.manifest)
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, limits.Timeout)
+	defer cancel()
+
+	if p.pool != nil {
+		message, err := p.buildRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.pool.Call(ctx, "generate", message)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, contextError(parent, p.name, limits.Timeout)
+			}
+			return nil, err
+		}
+		return decodeResponse(p.name, result)
+	}
+
+	protocol := Protocol(p)
+	stdin := request.Input
+	if protocol == ProtocolJSON {
+		var err error
+		stdin, err = p.encodeRequest(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cmd, err := p.command(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, contextError(parent, p.name, limits.Timeout)
+		}
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	if err := p.isolate(cmd, limits); err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	prepareCommand(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	// Do not wait for grandchildren that keep stdout open after a kill.
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = strings.NewReader(stdin)
+
+	stdout := &cappedBuffer{limit: limits.MaxOutput, onExceed: cancel}
+	stderr := &limitedBuffer{limit: workerStderrLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		// Start fails without running anything once ctx is done, which
+		// EnsureVenv can take long enough for.
+		if ctx.Err() != nil {
+			return nil, contextError(parent, p.name, limits.Timeout)
+		}
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	if !isSandboxed(cmd) {
+		if err := applyLimits(cmd.Process.Pid, limits); err != nil {
+			killProcessGroup(cmd)
+			cmd.Wait()
+			return nil, &ExecError{Plugin: p.name, Err: err}
+		}
+	}
+
+	err = cmd.Wait()
+	if stdout.exceeded {
+		return nil, &OutputLimitError{Plugin: p.name, Limit: limits.MaxOutput}
+	}
+	if ctx.Err() != nil {
+		return nil, contextError(parent, p.name, limits.Timeout)
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, &ExecError{Plugin: p.name, Err: err}
+		}
+		if limitErr := resourceLimitError(p.name, exitErr.ProcessState, stderr.String(), limits); limitErr != nil {
+			return nil, limitErr
+		}
+		return nil, exitError(p.name, protocol, exitErr.ExitCode(), stdout.Bytes(), stderr.String())
+	}
+
+	return parseResponse(p.name, protocol, stdout.Bytes(), stderr.String())
+}
+
+func (p *processPlugin) buildRequest(request *Request) (*Request, error) {
+	return buildRequest(p.name, p.manifest, p.options, request)
+}
+
+func (p *processPlugin) encodeRequest(request *Request) (string, error) {
+	return encodeRequest(p.name, p.manifest, p.options, request)
+}
+
+// executableDir returns the directory holding the real file behind path, so
+// that interpreters installed outside the system directories stay reachable
+// in the sandbox.
+func executableDir(path string) string {
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	return filepath.Dir(path)
+}
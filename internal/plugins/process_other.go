@@ -0,0 +1,22 @@
+//go:build !unix
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+)
+
+func prepareCommand(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// isExecutable accepts any file: Windows has no executable bit.
+func isExecutable(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// prepareCommand starts the plugin in its own process group, so that
+// killProcessGroup also stops any processes the plugin spawned.
+func prepareCommand(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode()&0o111 != 0
+}
@@ -0,0 +1,219 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/version"
+)
+
+// ProtocolVersion is the version of the JSON protocol spoken with plugins
+// whose manifest declares "protocol: json".
+const ProtocolVersion = 1
+
+const (
+	// ProtocolText is the compatibility mode: the JSON sample is written to
+	// stdin and stdout is the generated code. Output starting with "Error:"
+	// is treated as a failure.
+	ProtocolText = "text"
+	// ProtocolJSON exchanges a Request and a Response as JSON documents.
+	ProtocolJSON = "json"
+)
+
+const (
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+type RequestContext struct {
+	Generator         string `json:"generator"`
+	DevToolBoxVersion string `json:"devtoolbox_version"`
+	OutputExtension   string `json:"output_extension,omitempty"`
+}
+
+type Request struct {
+	Version int               `json:"version"`
+	Input   string            `json:"input"`
+	Format  string            `json:"format,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	IR      *ir.Document      `json:"ir,omitempty"`
+	Context RequestContext    `json:"context"`
+}
+
+type File struct {
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+type Location struct {
+	Path   string `json:"path,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (l *Location) String() string {
+	if l == nil {
+		return ""
+	}
+	parts := []string{}
+	if l.Path != "" {
+		parts = append(parts, l.Path)
+	}
+	if l.Line > 0 {
+		parts = append(parts, fmt.Sprint(l.Line))
+		if l.Column > 0 {
+			parts = append(parts, fmt.Sprint(l.Column))
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+type Diagnostic struct {
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	Location *Location `json:"location,omitempty"`
+}
+
+func (d Diagnostic) String() string {
+	if location := d.Location.String(); location != "" {
+		return fmt.Sprintf("%s: %s: %s", d.Severity, location, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+type ResponseError struct {
+	Code     ErrorCode `json:"code"`
+	Message  string    `json:"message"`
+	Location *Location `json:"location,omitempty"`
+}
+
+type Response struct {
+	Version     int            `json:"version"`
+	Files       []File         `json:"files,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	Error       *ResponseError `json:"error,omitempty"`
+}
+
+// Output joins the contents of all returned files.
+func (r *Response) Output() string {
+	contents := make([]string, 0, len(r.Files))
+	for _, file := range r.Files {
+		contents = append(contents, file.Content)
+	}
+	return strings.Join(contents, "\n\n")
+}
+
+func newRequestContext(name string, manifest *Manifest) RequestContext {
+	context := RequestContext{
+		Generator:         name,
+		DevToolBoxVersion: version.Version,
+	}
+	if manifest != nil {
+		context.OutputExtension = manifest.OutputExtension
+	}
+	return context
+}
+
+// buildRequest fills in the protocol version, the request context and the
+// options: those of the request, else the plugin defaults, completed with the
+// manifest defaults.
+func buildRequest(name string, manifest *Manifest, defaults map[string]string, request *Request) (*Request, error) {
+	options := request.Options
+	if options == nil {
+		options = defaults
+	}
+	options, err := optionsWithDefaults(name, manifest, options)
+	if err != nil {
+		return nil, err
+	}
+
+	message := *request
+	message.Version = ProtocolVersion
+	message.Options = options
+	message.Context = newRequestContext(name, manifest)
+	return &message, nil
+}
+
+func encodeRequest(name string, manifest *Manifest, defaults map[string]string, request *Request) (string, error) {
+	message, err := buildRequest(name, manifest, defaults, request)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseResponse reads the output of a plugin that exited successfully.
+func parseResponse(plugin, protocol string, stdout []byte, stderr string) (*Response, error) {
+	if protocol == ProtocolJSON {
+		return decodeResponse(plugin, stdout)
+	}
+
+	result := strings.TrimSpace(string(stdout))
+	if strings.HasPrefix(result, "Error:") {
+		return nil, &PluginError{
+			Plugin:  plugin,
+			Code:    CodeInternal,
+			Message: strings.TrimSpace(strings.TrimPrefix(result, "Error:")),
+			Stderr:  stderr,
+		}
+	}
+
+	return &Response{Version: ProtocolVersion, Files: []File{{Content: result}}}, nil
+}
+
+// exitError describes a plugin that exited with a non-zero code, preferring
+// the error reported in a JSON protocol response.
+func exitError(plugin, protocol string, exitCode int, stdout []byte, stderr string) error {
+	if protocol == ProtocolJSON {
+		var pluginErr *PluginError
+		if _, decodeErr := decodeResponse(plugin, stdout); errors.As(decodeErr, &pluginErr) {
+			pluginErr.ExitCode = exitCode
+			pluginErr.Stderr = stderr
+			return pluginErr
+		}
+	}
+	return &PluginError{
+		Plugin:   plugin,
+		Code:     exitCodeError(exitCode),
+		ExitCode: exitCode,
+		Stderr:   stderr,
+	}
+}
+
+// decodeResponse parses a JSON protocol response and turns a reported error
+// into a *PluginError.
+func decodeResponse(plugin string, stdout []byte) (*Response, error) {
+	var response Response
+	if err := json.Unmarshal(stdout, &response); err != nil {
+		return nil, &ProtocolError{Plugin: plugin, Message: fmt.Sprintf("invalid response: %v", err)}
+	}
+	if response.Version != ProtocolVersion {
+		return nil, &ProtocolError{Plugin: plugin, Message: fmt.Sprintf("unsupported protocol version %d, expected %d", response.Version, ProtocolVersion)}
+	}
+
+	if response.Error != nil {
+		code := response.Error.Code
+		if code == "" {
+			code = CodeInternal
+		}
+		return nil, &PluginError{
+			Plugin:   plugin,
+			Code:     code,
+			Message:  response.Error.Message,
+			Location: response.Error.Location,
+		}
+	}
+
+	if len(response.Files) == 0 {
+		return nil, &ProtocolError{Plugin: plugin, Message: "response contains neither files nor an error"}
+	}
+	return &response, nil
+}
@@ -0,0 +1,94 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/JIIL07/devtoolbox/internal/version"
+	"github.com/JIIL07/devtoolbox/pkg/sdk/python"
+)
+
+var (
+	pythonSDKMu  sync.Mutex
+	pythonSDKDir string
+)
+
+// PythonSDKPath extracts the embedded devtoolbox_sdk package into the user
+// cache directory and returns the directory to put on PYTHONPATH. It is
+// extracted once per process, and again if the cache was cleaned or moved
+// since. Files are rewritten only when they differ from the embedded ones, so
+// concurrent DevToolBox processes can share the directory.
+func PythonSDKPath() (string, error) {
+	pythonSDKMu.Lock()
+	defer pythonSDKMu.Unlock()
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory for the python SDK: %w", err)
+	}
+	dir := filepath.Join(cacheDir, "devtoolbox", "python-sdk", version.Version)
+	if dir == pythonSDKDir {
+		if _, err := os.Stat(filepath.Join(dir, python.Package)); err == nil {
+			return dir, nil
+		}
+	}
+
+	err = fs.WalkDir(python.FS, python.Package, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		return extractFile(python.FS, path, filepath.Join(dir, filepath.FromSlash(path)))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract python SDK: %w", err)
+	}
+	pythonSDKDir = dir
+	return dir, nil
+}
+
+func extractFile(fsys fs.FS, name, target string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".sdk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// pythonPath returns PYTHONPATH for Python plugins: the SDK first, then the
+// user's own entries.
+func pythonPath() (string, error) {
+	dir, err := PythonSDKPath()
+	if err != nil {
+		return "", err
+	}
+	if existing := os.Getenv("PYTHONPATH"); existing != "" {
+		return dir + string(os.PathListSeparator) + existing, nil
+	}
+	return dir, nil
+}
@@ -1,65 +1,50 @@
 package plugins
 
 import (
-	"bytes"
 	"fmt"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
+// PythonPlugin runs a Python script with the shared interpreter, the one set
+// in the manifest, or a virtual environment with the manifest requirements.
 type PythonPlugin struct {
-	name        string
-	description string
-	scriptPath  string
+	processPlugin
+}
+
+// ManifestProvider is implemented by generators described by a plugin manifest.
+type ManifestProvider interface {
+	Manifest() *Manifest
 }
 
 func NewPythonPlugin(name, description, scriptPath string) *PythonPlugin {
-	return &PythonPlugin{
+	return &PythonPlugin{processPlugin{
+		language:    LanguagePython,
 		name:        name,
 		description: description,
-		scriptPath:  scriptPath,
-	}
+		entrypoint:  scriptPath,
+		trust:       TrustTrusted,
+	}}
 }
 
-func (p *PythonPlugin) GetName() string {
-	return p.name
-}
-
-func (p *PythonPlugin) GetDescription() string {
-	return p.description
+// WithOptions returns a copy of the plugin that sends the given options with
+// every request.
+func (p *PythonPlugin) WithOptions(options map[string]string) (Plugin, error) {
+	configured := *p
+	if err := configured.withOptions(options); err != nil {
+		return nil, err
+	}
+	return &configured, nil
 }
 
-func (p *PythonPlugin) Generate(input string) (string, error) {
-	var cmd *exec.Cmd
-	
-	if _, err := exec.LookPath("/opt/venv/bin/python"); err == nil {
-		cmd = exec.Command("/opt/venv/bin/python", p.scriptPath)
-	} else if _, err := exec.LookPath("python3"); err == nil {
-		cmd = exec.Command("python3", p.scriptPath)
-	} else if _, err := exec.LookPath("python"); err == nil {
-		cmd = exec.Command("python", p.scriptPath)
-	} else {
-		return "", fmt.Errorf("python executable not found in PATH")
-	}
-	
-	cmd.Stdin = strings.NewReader(input)
-	
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("python plugin execution failed: %w, stderr: %s", err, stderr.String())
-	}
-	
-	result := strings.TrimSpace(stdout.String())
-	if strings.HasPrefix(result, "Error:") {
-		return "", fmt.Errorf("python plugin error: %s", result)
+func pythonExecutable() (string, error) {
+	for _, candidate := range []string{"/opt/venv/bin/python", "python3", "python"} {
+		if _, err := exec.LookPath(candidate); err == nil {
+			return candidate, nil
+		}
 	}
-	
-	return result, nil
+	return "", fmt.Errorf("python executable not found in PATH")
 }
 
 type PythonPluginLoader struct {
@@ -78,27 +63,49 @@ func (l *PythonPluginLoader) LoadPlugin(scriptPath string) (*PythonPlugin, error
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 	
+	manifest, err := FindManifest(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		if manifest.Language != LanguagePython {
+			return nil, fmt.Errorf("%s is a %s plugin, not a python plugin", manifest.Name, manifest.Language)
+		}
+		return NewPythonPluginFromManifest(manifest), nil
+	}
+	
 	pluginName := strings.TrimSuffix(filepath.Base(scriptPath), ".py")
 	description := fmt.Sprintf("Python plugin: %s", pluginName)
 	
 	return NewPythonPlugin(pluginName, description, absPath), nil
 }
 
-func (l *PythonPluginLoader) LoadOfficialPlugins() ([]*PythonPlugin, error) {
-	officialDir := filepath.Join(l.pluginsDir, "official")
-	pattern := filepath.Join(officialDir, "*.py")
-	
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find python plugins: %w", err)
+func NewPythonPluginFromManifest(manifest *Manifest) *PythonPlugin {
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("Python plugin: %s", manifest.Name)
 	}
 	
-	var plugins []*PythonPlugin
-	for _, match := range matches {
-		plugin, err := l.LoadPlugin(match)
+	plugin := NewPythonPlugin(manifest.Name, description, manifest.EntrypointPath())
+	plugin.SetManifest(manifest)
+	return plugin
+}
+
+// LoadOfficialPlugins loads the plugins in plugins/official, found as in the
+// system search path: Python scripts and plugins of any language described by
+// manifests. Plugins with invalid manifests are skipped.
+func (l *PythonPluginLoader) LoadOfficialPlugins() ([]ExternalPlugin, error) {
+	officialDir := filepath.Join(l.pluginsDir, "official")
+	infos, _ := scanSearchPath(SearchPath{Scope: ScopeSystem, Dir: officialDir})
+	
+	var plugins []ExternalPlugin
+	for _, info := range infos {
+		plugin, err := NewExternalPlugin(info.Type, info.Name, info.Description, info.Path)
 		if err != nil {
 			continue
 		}
+		plugin.SetManifest(info.Manifest)
+		plugin.SetTrust(TrustOfficial)
 		plugins = append(plugins, plugin)
 	}
 	
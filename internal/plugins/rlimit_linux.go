@@ -0,0 +1,49 @@
+package plugins
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type rlimit struct {
+	name     string
+	resource int
+	value    unix.Rlimit
+}
+
+func rlimits(limits Limits) []rlimit {
+	var result []rlimit
+	if limits.CPU > 0 {
+		seconds := uint64((limits.CPU + time.Second - 1) / time.Second)
+		// The soft limit sends SIGXCPU, the hard limit one second later SIGKILL.
+		result = append(result, rlimit{"CPU", unix.RLIMIT_CPU, unix.Rlimit{Cur: seconds, Max: seconds + 1}})
+	}
+	if limits.Memory > 0 {
+		memory := uint64(limits.Memory)
+		result = append(result, rlimit{"memory", unix.RLIMIT_AS, unix.Rlimit{Cur: memory, Max: memory}})
+	}
+	return result
+}
+
+// applyLimits sets CPU and address space rlimits on a started process.
+func applyLimits(pid int, limits Limits) error {
+	for _, limit := range rlimits(limits) {
+		if err := unix.Prlimit(pid, limit.resource, &limit.value, nil); err != nil {
+			return fmt.Errorf("failed to set %s limit: %w", limit.name, err)
+		}
+	}
+	return nil
+}
+
+// setLimits sets the rlimits of the current process; the sandbox helper calls
+// it right before executing the plugin.
+func setLimits(limits Limits) error {
+	for _, limit := range rlimits(limits) {
+		if err := unix.Setrlimit(limit.resource, &limit.value); err != nil {
+			return fmt.Errorf("failed to set %s limit: %w", limit.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package plugins
+
+// applyLimits is a no-op outside Linux: CPU and memory limits are not
+// enforced there, only the timeout and output size.
+func applyLimits(pid int, limits Limits) error {
+	return nil
+}
+
+func setLimits(limits Limits) error {
+	return nil
+}
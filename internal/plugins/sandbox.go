@@ -0,0 +1,127 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Trust says how much a plugin is trusted and therefore whether it may run
+// outside the sandbox.
+type Trust string
+
+const (
+	// TrustOfficial plugins ship with DevToolBox. They run sandboxed unless
+	// their manifest opts out with "sandbox: none".
+	TrustOfficial Trust = "official"
+	// TrustTrusted plugins were added with --trust trusted and follow their
+	// manifest like official ones.
+	TrustTrusted Trust = "trusted"
+	// TrustUntrusted plugins always run sandboxed. This is the default for
+	// plugins added with devtoolbox plugin add.
+	TrustUntrusted Trust = "untrusted"
+)
+
+const (
+	SandboxStrict = "strict"
+	SandboxNone   = "none"
+)
+
+// sandboxHelperArg makes the devtoolbox binary act as the sandbox helper.
+const sandboxHelperArg = "__devtoolbox_sandbox"
+
+var errSandboxUnsupported = errors.New("sandboxing is only supported on Linux with user namespaces")
+
+func ParseTrust(value string) (Trust, error) {
+	switch trust := Trust(strings.ToLower(value)); trust {
+	case TrustOfficial, TrustTrusted, TrustUntrusted:
+		return trust, nil
+	case "":
+		return TrustUntrusted, nil
+	default:
+		return "", fmt.Errorf("unknown trust level %q, expected %s or %s", value, TrustTrusted, TrustUntrusted)
+	}
+}
+
+// Sandboxed reports whether a plugin with the given trust level and manifest
+// runs in the sandbox.
+func Sandboxed(trust Trust, manifest *Manifest) bool {
+	if trust == TrustUntrusted {
+		return true
+	}
+	return manifest == nil || manifest.Sandbox != SandboxNone
+}
+
+// SandboxSupported reports whether plugins can be sandboxed on this system.
+func SandboxSupported() bool {
+	return sandboxSupported() == nil
+}
+
+// isSandboxed reports whether cmd starts through the sandbox helper.
+func isSandboxed(cmd *exec.Cmd) bool {
+	return len(cmd.Args) == 2 && cmd.Args[1] == sandboxHelperArg
+}
+
+// sandboxDirs lists the plugin files a sandboxed plugin may read.
+func sandboxDirs(scriptPath string, manifest *Manifest) []string {
+	dirs := []string{filepath.Dir(scriptPath)}
+	if manifest != nil && manifest.Path != "" && filepath.Dir(manifest.Path) != dirs[0] {
+		dirs = append(dirs, filepath.Dir(manifest.Path))
+	}
+	return dirs
+}
+
+type pythonRuntime struct {
+	executable string
+	prefixes   []string
+}
+
+var (
+	runtimeMu    sync.Mutex
+	runtimeCache = map[string]pythonRuntime{}
+)
+
+// resolvePython finds the real interpreter behind shims such as pyenv, so the
+// sandbox can expose only the interpreter and its standard library.
+func resolvePython(python string) (pythonRuntime, error) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	if runtime, ok := runtimeCache[python]; ok {
+		return runtime, nil
+	}
+
+	output, err := exec.Command(python, "-c", "import sys; print(sys.executable); print(sys.prefix); print(sys.base_prefix)").Output()
+	if err != nil {
+		return pythonRuntime{}, fmt.Errorf("failed to locate python interpreter: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 3 || !filepath.IsAbs(lines[0]) {
+		return pythonRuntime{}, fmt.Errorf("failed to locate python interpreter: unexpected output %q", output)
+	}
+	runtime := pythonRuntime{executable: lines[0], prefixes: []string{lines[1]}}
+	if lines[2] != lines[1] {
+		runtime.prefixes = append(runtime.prefixes, lines[2])
+	}
+	runtimeCache[python] = runtime
+	return runtime, nil
+}
+
+// sandboxPython rewrites a python command to run in the sandbox. The Python
+// SDK is exposed read-only and is the only PYTHONPATH entry.
+func sandboxPython(cmd *exec.Cmd, dirs []string, limits Limits) error {
+	runtime, err := resolvePython(cmd.Path)
+	if err != nil {
+		return err
+	}
+	cmd.Path = runtime.executable
+	cmd.Args[0] = runtime.executable
+	cmd.Env = nil
+	if sdk, err := PythonSDKPath(); err == nil {
+		dirs = append(dirs, sdk)
+		cmd.Env = []string{"PYTHONPATH=" + sdk}
+	}
+	return sandbox(cmd, append(dirs, runtime.prefixes...), limits)
+}
@@ -0,0 +1,269 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	sandboxConfigEnv = "DEVTOOLBOX_SANDBOX_CONFIG"
+	sandboxTmpSize   = "size=64m"
+)
+
+// sandboxSystemPaths are exposed read-only so that interpreters and shared
+// libraries work. Home directories, /var, /run and the rest stay hidden.
+var sandboxSystemPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/opt"}
+
+// sandboxEnv replaces the environment of sandboxed plugins.
+var sandboxEnv = []string{
+	"PATH=/usr/local/bin:/usr/bin:/bin",
+	"HOME=/work",
+	"TMPDIR=/tmp",
+	"LANG=C.UTF-8",
+	"PYTHONDONTWRITEBYTECODE=1",
+}
+
+type sandboxConfig struct {
+	Path     string   `json:"path"`
+	Args     []string `json:"args"`
+	Env      []string `json:"env,omitempty"`
+	ReadOnly []string `json:"read_only"`
+	Root     string   `json:"root"`
+	Limits   Limits   `json:"limits"`
+}
+
+// sandbox makes cmd start through the sandbox helper in new user, mount,
+// network, PID, IPC and UTS namespaces. The helper builds a read-only view
+// of the system and the plugin directories, with a private writable /tmp and
+// working directory /work, and then executes the original command with the
+// CPU and memory limits applied. Only variables set in cmd.Env are added to
+// the fixed sandbox environment; the caller's environment is not inherited.
+func sandbox(cmd *exec.Cmd, readOnly []string, limits Limits) error {
+	if err := sandboxSupported(); err != nil {
+		return err
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate sandbox helper: %w", err)
+	}
+
+	config, err := json.Marshal(sandboxConfig{
+		Path:     cmd.Path,
+		Args:     cmd.Args,
+		Env:      cmd.Env,
+		ReadOnly: append(append([]string{}, sandboxSystemPaths...), readOnly...),
+		Root:     filepath.Join(os.TempDir(), "devtoolbox-sandbox"),
+		Limits:   limits,
+	})
+	if err != nil {
+		return err
+	}
+
+	cmd.Path = self
+	cmd.Args = []string{self, sandboxHelperArg}
+	cmd.Env = []string{sandboxConfigEnv + "=" + string(config)}
+	cmd.Dir = ""
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	applyNamespaces(cmd.SysProcAttr)
+	return nil
+}
+
+func applyNamespaces(attr *syscall.SysProcAttr) {
+	attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET |
+		syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+}
+
+var (
+	supportOnce sync.Once
+	supportErr  error
+)
+
+// sandboxSupported checks once that namespaces can be created.
+func sandboxSupported() error {
+	supportOnce.Do(func() {
+		cmd := exec.Command("true")
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+		applyNamespaces(cmd.SysProcAttr)
+		if err := cmd.Run(); err != nil {
+			supportErr = fmt.Errorf("%w: %v", errSandboxUnsupported, err)
+		}
+	})
+	return supportErr
+}
+
+// RunSandboxHelper turns the process into the sandbox helper when it was
+// started by sandbox. It must be called at the start of main, before anything
+// else runs, and never returns in that case.
+func RunSandboxHelper() {
+	if len(os.Args) != 2 || os.Args[1] != sandboxHelperArg {
+		return
+	}
+
+	var config sandboxConfig
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxConfigEnv)), &config); err != nil {
+		sandboxFail(fmt.Errorf("invalid sandbox config: %w", err))
+	}
+	if err := enterSandbox(config); err != nil {
+		sandboxFail(err)
+	}
+	if err := setLimits(config.Limits); err != nil {
+		sandboxFail(err)
+	}
+	sandboxFail(syscall.Exec(config.Path, config.Args, append(append([]string{}, sandboxEnv...), config.Env...)))
+}
+
+func sandboxFail(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(125)
+}
+
+func enterSandbox(config sandboxConfig) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	root := config.Root
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=1m,mode=755"); err != nil {
+		return fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+
+	for _, dir := range []string{"tmp", "work"} {
+		target := filepath.Join(root, dir)
+		if err := os.Mkdir(target, 0o777); err != nil {
+			return err
+		}
+		if err := unix.Mount("tmpfs", target, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, sandboxTmpSize+",mode=1777"); err != nil {
+			return fmt.Errorf("failed to mount /%s: %w", dir, err)
+		}
+	}
+
+	for _, path := range config.ReadOnly {
+		if err := bindReadOnly(root, path); err != nil {
+			return err
+		}
+	}
+
+	if err := bindDevices(root); err != nil {
+		return err
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0o700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("failed to switch root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach host filesystem: %w", err)
+	}
+	if err := os.Remove("/.oldroot"); err != nil {
+		return err
+	}
+	if err := unix.Mount("tmpfs", "/", "tmpfs", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to make sandbox root read-only: %w", err)
+	}
+	return os.Chdir("/work")
+}
+
+// bindReadOnly exposes path at the same location inside root. Symlinks such
+// as /bin -> usr/bin are recreated instead of followed.
+func bindReadOnly(root, path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, path)
+	if _, err := os.Lstat(target); err == nil {
+		// Already visible through a parent directory.
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	if info.IsDir() {
+		err = os.Mkdir(target, 0o755)
+	} else {
+		err = os.WriteFile(target, nil, 0o644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to expose %s: %w", path, err)
+	}
+	// Flags locked by the outer namespace must be kept when remounting.
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV)
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err == nil {
+		flags |= lockedMountFlags(stat.Flags)
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make %s read-only: %w", path, err)
+	}
+	return nil
+}
+
+func lockedMountFlags(statFlags int64) uintptr {
+	var flags uintptr
+	for _, pair := range [][2]uintptr{
+		{unix.ST_NOEXEC, unix.MS_NOEXEC},
+		{unix.ST_NOATIME, unix.MS_NOATIME},
+		{unix.ST_NODIRATIME, unix.MS_NODIRATIME},
+		{unix.ST_RELATIME, unix.MS_RELATIME},
+	} {
+		if uintptr(statFlags)&pair[0] != 0 {
+			flags |= pair[1]
+		}
+	}
+	return flags
+}
+
+func bindDevices(root string) error {
+	if err := os.Mkdir(filepath.Join(root, "dev"), 0o755); err != nil {
+		return err
+	}
+	for _, device := range []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"} {
+		target := filepath.Join(root, device)
+		if err := os.WriteFile(target, nil, 0o666); err != nil {
+			return err
+		}
+		if err := unix.Mount(device, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("failed to expose %s: %w", device, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package plugins
+
+import "os/exec"
+
+func sandbox(cmd *exec.Cmd, readOnly []string, limits Limits) error {
+	return errSandboxUnsupported
+}
+
+func sandboxSupported() error {
+	return errSandboxUnsupported
+}
+
+// RunSandboxHelper does nothing outside Linux.
+func RunSandboxHelper() {}
@@ -0,0 +1,136 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JSON-RPC error codes used by ServeWorker.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+)
+
+// Handler is the plugin side of the JSON protocol: it answers one request.
+// Returning a *PluginError reports its code, message and location to
+// DevToolBox; any other error is reported as an internal error.
+type Handler func(ctx context.Context, request *Request) (*Response, error)
+
+// ServeOnce answers a single request read from r, as a plugin started once
+// per call does.
+func ServeOnce(ctx context.Context, handler Handler, r io.Reader, w io.Writer) error {
+	var request Request
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return writeJSON(w, errorResponse(&PluginError{Code: CodeInvalidInput, Message: fmt.Sprintf("invalid request: %v", err)}))
+	}
+	return writeJSON(w, handle(ctx, handler, &request))
+}
+
+// ServeWorker answers line-delimited JSON-RPC 2.0 requests from r until a
+// shutdown notification or EOF, as a plugin started with --worker does.
+func ServeWorker(ctx context.Context, handler Handler, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var message struct {
+			ID     *int64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			if err := writeJSON(w, rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if message.Method == "shutdown" {
+			return nil
+		}
+		if message.ID == nil {
+			continue
+		}
+
+		reply := rpcResponse{JSONRPC: "2.0", ID: *message.ID}
+		var result interface{}
+		switch message.Method {
+		case "generate":
+			var request Request
+			if err := json.Unmarshal(message.Params, &request); err != nil {
+				result = errorResponse(&PluginError{Code: CodeInvalidInput, Message: fmt.Sprintf("invalid request: %v", err)})
+			} else {
+				result = handle(ctx, handler, &request)
+			}
+		case "ping":
+			result = map[string]string{"status": "ok"}
+		default:
+			reply.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method not found: %s", message.Method)}
+		}
+
+		if result != nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			reply.Result = data
+		}
+		if err := writeJSON(w, reply); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func handle(ctx context.Context, handler Handler, request *Request) (response *Response) {
+	if request.Version != ProtocolVersion {
+		return errorResponse(&PluginError{Code: CodeUnsupported, Message: fmt.Sprintf("unsupported protocol version %d, expected %d", request.Version, ProtocolVersion)})
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			response = errorResponse(fmt.Errorf("panic: %v", recovered))
+		}
+	}()
+
+	response, err := handler(ctx, request)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if response == nil {
+		return errorResponse(errors.New("handler returned no response"))
+	}
+	response.Version = ProtocolVersion
+	return response
+}
+
+func errorResponse(err error) *Response {
+	var pluginErr *PluginError
+	if !errors.As(err, &pluginErr) {
+		pluginErr = &PluginError{Code: CodeInternal, Message: err.Error()}
+	}
+	code := pluginErr.Code
+	if code == "" {
+		code = CodeInternal
+	}
+	return &Response{
+		Version: ProtocolVersion,
+		Error:   &ResponseError{Code: code, Message: pluginErr.Message, Location: pluginErr.Location},
+	}
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,274 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of sources plugins are installed from.
+const (
+	SourceGit     = "git"
+	SourceArchive = "archive"
+	SourceIndex   = "index"
+	SourcePath    = "path"
+)
+
+// IndexEnv names the plugin index used when plugin install gets no --index.
+// Without it ~/.devtoolbox/index.yaml is used if it exists.
+const IndexEnv = "DEVTOOLBOX_PLUGIN_INDEX"
+
+// maxDownloadSize limits archives and indexes fetched over HTTP.
+const maxDownloadSize = 256 << 20
+
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// Source records where an installed plugin came from, so that plugin update
+// can fetch it again.
+type Source struct {
+	Kind string `json:"kind"`
+	// Location is a git URL, an archive path or URL, a local directory, or
+	// the plugin name for index sources.
+	Location string `json:"location"`
+	// Ref pins a git ref or an index version. Without it update follows the
+	// default branch or the latest indexed version.
+	Ref    string `json:"ref,omitempty"`
+	Subdir string `json:"subdir,omitempty"`
+	Index  string `json:"index,omitempty"`
+	// Resolved is what was actually installed: the git commit or, for index
+	// sources, the source of the chosen version.
+	Resolved string `json:"resolved,omitempty"`
+}
+
+// ParseSource recognizes git URLs (git+<url>, *.git, git@host:path), archives
+// (.tar.gz, .tgz, .tar, .zip; local or http), local directories and plugin
+// names looked up in an index. Git and index sources may be pinned with
+// @<ref> or @<version>.
+func ParseSource(spec string) (*Source, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("empty plugin source")
+	}
+	if _, err := os.Stat(spec); err == nil {
+		return localSource(spec)
+	}
+
+	location, ref := spec, ""
+	if i := strings.LastIndex(spec, "@"); i > 0 && !strings.ContainsAny(spec[i+1:], "/:") {
+		location, ref = spec[:i], spec[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(location, "git+"):
+		return &Source{Kind: SourceGit, Location: strings.TrimPrefix(location, "git+"), Ref: ref}, nil
+	case strings.HasSuffix(location, ".git") || strings.HasPrefix(location, "git@"):
+		return &Source{Kind: SourceGit, Location: location, Ref: ref}, nil
+	case isArchive(spec):
+		return &Source{Kind: SourceArchive, Location: spec}, nil
+	case pluginNamePattern.MatchString(location):
+		return &Source{Kind: SourceIndex, Location: location, Ref: ref}, nil
+	}
+	return nil, fmt.Errorf("unrecognized plugin source %q: expected a git URL, an archive, a directory or a plugin name", spec)
+}
+
+func localSource(path string) (*Source, error) {
+	if isArchive(path) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		return &Source{Kind: SourceArchive, Location: absPath}, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if !containsString(ManifestNames, filepath.Base(path)) {
+			return nil, fmt.Errorf("%s is not a plugin directory or manifest; use plugin add for single scripts", path)
+		}
+		path = filepath.Dir(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{Kind: SourcePath, Location: absPath}, nil
+}
+
+func isArchive(location string) bool {
+	lower := strings.ToLower(location)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func (s *Source) String() string {
+	var result string
+	switch s.Kind {
+	case SourceGit:
+		result = "git+" + s.Location
+	case SourceIndex:
+		result = s.Location
+	default:
+		return s.Location
+	}
+	if s.Ref != "" {
+		result += "@" + s.Ref
+	}
+	return result
+}
+
+// Index lists plugins available for installation by name, for example a file
+// shared in a team repository or served over HTTP:
+//
+//	plugins:
+//	  - name: kotlin-data
+//	    version: 1.2.0
+//	    source: https://example.com/kotlin-data-1.2.0.tar.gz
+//
+// Relative sources are resolved against the index location.
+type Index struct {
+	Plugins []IndexEntry `json:"plugins" yaml:"plugins"`
+
+	location string
+}
+
+type IndexEntry struct {
+	Name        string `json:"name" yaml:"name"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Source      string `json:"source" yaml:"source"`
+	Subdir      string `json:"subdir,omitempty" yaml:"subdir,omitempty"`
+}
+
+// DefaultIndex returns the index from DEVTOOLBOX_PLUGIN_INDEX or
+// ~/.devtoolbox/index.yaml, or "" if there is none.
+func DefaultIndex() string {
+	if index := os.Getenv(IndexEnv); index != "" {
+		return index
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(homeDir, ".devtoolbox", "index.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// LoadIndex reads an index from a file or an http(s) URL, in YAML or JSON.
+func LoadIndex(location string) (*Index, error) {
+	var data []byte
+	var err error
+	if isURL(location) {
+		data, err = download(location)
+	} else {
+		location, err = filepath.Abs(location)
+		if err == nil {
+			data, err = os.ReadFile(location)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin index: %w", err)
+	}
+
+	var index Index
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid plugin index %s: %w", location, err)
+	}
+	for _, entry := range index.Plugins {
+		if entry.Name == "" || entry.Source == "" {
+			return nil, fmt.Errorf("invalid plugin index %s: entries need a name and a source", location)
+		}
+		if _, err := parseVersion(entry.Version); err != nil {
+			return nil, fmt.Errorf("invalid plugin index %s: %s: %v", location, entry.Name, err)
+		}
+	}
+	index.location = location
+	return &index, nil
+}
+
+// Find returns the entry for the given version, or the latest one if version
+// is empty.
+func (i *Index) Find(name, version string) (*IndexEntry, error) {
+	var found *IndexEntry
+	for j := range i.Plugins {
+		entry := &i.Plugins[j]
+		if entry.Name != name {
+			continue
+		}
+		if version != "" {
+			if CompareVersions(entry.Version, version) == 0 {
+				return entry, nil
+			}
+			continue
+		}
+		if found == nil || CompareVersions(entry.Version, found.Version) > 0 {
+			found = entry
+		}
+	}
+	if found == nil {
+		if version != "" {
+			return nil, fmt.Errorf("plugin %s@%s not found in index %s", name, version, i.location)
+		}
+		return nil, fmt.Errorf("plugin %s not found in index %s", name, i.location)
+	}
+	return found, nil
+}
+
+// resolve turns the source of an entry into an absolute location.
+func (i *Index) resolve(source string) string {
+	if isURL(source) || strings.HasPrefix(source, "git+") || strings.HasPrefix(source, "git@") || filepath.IsAbs(source) {
+		return source
+	}
+	if isURL(i.location) {
+		base, err := url.Parse(i.location)
+		if err != nil {
+			return source
+		}
+		ref, err := url.Parse(source)
+		if err != nil {
+			return source
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(i.location), source)
+}
+
+func download(location string) ([]byte, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("GET %s: larger than %d MB", location, maxDownloadSize>>20)
+	}
+	return data, nil
+}
@@ -0,0 +1,448 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/naming"
+)
+
+// TemplateExtension is the extension of the templates parsed next to the
+// entrypoint of a template plugin.
+const TemplateExtension = ".tmpl"
+
+// typeKeys are the keys of a template type table besides kind:format.
+var typeKeys = []string{
+	string(ir.KindAny), string(ir.KindString), string(ir.KindInteger), string(ir.KindNumber),
+	string(ir.KindBoolean), string(ir.KindTime), string(ir.KindObject), string(ir.KindArray),
+	string(ir.KindMap), string(ir.KindRef), "nullable",
+}
+
+// TemplateSpec configures a template plugin. Types maps type model kinds,
+// optionally with a format as in time:date, to the types of the target
+// language; in array, map and nullable %s stands for the element type, in ref
+// for the model name. File is a template of the file name of every model:
+// with it the entrypoint is rendered once per model.
+type TemplateSpec struct {
+	Types map[string]string `json:"types,omitempty" yaml:"types,omitempty"`
+	File  string            `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+func (s *TemplateSpec) validate() []string {
+	if s == nil {
+		return nil
+	}
+	var problems []string
+	for key := range s.Types {
+		kind, _, _ := strings.Cut(key, ":")
+		if !containsString(typeKeys, kind) {
+			problems = append(problems, fmt.Sprintf("template.types: unknown kind %q, expected one of %s", key, strings.Join(typeKeys, ", ")))
+		}
+	}
+	if s.File != "" {
+		if _, err := template.New("file").Funcs(templateFuncs(nil)).Parse(s.File); err != nil {
+			problems = append(problems, fmt.Sprintf("template.file: %v", err))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// TemplateData is what the templates of a template plugin are executed with.
+type TemplateData struct {
+	// Generator is the name of the plugin.
+	Generator string
+	// Format is the format of the input, e.g. json or openapi.
+	Format string
+	// Options holds every option of the manifest, empty if not set.
+	Options  map[string]string
+	Models   []*ir.Model
+	Document *ir.Document
+	// Model is the model being rendered when template.file is set.
+	Model *ir.Model
+}
+
+// TemplatePlugin renders Go text/template files in-process. The entrypoint
+// receives TemplateData; the other .tmpl files in its directory are available
+// to it as named templates, e.g. {{template "field.tmpl" .}}. Besides the
+// text/template builtins, templates can use:
+//
+//	pascal, camel, snake, kebab  case conversion of names
+//	upper, lower, quote, join    string helpers
+//	indent N TEXT                indents every non-empty line by N spaces
+//	type T                       the target type of an *ir.Type, see TemplateSpec
+//
+// Templates cannot reach the filesystem or the network, so the plugin needs
+// no sandbox.
+type TemplatePlugin struct {
+	name        string
+	description string
+	path        string
+	manifest    *Manifest
+	options     map[string]string
+	trust       Trust
+	integrity   *Integrity
+	engine      *templateEngine
+}
+
+// templateEngine holds the parsed templates. It is shared by the copies
+// returned from WithOptions.
+type templateEngine struct {
+	mu        sync.Mutex
+	templates *template.Template
+	file      *template.Template
+}
+
+func NewTemplatePlugin(name, description, path string) *TemplatePlugin {
+	return &TemplatePlugin{
+		name:        name,
+		description: description,
+		path:        path,
+		trust:       TrustTrusted,
+		engine:      &templateEngine{},
+	}
+}
+
+func NewTemplatePluginFromManifest(manifest *Manifest) *TemplatePlugin {
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("Template plugin: %s", manifest.Name)
+	}
+
+	plugin := NewTemplatePlugin(manifest.Name, description, manifest.EntrypointPath())
+	plugin.SetManifest(manifest)
+	return plugin
+}
+
+func (p *TemplatePlugin) GetName() string {
+	return p.name
+}
+
+func (p *TemplatePlugin) GetDescription() string {
+	return p.description
+}
+
+func (p *TemplatePlugin) Manifest() *Manifest {
+	return p.manifest
+}
+
+// SetManifest attaches a manifest. The templates are parsed again on the
+// next call, with the type table of the new manifest.
+func (p *TemplatePlugin) SetManifest(manifest *Manifest) {
+	p.manifest = manifest
+	p.engine.reset()
+}
+
+func (p *TemplatePlugin) Trust() Trust {
+	return p.trust
+}
+
+// SetTrust records the trust level. Templates run without a sandbox at any
+// trust level, since they cannot reach anything outside their data.
+func (p *TemplatePlugin) SetTrust(trust Trust) {
+	p.trust = trust
+}
+
+// SetIntegrity attaches the checksums recorded when the plugin was added;
+// they are verified before every call, and only the templates they cover are
+// parsed.
+func (p *TemplatePlugin) SetIntegrity(integrity *Integrity) {
+	p.integrity = integrity
+	p.engine.reset()
+}
+
+// Workers returns nil: templates run in-process.
+func (p *TemplatePlugin) Workers() *WorkerPool {
+	return nil
+}
+
+// Close releases the parsed templates.
+func (p *TemplatePlugin) Close() error {
+	p.engine.reset()
+	return nil
+}
+
+// InputFormats returns the input formats declared in the manifest; nil means
+// the plugin accepts any format.
+func (p *TemplatePlugin) InputFormats() []string {
+	if p.manifest == nil {
+		return nil
+	}
+	return p.manifest.InputFormats
+}
+
+func (p *TemplatePlugin) Generate(input string) (string, error) {
+	return p.GenerateContext(context.Background(), input)
+}
+
+// GenerateContext is Generate bounded by ctx. Templates work on the type
+// model, which the caller has to provide through Invoke, so a bare JSON
+// sample is rejected.
+func (p *TemplatePlugin) GenerateContext(ctx context.Context, input string) (string, error) {
+	response, err := p.Invoke(ctx, &Request{Input: input, Format: "json"})
+	if err != nil {
+		return "", err
+	}
+	return response.Output(), nil
+}
+
+// WithOptions returns a copy of the plugin that renders with the given
+// options.
+func (p *TemplatePlugin) WithOptions(options map[string]string) (Plugin, error) {
+	if err := ValidateOptions(p.name, p.manifest, options); err != nil {
+		return nil, err
+	}
+
+	configured := *p
+	configured.options = options
+	return &configured, nil
+}
+
+// Invoke renders the templates with the type model of the request. The call
+// is bounded by ctx and the manifest timeout and output size.
+func (p *TemplatePlugin) Invoke(ctx context.Context, request *Request) (*Response, error) {
+	if err := p.integrity.Verify(); err != nil {
+		return nil, &IntegrityError{Plugin: p.name, Err: err}
+	}
+	if request.IR == nil {
+		return nil, &PluginError{Plugin: p.name, Code: CodeInvalidInput, Message: "template plugins need the type model of the input"}
+	}
+	request, err := buildRequest(p.name, p.manifest, p.options, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec *TemplateSpec
+	if p.manifest != nil {
+		spec = p.manifest.Template
+	}
+	templates, file, err := p.engine.load(p.path, spec, p.integrity.partials(p.path))
+	if err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+
+	data := TemplateData{
+		Generator: p.name,
+		Format:    request.Format,
+		Options:   map[string]string{},
+		Models:    request.IR.Models,
+		Document:  request.IR,
+	}
+	if p.manifest != nil {
+		for _, key := range p.manifest.OptionNames() {
+			data.Options[key] = ""
+		}
+	}
+	for key, value := range request.Options {
+		data.Options[key] = value
+	}
+
+	limits := pluginLimits(p.manifest)
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, limits.Timeout)
+	defer cancel()
+
+	type result struct {
+		response *Response
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		response, err := p.render(templates, file, data, limits.MaxOutput)
+		done <- result{response, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// A template cannot be interrupted; its result is dropped.
+		return nil, contextError(parent, p.name, limits.Timeout)
+	case result := <-done:
+		return result.response, result.err
+	}
+}
+
+func (p *TemplatePlugin) render(templates, file *template.Template, data TemplateData, limit int64) (*Response, error) {
+	response := &Response{Version: ProtocolVersion}
+	output := &cappedBuffer{limit: limit, onExceed: func() {}}
+	execute := func(tmpl *template.Template, data TemplateData) (string, error) {
+		start := output.buffer.Len()
+		err := tmpl.Execute(output, data)
+		if output.exceeded {
+			return "", &OutputLimitError{Plugin: p.name, Limit: limit}
+		}
+		if err != nil {
+			return "", &PluginError{Plugin: p.name, Code: CodeInternal, Message: err.Error()}
+		}
+		return output.String()[start:], nil
+	}
+
+	if file == nil {
+		content, err := execute(templates, data)
+		if err != nil {
+			return nil, err
+		}
+		response.Files = []File{{Content: content}}
+		return response, nil
+	}
+
+	for _, model := range data.Models {
+		data.Model = model
+		var name strings.Builder
+		if err := file.Execute(&name, data); err != nil {
+			return nil, &PluginError{Plugin: p.name, Code: CodeInternal, Message: "template.file: " + err.Error()}
+		}
+		content, err := execute(templates, data)
+		if err != nil {
+			return nil, err
+		}
+		response.Files = append(response.Files, File{Name: strings.TrimSpace(name.String()), Content: content})
+	}
+	return response, nil
+}
+
+// load parses the templates on first use.
+func (e *templateEngine) load(path string, spec *TemplateSpec, partials []string) (*template.Template, *template.Template, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.templates != nil {
+		return e.templates, e.file, nil
+	}
+
+	templates, err := parseTemplates(path, spec, partials)
+	if err != nil {
+		return nil, nil, err
+	}
+	var file *template.Template
+	if spec != nil && spec.File != "" {
+		file, err = template.New("file").Funcs(templateFuncs(spec.Types)).Option("missingkey=error").Parse(spec.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("template.file: %w", err)
+		}
+	}
+	e.templates = templates
+	e.file = file
+	return templates, file, nil
+}
+
+func (e *templateEngine) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.templates = nil
+	e.file = nil
+}
+
+// parseTemplates parses the entrypoint and its partial templates.
+func parseTemplates(path string, spec *TemplateSpec, partials []string) (*template.Template, error) {
+	var types map[string]string
+	if spec != nil {
+		types = spec.Types
+	}
+	templates, err := template.New(filepath.Base(path)).Funcs(templateFuncs(types)).Option("missingkey=error").ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, partial := range partials {
+		if _, err := templates.ParseFiles(partial); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// partialTemplates returns the templates next to the entrypoint, which are
+// also covered by the integrity checksums.
+func partialTemplates(entrypoint string) []string {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(entrypoint), "*"+TemplateExtension))
+	var partials []string
+	for _, match := range matches {
+		if filepath.Clean(match) != filepath.Clean(entrypoint) {
+			partials = append(partials, match)
+		}
+	}
+	return partials
+}
+
+// partials returns the templates next to the entrypoint whose checksums were
+// recorded. Templates added later are not parsed, so they cannot redefine the
+// recorded ones. Without recorded checksums all templates next to the
+// entrypoint are used.
+func (i *Integrity) partials(entrypoint string) []string {
+	if i == nil {
+		return partialTemplates(entrypoint)
+	}
+	var partials []string
+	for _, path := range i.paths() {
+		if filepath.Dir(path) == filepath.Dir(entrypoint) && filepath.Ext(path) == TemplateExtension && path != entrypoint {
+			partials = append(partials, path)
+		}
+	}
+	return partials
+}
+
+func templateFuncs(types map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"pascal": naming.ToPascalCase,
+		"camel":  naming.ToCamelCase,
+		"snake":  naming.ToSnakeCase,
+		"kebab":  naming.ToKebabCase,
+		"upper":  strings.ToUpper,
+		"lower":  strings.ToLower,
+		"quote":  strconv.Quote,
+		"join":   strings.Join,
+		"indent": indent,
+		"type":   func(t *ir.Type) string { return MapType(types, t) },
+	}
+}
+
+// MapType returns the target type of t according to a template type table.
+// Kinds missing from the table keep their type model name; referenced models
+// are named in PascalCase.
+func MapType(types map[string]string, t *ir.Type) string {
+	lookup := func(key, fallback string) string {
+		if value, ok := types[key]; ok {
+			return value
+		}
+		return fallback
+	}
+	if t == nil {
+		return lookup(string(ir.KindAny), string(ir.KindAny))
+	}
+
+	var result string
+	switch t.Kind {
+	case ir.KindArray:
+		result = strings.ReplaceAll(lookup(string(ir.KindArray), "[]%s"), "%s", MapType(types, t.Elem))
+	case ir.KindMap:
+		result = strings.ReplaceAll(lookup(string(ir.KindMap), "map[string]%s"), "%s", MapType(types, t.Elem))
+	case ir.KindRef:
+		result = strings.ReplaceAll(lookup(string(ir.KindRef), "%s"), "%s", naming.ToPascalCase(t.Ref))
+	default:
+		result = lookup(string(t.Kind), string(t.Kind))
+		if t.Format != "" {
+			result = lookup(string(t.Kind)+":"+t.Format, result)
+		}
+	}
+	if t.Nullable {
+		result = strings.ReplaceAll(lookup("nullable", "%s"), "%s", result)
+	}
+	return result
+}
+
+// indent prefixes every non-empty line of text with n spaces.
+func indent(n int, text string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
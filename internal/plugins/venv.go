@@ -0,0 +1,215 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WheelsEnv names a directory of wheels to install plugin requirements from
+// without network access. Without it ~/.devtoolbox/wheels is used if it
+// exists, and otherwise pip uses its configured index.
+const WheelsEnv = "DEVTOOLBOX_WHEELS"
+
+var requirementPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+
+// requirementSpecPattern accepts a PEP 508 name with optional extras and
+// version specifiers, e.g. "pydantic[email]>=2,<3". URLs, markers and pip
+// options are not requirements a manifest may pass to pip.
+var requirementSpecPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?` +
+	`(\[[A-Za-z0-9._-]+(,[A-Za-z0-9._-]+)*\])?` +
+	`(( *(~=|===|==|!=|<=|>=|<|>) *[A-Za-z0-9.*+!_-]+)( *, *(~=|===|==|!=|<=|>=|<|>) *[A-Za-z0-9.*+!_-]+)*)?$`)
+
+var (
+	venvMu sync.Mutex
+	// venvLocks serializes building each environment; other plugins keep
+	// running meanwhile.
+	venvLocks = map[string]*sync.Mutex{}
+)
+
+// requirementName returns the project name of a pip requirement specifier,
+// e.g. "jinja2" for "Jinja2>=3.1", normalized as pip does.
+func requirementName(requirement string) string {
+	name := requirementPattern.FindString(strings.TrimSpace(requirement))
+	return strings.ToLower(strings.NewReplacer("_", "-", ".", "-").Replace(name))
+}
+
+// validRequirement reports whether a manifest requirement is a plain PEP 508
+// name and version specifier.
+func validRequirement(requirement string) bool {
+	return requirementSpecPattern.MatchString(requirement)
+}
+
+func venvLock(dir string) *sync.Mutex {
+	venvMu.Lock()
+	defer venvMu.Unlock()
+	lock, ok := venvLocks[dir]
+	if !ok {
+		lock = &sync.Mutex{}
+		venvLocks[dir] = lock
+	}
+	return lock
+}
+
+// DefaultWheels returns the wheel directory from DEVTOOLBOX_WHEELS or
+// ~/.devtoolbox/wheels, or "" if there is none.
+func DefaultWheels() string {
+	if dir := os.Getenv(WheelsEnv); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Join(homeDir, ".devtoolbox", "wheels")
+	if _, err := os.Stat(dir); err != nil {
+		return ""
+	}
+	return dir
+}
+
+// VenvDir returns the virtual environment of a plugin with requirements, or
+// "" if it has none. Environments are cached per plugin, interpreter and set
+// of requirements, so changing either creates a new one.
+func VenvDir(manifest *Manifest) (string, error) {
+	if manifest == nil || len(manifest.Requirements) == 0 {
+		return "", nil
+	}
+	python, err := pythonExecutable()
+	if err != nil {
+		return "", err
+	}
+	base, err := resolvePython(python)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory for plugin environments: %w", err)
+	}
+
+	requirements := append([]string{}, manifest.Requirements...)
+	sort.Strings(requirements)
+	hash := sha256.Sum256([]byte(base.executable + "\n" + strings.Join(requirements, "\n")))
+	return filepath.Join(cacheDir, "devtoolbox", "venvs", manifest.Name+"-"+hex.EncodeToString(hash[:6])), nil
+}
+
+// EnsureVenv creates the virtual environment of a plugin with requirements if
+// it does not exist yet and returns its python executable, or "" if the
+// plugin has no requirements. Requirements are installed from the wheel
+// directory if there is one. The environment is built next to its final
+// location and renamed into place, so concurrent processes never see a
+// half-installed one. venv and pip are stopped when ctx ends.
+func EnsureVenv(ctx context.Context, manifest *Manifest) (string, error) {
+	dir, err := VenvDir(manifest)
+	if dir == "" || err != nil {
+		return "", err
+	}
+	for _, requirement := range manifest.Requirements {
+		if !validRequirement(requirement) {
+			return "", fmt.Errorf("invalid requirement %q of plugin %s", requirement, manifest.Name)
+		}
+	}
+
+	lock := venvLock(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(venvPython(dir)); err == nil {
+		return venvPython(dir), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dir), ".staging-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	python, err := pythonExecutable()
+	if err != nil {
+		return "", err
+	}
+	if output, err := exec.CommandContext(ctx, python, "-m", "venv", staging).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to create virtual environment for plugin %s: %v\n%s", manifest.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	requirements := filepath.Join(staging, "requirements.txt")
+	if err := os.WriteFile(requirements, []byte(strings.Join(manifest.Requirements, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+	args := []string{"-m", "pip", "install", "--disable-pip-version-check", "--no-input", "-r", requirements}
+	if wheels := DefaultWheels(); wheels != "" {
+		args = append(args, "--no-index", "--find-links", wheels)
+	}
+	if output, err := exec.CommandContext(ctx, venvPython(staging), args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to install requirements of plugin %s: %v\n%s", manifest.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	if err := os.Rename(staging, dir); err != nil {
+		if _, statErr := os.Stat(venvPython(dir)); statErr != nil {
+			return "", err
+		}
+	}
+	removeStaleVenvs(manifest.Name, dir)
+	return venvPython(dir), nil
+}
+
+// removeStaleVenvs deletes the environments of a plugin built for other
+// requirements or interpreters.
+func removeStaleVenvs(name, current string) {
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(current), name+"-*"))
+	for _, match := range matches {
+		hash := strings.TrimPrefix(filepath.Base(match), name+"-")
+		if match != current && len(hash) == 12 && !strings.Contains(hash, "-") {
+			os.RemoveAll(match)
+		}
+	}
+}
+
+func venvPython(dir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(dir, "Scripts", "python.exe")
+	}
+	return filepath.Join(dir, "bin", "python")
+}
+
+// InstalledRequirements lists the packages in the environment of a plugin by
+// normalized name, or nil if the environment was not created yet.
+func InstalledRequirements(manifest *Manifest) (map[string]string, error) {
+	dir, err := VenvDir(manifest)
+	if dir == "" || err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(venvPython(dir)); err != nil {
+		return nil, nil
+	}
+
+	output, err := exec.Command(venvPython(dir), "-m", "pip", "list", "--format=json", "--disable-pip-version-check").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages of plugin %s: %w", manifest.Name, err)
+	}
+	var packages []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(output, &packages); err != nil {
+		return nil, fmt.Errorf("failed to list packages of plugin %s: %w", manifest.Name, err)
+	}
+	installed := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		installed[requirementName(pkg.Name)] = pkg.Version
+	}
+	return installed, nil
+}
@@ -0,0 +1,277 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// wasmPageSize is the size of a WebAssembly memory page.
+	wasmPageSize = 64 << 10
+	// wasmMaxPages is the 4GB address space of a 32-bit module.
+	wasmMaxPages = 1 << 16
+)
+
+var (
+	wasmCacheOnce sync.Once
+	wasmCache     wazero.CompilationCache
+)
+
+// compilationCache keeps compiled modules in the user cache directory, so
+// that neither a reload nor the next CLI run compiles a module again.
+func compilationCache() wazero.CompilationCache {
+	wasmCacheOnce.Do(func() {
+		if dir, err := os.UserCacheDir(); err == nil {
+			if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(dir, "devtoolbox", "wasm")); err == nil {
+				wasmCache = cache
+				return
+			}
+		}
+		wasmCache = wazero.NewCompilationCache()
+	})
+	return wasmCache
+}
+
+// WasmPlugin runs a WASI command module (for example GOOS=wasip1 or
+// wasm32-wasi builds) in-process with a pure-Go runtime. Like other plugins
+// it reads the request from stdin and writes the response to stdout. The
+// module gets no filesystem, network or environment, so it is isolated
+// regardless of its trust level.
+type WasmPlugin struct {
+	name        string
+	description string
+	path        string
+	manifest    *Manifest
+	options     map[string]string
+	trust       Trust
+	integrity   *Integrity
+	engine      *wasmEngine
+}
+
+// wasmEngine holds the runtime and the compiled module. It is shared by the
+// copies returned from WithOptions.
+type wasmEngine struct {
+	mu      sync.Mutex
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+func NewWasmPlugin(name, description, path string) *WasmPlugin {
+	return &WasmPlugin{
+		name:        name,
+		description: description,
+		path:        path,
+		trust:       TrustTrusted,
+		engine:      &wasmEngine{},
+	}
+}
+
+func NewWasmPluginFromManifest(manifest *Manifest) *WasmPlugin {
+	description := manifest.Description
+	if description == "" {
+		description = fmt.Sprintf("WebAssembly plugin: %s", manifest.Name)
+	}
+
+	plugin := NewWasmPlugin(manifest.Name, description, manifest.EntrypointPath())
+	plugin.SetManifest(manifest)
+	return plugin
+}
+
+func (p *WasmPlugin) GetName() string {
+	return p.name
+}
+
+func (p *WasmPlugin) GetDescription() string {
+	return p.description
+}
+
+func (p *WasmPlugin) Manifest() *Manifest {
+	return p.manifest
+}
+
+// SetManifest attaches a manifest. The module is compiled again on the next
+// call, with the memory limit of the new manifest.
+func (p *WasmPlugin) SetManifest(manifest *Manifest) {
+	p.manifest = manifest
+	p.engine.close()
+}
+
+func (p *WasmPlugin) Trust() Trust {
+	return p.trust
+}
+
+// SetTrust records the trust level. WebAssembly plugins are always isolated.
+func (p *WasmPlugin) SetTrust(trust Trust) {
+	p.trust = trust
+}
+
+// SetIntegrity attaches the checksums recorded when the plugin was added;
+// they are verified before every call.
+func (p *WasmPlugin) SetIntegrity(integrity *Integrity) {
+	p.integrity = integrity
+}
+
+// Workers returns nil: modules are instantiated per call, which is cheap
+// once compiled.
+func (p *WasmPlugin) Workers() *WorkerPool {
+	return nil
+}
+
+// Close releases the runtime and the compiled module.
+func (p *WasmPlugin) Close() error {
+	p.engine.close()
+	return nil
+}
+
+// InputFormats returns the input formats declared in the manifest; nil means
+// the plugin accepts any format.
+func (p *WasmPlugin) InputFormats() []string {
+	if p.manifest == nil {
+		return nil
+	}
+	return p.manifest.InputFormats
+}
+
+func (p *WasmPlugin) Generate(input string) (string, error) {
+	return p.GenerateContext(context.Background(), input)
+}
+
+// GenerateContext is Generate bounded by ctx.
+func (p *WasmPlugin) GenerateContext(ctx context.Context, input string) (string, error) {
+	response, err := p.Invoke(ctx, &Request{Input: input, Format: "json"})
+	if err != nil {
+		return "", err
+	}
+	return response.Output(), nil
+}
+
+// WithOptions returns a copy of the plugin that sends the given options with
+// every request.
+func (p *WasmPlugin) WithOptions(options map[string]string) (Plugin, error) {
+	if err := ValidateOptions(p.name, p.manifest, options); err != nil {
+		return nil, err
+	}
+
+	configured := *p
+	configured.options = options
+	return &configured, nil
+}
+
+// Invoke instantiates the module and runs its _start function with the
+// request on stdin. The call is bounded by ctx and the manifest timeout,
+// output size and memory; the cpu limit does not apply in-process.
+func (p *WasmPlugin) Invoke(ctx context.Context, request *Request) (*Response, error) {
+	if err := p.integrity.Verify(); err != nil {
+		return nil, &IntegrityError{Plugin: p.name, Err: err}
+	}
+
+	limits := pluginLimits(p.manifest)
+	parent := ctx
+	ctx, cancel := context.WithTimeout(parent, limits.Timeout)
+	defer cancel()
+
+	protocol := Protocol(p)
+	stdin := request.Input
+	if protocol == ProtocolJSON {
+		var err error
+		stdin, err = encodeRequest(p.name, p.manifest, p.options, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	runtime, module, err := p.engine.load(p.path, limits)
+	if err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+
+	stdout := &cappedBuffer{limit: limits.MaxOutput, onExceed: cancel}
+	stderr := &limitedBuffer{limit: workerStderrLimit}
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(p.name).
+		WithStdin(strings.NewReader(stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr)
+
+	instance, err := runtime.InstantiateModule(ctx, module, config)
+	if instance != nil {
+		instance.Close(context.Background())
+	}
+	if stdout.exceeded {
+		return nil, &OutputLimitError{Plugin: p.name, Limit: limits.MaxOutput}
+	}
+	if ctx.Err() != nil {
+		return nil, contextError(parent, p.name, limits.Timeout)
+	}
+
+	if err != nil {
+		if limitErr := resourceLimitError(p.name, nil, stderr.String(), limits); limitErr != nil {
+			return nil, limitErr
+		}
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, exitError(p.name, protocol, int(exitErr.ExitCode()), stdout.Bytes(), stderr.String())
+		}
+		// A trap such as unreachable or an out of bounds memory access.
+		return nil, &PluginError{Plugin: p.name, Code: CodeInternal, Message: err.Error(), Stderr: stderr.String()}
+	}
+
+	return parseResponse(p.name, protocol, stdout.Bytes(), stderr.String())
+}
+
+// load compiles the module on first use.
+func (e *wasmEngine) load(path string, limits Limits) (wazero.Runtime, wazero.CompiledModule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.module != nil {
+		return e.runtime, e.module, nil
+	}
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read module: %w", err)
+	}
+
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithCompilationCache(compilationCache())
+	if pages := (limits.Memory + wasmPageSize - 1) / wasmPageSize; pages > 0 && pages < wasmMaxPages {
+		config = config.WithMemoryLimitPages(uint32(pages))
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, nil, err
+	}
+
+	module, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, nil, fmt.Errorf("invalid module %s: %w", path, err)
+	}
+
+	e.runtime = runtime
+	e.module = module
+	return runtime, module, nil
+}
+
+func (e *wasmEngine) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.runtime != nil {
+		e.runtime.Close(context.Background())
+	}
+	e.runtime = nil
+	e.module = nil
+}
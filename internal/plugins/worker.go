@@ -0,0 +1,558 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolSize       = 2
+	defaultIdleTimeout    = 5 * time.Minute
+	defaultMaxRequests    = 1000
+	defaultHealthInterval = 30 * time.Second
+	healthCheckTimeout    = 5 * time.Second
+	workerShutdownTimeout = 2 * time.Second
+	workerStderrLimit     = 64 * 1024
+)
+
+// WorkerConfig controls a pool of long-lived plugin processes. MaxOutput caps
+// a single response line and Memory sets an rlimit on every worker that is
+// not sandboxed (the sandbox applies it itself); a CPU limit is not applied
+// because it would count time across all requests.
+type WorkerConfig struct {
+	PoolSize       int
+	IdleTimeout    time.Duration
+	MaxRequests    int
+	HealthInterval time.Duration
+	MaxOutput      int64
+	Memory         int64
+}
+
+func (c WorkerConfig) withDefaults() WorkerConfig {
+	if c.PoolSize <= 0 {
+		c.PoolSize = defaultPoolSize
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultIdleTimeout
+	}
+	if c.MaxRequests <= 0 {
+		c.MaxRequests = defaultMaxRequests
+	}
+	if c.HealthInterval <= 0 {
+		c.HealthInterval = defaultHealthInterval
+	}
+	if c.MaxOutput <= 0 {
+		c.MaxOutput = DefaultMaxOutput
+	}
+	return c
+}
+
+type WorkerStats struct {
+	Idle    int `json:"idle"`
+	Busy    int `json:"busy"`
+	Started int `json:"started"`
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// WorkerPool keeps up to PoolSize plugin processes that serve line-delimited
+// JSON-RPC 2.0 requests on stdin/stdout. Crashed workers are replaced,
+// workers idle for longer than IdleTimeout are stopped, and a worker is
+// recycled after MaxRequests calls.
+type WorkerPool struct {
+	name    string
+	command func() (*exec.Cmd, error)
+	config  WorkerConfig
+	// slots holds one token for every worker that is not idle: serving a
+	// call, being health-checked or shutting down. A worker keeps its token
+	// until it has exited, so replacements never exceed PoolSize.
+	slots chan struct{}
+
+	mu      sync.Mutex
+	idle    []*worker
+	busy    int
+	started int
+	closed  bool
+	stop    chan struct{}
+	running bool
+}
+
+func NewWorkerPool(name string, config WorkerConfig, command func() (*exec.Cmd, error)) *WorkerPool {
+	config = config.withDefaults()
+	return &WorkerPool{
+		name:    name,
+		command: command,
+		config:  config,
+		slots:   make(chan struct{}, config.PoolSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Call sends one request to a free worker and returns the raw result. If a
+// reused worker turns out to be dead, the call is retried once on a fresh one.
+// When ctx ends first, the worker is killed and ctx.Err() is returned.
+func (p *WorkerPool) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	held := true
+	defer func() {
+		if held {
+			<-p.slots
+		}
+	}()
+
+	for attempt := 0; ; attempt++ {
+		w, err := p.take()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := w.callContext(ctx, method, params)
+		if err == nil {
+			held = false
+			p.put(w)
+			return result, nil
+		}
+
+		var callErr *rpcCallError
+		if errors.As(err, &callErr) {
+			held = false
+			p.put(w)
+			return nil, &ProtocolError{Plugin: p.name, Message: callErr.Error()}
+		}
+
+		reused := w.requests > 0
+		p.discard(w)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt == 0 && reused && !isWorkerReplyError(err) {
+			continue
+		}
+		return nil, p.workerError(w, err)
+	}
+}
+
+// isWorkerReplyError reports failures caused by what the worker answered
+// rather than by the worker dying; retrying them would not help.
+func isWorkerReplyError(err error) bool {
+	var protocolErr *ProtocolError
+	var limitErr *OutputLimitError
+	return errors.As(err, &protocolErr) || errors.As(err, &limitErr)
+}
+
+func (p *WorkerPool) Stats() WorkerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return WorkerStats{Idle: len(p.idle), Busy: p.busy, Started: p.started}
+}
+
+// Close stops idle workers; busy workers are stopped when they finish.
+func (p *WorkerPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, w := range idle {
+		w.shutdown()
+	}
+	return nil
+}
+
+func (p *WorkerPool) take() (*worker, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, &ExecError{Plugin: p.name, Err: errors.New("worker pool is closed")}
+	}
+	for len(p.idle) > 0 {
+		w := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if w.exited() {
+			continue
+		}
+		p.busy++
+		p.mu.Unlock()
+		return w, nil
+	}
+	p.busy++
+	p.started++
+	if !p.running {
+		p.running = true
+		go p.maintain()
+	}
+	p.mu.Unlock()
+
+	w, err := p.spawn()
+	if err != nil {
+		p.mu.Lock()
+		p.busy--
+		p.mu.Unlock()
+		return nil, err
+	}
+	return w, nil
+}
+
+// put returns a worker after a call and releases the slot of the call. A
+// worker that is retired keeps the slot until it has exited.
+func (p *WorkerPool) put(w *worker) {
+	w.requests++
+	w.lastUsed = time.Now()
+
+	p.mu.Lock()
+	p.busy--
+	if p.closed || w.requests >= p.config.MaxRequests || w.exited() {
+		p.mu.Unlock()
+		go func() {
+			w.shutdown()
+			<-p.slots
+		}()
+		return
+	}
+	p.idle = append(p.idle, w)
+	p.mu.Unlock()
+	<-p.slots
+}
+
+func (p *WorkerPool) discard(w *worker) {
+	p.mu.Lock()
+	p.busy--
+	p.mu.Unlock()
+	w.kill()
+}
+
+func (p *WorkerPool) spawn() (*worker, error) {
+	cmd, err := p.command()
+	if err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	// A separate pipe instead of StdoutPipe: cmd.Wait runs concurrently and
+	// must not close the reader while a response is still being read.
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	stderr := &limitedBuffer{limit: workerStderrLimit}
+	cmd.Stdout = writer
+	cmd.Stderr = stderr
+	prepareCommand(cmd)
+
+	if err := cmd.Start(); err != nil {
+		reader.Close()
+		writer.Close()
+		return nil, &ExecError{Plugin: p.name, Err: err}
+	}
+	writer.Close()
+
+	w := &worker{
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    bufio.NewReader(reader),
+		stderr:    stderr,
+		maxOutput: p.config.MaxOutput,
+		done:      make(chan struct{}),
+		lastUsed:  time.Now(),
+	}
+	go func() {
+		cmd.Wait()
+		reader.Close()
+		close(w.done)
+	}()
+
+	if !isSandboxed(cmd) {
+		if err := applyLimits(cmd.Process.Pid, Limits{Memory: p.config.Memory}); err != nil {
+			w.kill()
+			return nil, &ExecError{Plugin: p.name, Err: err}
+		}
+	}
+	return w, nil
+}
+
+// maintain stops idle workers and health-checks the rest. Each worker it
+// takes from the idle list occupies a slot; when all slots are taken, the
+// workers wait for the next round.
+func (p *WorkerPool) maintain() {
+	interval := p.config.HealthInterval
+	if p.config.IdleTimeout < interval {
+		interval = p.config.IdleTimeout
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		var expired, check []*worker
+		kept := p.idle[:0]
+		for _, w := range p.idle {
+			expire := w.exited() || time.Since(w.lastUsed) >= p.config.IdleTimeout
+			if !expire && time.Since(w.lastChecked) < p.config.HealthInterval {
+				kept = append(kept, w)
+				continue
+			}
+			select {
+			case p.slots <- struct{}{}:
+			default:
+				kept = append(kept, w)
+				continue
+			}
+			if expire {
+				expired = append(expired, w)
+			} else {
+				check = append(check, w)
+			}
+		}
+		p.idle = kept
+		p.busy += len(check)
+		p.mu.Unlock()
+
+		for _, w := range expired {
+			w.shutdown()
+			<-p.slots
+		}
+		for _, w := range check {
+			if _, err := w.callTimeout("ping", healthCheckTimeout); err != nil {
+				p.discard(w)
+				<-p.slots
+				continue
+			}
+			w.lastChecked = time.Now()
+			p.mu.Lock()
+			p.busy--
+			if p.closed {
+				p.mu.Unlock()
+				w.shutdown()
+				<-p.slots
+				continue
+			}
+			p.idle = append(p.idle, w)
+			p.mu.Unlock()
+			<-p.slots
+		}
+	}
+}
+
+func (p *WorkerPool) workerError(w *worker, err error) error {
+	var protocolErr *ProtocolError
+	if errors.As(err, &protocolErr) {
+		protocolErr.Plugin = p.name
+		return protocolErr
+	}
+	var limitErr *OutputLimitError
+	if errors.As(err, &limitErr) {
+		limitErr.Plugin = p.name
+		return limitErr
+	}
+
+	<-w.done
+	exitCode := 0
+	if w.cmd.ProcessState != nil {
+		exitCode = w.cmd.ProcessState.ExitCode()
+	}
+	return &PluginError{
+		Plugin:   p.name,
+		Code:     exitCodeError(exitCode),
+		Message:  fmt.Sprintf("worker exited unexpectedly: %v", err),
+		ExitCode: exitCode,
+		Stderr:   w.stderr.String(),
+	}
+}
+
+type worker struct {
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	stdout      *bufio.Reader
+	stderr      *limitedBuffer
+	maxOutput   int64
+	done        chan struct{}
+	nextID      int64
+	requests    int
+	lastUsed    time.Time
+	lastChecked time.Time
+}
+
+type rpcCallError struct {
+	method string
+	err    *rpcError
+}
+
+func (e *rpcCallError) Error() string {
+	return fmt.Sprintf("%s failed with code %d: %s", e.method, e.err.Code, e.err.Message)
+}
+
+func (w *worker) call(method string, params interface{}) (json.RawMessage, error) {
+	w.nextID++
+	id := w.nextID
+
+	data, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+	if _, err := w.stdin.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	for {
+		line, err := w.readLine()
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var response rpcResponse
+		if err := json.Unmarshal(line, &response); err != nil {
+			return nil, &ProtocolError{Message: fmt.Sprintf("invalid JSON-RPC response: %v", err)}
+		}
+		if response.ID != id {
+			return nil, &ProtocolError{Message: fmt.Sprintf("response id %d does not match request id %d", response.ID, id)}
+		}
+		if response.Error != nil {
+			return nil, &rpcCallError{method: method, err: response.Error}
+		}
+		return response.Result, nil
+	}
+}
+
+// readLine reads one response line of at most maxOutput bytes.
+func (w *worker) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := w.stdout.ReadSlice('\n')
+		line = append(line, chunk...)
+		if int64(len(line)) > w.maxOutput {
+			return nil, &OutputLimitError{Limit: w.maxOutput}
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// callContext kills the worker if ctx ends before the reply arrives.
+func (w *worker) callContext(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	type outcome struct {
+		result json.RawMessage
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := w.call(method, params)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		w.kill()
+		<-done
+		return nil, ctx.Err()
+	}
+}
+
+func (w *worker) callTimeout(method string, timeout time.Duration) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return w.callContext(ctx, method, nil)
+}
+
+func (w *worker) exited() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown asks the worker to exit and kills it if it does not.
+func (w *worker) shutdown() {
+	if data, err := json.Marshal(rpcRequest{JSONRPC: "2.0", Method: "shutdown"}); err == nil {
+		w.stdin.Write(append(data, '\n'))
+	}
+	w.stdin.Close()
+
+	select {
+	case <-w.done:
+	case <-time.After(workerShutdownTimeout):
+		w.kill()
+	}
+}
+
+func (w *worker) kill() {
+	killProcessGroup(w.cmd)
+	w.stdin.Close()
+	<-w.done
+}
+
+// limitedBuffer keeps the last limit bytes written to it.
+type limitedBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
@@ -0,0 +1,450 @@
+// Package scaffold creates plugin projects for devtoolbox plugin new: a
+// manifest, an entrypoint that speaks the plugin protocol, a sample input,
+// golden-file tests and a README, ready for plugin add.
+//
+// The generated plugins describe the models of the input; the golden files
+// are produced here from the same sample, so the tests of a new project pass
+// before the author changes anything.
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/JIIL07/devtoolbox/internal/version"
+)
+
+// LanguageGo is a Go plugin built with the SDK into an exec plugin.
+const LanguageGo = "go"
+
+// Languages are the languages New supports.
+var Languages = []string{plugins.LanguagePython, LanguageGo, plugins.LanguageExec, plugins.LanguageWasm}
+
+// sdkModule is the module of the Go SDK.
+const sdkModule = "github.com/JIIL07/devtoolbox"
+
+// DefaultSample is the sample input of a new plugin.
+const DefaultSample = `{
+  "id": 42,
+  "name": "Ada Lovelace",
+  "email": "ada@example.com",
+  "active": true,
+  "tags": ["admin", "author"],
+  "address": {"city": "London", "zip": null}
+}
+`
+
+// defaultTitle is the default of the title option of the generated plugins.
+const defaultTitle = "Data Model"
+
+//go:embed templates
+var templates embed.FS
+
+// Options describe the plugin to create.
+type Options struct {
+	Name     string
+	Language string
+	// Dir is the project directory; it must not exist or be empty.
+	Dir         string
+	Description string
+	// Sample is the sample input, DefaultSample if empty. SampleName is its
+	// file name in testdata and decides the input format by extension.
+	Sample     string
+	SampleName string
+	// Module is the module path of Go and WebAssembly plugins. Without it a
+	// go.mod is written only outside the DevToolBox module, which plugins in
+	// its tree use directly.
+	Module string
+	// SDKDir is a DevToolBox checkout the go.mod replaces the SDK module
+	// with, as the SDK has no release to require yet. It defaults to the
+	// source tree DevToolBox was built from, when that is still there.
+	SDKDir string
+}
+
+// Result describes the created project.
+type Result struct {
+	Dir string
+	// Files are relative to Dir and sorted.
+	Files []string
+	// Test is the shell command that runs the tests in Dir.
+	Test string
+}
+
+// file is a file of a project.
+type file struct {
+	Path     string
+	Template string
+	Purpose  string
+	Mode     os.FileMode
+}
+
+// layouts are the files written from templates, besides plugin.yaml and
+// README.md.
+var layouts = map[string][]file{
+	plugins.LanguagePython: {
+		{Path: "main.py", Template: "python/main.py.tmpl", Purpose: "Entrypoint: the generator, served with the Python SDK"},
+		{Path: "test_main.py", Template: "python/test_main.py.tmpl", Purpose: "Golden-file and protocol tests (unittest or pytest)"},
+	},
+	LanguageGo: {
+		{Path: "main.go", Template: "go/main.go.tmpl", Purpose: "Entrypoint: the generator, served with the Go SDK"},
+		{Path: "main_test.go", Template: "go/main_test.go.tmpl", Purpose: "Golden-file tests"},
+		{Path: ".gitignore", Template: "go/gitignore.tmpl", Purpose: "Ignores the built binary"},
+	},
+	plugins.LanguageWasm: {
+		{Path: "main.go", Template: "go/main.go.tmpl", Purpose: "Entrypoint: the generator, served with the Go SDK and built for WASI"},
+		{Path: "main_test.go", Template: "go/main_test.go.tmpl", Purpose: "Golden-file tests, run natively"},
+		{Path: ".gitignore", Template: "wasm/gitignore.tmpl", Purpose: "Ignores the built module"},
+	},
+	plugins.LanguageExec: {
+		{Path: "main.sh", Template: "exec/main.sh.tmpl", Purpose: "Entrypoint: a shell script speaking the text protocol", Mode: 0755},
+		{Path: "test.sh", Template: "exec/test.sh.tmpl", Purpose: "Golden-file test", Mode: 0755},
+	},
+}
+
+var descriptions = map[string]string{
+	plugins.LanguagePython: "Lists the models and fields of the input",
+	LanguageGo:             "Lists the models and fields of the input",
+	plugins.LanguageWasm:   "Lists the models and fields of the input",
+	plugins.LanguageExec:   "Wraps the input sample in a JavaScript module",
+}
+
+var labels = map[string]string{
+	plugins.LanguagePython: "Python",
+	LanguageGo:             "Go",
+	plugins.LanguageWasm:   "WebAssembly",
+	plugins.LanguageExec:   "executable",
+}
+
+// templateData is passed to the templates.
+type templateData struct {
+	Name              string
+	Description       string
+	Language          string
+	LanguageLabel     string
+	DevToolBoxVersion string
+	Sample            string
+	Format            string
+	Golden            string
+	Module            string
+	SDKDir            string
+	Build             bool
+	Files             []file
+}
+
+// New creates a plugin project and checks that its manifest is valid.
+func New(options Options) (*Result, error) {
+	if err := plugins.ValidateName(options.Name); err != nil {
+		return nil, err
+	}
+	layout, ok := layouts[options.Language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %q, use one of %s", options.Language, strings.Join(Languages, ", "))
+	}
+	if options.Dir == "" {
+		options.Dir = options.Name
+	}
+	if err := checkEmpty(options.Dir); err != nil {
+		return nil, err
+	}
+	if options.Description == "" {
+		options.Description = descriptions[options.Language]
+	}
+	if options.Sample == "" {
+		options.Sample, options.SampleName = DefaultSample, ""
+	}
+	if options.SampleName == "" {
+		options.SampleName = "input.json"
+	}
+	options.SampleName = filepath.Base(options.SampleName)
+
+	data := templateData{
+		Name:              options.Name,
+		Description:       strings.TrimSuffix(options.Description, "."),
+		Language:          options.Language,
+		LanguageLabel:     labels[options.Language],
+		DevToolBoxVersion: version.Version,
+		Sample:            options.SampleName,
+		Golden:            "expected.txt",
+		Build:             options.Language == LanguageGo || options.Language == plugins.LanguageWasm,
+	}
+
+	generated := map[string][]byte{"testdata/" + options.SampleName: []byte(options.Sample)}
+	if options.Language == plugins.LanguageExec {
+		data.Golden = "expected.js"
+		generated["testdata/expected.js"] = []byte("export default " + strings.TrimRight(options.Sample, "\n") + ";\n")
+	} else {
+		format := core.FormatFromExtension(options.SampleName)
+		if format == core.FormatAuto {
+			format = core.DetectFormat(options.Sample)
+		}
+		doc, err := core.ParseInput(options.Sample, format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sample %s: %w", options.SampleName, err)
+		}
+		data.Format = string(format)
+		generated["testdata/expected.txt"] = []byte(Describe(defaultTitle, doc))
+		if options.Language == plugins.LanguagePython {
+			request, err := json.MarshalIndent(plugins.Request{
+				Version: plugins.ProtocolVersion,
+				Input:   options.Sample,
+				Format:  string(format),
+				Options: map[string]string{"title": defaultTitle},
+				IR:      doc,
+				Context: plugins.RequestContext{Generator: options.Name, DevToolBoxVersion: version.Version, OutputExtension: ".txt"},
+			}, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			generated["testdata/request.json"] = append(request, '\n')
+		}
+	}
+
+	if data.Build {
+		data.Module = options.Module
+		if data.Module == "" && !insideSDKModule(options.Dir) {
+			data.Module = options.Name
+		}
+		if data.Module != "" {
+			dir, err := sdkDir(options.SDKDir)
+			if err != nil {
+				return nil, err
+			}
+			if strings.ContainsAny(dir, " \t\"'`") {
+				dir = strconv.Quote(dir)
+			}
+			data.SDKDir = dir
+			layout = append(layout, file{Path: "go.mod", Template: "go/go.mod.tmpl", Purpose: "Go module requiring the DevToolBox SDK"})
+		}
+	}
+	data.Files = append([]file{}, layout...)
+	for _, path := range sortedPaths(generated) {
+		data.Files = append(data.Files, file{Path: path, Purpose: purpose(path, options.SampleName)})
+	}
+
+	layout = append(layout,
+		file{Path: "plugin.yaml", Template: options.Language + "/plugin.yaml.tmpl"},
+		file{Path: "README.md", Template: "README.md.tmpl"},
+	)
+	for _, f := range layout {
+		content, err := render(f.Template, data)
+		if err != nil {
+			return nil, err
+		}
+		generated[f.Path] = content
+	}
+
+	result := &Result{Dir: options.Dir, Files: sortedPaths(generated)}
+	for _, path := range result.Files {
+		mode := os.FileMode(0644)
+		for _, f := range layout {
+			if f.Path == path && f.Mode != 0 {
+				mode = f.Mode
+			}
+		}
+		target := filepath.Join(options.Dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, generated[path], mode); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := plugins.LoadManifest(filepath.Join(options.Dir, "plugin.yaml")); err != nil {
+		return nil, err
+	}
+	switch options.Language {
+	case plugins.LanguagePython:
+		sdk, err := plugins.PythonSDKPath()
+		if err != nil {
+			return nil, err
+		}
+		result.Test = pythonTest(sdk)
+	case plugins.LanguageExec:
+		result.Test = "sh test.sh"
+	default:
+		result.Test = "go test ./..."
+		if data.Module != "" {
+			result.Test = "go mod tidy && " + result.Test
+		}
+	}
+	return result, nil
+}
+
+// Describe renders the output of the generated plugins for a type model:
+// a heading with title and every model with its fields.
+func Describe(title string, doc *ir.Document) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n", title)
+	for _, model := range doc.Models {
+		fmt.Fprintf(&builder, "\n%s\n", model.Name)
+		if model.Type == nil || model.Type.Kind != ir.KindObject {
+			fmt.Fprintf(&builder, "  = %s\n", typeName(model.Type))
+			continue
+		}
+		for _, field := range model.Type.Fields {
+			fmt.Fprintf(&builder, "  %s: %s\n", field.Name, typeName(field.Type))
+		}
+	}
+	return builder.String()
+}
+
+func typeName(t *ir.Type) string {
+	if t == nil {
+		return "any"
+	}
+	var name string
+	switch t.Kind {
+	case ir.KindRef:
+		name = t.Ref
+	case ir.KindArray:
+		name = "[]" + typeName(t.Elem)
+	case ir.KindMap:
+		name = "map[string]" + typeName(t.Elem)
+	default:
+		name = string(t.Kind)
+	}
+	if t.Nullable {
+		name += "?"
+	}
+	return name
+}
+
+func render(name string, data templateData) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buffer.Bytes(), nil
+}
+
+func purpose(path, sample string) string {
+	switch path {
+	case "testdata/" + sample:
+		return "Sample input"
+	case "testdata/request.json":
+		return "The request DevToolBox sends for the sample"
+	default:
+		return "Expected output for the sample (golden file)"
+	}
+}
+
+// checkEmpty fails if dir exists and is not an empty directory.
+func checkEmpty(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot create plugin in %s: %w", dir, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("directory %s already exists and is not empty", dir)
+	}
+	return nil
+}
+
+// pythonTest is the command that runs the tests of a Python plugin with the
+// SDK DevToolBox provides on PYTHONPATH, as plugin test does.
+func pythonTest(sdk string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf(`set "PYTHONPATH=%s" && python -m unittest`, sdk)
+	}
+	return "PYTHONPATH=" + shellQuote(sdk) + " python3 -m unittest"
+}
+
+func shellQuote(s string) string {
+	if !strings.ContainsAny(s, " \t'\"$`\\*?[]#~&;|<>(){}!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sdkDir returns the absolute path of the DevToolBox checkout for the
+// replace directive: dir, or the source tree of this build.
+func sdkDir(dir string) (string, error) {
+	if dir == "" {
+		dir = sourceDir()
+		if dir == "" {
+			return "", fmt.Errorf("the Go SDK has no release yet; pass the path of a DevToolBox checkout with --sdk-dir")
+		}
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(abs, "go.mod"))
+	if err != nil || modulePath(data) != sdkModule {
+		return "", fmt.Errorf("%s is not a DevToolBox checkout", dir)
+	}
+	return abs, nil
+}
+
+// sourceDir returns the root of the DevToolBox module this package was
+// compiled from, or "" if the binary was built with -trimpath or the
+// sources are gone.
+func sourceDir() string {
+	_, file, _, ok := runtime.Caller(0)
+	if !ok || !filepath.IsAbs(file) {
+		return ""
+	}
+	root := filepath.Dir(filepath.Dir(filepath.Dir(file)))
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err != nil || modulePath(data) != sdkModule {
+		return ""
+	}
+	return root
+}
+
+// insideSDKModule reports whether dir is inside the DevToolBox module, where
+// plugins import the SDK without a go.mod of their own.
+func insideSDKModule(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for current := filepath.Dir(abs); ; current = filepath.Dir(current) {
+		if data, err := os.ReadFile(filepath.Join(current, "go.mod")); err == nil {
+			return modulePath(data) == sdkModule
+		}
+		if parent := filepath.Dir(current); parent == current {
+			return false
+		}
+	}
+}
+
+func modulePath(gomod []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,5 @@
+package version
+
+// Version is the DevToolBox release version. It can be overridden at build
+// time with -ldflags "-X github.com/JIIL07/devtoolbox/internal/version.Version=...".
+var Version = "0.1.0"
@@ -0,0 +1,87 @@
+"""Python SDK for DevToolBox plugins.
+
+DevToolBox puts this package on PYTHONPATH for every Python plugin, so a
+plugin with "protocol: json" in its manifest only provides a handler:
+
+    from devtoolbox_sdk import serve
+
+    def generate(request):
+        return "\\n".join(model.name for model in request.ir.models)
+
+    if __name__ == "__main__":
+        serve(generate)
+
+The package is versioned together with DevToolBox and the Go SDK.
+"""
+
+from .ir import (
+    KIND_ANY,
+    KIND_ARRAY,
+    KIND_BOOLEAN,
+    KIND_INTEGER,
+    KIND_MAP,
+    KIND_NUMBER,
+    KIND_OBJECT,
+    KIND_REF,
+    KIND_STRING,
+    KIND_TIME,
+    Document,
+    Field,
+    Model,
+    Type,
+)
+from .naming import to_camel_case, to_pascal_case, to_snake_case
+from .protocol import (
+    CODE_INTERNAL,
+    CODE_INVALID_INPUT,
+    CODE_INVALID_OPTIONS,
+    CODE_UNSUPPORTED,
+    PROTOCOL_VERSION,
+    SEVERITY_INFO,
+    SEVERITY_WARNING,
+    Diagnostic,
+    File,
+    Location,
+    PluginError,
+    Request,
+    Response,
+    handle,
+    serve,
+)
+
+__version__ = "0.1.0"
+
+__all__ = [
+    "CODE_INTERNAL",
+    "CODE_INVALID_INPUT",
+    "CODE_INVALID_OPTIONS",
+    "CODE_UNSUPPORTED",
+    "KIND_ANY",
+    "KIND_ARRAY",
+    "KIND_BOOLEAN",
+    "KIND_INTEGER",
+    "KIND_MAP",
+    "KIND_NUMBER",
+    "KIND_OBJECT",
+    "KIND_REF",
+    "KIND_STRING",
+    "KIND_TIME",
+    "PROTOCOL_VERSION",
+    "SEVERITY_INFO",
+    "SEVERITY_WARNING",
+    "Diagnostic",
+    "Document",
+    "Field",
+    "File",
+    "Location",
+    "Model",
+    "PluginError",
+    "Request",
+    "Response",
+    "Type",
+    "handle",
+    "serve",
+    "to_camel_case",
+    "to_pascal_case",
+    "to_snake_case",
+]
@@ -0,0 +1,145 @@
+"""Type model sent to plugins as request.ir, mirroring internal/ir."""
+
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional
+
+KIND_ANY = "any"
+KIND_STRING = "string"
+KIND_INTEGER = "integer"
+KIND_NUMBER = "number"
+KIND_BOOLEAN = "boolean"
+KIND_TIME = "time"
+KIND_OBJECT = "object"
+KIND_ARRAY = "array"
+KIND_MAP = "map"
+KIND_REF = "ref"
+
+ROLE_PARAMS = "params"
+ROLE_REQUEST = "request"
+ROLE_RESPONSE = "response"
+
+
+def _compact(data: Dict[str, Any]) -> Dict[str, Any]:
+    """Drops empty values like the omitempty tags on the Go side."""
+    return {key: value for key, value in data.items() if value not in (None, "", False, [], {})}
+
+
+@dataclass
+class Type:
+    kind: str = KIND_ANY
+    format: str = ""
+    ref: str = ""
+    elem: Optional["Type"] = None
+    fields: List["Field"] = field(default_factory=list)
+    nullable: bool = False
+    enum: List[str] = field(default_factory=list)
+
+    @classmethod
+    def from_dict(cls, data: Optional[Dict[str, Any]]) -> "Type":
+        data = data or {}
+        return cls(
+            kind=data.get("kind") or KIND_ANY,
+            format=data.get("format", ""),
+            ref=data.get("ref", ""),
+            elem=cls.from_dict(data["elem"]) if data.get("elem") else None,
+            fields=[Field.from_dict(f) for f in data.get("fields") or []],
+            nullable=data.get("nullable", False),
+            enum=list(data.get("enum") or []),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = _compact({
+            "format": self.format,
+            "ref": self.ref,
+            "elem": self.elem.to_dict() if self.elem else None,
+            "fields": [f.to_dict() for f in self.fields],
+            "nullable": self.nullable,
+            "enum": self.enum,
+        })
+        return {"kind": self.kind, **result}
+
+    def refs(self) -> List[str]:
+        """Names of the models this type refers to."""
+        if self.kind == KIND_REF:
+            return [self.ref]
+        result = self.elem.refs() if self.elem else []
+        for f in self.fields:
+            result.extend(f.type.refs())
+        return result
+
+
+@dataclass
+class Field:
+    name: str
+    type: Type = field(default_factory=Type)
+    required: bool = False
+    description: str = ""
+    xml: str = ""
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Field":
+        return cls(
+            name=data["name"],
+            type=Type.from_dict(data.get("type")),
+            required=data.get("required", False),
+            description=data.get("description", ""),
+            xml=data.get("xml", ""),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {"name": self.name, "type": self.type.to_dict(), **_compact({
+            "required": self.required,
+            "description": self.description,
+            "xml": self.xml,
+        })}
+
+
+@dataclass
+class Model:
+    name: str
+    type: Type = field(default_factory=Type)
+    description: str = ""
+    role: str = ""
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Model":
+        return cls(
+            name=data["name"],
+            type=Type.from_dict(data.get("type")),
+            description=data.get("description", ""),
+            role=data.get("role", ""),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {"name": self.name, "type": self.type.to_dict(), **_compact({
+            "description": self.description,
+            "role": self.role,
+        })}
+
+
+@dataclass
+class Document:
+    models: List[Model] = field(default_factory=list)
+    format: str = ""
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Document":
+        return cls(
+            models=[Model.from_dict(m) for m in data.get("models") or []],
+            format=data.get("format", ""),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"models": [m.to_dict() for m in self.models]}
+        if self.format:
+            result["format"] = self.format
+        return result
+
+    def model(self, name: str) -> Optional[Model]:
+        for model in self.models:
+            if model.name == name:
+                return model
+        return None
+
+    def names(self) -> List[str]:
+        return [model.name for model in self.models]
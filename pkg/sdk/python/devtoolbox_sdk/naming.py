@@ -0,0 +1,36 @@
+"""Identifier conversions matching the built-in generators."""
+
+from typing import List
+
+
+def _split_words(s: str) -> List[str]:
+    parts = []
+    current = ""
+    for i, ch in enumerate(s):
+        if not ch.isalnum():
+            if current:
+                parts.append(current)
+            current = ""
+        elif i > 0 and "A" <= ch <= "Z":
+            if current:
+                parts.append(current)
+            current = ch
+        else:
+            current += ch
+    if current:
+        parts.append(current)
+    return parts
+
+
+def to_pascal_case(s: str) -> str:
+    """user_id, userId and user-id all become UserId."""
+    return "".join(part[:1].upper() + part[1:].lower() for part in _split_words(s))
+
+
+def to_camel_case(s: str) -> str:
+    pascal = to_pascal_case(s)
+    return pascal[:1].lower() + pascal[1:]
+
+
+def to_snake_case(s: str) -> str:
+    return "_".join(part.lower() for part in _split_words(s))
@@ -0,0 +1,236 @@
+"""The JSON plugin protocol: requests, responses and the serve loop."""
+
+import json
+import sys
+import traceback
+from dataclasses import dataclass, field
+from typing import Any, Callable, Dict, List, Optional, Sequence, TextIO, Union
+
+from .ir import Document
+
+PROTOCOL_VERSION = 1
+
+CODE_INVALID_INPUT = "invalid_input"
+CODE_INVALID_OPTIONS = "invalid_options"
+CODE_UNSUPPORTED = "unsupported"
+CODE_INTERNAL = "internal"
+
+SEVERITY_WARNING = "warning"
+SEVERITY_INFO = "info"
+
+# JSON-RPC error codes used in worker mode.
+_RPC_PARSE_ERROR = -32700
+_RPC_METHOD_NOT_FOUND = -32601
+
+
+@dataclass
+class Location:
+    path: str = ""
+    line: int = 0
+    column: int = 0
+
+    def to_dict(self) -> Dict[str, Any]:
+        return {key: value for key, value in vars(self).items() if value}
+
+
+class PluginError(Exception):
+    """Reported to DevToolBox with its code, message and location."""
+
+    def __init__(self, code: str, message: str, location: Optional[Location] = None):
+        super().__init__(message)
+        self.code = code
+        self.message = message
+        self.location = location
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"code": self.code, "message": self.message}
+        if self.location:
+            result["location"] = self.location.to_dict()
+        return result
+
+
+@dataclass
+class File:
+    content: str
+    name: str = ""
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"content": self.content}
+        if self.name:
+            result["name"] = self.name
+        return result
+
+
+@dataclass
+class Diagnostic:
+    message: str
+    severity: str = SEVERITY_WARNING
+    location: Optional[Location] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"severity": self.severity, "message": self.message}
+        if self.location:
+            result["location"] = self.location.to_dict()
+        return result
+
+
+@dataclass
+class Request:
+    version: int = PROTOCOL_VERSION
+    input: str = ""
+    format: str = ""
+    options: Dict[str, str] = field(default_factory=dict)
+    ir: Optional[Document] = None
+    context: Dict[str, str] = field(default_factory=dict)
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Request":
+        return cls(
+            version=data.get("version", 0),
+            input=data.get("input", ""),
+            format=data.get("format", ""),
+            options=dict(data.get("options") or {}),
+            ir=Document.from_dict(data["ir"]) if data.get("ir") else None,
+            context=dict(data.get("context") or {}),
+        )
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"version": self.version, "input": self.input, "context": self.context}
+        if self.format:
+            result["format"] = self.format
+        if self.options:
+            result["options"] = self.options
+        if self.ir:
+            result["ir"] = self.ir.to_dict()
+        return result
+
+    def option(self, key: str, default: str = "") -> str:
+        """Returns an option; unset options already hold the manifest default."""
+        return self.options.get(key, default)
+
+    def bool_option(self, key: str, default: bool = False) -> bool:
+        value = self.options.get(key)
+        if value is None:
+            return default
+        if value.lower() in ("1", "t", "true"):
+            return True
+        if value.lower() in ("0", "f", "false"):
+            return False
+        raise PluginError(CODE_INVALID_OPTIONS, f"option {key}: expected boolean, got {value!r}")
+
+    def int_option(self, key: str, default: int = 0) -> int:
+        value = self.options.get(key)
+        if value is None:
+            return default
+        try:
+            return int(value)
+        except ValueError:
+            raise PluginError(CODE_INVALID_OPTIONS, f"option {key}: expected integer, got {value!r}") from None
+
+
+@dataclass
+class Response:
+    files: List[File] = field(default_factory=list)
+    diagnostics: List[Diagnostic] = field(default_factory=list)
+
+    def output(self) -> str:
+        """Joins the contents of all files, like DevToolBox does."""
+        return "\n".join(f.content for f in self.files)
+
+    def warn(self, message: str, path: str = "") -> None:
+        self.diagnostics.append(Diagnostic(message, SEVERITY_WARNING, Location(path) if path else None))
+
+    def to_dict(self) -> Dict[str, Any]:
+        result = {"version": PROTOCOL_VERSION, "files": [f.to_dict() for f in self.files]}
+        if self.diagnostics:
+            result["diagnostics"] = [d.to_dict() for d in self.diagnostics]
+        return result
+
+
+Result = Union[Response, str, File, Sequence[File]]
+Handler = Callable[[Request], Result]
+
+
+def _error(error: PluginError) -> Dict[str, Any]:
+    return {"version": PROTOCOL_VERSION, "error": error.to_dict()}
+
+
+def _to_response(result: Result) -> Response:
+    if isinstance(result, Response):
+        return result
+    if isinstance(result, str):
+        return Response(files=[File(result)])
+    if isinstance(result, File):
+        return Response(files=[result])
+    return Response(files=list(result))
+
+
+def handle(handler: Handler, message: Dict[str, Any]) -> Dict[str, Any]:
+    """Answers one request given as a decoded JSON document.
+
+    The handler may return a Response, a string, a File or a list of files.
+    A PluginError is reported with its code; other exceptions become
+    internal errors carrying the traceback in their message.
+    """
+    if message.get("version") != PROTOCOL_VERSION:
+        return _error(PluginError(
+            CODE_UNSUPPORTED,
+            f"unsupported protocol version {message.get('version')}, expected {PROTOCOL_VERSION}",
+        ))
+    try:
+        return _to_response(handler(Request.from_dict(message))).to_dict()
+    except PluginError as e:
+        return _error(e)
+    except Exception as e:
+        return _error(PluginError(CODE_INTERNAL, f"{e}\n{traceback.format_exc()}".strip()))
+
+
+def _serve_worker(handler: Handler, stdin: TextIO, stdout: TextIO) -> None:
+    for line in stdin:
+        if not line.strip():
+            continue
+        try:
+            message = json.loads(line)
+        except ValueError as e:
+            reply = {"jsonrpc": "2.0", "id": None, "error": {"code": _RPC_PARSE_ERROR, "message": str(e)}}
+            stdout.write(json.dumps(reply) + "\n")
+            stdout.flush()
+            continue
+
+        method = message.get("method")
+        if method == "shutdown":
+            return
+        if "id" not in message:
+            continue
+
+        if method == "generate":
+            reply = {"result": handle(handler, message.get("params") or {})}
+        elif method == "ping":
+            reply = {"result": {"status": "ok"}}
+        else:
+            reply = {"error": {"code": _RPC_METHOD_NOT_FOUND, "message": f"method not found: {method}"}}
+        reply.update({"jsonrpc": "2.0", "id": message["id"]})
+        stdout.write(json.dumps(reply) + "\n")
+        stdout.flush()
+
+
+def serve(handler: Handler, argv: Optional[List[str]] = None,
+          stdin: Optional[TextIO] = None, stdout: Optional[TextIO] = None) -> None:
+    """Runs the plugin: answers one request from stdin, or JSON-RPC requests
+    when started with --worker, so the manifest may enable worker mode."""
+    argv = sys.argv[1:] if argv is None else argv
+    stdin = stdin or sys.stdin
+    stdout = stdout or sys.stdout
+
+    if "--worker" in argv:
+        _serve_worker(handler, stdin, stdout)
+        return
+
+    try:
+        message = json.load(stdin)
+    except ValueError as e:
+        reply = _error(PluginError(CODE_INVALID_INPUT, f"invalid request: {e}"))
+    else:
+        reply = handle(handler, message)
+    stdout.write(json.dumps(reply) + "\n")
+    stdout.flush()
@@ -0,0 +1,100 @@
+"""Helpers for testing plugins with pytest.
+
+    from devtoolbox_sdk.testing import invoke, golden, make_request
+
+    def test_generate():
+        response = invoke(generate, make_request(models=[...]))
+        golden("testdata/user.kt", response.output())
+
+run_plugin starts the plugin script as DevToolBox does, to check the
+protocol end to end.
+"""
+
+import json
+import os
+import subprocess
+import sys
+from typing import Any, Dict, List, Optional, Sequence
+
+from . import __version__
+from .ir import Document, Model
+from .protocol import PROTOCOL_VERSION, PluginError, Request, Response, File, Diagnostic, Location, handle
+
+UPDATE_ENV = "DEVTOOLBOX_UPDATE_GOLDEN"
+
+
+def make_request(models: Optional[Sequence[Model]] = None, input: str = "",
+                 options: Optional[Dict[str, str]] = None, format: str = "json",
+                 generator: str = "test") -> Request:
+    """Builds a request like the ones DevToolBox sends."""
+    return Request(
+        input=input,
+        format=format,
+        options=dict(options or {}),
+        ir=Document(models=list(models or []), format=format),
+        context={"generator": generator, "devtoolbox_version": __version__},
+    )
+
+
+def _response(reply: Dict[str, Any]) -> Response:
+    if reply.get("error"):
+        error = reply["error"]
+        location = Location(**error["location"]) if error.get("location") else None
+        raise PluginError(error["code"], error["message"], location)
+    return Response(
+        files=[File(f["content"], f.get("name", "")) for f in reply.get("files") or []],
+        diagnostics=[
+            Diagnostic(d["message"], d["severity"], Location(**d["location"]) if d.get("location") else None)
+            for d in reply.get("diagnostics") or []
+        ],
+    )
+
+
+def invoke(handler, request: Request) -> Response:
+    """Runs the handler through the protocol and raises PluginError on errors."""
+    return _response(handle(handler, json.loads(json.dumps(request.to_dict()))))
+
+
+def run_plugin(script: str, request: Request, worker: bool = False,
+               python: Optional[str] = None, timeout: float = 30) -> Response:
+    """Runs a plugin script in a separate process, once or as a worker."""
+    args: List[str] = [python or sys.executable, script]
+    message = request.to_dict()
+    if worker:
+        args.append("--worker")
+        stdin = json.dumps({"jsonrpc": "2.0", "id": 1, "method": "generate", "params": message}) + "\n"
+    else:
+        stdin = json.dumps(message)
+
+    env = dict(os.environ)
+    sdk_path = os.path.dirname(os.path.dirname(os.path.abspath(__file__)))
+    env["PYTHONPATH"] = os.pathsep.join(p for p in (sdk_path, env.get("PYTHONPATH")) if p)
+
+    process = subprocess.run(args, input=stdin, capture_output=True, text=True, timeout=timeout, env=env)
+    if process.returncode != 0 and not process.stdout.strip():
+        raise RuntimeError(f"plugin exited with code {process.returncode}: {process.stderr.strip()}")
+
+    reply = json.loads(process.stdout.splitlines()[0])
+    if worker:
+        if "error" in reply:
+            raise RuntimeError(f"worker error: {reply['error']}")
+        reply = reply["result"]
+    if reply.get("version") != PROTOCOL_VERSION:
+        raise RuntimeError(f"unexpected protocol version in {reply}")
+    return _response(reply)
+
+
+def golden(path: str, content: str) -> None:
+    """Compares content with the file at path; with DEVTOOLBOX_UPDATE_GOLDEN=1
+    the file is written instead."""
+    if os.environ.get(UPDATE_ENV):
+        os.makedirs(os.path.dirname(path) or ".", exist_ok=True)
+        with open(path, "w", encoding="utf-8") as f:
+            f.write(content)
+        return
+
+    if not os.path.exists(path):
+        raise AssertionError(f"golden file {path} is missing (run with {UPDATE_ENV}=1 to create it)")
+    with open(path, encoding="utf-8") as f:
+        expected = f.read()
+    assert content == expected, f"output does not match {path} (run with {UPDATE_ENV}=1 to update)"
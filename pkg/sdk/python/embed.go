@@ -0,0 +1,14 @@
+// Package python embeds the Python plugin SDK, the devtoolbox_sdk package,
+// which DevToolBox puts on PYTHONPATH for Python plugins. It can also be
+// installed with pip from this directory.
+package python
+
+import "embed"
+
+// Package is the name of the importable Python package.
+const Package = "devtoolbox_sdk"
+
+// FS holds the Python package under Package.
+//
+//go:embed devtoolbox_sdk/*.py
+var FS embed.FS
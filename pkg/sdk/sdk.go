@@ -0,0 +1,210 @@
+// Package sdk is the Go SDK for DevToolBox plugins. A plugin is a separate
+// binary that speaks the JSON plugin protocol; with Serve its main is a few
+// lines:
+//
+//	func main() {
+//		sdk.Serve(sdk.HandlerFunc(func(ctx context.Context, request *sdk.Request) (*sdk.Response, error) {
+//			return sdk.Files(sdk.File{Content: render(request.IR)}), nil
+//		}))
+//	}
+//
+// Serve handles both one-shot calls and worker mode, so the manifest may
+// enable worker: without further changes. Existing generators written against
+// the CodeGenerator interface can be served unchanged with FromGenerator.
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// ProtocolVersion is the version of the plugin protocol implemented by Serve.
+const ProtocolVersion = plugins.ProtocolVersion
+
+// Generator interfaces shared with the built-in generators.
+type (
+	CodeGenerator     = core.CodeGenerator
+	DocumentGenerator = core.DocumentGenerator
+	FileGenerator     = core.FileGenerator
+	GeneratedFile     = core.GeneratedFile
+)
+
+// Type model sent to plugins as request.IR.
+type (
+	Document = ir.Document
+	Model    = ir.Model
+	Type     = ir.Type
+	Field    = ir.Field
+	Kind     = ir.Kind
+)
+
+const (
+	KindAny     = ir.KindAny
+	KindString  = ir.KindString
+	KindInteger = ir.KindInteger
+	KindNumber  = ir.KindNumber
+	KindBoolean = ir.KindBoolean
+	KindTime    = ir.KindTime
+	KindObject  = ir.KindObject
+	KindArray   = ir.KindArray
+	KindMap     = ir.KindMap
+	KindRef     = ir.KindRef
+)
+
+// Protocol messages.
+type (
+	Request        = plugins.Request
+	RequestContext = plugins.RequestContext
+	Response       = plugins.Response
+	File           = plugins.File
+	Diagnostic     = plugins.Diagnostic
+	Location       = plugins.Location
+	ErrorCode      = plugins.ErrorCode
+	Error          = plugins.PluginError
+)
+
+const (
+	CodeInvalidInput   = plugins.CodeInvalidInput
+	CodeInvalidOptions = plugins.CodeInvalidOptions
+	CodeUnsupported    = plugins.CodeUnsupported
+	CodeInternal       = plugins.CodeInternal
+
+	SeverityWarning = plugins.SeverityWarning
+	SeverityInfo    = plugins.SeverityInfo
+)
+
+// Handler answers one generate request.
+type Handler interface {
+	Handle(ctx context.Context, request *Request) (*Response, error)
+}
+
+type HandlerFunc func(ctx context.Context, request *Request) (*Response, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, request *Request) (*Response, error) {
+	return f(ctx, request)
+}
+
+// FromGenerator serves a generator written like the built-in ones. Request
+// options are applied through Configure; document generators get the type
+// model, others the raw input.
+func FromGenerator(generator CodeGenerator) Handler {
+	return HandlerFunc(func(ctx context.Context, request *Request) (*Response, error) {
+		configured, err := core.Configure(generator, request.Options)
+		if err != nil {
+			return nil, Errorf(CodeInvalidOptions, "%v", err)
+		}
+
+		if request.IR != nil {
+			if document, ok := configured.(DocumentGenerator); ok {
+				code, err := document.GenerateDocument(request.IR)
+				if err != nil {
+					return nil, Errorf(CodeInvalidInput, "%v", err)
+				}
+				return Files(File{Content: code}), nil
+			}
+			if files, ok := configured.(FileGenerator); ok {
+				generated, err := files.GenerateFiles(request.IR)
+				if err != nil {
+					return nil, Errorf(CodeInvalidInput, "%v", err)
+				}
+				response := Files()
+				for _, file := range generated {
+					response.Files = append(response.Files, File{Name: file.Name, Content: file.Content})
+				}
+				return response, nil
+			}
+		}
+
+		code, err := configured.Generate(request.Input)
+		if err != nil {
+			return nil, Errorf(CodeInvalidInput, "%v", err)
+		}
+		return Files(File{Content: code}), nil
+	})
+}
+
+// Files builds a successful response.
+func Files(files ...File) *Response {
+	return &Response{Version: ProtocolVersion, Files: files}
+}
+
+// Errorf returns an error that is reported to DevToolBox with the given code.
+func Errorf(code ErrorCode, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Options are the generator options of a request. Values not set by the user
+// already hold the defaults from the manifest.
+type Options map[string]string
+
+// String returns the option, or fallback if it is not set.
+func (o Options) String(key, fallback string) string {
+	if value, ok := o[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+// Bool returns the option as a boolean, or fallback if it is not set.
+func (o Options) Bool(key string, fallback bool) (bool, error) {
+	value, ok := o[key]
+	if !ok {
+		return fallback, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, Errorf(CodeInvalidOptions, "option %s: expected boolean, got %q", key, value)
+	}
+	return result, nil
+}
+
+// Int returns the option as an integer, or fallback if it is not set.
+func (o Options) Int(key string, fallback int) (int, error) {
+	value, ok := o[key]
+	if !ok {
+		return fallback, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, Errorf(CodeInvalidOptions, "option %s: expected integer, got %q", key, value)
+	}
+	return result, nil
+}
+
+// Serve runs the plugin: it answers one request from stdin, or serves
+// JSON-RPC requests when started with --worker, and then exits.
+func Serve(handler Handler) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker := false
+	for _, arg := range os.Args[1:] {
+		if arg == "--worker" {
+			worker = true
+		}
+	}
+
+	if err := ServeIO(ctx, handler, worker, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// ServeIO is Serve with explicit streams, for tests and embedding.
+func ServeIO(ctx context.Context, handler Handler, worker bool, in io.Reader, out io.Writer) error {
+	handle := plugins.Handler(handler.Handle)
+	if worker {
+		return plugins.ServeWorker(ctx, handle, in, out)
+	}
+	return plugins.ServeOnce(ctx, handle, in, out)
+}
@@ -0,0 +1,85 @@
+// Package sdktest helps testing plugins written with the Go SDK: it builds
+// requests exactly as DevToolBox does and compares output with golden files.
+package sdktest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/version"
+	"github.com/JIIL07/devtoolbox/pkg/sdk"
+)
+
+// UpdateEnv makes Golden rewrite golden files instead of comparing them.
+const UpdateEnv = "DEVTOOLBOX_UPDATE_GOLDEN"
+
+// NewRequest parses input into the type model and builds the request the
+// plugin would receive. An empty format detects it from the input.
+func NewRequest(name, input, format string, options map[string]string) (*sdk.Request, error) {
+	inputFormat, err := core.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if inputFormat == core.FormatAuto {
+		inputFormat = core.DetectFormat(input)
+	}
+
+	doc, err := core.ParseInput(input, inputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sdk.Request{
+		Version: sdk.ProtocolVersion,
+		Input:   input,
+		Format:  string(inputFormat),
+		Options: options,
+		IR:      doc,
+		Context: sdk.RequestContext{Generator: name, DevToolBoxVersion: version.Version},
+	}, nil
+}
+
+// Run sends input to the handler and fails the test if the request cannot be
+// built or the handler returns an error.
+func Run(t testing.TB, handler sdk.Handler, input string, options map[string]string) *sdk.Response {
+	t.Helper()
+	request, err := NewRequest(t.Name(), input, "", options)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	response, err := handler.Handle(context.Background(), request)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if response == nil || len(response.Files) == 0 {
+		t.Fatal("handler returned no files")
+	}
+	return response
+}
+
+// Golden compares content with the file at path. With DEVTOOLBOX_UPDATE_GOLDEN=1
+// the file is written instead.
+func Golden(t testing.TB, path, content string) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if string(expected) != content {
+		t.Errorf("output does not match %s (run with %s=1 to update)\n--- expected\n%s\n--- got\n%s", path, UpdateEnv, expected, content)
+	}
+}
@@ -0,0 +1,22 @@
+// Command json_schema is an example Go plugin written with the plugin SDK.
+// DevToolBox builds it on devtoolbox plugin add plugins/custom/json_schema.
+package main
+
+import (
+	"context"
+
+	"github.com/JIIL07/devtoolbox/pkg/sdk"
+)
+
+func main() {
+	sdk.Serve(sdk.HandlerFunc(func(ctx context.Context, request *sdk.Request) (*sdk.Response, error) {
+		if request.IR == nil {
+			return nil, sdk.Errorf(sdk.CodeInvalidInput, "expected a request with a type model")
+		}
+		content, err := render(sdk.Options(request.Options).String("title", "Data Model"), request.IR)
+		if err != nil {
+			return nil, err
+		}
+		return sdk.Files(sdk.File{Content: content}), nil
+	}))
+}
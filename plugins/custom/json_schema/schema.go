@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/JIIL07/devtoolbox/pkg/sdk"
+)
+
+// render describes every model of the document under $defs. The first model
+// is the root of the schema.
+func render(title string, doc *sdk.Document) (string, error) {
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   title,
+	}
+	defs := make(map[string]interface{}, len(doc.Models))
+	for _, model := range doc.Models {
+		def := schemaOf(model.Type)
+		if model.Description != "" {
+			def["description"] = model.Description
+		}
+		defs[model.Name] = def
+	}
+	if len(doc.Models) > 0 {
+		schema["$ref"] = "#/$defs/" + doc.Models[0].Name
+		schema["$defs"] = defs
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+func schemaOf(t *sdk.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	schema := map[string]interface{}{}
+	switch t.Kind {
+	case sdk.KindString, sdk.KindInteger, sdk.KindNumber, sdk.KindBoolean:
+		schema["type"] = string(t.Kind)
+		if t.Format != "" {
+			schema["format"] = t.Format
+		}
+	case sdk.KindTime:
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	case sdk.KindRef:
+		schema["$ref"] = "#/$defs/" + t.Ref
+	case sdk.KindArray:
+		schema["type"] = "array"
+		schema["items"] = schemaOf(t.Elem)
+	case sdk.KindMap:
+		schema["type"] = "object"
+		schema["additionalProperties"] = schemaOf(t.Elem)
+	case sdk.KindObject:
+		properties := make(map[string]interface{}, len(t.Fields))
+		var required []string
+		for _, field := range t.Fields {
+			property := schemaOf(field.Type)
+			if field.Description != "" {
+				property["description"] = field.Description
+			}
+			properties[field.Name] = property
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	if len(t.Enum) > 0 {
+		schema["enum"] = t.Enum
+	}
+
+	if t.Nullable {
+		return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+	}
+	return schema
+}
@@ -0,0 +1,75 @@
+from devtoolbox_sdk import (
+    CODE_INVALID_INPUT,
+    KIND_ANY,
+    KIND_ARRAY,
+    KIND_MAP,
+    KIND_OBJECT,
+    KIND_REF,
+    File,
+    PluginError,
+    Response,
+    serve,
+    to_camel_case,
+)
+
+SCALARS = {
+    "string": "String",
+    "integer": "Long",
+    "number": "Double",
+    "boolean": "Boolean",
+    "time": "String",
+    "any": "Any",
+}
+
+
+def kotlin_type(t, response, path):
+    if t.kind == KIND_ARRAY:
+        result = f"List<{kotlin_type(t.elem, response, path + '[]')}>"
+    elif t.kind == KIND_MAP:
+        result = f"Map<String, {kotlin_type(t.elem, response, path + '{}')}>"
+    elif t.kind == KIND_REF:
+        result = t.ref
+    elif t.kind == KIND_OBJECT:
+        result = "Map<String, Any>"
+    else:
+        if t.kind == KIND_ANY:
+            response.warn("type is unknown, using Any", path)
+        result = SCALARS.get(t.kind, "Any")
+    if t.nullable:
+        result += "?"
+    return result
+
+
+def data_class(model, response):
+    if model.type.kind != KIND_OBJECT:
+        return f"typealias {model.name} = {kotlin_type(model.type, response, model.name)}"
+
+    lines = [f"data class {model.name}("]
+    for field in model.type.fields:
+        field_type = kotlin_type(field.type, response, f"{model.name}.{field.name}")
+        if not field.required and not field_type.endswith("?"):
+            field_type += "?"
+        default = " = null" if field_type.endswith("?") else ""
+        lines.append(f"    val {to_camel_case(field.name) or 'field'}: {field_type}{default},")
+    lines.append(")")
+    return "\n".join(lines)
+
+
+def generate(request):
+    if request.ir is None:
+        raise PluginError(CODE_INVALID_INPUT, "request has no type model")
+
+    package = request.option("package")
+    header = f"package {package}\n\n" if package else ""
+    response = Response()
+
+    classes = [(m.name, data_class(m, response)) for m in request.ir.models]
+    if request.bool_option("files"):
+        response.files = [File(header + code + "\n", f"{name}.kt") for name, code in classes]
+    else:
+        response.files = [File(header + "\n\n".join(code for _, code in classes))]
+    return response
+
+
+if __name__ == "__main__":
+    serve(generate)
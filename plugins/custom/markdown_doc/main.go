@@ -0,0 +1,92 @@
+//go:build wasip1
+
+// Command markdown_doc is an example WebAssembly plugin. Build it with
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o plugins/custom/markdown_doc/markdown_doc.wasm ./plugins/custom/markdown_doc
+//
+// and add it with devtoolbox plugin add plugins/custom/markdown_doc.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+type request struct {
+	Options map[string]string `json:"options"`
+	IR      *ir.Document      `json:"ir"`
+}
+
+type file struct {
+	Content string `json:"content"`
+}
+
+type response struct {
+	Version int               `json:"version"`
+	Files   []file            `json:"files,omitempty"`
+	Error   map[string]string `json:"error,omitempty"`
+}
+
+func main() {
+	var req request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil || req.IR == nil {
+		reply(response{Version: 1, Error: map[string]string{"code": "invalid_input", "message": "expected a request with a type model"}})
+		os.Exit(1)
+	}
+	reply(response{Version: 1, Files: []file{{Content: render(req.Options["title"], req.IR)}}})
+}
+
+func reply(resp response) {
+	json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+func render(title string, doc *ir.Document) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n", title)
+	for _, model := range doc.Models {
+		fmt.Fprintf(&builder, "\n## %s\n\n", model.Name)
+		if model.Description != "" {
+			fmt.Fprintf(&builder, "%s\n\n", model.Description)
+		}
+		if model.Type == nil || model.Type.Kind != ir.KindObject {
+			fmt.Fprintf(&builder, "Alias of `%s`.\n", typeName(model.Type))
+			continue
+		}
+		builder.WriteString("| Field | Type | Required | Description |\n")
+		builder.WriteString("|---|---|---|---|\n")
+		for _, field := range model.Type.Fields {
+			required := ""
+			if field.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(&builder, "| %s | `%s` | %s | %s |\n", field.Name, typeName(field.Type), required, field.Description)
+		}
+	}
+	return builder.String()
+}
+
+func typeName(t *ir.Type) string {
+	if t == nil {
+		return string(ir.KindAny)
+	}
+	name := string(t.Kind)
+	switch t.Kind {
+	case ir.KindRef:
+		name = t.Ref
+	case ir.KindArray:
+		name = typeName(t.Elem) + "[]"
+	case ir.KindMap:
+		name = "map<string, " + typeName(t.Elem) + ">"
+	}
+	if t.Format != "" && t.Kind != ir.KindRef {
+		name += " (" + t.Format + ")"
+	}
+	if t.Nullable {
+		name += "?"
+	}
+	return name
+}
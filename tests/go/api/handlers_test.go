@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/JIIL07/devtoolbox/internal/api"
+	"github.com/JIIL07/devtoolbox/internal/config"
 	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
 )
 
 func TestHandler_Generate(t *testing.T) {
@@ -116,6 +122,94 @@ func TestHandler_ListGenerators(t *testing.T) {
 	}
 }
 
+func TestHandler_ListGeneratorsManifest(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	plugin := plugins.NewPythonPlugin("kotlin-gen", "Kotlin data classes", "gen.py")
+	plugin.SetManifest(&plugins.Manifest{
+		Name:            "kotlin-gen",
+		Version:         "1.0.0",
+		Author:          "Jane",
+		Language:        "python",
+		InputFormats:    []string{"json"},
+		OutputExtension: ".kt",
+	})
+	registry.Register(plugin)
+	handler := api.NewHandler(registry)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/generators", handler.ListGenerators)
+
+	req, _ := http.NewRequest("GET", "/generators", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response api.ListGeneratorsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	for _, gen := range response.Generators {
+		if gen.Name != "kotlin-gen" {
+			continue
+		}
+		if gen.Version != "1.0.0" || gen.Author != "Jane" || gen.OutputExtension != ".kt" || len(gen.InputFormats) != 1 {
+			t.Errorf("expected manifest metadata, got %+v", gen)
+		}
+		return
+	}
+	t.Error("expected kotlin-gen generator in list")
+}
+
+func TestHandler_Pipeline(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	pipeline, err := core.NewPipeline(registry, "api-models", config.Pipeline{Steps: []config.Step{
+		{Processor: "redact", Options: map[string]string{"fields": "password"}},
+		{Generator: "go-struct"},
+		{Processor: "gofmt"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registry.Register(pipeline)
+	handler := api.NewHandler(registry)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/generate", handler.Generate)
+	router.GET("/generators", handler.ListGenerators)
+
+	body, _ := json.Marshal(api.GenerateRequest{
+		Template: "api-models",
+		Input:    `{"name": "Ada", "password": "secret"}`,
+		Options:  map[string]string{"package": "api"},
+	})
+	req, _ := http.NewRequest("POST", "/generate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var generated api.GenerateResponse
+	json.Unmarshal(w.Body.Bytes(), &generated)
+	if w.Code != http.StatusOK || !strings.HasPrefix(generated.Code, "package api\n") || strings.Contains(generated.Code, "Password") {
+		t.Errorf("unexpected response %d: %+v", w.Code, generated)
+	}
+
+	req, _ = http.NewRequest("GET", "/generators", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var listing api.ListGeneratorsResponse
+	json.Unmarshal(w.Body.Bytes(), &listing)
+	for _, gen := range listing.Generators {
+		if gen.Name == "api-models" {
+			if strings.Join(gen.Pipeline, " ") != "redact go-struct gofmt" {
+				t.Errorf("expected the pipeline steps, got %+v", gen)
+			}
+			return
+		}
+	}
+	t.Error("expected api-models in list")
+}
+
 func TestHandler_Health(t *testing.T) {
 	registry := core.NewGeneratorRegistry()
 	handler := api.NewHandler(registry)
@@ -132,3 +226,140 @@ func TestHandler_Health(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
 	}
 }
+
+func TestHandler_Reload(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	t.Setenv(plugins.PluginPathEnv, dir)
+
+	reloader := core.NewReloader(core.NewGeneratorRegistry(), core.LoadGeneratorRegistry)
+	defer reloader.Close()
+	handler := api.NewReloadableHandler(reloader)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/reload", api.AdminMiddleware(), handler.Reload)
+
+	reload := func(remoteAddr, authorization string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/reload", nil)
+		req.RemoteAddr = remoteAddr
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := reload("192.0.2.1:1234", ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected remote reload to be forbidden, got %d", w.Code)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "kotlin_gen.py"), []byte("print('kotlin')\n"), 0644); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	w := reload("127.0.0.1:1234", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response api.ReloadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Added) != 1 || response.Added[0] != "kotlin_gen" {
+		t.Errorf("Expected kotlin_gen to be added, got %+v", response)
+	}
+
+	t.Setenv(api.AdminTokenEnv, "secret")
+	if w := reload("127.0.0.1:1234", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong token to be rejected, got %d", w.Code)
+	}
+	if w := reload("192.0.2.1:1234", "secret"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a token without the Bearer prefix to be rejected, got %d", w.Code)
+	}
+	if w := reload("192.0.2.1:1234", "Bearer secret"); w.Code != http.StatusOK {
+		t.Errorf("Expected the token to allow remote reload, got %d", w.Code)
+	}
+}
+
+func TestHandler_ListGeneratorsETag(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		plugin := plugins.NewPythonPlugin("kotlin-gen", "Kotlin data classes", "gen.py")
+		plugin.SetManifest(&plugins.Manifest{Name: "kotlin-gen", Version: version})
+		registry.Register(plugin)
+	}
+	registry.Alias("kt", "kotlin-gen")
+	handler := api.NewHandler(registry)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/generators", handler.ListGenerators)
+
+	list := func(etag string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/generators", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := list("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", w.Code, etag)
+	}
+	var response api.ListGeneratorsResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	names := make([]string, len(response.Generators))
+	for i, gen := range response.Generators {
+		names[i] = gen.Name
+		if gen.Name == "kotlin-gen" && (gen.Version != "1.0.0" || len(gen.Versions) != 1 || gen.Versions[0] != "2.0.0" || len(gen.Aliases) != 1 || gen.Aliases[0] != "kt") {
+			t.Errorf("expected versions and aliases, got %+v", gen)
+		}
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("expected generators sorted by name, got %v", names)
+	}
+
+	if w := list(etag); w.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	registry.Alias("kotlin", "kotlin-gen")
+	w = list(etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected a new listing after a change, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestCORSMiddleware_SkipsAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(api.CORSMiddleware())
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/admin/reload", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Origin", "https://example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("GET", "/health"); w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected CORS headers on /health, got %v", w.Header())
+	}
+	for _, method := range []string{"POST", "OPTIONS"} {
+		w := request(method, "/admin/reload")
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+			t.Errorf("Expected no CORS headers on %s /admin/reload, got %q", method, origin)
+		}
+		if method == "OPTIONS" && w.Code == http.StatusNoContent {
+			t.Errorf("Expected the admin preflight not to be answered")
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// project creates a user and a project configuration and changes into a
+// subdirectory of the project.
+func project(t *testing.T, user, project string) (string, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	userPath := filepath.Join(home, ".devtoolbox", "config.yaml")
+	if user != "" {
+		writeFile(t, userPath, user)
+	}
+
+	root := t.TempDir()
+	projectPath := filepath.Join(root, ".devtoolbox.yaml")
+	writeFile(t, projectPath, project)
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chdir(t, filepath.Join(root, "src"))
+	return userPath, projectPath
+}
+
+func versioned(name, version, scope string) plugins.PluginInfo {
+	return plugins.PluginInfo{
+		Name:     name,
+		Path:     filepath.Join("/plugins", scope, name),
+		Scope:    scope,
+		Manifest: &plugins.Manifest{Name: name, Version: version},
+	}
+}
+
+func TestLoad_ProjectOverridesUser(t *testing.T) {
+	userPath, projectPath := project(t, `
+plugins:
+  disabled: [legacy-gen]
+  enabled: [kotlin-data]
+options:
+  go-struct:
+    package: models
+    db: true
+`, `
+plugins:
+  default: disabled
+  enabled: [legacy-gen]
+options:
+  go-struct:
+    package: api
+`)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Layers) != 2 || cfg.Layers[1].Scope != config.ScopeProject {
+		t.Fatalf("expected user and project layers, got %+v", cfg.Layers)
+	}
+
+	for name, expected := range map[string]struct {
+		enabled bool
+		source  string
+	}{
+		"legacy-gen":  {true, projectPath},
+		"kotlin-data": {false, projectPath},
+	} {
+		enabled, source := cfg.PluginEnabled(name)
+		if enabled != expected.enabled || source != expected.source {
+			t.Errorf("%s: expected %v from %s, got %v from %s", name, expected.enabled, expected.source, enabled, source)
+		}
+	}
+	if state := cfg.DefaultState(); state.Value != "disabled" || state.Source != projectPath {
+		t.Errorf("unexpected default state %+v", state)
+	}
+
+	options := cfg.Options("go-struct")
+	if options["package"] != (config.Value{Value: "api", Source: projectPath}) {
+		t.Errorf("expected package from the project, got %+v", options["package"])
+	}
+	if options["db"] != (config.Value{Value: "true", Source: userPath}) {
+		t.Errorf("expected db from the user config, got %+v", options["db"])
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"state":   "plugins:\n  default: maybe\n",
+		"both":    "plugins:\n  enabled: [a]\n  disabled: [a]\n",
+		"version": "plugins:\n  versions:\n    a: \"~1.2\"\n",
+		"syntax":  "plugins: [\n",
+		"options": "options:\n  go-struct: [package]\n",
+		"operand": "plugins:\n  versions:\n    a: \">=1.0 <=two\"\n",
+		"alias":   "aliases:\n  ts@1: ts_interface_gen\n",
+		"step":    "pipelines:\n  p:\n    steps:\n      - generator: go-struct\n        processor: gofmt\n",
+		"no gen":  "pipelines:\n  p:\n    steps:\n      - processor: gofmt\n",
+		"format":  "pipelines:\n  p:\n    steps:\n      - generator: go-struct\n      - processor: gofmt\n        format: json\n",
+		"name":    "pipelines:\n  p@2:\n    steps:\n      - generator: go-struct\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			project(t, "", content)
+			if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), ".devtoolbox.yaml") {
+				t.Errorf("expected an error naming the file, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	userPath, projectPath := project(t, `
+pipelines:
+  models:
+    steps:
+      - generator: go-struct
+  tables:
+    steps:
+      - generator: sql-postgres
+`, `
+pipelines:
+  models:
+    description: Formatted models
+    steps:
+      - processor: redact
+        options: {fields: password}
+      - generator: go-struct
+      - processor: gofmt
+`)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pipelines := cfg.Pipelines()
+	if len(pipelines) != 2 || pipelines["tables"].Source != userPath {
+		t.Fatalf("unexpected pipelines %+v", pipelines)
+	}
+	models := pipelines["models"]
+	if models.Source != projectPath || len(models.Pipeline.Steps) != 3 {
+		t.Fatalf("the project should replace the whole pipeline, got %+v", models)
+	}
+	if step := models.Pipeline.Steps[0]; step.Name() != "redact" || step.Options["fields"] != "password" {
+		t.Errorf("unexpected step %+v", step)
+	}
+}
+
+func TestApply(t *testing.T) {
+	_, projectPath := project(t, "", `
+plugins:
+  disabled: [legacy-gen]
+  enabled: [missing-gen]
+  versions:
+    kotlin-data: ">=1.2.0 <2.0.0"
+    swift-gen: ">=3.0"
+`)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	discovery := &plugins.Discovery{
+		Plugins: []plugins.PluginInfo{
+			versioned("kotlin-data", "2.1.0", plugins.ScopeProject),
+			versioned("legacy-gen", "1.0.0", plugins.ScopeUser),
+			versioned("swift-gen", "1.0.0", plugins.ScopeUser),
+		},
+		Conflicts: []plugins.Conflict{{
+			Name: "kotlin-data",
+			Used: versioned("kotlin-data", "2.1.0", plugins.ScopeProject),
+			Shadowed: []plugins.PluginInfo{
+				versioned("kotlin-data", "1.4.0", plugins.ScopeUser),
+				versioned("kotlin-data", "1.3.0", plugins.ScopeSystem),
+			},
+		}, {
+			Name:     "legacy-gen",
+			Used:     versioned("legacy-gen", "1.0.0", plugins.ScopeUser),
+			Shadowed: []plugins.PluginInfo{versioned("legacy-gen", "0.9.0", plugins.ScopeSystem)},
+		}},
+	}
+	disabled := cfg.Apply(discovery, []string{"go-struct"})
+
+	if disabled["legacy-gen"] != projectPath || len(disabled) != 1 {
+		t.Errorf("expected legacy-gen to be disabled by %s, got %v", projectPath, disabled)
+	}
+	if len(discovery.Plugins) != 1 || discovery.Plugins[0].Manifest.Version != "1.4.0" {
+		t.Fatalf("expected only kotlin-data 1.4.0, got %+v", discovery.Plugins)
+	}
+
+	if len(discovery.Conflicts) != 1 {
+		t.Fatalf("expected the conflicts of unused plugins to be dropped, got %+v", discovery.Conflicts)
+	}
+	conflict := discovery.Conflicts[0]
+	if conflict.Used.Manifest.Version != "1.4.0" || len(conflict.Shadowed) != 2 || conflict.Shadowed[0].Manifest.Version != "2.1.0" {
+		t.Errorf("expected the conflict to use 1.4.0 and shadow 2.1.0 and 1.3.0, got %s", conflict)
+	}
+
+	var messages []string
+	for _, err := range discovery.Errors {
+		messages = append(messages, err.Error())
+	}
+	joined := strings.Join(messages, "\n")
+	if len(messages) != 2 || !strings.Contains(joined, "swift-gen: no version matches >=3.0") || !strings.Contains(joined, "missing-gen enabled by") {
+		t.Errorf("unexpected errors:\n%s", joined)
+	}
+}
+
+func TestSetPluginEnabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".devtoolbox.yaml")
+	writeFile(t, path, `# project settings
+plugins:
+  enabled: [kotlin-data, legacy-gen] # used by the mobile apps
+options:
+  go-struct:
+    package: api
+`)
+
+	if err := config.SetPluginEnabled(path, "legacy-gen", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := config.SetPluginEnabled(path, "kotlin-data", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"# project settings", "# used by the mobile apps", "package: api"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %q to be kept:\n%s", expected, data)
+		}
+	}
+
+	file, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(file.Plugins.Enabled, ",") != "kotlin-data" || strings.Join(file.Plugins.Disabled, ",") != "legacy-gen" {
+		t.Errorf("unexpected plugin lists %+v", file.Plugins)
+	}
+
+	if err := config.SetPluginEnabled(path, "kotlin-data", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if file, _ := config.LoadFile(path); len(file.Plugins.Enabled) != 0 || len(file.Plugins.Disabled) != 2 {
+		t.Errorf("expected both plugins to be disabled, got %+v", file.Plugins)
+	}
+}
+
+func TestSetPluginEnabled_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "home", ".devtoolbox", "config.yaml")
+	if err := config.SetPluginEnabled(path, "kotlin-data", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file, err := config.LoadFile(path)
+	if err != nil || file == nil {
+		t.Fatalf("expected a config file, got %v", err)
+	}
+	if strings.Join(file.Plugins.Disabled, ",") != "kotlin-data" {
+		t.Errorf("unexpected plugin lists %+v", file.Plugins)
+	}
+}
@@ -0,0 +1,218 @@
+package conformance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/conformance"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// jsonScript answers requests with a type model and rejects the others, like
+// a plugin that validates its input.
+const jsonScript = `#!/bin/sh
+request=$(cat)
+case "$request" in
+*'"ir"'*) printf '{"version":1,"files":[{"name":"model.txt","content":"model"}]}\n' ;;
+*) printf '{"version":1,"error":{"code":"invalid_input","message":"no type model"}}\n'; exit 2 ;;
+esac
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// newPlugin writes a shell plugin with a sample fixture and returns it.
+func newPlugin(t *testing.T, protocol, script string) plugins.Plugin {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test - shell plugins need a Unix shell")
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.sh"), script)
+	writeFile(t, filepath.Join(dir, "testdata", "user.json"), `{"id": 1, "name": "Ada"}`)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: shell-gen\nversion: 1.0.0\nlanguage: exec\nentrypoint: gen.sh\nprotocol: "+protocol+"\nfixtures: [testdata/*.json]\n")
+
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := plugins.NewPluginFromManifest(manifest)
+	plugin.SetTrust(plugins.TrustTrusted)
+	return plugin
+}
+
+func run(t *testing.T, plugin plugins.Plugin, options conformance.Options) *conformance.Report {
+	t.Helper()
+	if options.Fixtures == nil {
+		fixtures, err := conformance.Fixtures("", plugin.Manifest())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		options.Fixtures = fixtures
+	}
+	report, err := conformance.Run(context.Background(), plugin, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return report
+}
+
+func status(report *conformance.Report, fixture, name string) conformance.Check {
+	for _, check := range report.Checks {
+		if check.Fixture == fixture && check.Name == name {
+			return check
+		}
+	}
+	return conformance.Check{Name: name, Status: "missing"}
+}
+
+func TestRun_Golden(t *testing.T) {
+	plugin := newPlugin(t, plugins.ProtocolJSON, jsonScript)
+	golden := t.TempDir()
+
+	report := run(t, plugin, conformance.Options{GoldenDir: golden})
+	if check := status(report, "fixtures/testdata/user.json", "golden"); check.Status != conformance.StatusSkip {
+		t.Errorf("missing golden files should be skipped, got %+v", check)
+	}
+
+	report = run(t, plugin, conformance.Options{GoldenDir: golden, Update: true})
+	path := filepath.Join(golden, "fixtures", "testdata", "user.json.golden")
+	if data, err := os.ReadFile(path); err != nil || string(data) != "model" {
+		t.Fatalf("golden file not recorded: %q, %v", data, err)
+	}
+
+	report = run(t, plugin, conformance.Options{GoldenDir: golden, Runs: 3})
+	if report.Failed() || report.Count(conformance.StatusWarn) > 0 {
+		t.Errorf("conforming plugin should pass: %+v", report.Checks)
+	}
+	for _, name := range []string{"malformed input", "generate", "protocol", "deterministic", "golden"} {
+		fixture := "fixtures/testdata/user.json"
+		if name == "malformed input" {
+			fixture = ""
+		}
+		if check := status(report, fixture, name); check.Status != conformance.StatusPass {
+			t.Errorf("expected %s to pass, got %+v", name, check)
+		}
+	}
+
+	writeFile(t, path, "other")
+	report = run(t, plugin, conformance.Options{GoldenDir: golden})
+	if check := status(report, "fixtures/testdata/user.json", "golden"); check.Status != conformance.StatusFail || !strings.Contains(check.Detail, "line 1") {
+		t.Errorf("changed output should fail, got %+v", check)
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		script   string
+		options  conformance.Options
+		check    string
+	}{
+		{"nondeterministic", plugins.ProtocolText, "#!/bin/sh\ncat >/dev/null\nhead -c 8 /dev/urandom | od -An -tx1\n", conformance.Options{}, "deterministic"},
+		{"timeout", plugins.ProtocolText, "#!/bin/sh\nsleep 5\n", conformance.Options{Timeout: 200 * time.Millisecond}, "generate"},
+		{"empty output", plugins.ProtocolText, "#!/bin/sh\ncat >/dev/null\n", conformance.Options{}, "protocol"},
+		{"file outside the output directory", plugins.ProtocolJSON, "#!/bin/sh\ncat >/dev/null\nprintf '{\"version\":1,\"files\":[{\"name\":\"../x.txt\",\"content\":\"x\"}]}'\n", conformance.Options{}, "protocol"},
+		{"unknown severity", plugins.ProtocolJSON, "#!/bin/sh\ncat >/dev/null\nprintf '{\"version\":1,\"files\":[{\"content\":\"x\"}],\"diagnostics\":[{\"severity\":\"fatal\",\"message\":\"m\"}]}'\n", conformance.Options{}, "protocol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := run(t, newPlugin(t, tt.protocol, tt.script), tt.options)
+			if check := status(report, "fixtures/testdata/user.json", tt.check); check.Status != conformance.StatusFail {
+				t.Errorf("expected %s to fail, got %+v", tt.check, check)
+			}
+			if !report.Failed() {
+				t.Error("the report should fail")
+			}
+		})
+	}
+}
+
+func TestRun_MalformedInput(t *testing.T) {
+	accepting := "#!/bin/sh\ncat >/dev/null\nprintf '{\"version\":1,\"files\":[{\"content\":\"x\"}]}'\n"
+	failing := "#!/bin/sh\ncat >/dev/null\necho crashed >&2\nexit 1\n"
+
+	tests := []struct {
+		name     string
+		protocol string
+		script   string
+		expected conformance.Status
+	}{
+		{"json rejects with invalid_input", plugins.ProtocolJSON, jsonScript, conformance.StatusPass},
+		{"json accepts", plugins.ProtocolJSON, accepting, conformance.StatusFail},
+		{"json crashes", plugins.ProtocolJSON, failing, conformance.StatusWarn},
+		{"text reports an error", plugins.ProtocolText, "#!/bin/sh\ncat >/dev/null\necho 'Error: invalid JSON'\n", conformance.StatusPass},
+		{"text accepts", plugins.ProtocolText, "#!/bin/sh\ncat\n", conformance.StatusWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := run(t, newPlugin(t, tt.protocol, tt.script), conformance.Options{Fixtures: []conformance.Fixture{}})
+			if check := status(report, "", "malformed input"); check.Status != tt.expected {
+				t.Errorf("expected %s, got %+v", tt.expected, check)
+			}
+			if check := status(report, "", "fixtures"); check.Status != conformance.StatusWarn {
+				t.Errorf("a run without fixtures should warn, got %+v", check)
+			}
+		})
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	examples := t.TempDir()
+	writeFile(t, filepath.Join(examples, "b.json"), "{}")
+	writeFile(t, filepath.Join(examples, "a.json"), "{}")
+	writeFile(t, filepath.Join(examples, "README.md"), "")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.py"), "")
+	writeFile(t, filepath.Join(dir, "cases", "order.yaml"), "id: 1\n")
+	writeFile(t, filepath.Join(dir, "cases", "schema.sql"), "CREATE TABLE t (id INT);\n")
+	writeFile(t, filepath.Join(dir, "gen.plugin.yaml"), "name: gen\nversion: 1.0.0\nentrypoint: gen.py\nfixtures: [cases/*.yaml, cases/*.sql, cases/order.yaml]\n")
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "gen.plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fixtures, err := conformance.Fixtures(examples, manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []conformance.Fixture{
+		{Name: "examples/a.json", Format: core.FormatAuto},
+		{Name: "examples/b.json", Format: core.FormatAuto},
+		{Name: "fixtures/cases/order.yaml", Format: core.FormatAuto},
+		{Name: "fixtures/cases/schema.sql", Format: core.FormatSQL},
+	}
+	if len(fixtures) != len(expected) {
+		t.Fatalf("unexpected fixtures %+v", fixtures)
+	}
+	for i, fixture := range fixtures {
+		if fixture.Name != expected[i].Name || fixture.Format != expected[i].Format {
+			t.Errorf("fixture %d: expected %+v, got %+v", i, expected[i], fixture)
+		}
+	}
+
+	// A script-specific manifest shares its directory with other plugins.
+	if golden := conformance.DefaultGoldenDir("gen", manifest.EntrypointPath(), manifest); golden != filepath.Join(dir, "testdata", "golden", "gen") {
+		t.Errorf("unexpected golden directory %s", golden)
+	}
+	if golden := conformance.GoldenPath("golden", fixtures[2]); golden != filepath.Join("golden", "fixtures", "cases", "order.yaml.golden") {
+		t.Errorf("unexpected golden path %s", golden)
+	}
+}
@@ -0,0 +1,14 @@
+package conformance
+
+import (
+	"os"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// The test binary doubles as the sandbox helper, like the devtoolbox binary.
+func TestMain(m *testing.M) {
+	plugins.RunSandboxHelper()
+	os.Exit(m.Run())
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const ddlSchema = `-- схема
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+
+CREATE TABLE IF NOT EXISTS public.users (
+    id BIGSERIAL PRIMARY KEY,
+    balance NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    external_id UUID,
+    profile JSONB,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    mood mood,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT users_balance_check CHECK (balance >= 0)
+);
+
+CREATE INDEX users_mood_idx ON users (mood);
+
+CREATE TABLE ` + "`orders`" + ` (
+  ` + "`id`" + ` int unsigned NOT NULL AUTO_INCREMENT,
+  ` + "`key`" + ` varchar(32) DEFAULT NULL COMMENT 'ключ',
+  ` + "`paid`" + ` tinyint(1) NOT NULL DEFAULT '0',
+  PRIMARY KEY (` + "`id`" + `),
+  KEY ` + "`idx_key`" + ` (` + "`key`" + `)
+) ENGINE=InnoDB;`
+
+func TestParseSQL(t *testing.T) {
+	doc, err := core.ParseSQL(ddlSchema)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if names := doc.Names(); len(names) != 2 || names[0] != "Users" || names[1] != "Orders" {
+		t.Fatalf("неожиданные модели: %v", names)
+	}
+
+	expected := []struct {
+		name     string
+		kind     ir.Kind
+		format   string
+		nullable bool
+	}{
+		{"id", ir.KindInteger, "int64", false},
+		{"balance", ir.KindString, "decimal", false},
+		{"external_id", ir.KindString, "uuid", true},
+		{"profile", ir.KindAny, "json", true},
+		{"tags", ir.KindArray, "", false},
+		{"mood", ir.KindString, "", true},
+		{"created_at", ir.KindTime, "date-time", false},
+	}
+
+	fields := doc.Models[0].Type.Fields
+	if len(fields) != len(expected) {
+		t.Fatalf("ожидалось %d полей, получили %d", len(expected), len(fields))
+	}
+	for i, tt := range expected {
+		field := fields[i]
+		if field.Name != tt.name || field.Type.Kind != tt.kind || field.Type.Format != tt.format || field.Type.Nullable != tt.nullable {
+			t.Errorf("поле %d: ожидалось %s %s/%s nullable=%v, получили %s %s/%s nullable=%v",
+				i, tt.name, tt.kind, tt.format, tt.nullable, field.Name, field.Type.Kind, field.Type.Format, field.Type.Nullable)
+		}
+	}
+
+	if enum := fields[5].Type.Enum; len(enum) != 2 || enum[0] != "happy" {
+		t.Errorf("ожидалось перечисление mood, получили %v", enum)
+	}
+
+	orders := doc.Models[1].Type.Fields
+	if len(orders) != 3 {
+		t.Fatalf("ожидалось 3 колонки в orders, получили %d", len(orders))
+	}
+	if orders[1].Name != "key" || orders[1].Description != "ключ" {
+		t.Errorf("колонка key разобрана неверно: %+v", orders[1])
+	}
+	if orders[2].Type.Kind != ir.KindBoolean {
+		t.Errorf("tinyint(1) должен стать boolean, получили %s", orders[2].Type.Kind)
+	}
+}
+
+func TestGoStructGenerator_FromSQL(t *testing.T) {
+	input := `CREATE TABLE accounts (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    note TEXT,
+    avatar BLOB,
+    closed_at DATETIME
+);`
+
+	tests := []struct {
+		name     string
+		options  map[string]string
+		expected string
+	}{
+		{
+			name:    "указатели",
+			options: map[string]string{"package": "models"},
+			expected: `package models
+
+import "time"
+
+type Accounts struct {
+	Id int32 ` + "`json:\"id\" db:\"id\"`" + `
+	Name string ` + "`json:\"name\" db:\"name\"`" + `
+	Note *string ` + "`json:\"note\" db:\"note\"`" + `
+	Avatar []byte ` + "`json:\"avatar\" db:\"avatar\"`" + `
+	ClosedAt *time.Time ` + "`json:\"closed_at\" db:\"closed_at\"`" + `
+}`,
+		},
+		{
+			name:    "sql.Null",
+			options: map[string]string{"package": "models", "null": "sql"},
+			expected: `package models
+
+import "database/sql"
+
+type Accounts struct {
+	Id int32 ` + "`json:\"id\" db:\"id\"`" + `
+	Name string ` + "`json:\"name\" db:\"name\"`" + `
+	Note sql.NullString ` + "`json:\"note\" db:\"note\"`" + `
+	Avatar []byte ` + "`json:\"avatar\" db:\"avatar\"`" + `
+	ClosedAt sql.NullTime ` + "`json:\"closed_at\" db:\"closed_at\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := core.Configure(core.NewGoStructGenerator(), tt.options)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			result, err := generator.Generate(input)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSQL_Errors(t *testing.T) {
+	inputs := []string{
+		"CREATE INDEX idx ON users (id);",
+		"CREATE TABLE users (id INTEGER",
+		"CREATE TABLE users (id INTEGER, name VARCHAR(10) DEFAULT 'x);",
+	}
+
+	for _, input := range inputs {
+		if _, err := core.ParseSQL(input); err == nil {
+			t.Errorf("ожидалась ошибка для %q", input)
+		}
+	}
+}
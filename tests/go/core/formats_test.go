@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected core.InputFormat
+	}{
+		{"json", `{"name":"John"}`, core.FormatJSON},
+		{"невалидный json", `{"name":"string","age":}`, core.FormatJSON},
+		{"yaml", "name: John\nage: 30\n", core.FormatYAML},
+		{"toml", "[server]\nport = 8080\n", core.FormatTOML},
+		{"xml", `<?xml version="1.0"?><user id="1"/>`, core.FormatXML},
+		{"openapi", "openapi: 3.0.0\ninfo:\n  title: x\n", core.FormatOpenAPI},
+		{"sql", "-- users\ncreate table users (id int);", core.FormatSQL},
+		{"yaml с ключом create", "create: true\n", core.FormatYAML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := core.DetectFormat(tt.input); result != tt.expected {
+				t.Errorf("ожидался формат %s, получили %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGoStructGenerator_SampleFormats(t *testing.T) {
+	generator := core.NewGoStructGenerator()
+
+	tests := []struct {
+		name     string
+		input    string
+		format   core.InputFormat
+		expected string
+	}{
+		{
+			name:   "yaml",
+			input:  "user_name: John\nage: 30\ntags:\n  - a\n",
+			format: core.FormatYAML,
+			expected: `type GeneratedStruct struct {
+	Age int ` + "`json:\"age\"`" + `
+	Tags []string ` + "`json:\"tags\"`" + `
+	UserName string ` + "`json:\"user_name\"`" + `
+}`,
+		},
+		{
+			name:   "toml",
+			input:  "title = \"app\"\n\n[database]\nport = 5432\nratio = 0.5\n",
+			format: core.FormatTOML,
+			expected: `type GeneratedStruct struct {
+	Database Database ` + "`json:\"database\"`" + `
+	Title string ` + "`json:\"title\"`" + `
+}
+
+type Database struct {
+	Port int ` + "`json:\"port\"`" + `
+	Ratio float64 ` + "`json:\"ratio\"`" + `
+}`,
+		},
+		{
+			name:   "xml",
+			input:  `<order id="7"><item sku="a">Pen</item><item sku="b">Ink</item><paid>true</paid></order>`,
+			format: core.FormatXML,
+			expected: `type Order struct {
+	Id int ` + "`json:\"id\" xml:\"id,attr\"`" + `
+	Item []Item ` + "`json:\"item\" xml:\"item\"`" + `
+	Paid bool ` + "`json:\"paid\" xml:\"paid\"`" + `
+}
+
+type Item struct {
+	Value string ` + "`json:\"value\" xml:\",chardata\"`" + `
+	Sku string ` + "`json:\"sku\" xml:\"sku,attr\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := core.GenerateWithFormat(context.Background(), generator, tt.input, tt.format)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			if strings.TrimSpace(result) != tt.expected {
+				t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConvertToJSON(t *testing.T) {
+	result, err := core.ConvertToJSON("<config debug=\"true\"><port>80</port></config>", core.FormatAuto)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &data); err != nil {
+		t.Fatalf("результат не является JSON: %v", err)
+	}
+
+	if data["@debug"] != true || data["port"] != float64(80) {
+		t.Errorf("неожиданный результат преобразования: %s", result)
+	}
+}
+
+func TestGenerateWithFormat_ConvertsSamplesForJSONGenerators(t *testing.T) {
+	generator := &recordingGenerator{}
+
+	if _, err := core.GenerateWithFormat(context.Background(), generator, "name: John\n", core.FormatYAML); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(generator.input), &data); err != nil || data["name"] != "John" {
+		t.Errorf("генератор должен получить JSON, получил %q", generator.input)
+	}
+}
+
+type recordingGenerator struct {
+	input string
+}
+
+func (r *recordingGenerator) Generate(input string) (string, error) {
+	r.input = input
+	return "", nil
+}
+
+func (r *recordingGenerator) GetName() string {
+	return "recording"
+}
+
+func (r *recordingGenerator) GetDescription() string {
+	return "Записывает входные данные"
+}
@@ -0,0 +1,14 @@
+package core
+
+import (
+	"os"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// Тестовый бинарник служит помощником песочницы, как и бинарник devtoolbox.
+func TestMain(m *testing.M) {
+	plugins.RunSandboxHelper()
+	os.Exit(m.Run())
+}
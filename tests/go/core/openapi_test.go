@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+const petstoreSpec = `
+openapi: 3.1.0
+info:
+  title: Petstore
+  version: 1.0.0
+paths:
+  /pets/{petId}:
+    parameters:
+      - $ref: "#/components/parameters/PetId"
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+    put:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        default:
+          description: ok
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      schema:
+        type: string
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+        nickname:
+          type: [string, "null"]
+        owner:
+          $ref: "#/components/schemas/pet_owner"
+        tags:
+          type: array
+          items:
+            $ref: "#/components/schemas/Tag"
+    pet_owner:
+      type: object
+      properties:
+        email:
+          type: string
+    Tag:
+      $ref: "#/components/schemas/Pet/properties/name"
+`
+
+func TestParseOpenAPI_Models(t *testing.T) {
+	doc, err := core.ParseOpenAPI(petstoreSpec)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := []string{"Pet", "Tag", "PetOwner", "GetPetParams", "GetPetResponse", "PutPetsPetIdParams", "PutPetsPetIdRequest"}
+	if strings.Join(doc.Names(), ",") != strings.Join(expected, ",") {
+		t.Errorf("ожидались модели %v, получили %v", expected, doc.Names())
+	}
+
+	tag, _ := doc.Model("Tag")
+	if tag.Type.Kind != ir.KindString {
+		t.Errorf("ожидалось, что Tag разрешится в string, получили %s", tag.Type.Kind)
+	}
+
+	response, _ := doc.Model("GetPetResponse")
+	if response.Type.Kind != ir.KindRef || response.Type.Ref != "Pet" {
+		t.Errorf("ожидалась ссылка на Pet, получили %+v", response.Type)
+	}
+
+	params, _ := doc.Model("PutPetsPetIdParams")
+	if len(params.Type.Fields) != 1 || !params.Type.Fields[0].Required {
+		t.Errorf("ожидался обязательный path параметр petId, получили %+v", params.Type.Fields)
+	}
+}
+
+func TestGoStructGenerator_OpenAPI(t *testing.T) {
+	generator := core.NewGoStructGenerator()
+
+	result, err := generator.Generate(petstoreSpec)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := `type Pet struct {
+	Id int64 ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Nickname *string ` + "`json:\"nickname,omitempty\"`" + `
+	Owner PetOwner ` + "`json:\"owner,omitempty\"`" + `
+	Tags []Tag ` + "`json:\"tags,omitempty\"`" + `
+}`
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось начало:\n%s", result, expected)
+	}
+
+	if !strings.Contains(result, "type GetPetResponse Pet") {
+		t.Errorf("ожидался тип ответа GetPetResponse, получили:\n%s", result)
+	}
+}
+
+func TestGoStructGenerator_GenerateFiles(t *testing.T) {
+	generator := core.NewGoStructGenerator()
+	generator.SetPackageName("api")
+
+	files, err := core.GenerateFiles(context.Background(), generator, petstoreSpec, core.FormatOpenAPI)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	if len(files) != 7 {
+		t.Fatalf("ожидалось 7 файлов, получили %d", len(files))
+	}
+
+	if files[2].Name != "pet_owner.go" {
+		t.Errorf("ожидалось имя файла pet_owner.go, получили %s", files[2].Name)
+	}
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Content, "package api\n") {
+			t.Errorf("файл %s не начинается с объявления пакета", file.Name)
+		}
+	}
+}
+
+func TestParseOpenAPI_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "версия 2",
+			input: `{"swagger": "2.0", "openapi": "2.0"}`,
+		},
+		{
+			name: "неизвестная схема",
+			input: `openapi: 3.0.0
+components:
+  schemas:
+    A:
+      $ref: "#/components/schemas/B"`,
+		},
+		{
+			name: "внешняя ссылка",
+			input: `openapi: 3.0.0
+components:
+  schemas:
+    A:
+      $ref: "common.yaml#/components/schemas/B"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := core.ParseOpenAPI(tt.input); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+}
+
+func TestGenerateWithFormat_Unsupported(t *testing.T) {
+	generator := &testGenerator{name: "test-generator"}
+
+	if _, err := core.GenerateWithFormat(context.Background(), generator, petstoreSpec, core.FormatAuto); err == nil {
+		t.Error("ожидалась ошибка для генератора без поддержки OpenAPI")
+	}
+
+	result, err := core.GenerateWithFormat(context.Background(), generator, `{"name":"x"}`, core.FormatAuto)
+	if err != nil || result != "test output" {
+		t.Errorf("JSON должен передаваться генератору как есть, получили %q, %v", result, err)
+	}
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// sampleGenerator всегда возвращает одну и ту же схему SQL, как генератор
+// промежуточного представления в начале конвейера.
+type sampleGenerator struct{}
+
+func (sampleGenerator) Generate(input string) (string, error) {
+	return "CREATE TABLE users (id INT NOT NULL, password TEXT, created_at TIMESTAMP NOT NULL, user_name TEXT);", nil
+}
+
+func (sampleGenerator) GetName() string        { return "sample" }
+func (sampleGenerator) GetDescription() string { return "Пример" }
+
+func step(generator, processor string, options map[string]string) config.Step {
+	return config.Step{Generator: generator, Processor: processor, Options: options}
+}
+
+func newPipeline(t *testing.T, registry *core.GeneratorRegistry, steps ...config.Step) *core.Pipeline {
+	t.Helper()
+	pipeline, err := core.NewPipeline(registry, "models", config.Pipeline{Steps: steps})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if err := registry.Register(pipeline); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	return pipeline
+}
+
+func TestPipeline_Chain(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	registry.Register(sampleGenerator{})
+	pipeline := newPipeline(t, registry,
+		step("sample", "", nil),
+		step("", "redact", map[string]string{"fields": "password"}),
+		config.Step{Generator: "go-struct", Format: "sql", Options: map[string]string{"package": "api"}},
+		step("", "license-header", map[string]string{"text": "Copyright Example"}),
+		step("", "goimports", nil),
+	)
+
+	output, err := core.GenerateWithFormat(context.Background(), pipeline, "{}", core.FormatAuto)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for _, expected := range []string{"// Copyright Example\n\npackage api\n", "\"time\"", "CreatedAt time.Time", "UserName"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("ожидалось %q в выводе:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "Password") {
+		t.Errorf("redact должен убрать поле password:\n%s", output)
+	}
+	if steps := strings.Join(pipeline.Steps(), " "); steps != "sample redact go-struct license-header goimports" {
+		t.Errorf("неожиданные шаги %s", steps)
+	}
+
+	// Настройки без имени шага относятся к последнему генератору.
+	configured, err := core.Configure(pipeline, map[string]string{"package": "models", "redact.fields": "id,user_name"})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	output, err = core.GenerateWithFormat(context.Background(), configured, "", core.FormatAuto)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if !strings.Contains(output, "package models") || !strings.Contains(output, "Password") || strings.Contains(output, "UserName") {
+		t.Errorf("настройки не применились:\n%s", output)
+	}
+	if _, err := core.Configure(pipeline, map[string]string{"redact.mode": "x"}); err == nil {
+		t.Error("неизвестная настройка обработчика должна быть ошибкой")
+	}
+}
+
+func TestPipeline_Files(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	pipeline := newPipeline(t, registry,
+		step("", "normalize-keys", map[string]string{"case": "camel"}),
+		step("go-struct", "", map[string]string{"package": "api"}),
+		step("", "license-header", map[string]string{"text": "SPDX-License-Identifier: MIT"}),
+	)
+
+	files, err := core.GenerateFiles(context.Background(), pipeline, `{"user_id": 1, "profile": {"first_name": "a"}}`, core.FormatJSON)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("ожидалось два файла, получили %+v", files)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Content, "// SPDX-License-Identifier: MIT\n\npackage api") {
+			t.Errorf("%s: нет заголовка:\n%s", file.Name, file.Content)
+		}
+	}
+	if !strings.Contains(files[0].Content+files[1].Content, `json:"firstName"`) {
+		t.Errorf("normalize-keys должен переименовать поля: %+v", files)
+	}
+}
+
+func TestNewPipeline_Errors(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	for name, steps := range map[string][]config.Step{
+		"без генератора":              {step("", "gofmt", nil)},
+		"код перед генератором":       {step("", "gofmt", nil), step("go-struct", "", nil)},
+		"модель после генератора":     {step("go-struct", "", nil), step("", "redact", map[string]string{"fields": "a"})},
+		"неизвестный обработчик":      {step("go-struct", "", nil), step("", "prettier", nil)},
+		"неизвестная настройка":       {step("go-struct", "", nil), step("", "gofmt", map[string]string{"tabs": "4"})},
+		"redact без полей":            {step("", "redact", nil), step("go-struct", "", nil)},
+		"генератор и обработчик":      {{Generator: "go-struct", Processor: "gofmt"}},
+		"формат у первого генератора": {{Generator: "go-struct", Format: "yaml"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := core.NewPipeline(registry, "models", config.Pipeline{Steps: steps}); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+		})
+	}
+
+	pipeline := newPipeline(t, registry, step("missing", "", nil))
+	_, err := core.GenerateWithFormat(context.Background(), pipeline, "{}", core.FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), "шаг 1 (missing)") {
+		t.Errorf("ошибка должна называть шаг, получили %v", err)
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	processor, err := core.NewProcessor("goimports", nil)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	code := "package api\n\nimport (\n\t\"fmt\"\n\tyaml \"gopkg.in/yaml.v3\"\n)\n\nvar _ = yaml.Marshal\n\nvar created = time.Now()\n"
+	output, err := processor.(core.CodeProcessor).ProcessCode(code, "api.go")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if strings.Contains(output, `"fmt"`) || !strings.Contains(output, "import (\n\t\"time\"\n\n\tyaml \"gopkg.in/yaml.v3\"\n)") {
+		t.Errorf("goimports должен убрать fmt и добавить time:\n%s", output)
+	}
+	if same, _ := processor.(core.CodeProcessor).ProcessCode("x = 1", "notes.txt"); same != "x = 1" {
+		t.Error("файлы не на Go не должны меняться")
+	}
+
+	processor, err = core.NewProcessor("license-header", map[string]string{"text": "Copyright\n\nMIT"})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	header := processor.(core.CodeProcessor)
+	output, _ = header.ProcessCode("#!/usr/bin/env python3\nprint(1)\n", "gen.py")
+	if output != "#!/usr/bin/env python3\n# Copyright\n#\n# MIT\n\nprint(1)\n" {
+		t.Errorf("неожиданный заголовок:\n%s", output)
+	}
+	if again, _ := header.ProcessCode(output, "gen.py"); again != output {
+		t.Errorf("повторный заголовок:\n%s", again)
+	}
+
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "User", Type: &ir.Type{Kind: ir.KindObject, Fields: []*ir.Field{
+		{Name: "user_id", Type: ir.Primitive(ir.KindInteger)},
+		{Name: "userId", Type: ir.Primitive(ir.KindInteger)},
+	}}})
+	processor, _ = core.NewProcessor("normalize-keys", nil)
+	if _, err := processor.(core.DocumentProcessor).ProcessDocument(doc); err == nil {
+		t.Error("совпавшие после нормализации имена должны быть ошибкой")
+	}
+	processor, _ = core.NewProcessor("redact", map[string]string{"fields": "userId"})
+	redacted, err := processor.(core.DocumentProcessor).ProcessDocument(doc)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if len(redacted.Models[0].Type.Fields) != 0 || len(doc.Models[0].Type.Fields) != 2 {
+		t.Errorf("redact должен убрать оба поля из копии документа: %+v", redacted.Models[0].Type.Fields)
+	}
+}
+
+func TestLoadGeneratorRegistry_Pipelines(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(plugins.PluginPathEnv, t.TempDir())
+	configPath := filepath.Join(home, ".devtoolbox", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	content := `
+aliases:
+  m: models
+pipelines:
+  models:
+    steps:
+      - generator: go-struct
+      - processor: gofmt
+  nested:
+    steps:
+      - generator: m
+  go-struct:
+    steps:
+      - generator: py-dataclass
+  missing:
+    steps:
+      - generator: kotlin-data
+  first:
+    steps:
+      - generator: second
+  second:
+    steps:
+      - generator: first
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	registry, discovery, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for _, name := range []string{"models", "m", "nested"} {
+		if generator, ok := registry.Get(name); !ok {
+			t.Errorf("конвейер %s не зарегистрирован", name)
+		} else if _, ok := generator.(*core.Pipeline); !ok {
+			t.Errorf("%s: ожидался конвейер, получили %T", name, generator)
+		}
+	}
+	for _, name := range []string{"missing", "first", "second"} {
+		if _, ok := registry.Get(name); ok {
+			t.Errorf("ошибочный конвейер %s зарегистрирован", name)
+		}
+	}
+	if generator, _ := registry.Get("go-struct"); generator.GetName() != "go-struct" {
+		t.Error("конвейер не должен заменять встроенный генератор")
+	}
+	if len(discovery.Errors) != 4 {
+		t.Errorf("ожидалось четыре ошибки, получили %v", discovery.Errors)
+	}
+
+	generator, _ := registry.Get("nested")
+	output, err := core.GenerateWithFormat(context.Background(), generator, `{"id": 1}`, core.FormatJSON)
+	if err != nil || !strings.Contains(output, "Id int `json:\"id\"`") {
+		t.Errorf("неожиданный результат %q, %v", output, err)
+	}
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/config"
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+type fakePlugin struct {
+	manifest *plugins.Manifest
+	response *plugins.Response
+	request  *plugins.Request
+}
+
+func (p *fakePlugin) GetName() string                 { return "fake" }
+func (p *fakePlugin) GetDescription() string          { return "Fake plugin" }
+func (p *fakePlugin) Manifest() *plugins.Manifest     { return p.manifest }
+func (p *fakePlugin) Generate(string) (string, error) { return "", nil }
+
+func (p *fakePlugin) Invoke(ctx context.Context, request *plugins.Request) (*plugins.Response, error) {
+	p.request = request
+	return p.response, nil
+}
+
+func (p *fakePlugin) WithOptions(map[string]string) (plugins.Plugin, error) {
+	return p, nil
+}
+
+func newFakePlugin(files ...plugins.File) *fakePlugin {
+	return &fakePlugin{
+		manifest: &plugins.Manifest{Name: "fake", Protocol: plugins.ProtocolJSON, OutputExtension: ".txt"},
+		response: &plugins.Response{Version: plugins.ProtocolVersion, Files: files},
+	}
+}
+
+func TestGenerateWithFormat_StructuredPlugin(t *testing.T) {
+	plugin := newFakePlugin(plugins.File{Content: "a"}, plugins.File{Content: "b"})
+
+	result, err := core.GenerateWithFormat(context.Background(), plugin, "user_name: John\n", core.FormatAuto)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if result != "a\n\nb" {
+		t.Errorf("ожидалось объединение файлов, получили %q", result)
+	}
+
+	request := plugin.request
+	if request.Format != "yaml" || request.Input != "user_name: John\n" {
+		t.Errorf("в запросе неверный исходный текст или формат: %+v", request)
+	}
+	if request.IR == nil || request.IR.Models[0].Type.Fields[0].Name != "user_name" {
+		t.Errorf("в запросе нет модели типов: %+v", request.IR)
+	}
+}
+
+func TestPluginFiles(t *testing.T) {
+	plugin := newFakePlugin(plugins.File{Content: "a"}, plugins.File{Name: "models/user.txt", Content: "b"})
+
+	files, err := core.PluginFiles(plugin, plugin.response)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if files[0].Name != "fake_1.txt" || !strings.HasSuffix(files[1].Name, "user.txt") {
+		t.Errorf("неожиданные имена файлов: %+v", files)
+	}
+
+	for _, name := range []string{"../escape.txt", "/etc/passwd"} {
+		plugin.response.Files = []plugins.File{{Name: name, Content: "x"}}
+		if _, err := core.PluginFiles(plugin, plugin.response); err == nil {
+			t.Errorf("ожидалась ошибка для имени %s", name)
+		}
+	}
+}
+
+func TestLoadGeneratorRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"kotlin_gen.py": "print('kotlin')\n",
+		"go-struct.py":  "print('не встроенный')\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+	t.Setenv(plugins.PluginPathEnv, dir)
+
+	registry, discovery, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, ok := registry.Get("kotlin_gen"); !ok {
+		t.Error("плагин из DEVTOOLBOX_PLUGIN_PATH не зарегистрирован")
+	}
+	generator, ok := registry.Get("go-struct")
+	if _, external := generator.(plugins.ExternalPlugin); !ok || external {
+		t.Error("плагин заменил встроенный генератор go-struct")
+	}
+
+	found := false
+	for _, conflict := range discovery.Conflicts {
+		if conflict.Name == "go-struct" && conflict.Used.Scope == plugins.ScopeBuiltin {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("ожидался конфликт для go-struct, получили %+v", discovery.Conflicts)
+	}
+}
+
+func TestLoadGeneratorRegistry_Config(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	for _, name := range []string{"kotlin_gen.py", "swift_gen.py"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("print('ok')\n"), 0644); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+	t.Setenv(plugins.PluginPathEnv, dir)
+
+	configPath := filepath.Join(home, ".devtoolbox", "config.yaml")
+	if err := config.SetPluginEnabled(configPath, "kotlin_gen", false); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	data = append(data, "options:\n  go-struct:\n    package: api\n    db: \"true\"\n"...)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, ok := registry.Get("kotlin_gen"); ok {
+		t.Error("отключённый плагин зарегистрирован")
+	}
+	if source, disabled := registry.Disabled("kotlin_gen"); !disabled || source != configPath {
+		t.Errorf("ожидалось, что kotlin_gen отключён в %s, получили %q", configPath, source)
+	}
+	if _, ok := registry.Get("swift_gen"); !ok {
+		t.Error("плагин swift_gen не зарегистрирован")
+	}
+
+	options := registry.Options("go-struct", map[string]string{"db": "false"})
+	if options["package"] != "api" || options["db"] != "false" {
+		t.Errorf("настройки из --opt должны переопределять конфигурацию, получили %v", options)
+	}
+	if options := registry.Options("sql-postgres", nil); len(options) != 0 {
+		t.Errorf("неожиданные настройки %v", options)
+	}
+}
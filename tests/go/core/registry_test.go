@@ -0,0 +1,288 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func versionedPlugin(name, version string) plugins.ExternalPlugin {
+	plugin := plugins.NewPythonPlugin(name, "Плагин "+version, name+".py")
+	plugin.SetManifest(&plugins.Manifest{Name: name, Version: version})
+	return plugin
+}
+
+func TestGeneratorRegistry_Duplicate(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+
+	err := registry.Register(&testGenerator{name: "go-struct"})
+	if !errors.Is(err, core.ErrGeneratorExists) {
+		t.Fatalf("ожидалась ошибка ErrGeneratorExists, получили %v", err)
+	}
+	if generator, _ := registry.Get("go-struct"); generator.GetDescription() == "" {
+		t.Error("повторная регистрация не должна заменять генератор")
+	}
+
+	replacement := &testGenerator{name: "go-struct", description: "Замена"}
+	if err := registry.Replace(replacement); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if generator, _ := registry.Get("go-struct"); generator != replacement {
+		t.Error("Replace должен заменять генератор")
+	}
+
+	if err := registry.Register(&testGenerator{name: "go@struct"}); err == nil {
+		t.Error("имя с @ должно быть ошибкой")
+	}
+	if err := registry.Replace(&testGenerator{name: "go@struct"}); err == nil {
+		t.Error("Replace должен возвращать ошибку для имени с @")
+	}
+	registry.Alias("go", "go-struct")
+	if err := registry.Replace(&testGenerator{name: "go"}); !errors.Is(err, core.ErrGeneratorExists) {
+		t.Errorf("Replace не должен занимать псевдоним, получили %v", err)
+	}
+}
+
+func TestGeneratorRegistry_Order(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	for _, name := range []string{"zeta", "alpha", "mid"} {
+		if err := registry.Register(&testGenerator{name: name}); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+
+	names := registry.GetNames()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("имена должны быть отсортированы: %v", names)
+	}
+	for i, generator := range registry.List() {
+		if generator.GetName() != names[i] {
+			t.Fatalf("List и GetNames должны идти в одном порядке: %s, %s", generator.GetName(), names[i])
+		}
+	}
+}
+
+func TestGeneratorRegistry_Versions(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	for _, version := range []string{"1.4.0", "2.1.0", "2.3.1"} {
+		if err := registry.Register(versionedPlugin("kotlin", version)); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+	if err := registry.Register(versionedPlugin("kotlin", "2.1.0")); !errors.Is(err, core.ErrGeneratorExists) {
+		t.Errorf("ожидалась ошибка ErrGeneratorExists, получили %v", err)
+	}
+
+	for key, expected := range map[string]string{
+		"kotlin":       "1.4.0",
+		"kotlin@2":     "2.3.1",
+		"kotlin@2.1":   "2.1.0",
+		"kotlin@v2.3":  "2.3.1",
+		"kotlin@1.4.0": "1.4.0",
+		"kotlin@3":     "",
+		"kotlin@2.10":  "",
+	} {
+		generator, ok := registry.Get(key)
+		if expected == "" {
+			if ok {
+				t.Errorf("%s: версия не должна находиться", key)
+			}
+			continue
+		}
+		if !ok || generator.GetDescription() != "Плагин "+expected {
+			t.Errorf("%s: ожидалась версия %s", key, expected)
+		}
+	}
+	if versions := registry.Versions("kotlin"); !reflect.DeepEqual(versions, []string{"1.4.0", "2.1.0", "2.3.1"}) {
+		t.Errorf("неожиданные версии %v", versions)
+	}
+
+	if err := registry.Unregister("kotlin@2.3.1"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if generator, _ := registry.Get("kotlin@2"); generator == nil || generator.GetDescription() != "Плагин 2.1.0" {
+		t.Error("после удаления версии должна находиться 2.1.0")
+	}
+	if err := registry.Unregister("kotlin@9"); !errors.Is(err, core.ErrGeneratorNotFound) {
+		t.Errorf("ожидалась ошибка ErrGeneratorNotFound, получили %v", err)
+	}
+	if err := registry.Unregister("kotlin"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, ok := registry.Get("kotlin@2.1.0"); ok {
+		t.Error("удаление по имени должно удалять все версии")
+	}
+}
+
+func TestGeneratorRegistry_Aliases(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	registry.Register(versionedPlugin("ts_interface_gen", "1.0.0"))
+	registry.Register(versionedPlugin("ts_interface_gen", "2.0.0"))
+
+	if err := registry.Alias("ts", "ts_interface_gen"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if generator, ok := registry.Get("ts@2"); !ok || generator.GetDescription() != "Плагин 2.0.0" {
+		t.Error("псевдоним должен поддерживать выбор версии")
+	}
+	if name := registry.Resolve("ts@2"); name != "ts_interface_gen" {
+		t.Errorf("ожидалось имя ts_interface_gen, получили %s", name)
+	}
+	// Псевдоним псевдонима ведёт сразу на генератор.
+	if err := registry.Alias("typescript", "ts"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if aliases := registry.Aliases(); aliases["typescript"] != "ts_interface_gen" {
+		t.Errorf("неожиданные псевдонимы %v", aliases)
+	}
+
+	for alias, target := range map[string]string{
+		"go-struct": "ts_interface_gen",
+		"kt":        "kotlin",
+		"ts@2":      "ts_interface_gen",
+	} {
+		if err := registry.Alias(alias, target); err == nil {
+			t.Errorf("псевдоним %s -> %s должен быть ошибкой", alias, target)
+		}
+	}
+	if err := registry.Register(&testGenerator{name: "ts"}); !errors.Is(err, core.ErrGeneratorExists) {
+		t.Errorf("генератор с именем псевдонима должен быть ошибкой, получили %v", err)
+	}
+
+	if err := registry.Unregister("ts"); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if _, ok := registry.Get("ts_interface_gen"); !ok {
+		t.Error("удаление псевдонима не должно удалять генератор")
+	}
+}
+
+func TestGeneratorRegistry_Subscribe(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	var changes []string
+	cancel := registry.Subscribe(func(change core.Change) {
+		// Подписчик вызывается вне блокировки и может читать реестр.
+		registry.GetNames()
+		changes = append(changes, change.String())
+	})
+
+	registry.Register(versionedPlugin("kotlin", "1.0.0"))
+	registry.Register(versionedPlugin("kotlin", "1.0.0"))
+	registry.Replace(versionedPlugin("kotlin", "1.0.0"))
+	registry.Alias("kt", "kotlin")
+	registry.Unregister("kotlin")
+	cancel()
+	registry.Register(&testGenerator{name: "after-cancel"})
+
+	expected := []string{
+		"added kotlin@1.0.0",
+		"updated kotlin@1.0.0",
+		"added kt -> kotlin",
+		"removed kotlin@1.0.0",
+		"removed kt -> kotlin",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("неожиданные изменения:\n%v\nожидалось:\n%v", changes, expected)
+	}
+}
+
+func TestGeneratorRegistry_Concurrent(t *testing.T) {
+	registry := core.NewGeneratorRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("gen-%d-%d", i, j)
+				if err := registry.Register(&testGenerator{name: name}); err != nil {
+					t.Errorf("неожиданная ошибка: %v", err)
+					return
+				}
+				registry.Alias("alias-"+name, name)
+				registry.Get("alias-" + name)
+				registry.List()
+				registry.Versions(name)
+				registry.Options("alias-"+name, nil)
+				registry.Disabled(name)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if names := registry.GetNames(); len(names) != 5+8*50 {
+		t.Errorf("ожидалось %d генераторов, получили %d", 5+8*50, len(names))
+	}
+}
+
+func TestLoadGeneratorRegistry_VersionsAndAliases(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	first, second := t.TempDir(), t.TempDir()
+	t.Setenv(plugins.PluginPathEnv, first+string(os.PathListSeparator)+second)
+
+	writeManifest := func(dir, name, version, extra string) {
+		t.Helper()
+		pluginDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(pluginDir, 0755); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+		manifest := fmt.Sprintf("name: %s\nversion: %s\nentrypoint: main.py\n%s", name, version, extra)
+		for file, content := range map[string]string{"plugin.yaml": manifest, "main.py": "print('ok')\n"} {
+			if err := os.WriteFile(filepath.Join(pluginDir, file), []byte(content), 0644); err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+		}
+	}
+	writeManifest(first, "kotlin", "2.0.0", "aliases: [kt]\n")
+	writeManifest(second, "kotlin", "1.4.0", "")
+	writeManifest(first, "go-struct", "2.0.0", "")
+
+	configPath := filepath.Join(home, ".devtoolbox", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	config := "plugins:\n  versions:\n    kotlin: \"<2.0.0\"\naliases:\n  gs: go-struct\n  kt1: missing\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	registry, discovery, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	defer registry.Close()
+
+	if versions := registry.Versions("kotlin"); !reflect.DeepEqual(versions, []string{"1.4.0", "2.0.0"}) {
+		t.Errorf("ограничение версии должно выбирать версию по умолчанию, получили %v", versions)
+	}
+	if generator, ok := registry.Get("go-struct"); !ok || generator.GetDescription() != core.NewGoStructGenerator().GetDescription() {
+		t.Error("по умолчанию должен использоваться встроенный go-struct")
+	}
+	if _, ok := registry.Get("go-struct@2"); !ok {
+		t.Error("плагин go-struct 2.0.0 должен быть доступен как go-struct@2")
+	}
+	// Псевдоним из манифеста неиспользуемой версии не регистрируется.
+	if _, ok := registry.Get("kt"); ok {
+		t.Error("псевдоним kt неиспользуемой версии зарегистрирован")
+	}
+	if name := registry.Resolve("gs"); name != "go-struct" {
+		t.Errorf("псевдоним из конфигурации не зарегистрирован: %s", name)
+	}
+
+	found := false
+	for _, err := range discovery.Errors {
+		found = found || errors.Is(err, core.ErrGeneratorNotFound)
+	}
+	if !found {
+		t.Errorf("ожидалась ошибка для псевдонима kt1, получили %v", discovery.Errors)
+	}
+}
@@ -0,0 +1,339 @@
+package core
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// reloadEnv создаёт пустой HOME и каталог плагинов из DEVTOOLBOX_PLUGIN_PATH.
+func reloadEnv(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 недоступен")
+	}
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	t.Setenv(plugins.PluginPathEnv, dir)
+	return dir
+}
+
+func writePlugin(t *testing.T, dir, name, output string) {
+	t.Helper()
+	script := "print(" + `"` + output + `"` + ")\n"
+	if err := os.WriteFile(filepath.Join(dir, name+".py"), []byte(script), 0644); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+}
+
+func generate(t *testing.T, registry *core.GeneratorRegistry, name string) string {
+	t.Helper()
+	generator, ok := registry.Get(name)
+	if !ok {
+		t.Fatalf("генератор %s не найден", name)
+	}
+	output, err := core.GenerateWithFormat(context.Background(), generator, "{}", core.FormatAuto)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	return strings.TrimSpace(output)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := reloadEnv(t)
+	writePlugin(t, dir, "kept", "kept")
+	writePlugin(t, dir, "changed", "v1")
+	writePlugin(t, dir, "removed", "removed")
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	old, release := reloader.Acquire()
+	kept, _ := old.Get("kept")
+
+	// Меняем время изменения явно: на быстрых ФС оно может совпасть.
+	writePlugin(t, dir, "changed", "v2")
+	later := time.Now().Add(time.Second)
+	os.Chtimes(filepath.Join(dir, "changed.py"), later, later)
+	os.Remove(filepath.Join(dir, "removed.py"))
+	writePlugin(t, dir, "added", "added")
+
+	report, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if got := report.String(); got != "added added; updated changed; removed removed" {
+		t.Errorf("неожиданный отчёт: %s", got)
+	}
+
+	current, releaseCurrent := reloader.Acquire()
+	defer releaseCurrent()
+	if generator, _ := current.Get("kept"); generator != kept {
+		t.Error("неизменившийся плагин должен переходить в новый реестр")
+	}
+	if _, ok := current.Get("removed"); ok {
+		t.Error("удалённый плагин остался в реестре")
+	}
+	if output := generate(t, current, "changed"); output != "v2" {
+		t.Errorf("ожидалась новая версия плагина, получили %q", output)
+	}
+	if _, ok := old.Get("removed"); !ok {
+		t.Error("реестр, полученный до перезагрузки, не должен меняться")
+	}
+	release()
+
+	report, err = reloader.Reload()
+	if err != nil || report.Changed() {
+		t.Errorf("ожидалась перезагрузка без изменений, получили %v, %v", report, err)
+	}
+}
+
+const workerPlugin = `import json, os, sys
+for line in sys.stdin:
+    message = json.loads(line)
+    if message["method"] == "shutdown":
+        break
+    reply = {"jsonrpc": "2.0", "id": message["id"], "result": {"version": 1, "files": [{"content": str(os.getpid())}]}}
+    print(json.dumps(reply), flush=True)
+`
+
+func TestReloader_InFlightRequests(t *testing.T) {
+	dir := filepath.Join(reloadEnv(t), "pid")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for name, content := range map[string]string{
+		"main.py":     workerPlugin,
+		"plugin.yaml": "name: pid\nversion: 1.0.0\nentrypoint: main.py\nprotocol: json\nworker:\n  enabled: true\n  pool_size: 1\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	old, release := reloader.Acquire()
+	pid := generate(t, old, "pid")
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	report, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if report.String() != "removed pid" {
+		t.Errorf("неожиданный отчёт: %s", report)
+	}
+
+	// Запрос, начатый до перезагрузки, продолжает работать с тем же процессом.
+	if output := generate(t, old, "pid"); output != pid {
+		t.Errorf("ожидался рабочий процесс %s, получили %q", pid, output)
+	}
+	release()
+
+	generator, _ := old.Get("pid")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := core.GenerateWithFormat(context.Background(), generator, "{}", core.FormatAuto)
+		if err != nil && strings.Contains(err.Error(), "closed") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("удалённый плагин не остановлен после завершения запросов, последняя ошибка: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloader_KeepsRuntimeGenerators(t *testing.T) {
+	dir := reloadEnv(t)
+	writePlugin(t, dir, "kept", "kept")
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	custom := &testGenerator{name: "custom"}
+	for _, err := range []error{
+		registry.Register(custom),
+		registry.Register(&testGenerator{name: "later"}),
+		registry.Alias("c", "custom"),
+		registry.Alias("k", "kept"),
+	} {
+		if err != nil {
+			t.Fatalf("неожиданная ошибка: %v", err)
+		}
+	}
+
+	// Плагин с именем генератора, добавленного во время работы, заменяет его.
+	writePlugin(t, dir, "later", "plugin")
+	report, err := reloader.Reload()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if got := report.String(); got != "added later" {
+		t.Errorf("неожиданный отчёт: %s", got)
+	}
+
+	current, release := reloader.Acquire()
+	defer release()
+	for _, name := range []string{"custom", "c"} {
+		if generator, _ := current.Get(name); generator != custom {
+			t.Errorf("генератор %s, добавленный во время работы, должен переходить в новый реестр", name)
+		}
+	}
+	if current.Resolve("k") != "kept" {
+		t.Error("псевдоним, добавленный во время работы, должен переходить в новый реестр")
+	}
+	if output := generate(t, current, "later"); output != "plugin" {
+		t.Errorf("ожидался найденный плагин, получили %q", output)
+	}
+}
+
+func TestReloader_ReloadFailureKeepsRegistry(t *testing.T) {
+	dir := reloadEnv(t)
+	writePlugin(t, dir, "kept", "kept")
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	home, _ := os.UserHomeDir()
+	if err := os.MkdirAll(filepath.Join(home, ".devtoolbox"), 0755); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".devtoolbox", "config.yaml"), []byte("plugins: {default: maybe}\n"), 0644); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	os.Remove(filepath.Join(dir, "kept.py"))
+
+	if _, err := reloader.Reload(); err == nil {
+		t.Fatal("ожидалась ошибка конфигурации")
+	}
+	current, release := reloader.Acquire()
+	defer release()
+	if _, ok := current.Get("kept"); !ok {
+		t.Error("после неудачной перезагрузки должен остаться прежний реестр")
+	}
+}
+
+func TestReloader_Watch(t *testing.T) {
+	dir := reloadEnv(t)
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	var mu sync.Mutex
+	var reports []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 20*time.Millisecond, func(report *core.ReloadReport, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			reports = append(reports, err.Error())
+			return
+		}
+		reports = append(reports, report.String())
+	})
+
+	writePlugin(t, dir, "watched", "watched")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		done := len(reports) > 0
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("изменение не обнаружено")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 1 || reports[0] != "added watched" {
+		t.Errorf("неожиданные отчёты: %v", reports)
+	}
+	current, release := reloader.Acquire()
+	defer release()
+	if _, ok := current.Get("watched"); !ok {
+		t.Error("новый плагин не зарегистрирован")
+	}
+}
+
+func TestReloader_WithoutLoad(t *testing.T) {
+	reloader := core.NewReloader(core.NewGeneratorRegistry(), nil)
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("без функции загрузки перезагрузка должна возвращать ошибку")
+	}
+}
+
+func TestReloader_Subscribe(t *testing.T) {
+	dir := reloadEnv(t)
+	writePlugin(t, dir, "removed", "removed")
+
+	registry, _, err := core.LoadGeneratorRegistry()
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	reloader := core.NewReloader(registry, core.LoadGeneratorRegistry)
+	defer reloader.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	cancel := reloader.Subscribe(func(change core.Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change.String())
+	})
+	defer cancel()
+
+	os.Remove(filepath.Join(dir, "removed.py"))
+	writePlugin(t, dir, "added", "added")
+	if _, err := reloader.Reload(); err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	// Изменения нового реестра тоже доходят до подписчиков.
+	current, release := reloader.Acquire()
+	current.Register(&testGenerator{name: "manual"})
+	release()
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := "added added, removed removed, added manual"
+	if got := strings.Join(changes, ", "); got != expected {
+		t.Errorf("ожидалось %q, получили %q", expected, got)
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+)
+
+const sqlSample = `{"id":1,"name":"Ann","address":{"city":"X"},"tags":["a"],"orders":[{"total":1.5}]}`
+
+func TestSQLGenerator_NestedJSON(t *testing.T) {
+	tests := []struct {
+		dialect  core.SQLDialect
+		expected string
+	}{
+		{
+			dialect: core.DialectPostgres,
+			expected: `CREATE TABLE generated_struct (
+    id BIGINT PRIMARY KEY,
+    address JSONB NOT NULL,
+    name TEXT NOT NULL,
+    orders JSONB NOT NULL,
+    tags TEXT[] NOT NULL
+);`,
+		},
+		{
+			dialect: core.DialectMySQL,
+			expected: `CREATE TABLE generated_struct (
+    id BIGINT PRIMARY KEY,
+    address JSON NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    orders JSON NOT NULL,
+    tags JSON NOT NULL
+);`,
+		},
+		{
+			dialect: core.DialectSQLite,
+			expected: `CREATE TABLE generated_struct (
+    id INTEGER PRIMARY KEY,
+    address TEXT NOT NULL,
+    name TEXT NOT NULL,
+    orders TEXT NOT NULL,
+    tags TEXT NOT NULL
+);`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dialect), func(t *testing.T) {
+			result, err := core.NewSQLGenerator(tt.dialect).Generate(sqlSample)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSQLGenerator_NestedTables(t *testing.T) {
+	generator, err := core.Configure(core.NewSQLGenerator(core.DialectPostgres), map[string]string{"nested": "tables"})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	result, err := generator.Generate(sqlSample)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	statements := strings.Split(result, "\n\n")
+	if len(statements) != 4 {
+		t.Fatalf("ожидалось 4 таблицы, получили %d:\n%s", len(statements), result)
+	}
+
+	if !strings.HasPrefix(statements[0], "CREATE TABLE address (") {
+		t.Errorf("таблица address должна создаваться до ссылающейся на нее таблицы:\n%s", result)
+	}
+
+	for _, expected := range []string{
+		"FOREIGN KEY (address_id) REFERENCES address (id)",
+		"FOREIGN KEY (generated_struct_id) REFERENCES generated_struct (id)",
+		"CREATE TABLE generated_struct_tags (",
+		"PRIMARY KEY (generated_struct_id, position)",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ожидалось %q в результате:\n%s", expected, result)
+		}
+	}
+}
+
+func TestSQLGenerator_NullValues(t *testing.T) {
+	generator, _ := core.Configure(core.NewSQLGenerator(core.DialectPostgres), map[string]string{"nested": "tables"})
+	result, err := generator.Generate(`{"name":"a","deleted_at":null,"tags":[null]}`)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+	for _, expected := range []string{"    deleted_at JSONB,\n", "    value JSONB,\n", "    name TEXT NOT NULL\n"} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ожидалось %q в результате:\n%s", expected, result)
+		}
+	}
+}
+
+func TestSQLGenerator_OpenAPI(t *testing.T) {
+	result, err := core.NewSQLGenerator(core.DialectMySQL).Generate(`
+openapi: 3.0.0
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    User:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: string
+          format: uuid
+        name:
+          type: string
+        role:
+          type: string
+          enum: [admin, member]
+`)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := "CREATE TABLE `user` (\n" +
+		"    id CHAR(36) PRIMARY KEY,\n" +
+		"    name VARCHAR(255) NOT NULL,\n" +
+		"    role VARCHAR(255) CHECK (role IN ('admin', 'member'))\n" +
+		");"
+	if result != expected {
+		t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, expected)
+	}
+}
+
+func TestGoStructGenerator_DBTags(t *testing.T) {
+	generator, err := core.Configure(core.NewGoStructGenerator(), map[string]string{"db": "tables"})
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	result, err := generator.Generate(`{"userName":"a","address":{"city":"X"}}`)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Address Address `json:\"address\" db:\"-\"`",
+		"UserName string `json:\"userName\" db:\"user_name\"`",
+		"AddressId int64 `json:\"-\" db:\"address_id\"`",
+		"Id int64 `json:\"-\" db:\"id\"`",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("ожидалось %q в результате:\n%s", expected, result)
+		}
+	}
+}
+
+// ddlColumns возвращает колонки каждой таблицы из CREATE TABLE.
+func ddlColumns(ddl string) map[string][]string {
+	tables := make(map[string][]string)
+	var table string
+	for _, line := range strings.Split(ddl, "\n") {
+		switch {
+		case strings.HasPrefix(line, "CREATE TABLE "):
+			table = strings.Trim(strings.Fields(line)[2], "\"`")
+			tables[table] = nil
+		case strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "    PRIMARY KEY") && !strings.HasPrefix(line, "    FOREIGN KEY"):
+			tables[table] = append(tables[table], strings.Trim(strings.Fields(line)[0], "\"`"))
+		}
+	}
+	for _, columns := range tables {
+		sort.Strings(columns)
+	}
+	return tables
+}
+
+// structColumns возвращает теги db каждой структуры по имени ее таблицы.
+func structColumns(code string) map[string][]string {
+	structs := make(map[string][]string)
+	tag := regexp.MustCompile(`db:"([^"]+)"`)
+	var table string
+	for _, line := range strings.Split(code, "\n") {
+		if strings.HasPrefix(line, "type ") {
+			table = core.SQLTableName(strings.Fields(line)[1])
+			structs[table] = nil
+		} else if match := tag.FindStringSubmatch(line); match != nil && match[1] != "-" {
+			structs[table] = append(structs[table], match[1])
+		}
+	}
+	for _, columns := range structs {
+		sort.Strings(columns)
+	}
+	return structs
+}
+
+func TestGoStructGenerator_DBTagsMatchDDL(t *testing.T) {
+	input := `{"name":"Ann","id_card":{"id":"A-1","issued":"2020-01-01"},"tags":["a"],"orders":[{"total":1.5,"items":[{"sku":"x"}]}],"meta":{"a":1}}`
+	for _, nested := range []string{"json", "tables"} {
+		t.Run(nested, func(t *testing.T) {
+			sqlGenerator, _ := core.Configure(core.NewSQLGenerator(core.DialectPostgres), map[string]string{"nested": nested})
+			goGenerator, _ := core.Configure(core.NewGoStructGenerator(), map[string]string{"db": nested})
+			ddl, err := sqlGenerator.Generate(input)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+			code, err := goGenerator.Generate(input)
+			if err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			structs := structColumns(code)
+			for table, columns := range ddlColumns(ddl) {
+				fields, ok := structs[table]
+				if !ok {
+					// Таблицы массивов значений не имеют своей структуры.
+					continue
+				}
+				if strings.Join(fields, ",") != strings.Join(columns, ",") {
+					t.Errorf("%s: колонки %v, теги db %v\n%s\n\n%s", table, columns, fields, ddl, code)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigure_Errors(t *testing.T) {
+	if _, err := core.Configure(core.NewGoStructGenerator(), map[string]string{"unknown": "1"}); err == nil {
+		t.Error("ожидалась ошибка для неизвестной настройки")
+	}
+
+	if _, err := core.Configure(core.NewSQLGenerator(core.DialectSQLite), map[string]string{"nested": "xml"}); err == nil {
+		t.Error("ожидалась ошибка для недопустимого значения")
+	}
+
+	if _, err := core.Configure(&testGenerator{name: "test-generator"}, map[string]string{"a": "b"}); err == nil {
+		t.Error("ожидалась ошибка для генератора без настроек")
+	}
+}
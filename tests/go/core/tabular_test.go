@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/ir"
+)
+
+func TestInferCSV(t *testing.T) {
+	input := "id,price,active,created_at,comment,code\n" +
+		"1,10,true,2024-01-02,,A1\n" +
+		"2,10.5,false,2024-01-03T10:00:00Z,ok,42\n"
+
+	doc, err := core.InferCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := []struct {
+		name     string
+		kind     ir.Kind
+		nullable bool
+	}{
+		{"id", ir.KindInteger, false},
+		{"price", ir.KindNumber, false},
+		{"active", ir.KindBoolean, false},
+		{"created_at", ir.KindTime, false},
+		{"comment", ir.KindString, true},
+		{"code", ir.KindString, false},
+	}
+
+	fields := doc.Models[0].Type.Fields
+	if len(fields) != len(expected) {
+		t.Fatalf("ожидалось %d полей, получили %d", len(expected), len(fields))
+	}
+
+	for i, tt := range expected {
+		field := fields[i]
+		if field.Name != tt.name || field.Type.Kind != tt.kind || field.Type.Nullable != tt.nullable {
+			t.Errorf("поле %d: ожидалось %s %s nullable=%v, получили %s %s nullable=%v",
+				i, tt.name, tt.kind, tt.nullable, field.Name, field.Type.Kind, field.Type.Nullable)
+		}
+	}
+}
+
+func TestInferNDJSON(t *testing.T) {
+	input := `{"id":1,"user":{"name":"a"}}
+{"id":2,"user":{"name":"b","age":3},"note":null}
+`
+
+	doc, err := core.InferNDJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	result, err := core.NewGoStructGenerator().GenerateDocument(doc)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := `type Record struct {
+	Id int ` + "`json:\"id\"`" + `
+	Note interface{} ` + "`json:\"note,omitempty\"`" + `
+	User User ` + "`json:\"user\"`" + `
+}
+
+type User struct {
+	Age int ` + "`json:\"age,omitempty\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}`
+	if result != expected {
+		t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, expected)
+	}
+}
+
+func TestInferNDJSON_EmptyFirstRecord(t *testing.T) {
+	input := `{"user":{}}
+{"a":1,"user":{"name":"b"}}
+`
+
+	doc, err := core.InferNDJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	result, err := core.NewGoStructGenerator().GenerateDocument(doc)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	for _, expected := range []string{
+		"A int `json:\"a,omitempty\"`",
+		"Name string `json:\"name,omitempty\"`",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("поле из второй записи должно быть необязательным, ожидалось %q:\n%s", expected, result)
+		}
+	}
+}
+
+func TestInferNDJSON_Errors(t *testing.T) {
+	inputs := []string{"", "{\"id\":1}\n[1,2]\n", "{\"id\":1}\n{\"id\":\n"}
+
+	for _, input := range inputs {
+		if _, err := core.InferNDJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("ожидалась ошибка для %q", input)
+		}
+	}
+}
+
+func TestPythonDataclassGenerator_Generate(t *testing.T) {
+	generator := core.NewPythonDataclassGenerator()
+
+	result, err := core.GenerateWithFormat(context.Background(), generator, "id,name,score\n1,Ann,\n2,Bob,1.5\n", core.FormatCSV)
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	expected := `from __future__ import annotations
+
+from dataclasses import dataclass
+from typing import Optional
+
+
+@dataclass
+class Record:
+    id: int
+    name: str
+    score: Optional[float] = None`
+	if result != expected {
+		t.Errorf("результат не соответствует ожидаемому:\nПолучено:\n%s\n\nОжидалось:\n%s", result, expected)
+	}
+}
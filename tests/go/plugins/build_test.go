@@ -0,0 +1,117 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// goPluginManifest builds the example Go plugin into dir; the build runs in
+// dir, so it points the go tool at the example with -C. It keeps the go
+// caches of the real home directory, which tests replace.
+func goPluginManifest(t *testing.T, dir, extra string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("Skipping test - go not available")
+	}
+	for _, name := range []string{"GOCACHE", "GOMODCACHE"} {
+		value, err := exec.Command("go", "env", name).Output()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Setenv(name, strings.TrimSpace(string(value)))
+	}
+	example, err := filepath.Abs(filepath.Join("..", "..", "..", "plugins", "custom", "json_schema"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(dir, "plugin.yaml")
+	writeFile(t, path, `name: json-schema
+version: 1.0.0
+language: exec
+entrypoint: bin/json-schema
+build: [go, -C, "`+filepath.ToSlash(example)+`", build, -o, "`+filepath.ToSlash(filepath.Join(dir, "bin", "json-schema"))+`", .]
+protocol: json
+options:
+  title:
+    default: Test
+`+extra)
+	return path
+}
+
+func TestPluginManager_BuildsGoPlugin(t *testing.T) {
+	dir := t.TempDir()
+	path := goPluginManifest(t, dir, "worker:\n  enabled: true\n")
+	t.Setenv("HOME", t.TempDir())
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(path, plugins.TrustTrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	manifest, err := plugins.LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := plugins.NewPluginFromManifest(manifest)
+	plugin.SetTrust(plugins.TrustTrusted)
+	t.Cleanup(func() { plugin.Close() })
+
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "User", Type: &ir.Type{Kind: ir.KindObject, Fields: []*ir.Field{
+		{Name: "id", Type: ir.Primitive(ir.KindInteger), Required: true},
+	}}})
+
+	for i := 0; i < 2; i++ {
+		response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: doc})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		output := response.Output()
+		if !strings.Contains(output, `"$ref": "#/$defs/User"`) || !strings.Contains(output, `"title": "Test"`) {
+			t.Errorf("unexpected output:\n%s", output)
+		}
+	}
+
+	_, err = plugin.Invoke(context.Background(), &plugins.Request{Input: "{}"})
+	var pluginErr *plugins.PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.Code != plugins.CodeInvalidInput {
+		t.Errorf("expected invalid_input error, got %v", err)
+	}
+}
+
+func TestPluginManager_BuildErrors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("Skipping test - sh not available")
+	}
+
+	tests := []struct {
+		build   string
+		message string
+	}{
+		{`[sh, -c, "echo compile error; exit 1"]`, "compile error"},
+		{`[sh, -c, "exit 0"]`, "did not produce entrypoint bin/gen"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: gen
+version: 1.0.0
+language: exec
+entrypoint: bin/gen
+build: `+tt.build+`
+`)
+		err := plugins.NewPluginManager().AddPlugin(filepath.Join(dir, "plugin.yaml"), plugins.TrustUntrusted)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.build, tt.message, err)
+		}
+	}
+}
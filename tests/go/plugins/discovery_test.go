@@ -0,0 +1,248 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func discoveredPlugin(discovery *plugins.Discovery, name string) *plugins.PluginInfo {
+	for i := range discovery.Plugins {
+		if discovery.Plugins[i].Name == name {
+			return &discovery.Plugins[i]
+		}
+	}
+	return nil
+}
+
+func TestPluginManager_DefaultSearchPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".devtoolbox"), 0755)
+	extra := t.TempDir()
+	t.Setenv(plugins.PluginPathEnv, extra)
+
+	project, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.MkdirAll(filepath.Join(project, ".devtoolbox"), 0755)
+	os.MkdirAll(filepath.Join(project, "src", "api"), 0755)
+	chdir(t, filepath.Join(project, "src", "api"))
+
+	if dir := plugins.FindProjectDir(); dir != project {
+		t.Errorf("expected project %s, got %q", project, dir)
+	}
+
+	manager := plugins.NewPluginManager()
+	paths := manager.DefaultSearchPaths()
+	scopes := map[string][]string{}
+	for i, path := range paths {
+		scopes[path.Scope] = append(scopes[path.Scope], path.Dir)
+		if i > 0 && scopeOrder(paths[i-1].Scope) > scopeOrder(path.Scope) {
+			t.Errorf("search paths out of precedence order: %+v", paths)
+		}
+	}
+	if len(scopes[plugins.ScopeSystem]) == 0 {
+		t.Errorf("expected system search paths, got %+v", paths)
+	}
+	if got := scopes[plugins.ScopeUser]; len(got) != 1 || got[0] != manager.PluginsDir() {
+		t.Errorf("unexpected user search paths %v", got)
+	}
+	cwdOfficial := filepath.Join(project, "src", "api", "plugins", "official")
+	if got := scopes[plugins.ScopeProject]; len(got) != 2 || got[0] != filepath.Join(project, ".devtoolbox", "plugins") || got[1] != cwdOfficial {
+		t.Errorf("unexpected project search paths %v", got)
+	}
+	for _, dir := range scopes[plugins.ScopeSystem] {
+		if dir == cwdOfficial {
+			t.Errorf("plugins/official in the working directory must not be a system path: %v", scopes[plugins.ScopeSystem])
+		}
+	}
+	if got := scopes[plugins.ScopeEnv]; len(got) != 1 || got[0] != extra {
+		t.Errorf("unexpected env search paths %v", got)
+	}
+
+	chdir(t, home)
+	if dir := plugins.FindProjectDir(); dir != "" {
+		t.Errorf("expected ~/.devtoolbox not to be a project, got %q", dir)
+	}
+}
+
+func TestPluginManager_DiscoverWorkingDirectoryOfficial(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(plugins.PluginPathEnv, "")
+	repo := t.TempDir()
+	writeTree(t, repo, map[string]string{
+		"plugins/official/evil.plugin.yaml": "name: evil\nversion: 1.0.0\nentrypoint: evil.py\nsandbox: none\n",
+		"plugins/official/evil.py":          "print('evil')\n",
+	})
+	chdir(t, repo)
+
+	discovery, err := plugins.NewPluginManager().Discover(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := discoveredPlugin(discovery, "evil")
+	if plugin == nil {
+		t.Fatalf("expected the plugin to be found, got %+v", discovery.Plugins)
+	}
+	if plugin.Scope != plugins.ScopeProject || plugin.Trust != plugins.TrustUntrusted {
+		t.Errorf("expected a project plugin that is untrusted, got %s %s", plugin.Scope, plugin.Trust)
+	}
+	if !plugins.Sandboxed(plugin.Trust, plugin.Manifest) {
+		t.Error("expected sandbox: none to be ignored for the plugin")
+	}
+}
+
+func scopeOrder(scope string) int {
+	return map[string]int{plugins.ScopeSystem: 0, plugins.ScopeUser: 1, plugins.ScopeProject: 2, plugins.ScopeEnv: 3}[scope]
+}
+
+func TestPluginManager_Discover(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manager := plugins.NewPluginManager()
+	system, project, env := t.TempDir(), t.TempDir(), t.TempDir()
+	manager.SetSearchPaths([]plugins.SearchPath{
+		{Scope: plugins.ScopeSystem, Dir: system},
+		{Scope: plugins.ScopeUser, Dir: manager.PluginsDir()},
+		{Scope: plugins.ScopeProject, Dir: project},
+		{Scope: plugins.ScopeEnv, Dir: env},
+	})
+
+	writeTree(t, system, map[string]string{
+		"ts_gen.py":           "print('system')\n",
+		"ts_gen.plugin.yaml":  "name: ts-gen\nversion: 1.0.0\nentrypoint: ts_gen.py\n",
+		"shared.py":           "print('system')\n",
+		"go-struct.py":        "print('shadowing a built-in')\n",
+		"kotlin/plugin.yaml":  "name: kotlin\nversion: 1.0.0\nentrypoint: main.py\n",
+		"kotlin/main.py":      "print('system')\n",
+		"broken/plugin.yaml":  "name: Broken\nversion: 1.0.0\nentrypoint: main.py\n",
+		"broken/main.py":      "",
+		".hidden/plugin.yaml": "name: hidden\nversion: 1.0.0\nentrypoint: main.py\n",
+		".hidden/main.py":     "",
+		"unbuilt/plugin.yaml": "name: unbuilt\nversion: 1.0.0\nlanguage: exec\nentrypoint: bin/gen\nbuild: go build -o bin/gen .\n",
+		"notes/README.md":     "not a plugin\n",
+		"helper.sh":           "#!/bin/sh\n",
+	})
+	writeTree(t, project, map[string]string{
+		"shared.py":          "print('project')\n",
+		"kotlin/plugin.yaml": "name: kotlin\nversion: 2.0.0\nentrypoint: main.py\n",
+		"kotlin/main.py":     "print('project')\n",
+	})
+	writeTree(t, env, map[string]string{
+		"shared.py": "print('env')\n",
+	})
+
+	// Installed plugins are found in the user scope and keep what plugin
+	// install recorded.
+	src := t.TempDir()
+	writeTree(t, src, pluginFiles("1.0.0"))
+	installSource(t, manager, src, plugins.InstallOptions{Trust: plugins.TrustTrusted})
+	// Plugins added elsewhere belong to the user scope too.
+	added := t.TempDir()
+	writeFile(t, filepath.Join(added, "added.py"), "print('added')\n")
+	if err := manager.AddPlugin(filepath.Join(added, "added.py"), plugins.TrustUntrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	discovery, err := manager.Discover([]string{"go-struct"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, plugin := range discovery.Plugins {
+		names = append(names, plugin.Name)
+	}
+	if got := strings.Join(names, ","); got != "added,greeter,kotlin,shared,ts-gen" {
+		t.Fatalf("unexpected plugins %s", got)
+	}
+
+	tests := []struct {
+		name  string
+		scope string
+		path  string
+		trust plugins.Trust
+	}{
+		{"ts-gen", plugins.ScopeSystem, filepath.Join(system, "ts_gen.py"), plugins.TrustOfficial},
+		{"kotlin", plugins.ScopeProject, filepath.Join(project, "kotlin", "main.py"), plugins.TrustUntrusted},
+		{"shared", plugins.ScopeEnv, filepath.Join(env, "shared.py"), plugins.TrustUntrusted},
+		{"greeter", plugins.ScopeUser, filepath.Join(manager.PluginsDir(), "greeter", "main.py"), plugins.TrustTrusted},
+		{"added", plugins.ScopeUser, filepath.Join(added, "added.py"), plugins.TrustUntrusted},
+	}
+	for _, tt := range tests {
+		plugin := discoveredPlugin(discovery, tt.name)
+		if plugin.Scope != tt.scope || plugin.Path != tt.path || plugin.Trust != tt.trust {
+			t.Errorf("%s: expected %s %s %s, got %s %s %s", tt.name, tt.scope, tt.path, tt.trust, plugin.Scope, plugin.Path, plugin.Trust)
+		}
+	}
+	if greeter := discoveredPlugin(discovery, "greeter"); greeter.Integrity == nil || greeter.Source == nil {
+		t.Errorf("expected installed plugin to keep its checksums and source, got %+v", greeter)
+	}
+	if kotlin := discoveredPlugin(discovery, "kotlin"); kotlin.Manifest.Version != "2.0.0" {
+		t.Errorf("expected project manifest, got %+v", kotlin.Manifest)
+	}
+
+	conflicts := map[string]plugins.Conflict{}
+	for _, conflict := range discovery.Conflicts {
+		conflicts[conflict.Name] = conflict
+	}
+	if len(conflicts) != 3 {
+		t.Errorf("expected conflicts for go-struct, kotlin and shared, got %+v", discovery.Conflicts)
+	}
+	if conflict := conflicts["go-struct"]; conflict.Used.Scope != plugins.ScopeBuiltin || !strings.Contains(conflict.String(), "using the built-in generator") {
+		t.Errorf("unexpected conflict %s", conflict)
+	}
+	shared := conflicts["shared"]
+	if len(shared.Shadowed) != 2 || shared.Shadowed[0].Scope != plugins.ScopeProject || shared.Shadowed[1].Scope != plugins.ScopeSystem {
+		t.Errorf("expected env to shadow project and system, got %s", shared)
+	}
+	if !strings.Contains(shared.String(), "using env "+filepath.Join(env, "shared.py")) {
+		t.Errorf("unexpected conflict message %s", shared)
+	}
+
+	var errs []string
+	for _, err := range discovery.Errors {
+		errs = append(errs, err.Error())
+	}
+	joined := strings.Join(errs, "\n")
+	if len(errs) != 2 || !strings.Contains(joined, `name "Broken"`) || !strings.Contains(joined, "plugin unbuilt") {
+		t.Errorf("expected the broken and unbuilt plugins to be reported, got %v", errs)
+	}
+
+	list, err := manager.ListPlugins()
+	if err != nil || len(list) != 6 {
+		t.Errorf("expected ListPlugins to return the discovered plugins, got %d, %v", len(list), err)
+	}
+}
+
+func TestPluginManager_DiscoverPluginDirectory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, pluginFiles("1.0.0"))
+
+	manager := plugins.NewPluginManager()
+	manager.SetSearchPaths([]plugins.SearchPath{{Scope: plugins.ScopeEnv, Dir: dir}})
+	discovery, err := manager.Discover(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(discovery.Plugins) != 1 || discovery.Plugins[0].Name != "greeter" {
+		t.Errorf("expected a search path with a manifest to be one plugin, got %+v", discovery.Plugins)
+	}
+}
@@ -0,0 +1,156 @@
+package plugins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// shellScript answers a JSON protocol request with the option "greeting".
+const shellScript = `#!/bin/sh
+request=$(cat)
+greeting=$(printf '%s' "$request" | sed -n 's/.*"greeting":"\([^"]*\)".*/\1/p')
+printf '{"version":1,"files":[{"content":"%s from sh"}]}\n' "$greeting"
+`
+
+func requireUnix(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test - shell plugins need a Unix shell")
+	}
+}
+
+func TestExecPlugin_Invoke(t *testing.T) {
+	requireUnix(t)
+
+	tests := []struct {
+		name        string
+		entrypoint  string
+		interpreter string
+		executable  bool
+	}{
+		{"default interpreter by extension", "gen.sh", "", false},
+		{"executable run directly", "gen", "", true},
+		{"interpreter from manifest", "gen.txt", "interpreter: [sh, -e]\n", false},
+		{"interpreter as string", "gen.txt", "interpreter: sh -e\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, tt.entrypoint), shellScript)
+			if tt.executable {
+				os.Chmod(filepath.Join(dir, tt.entrypoint), 0755)
+			}
+			writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: shell-gen
+version: 1.0.0
+language: exec
+entrypoint: `+tt.entrypoint+`
+protocol: json
+options:
+  greeting:
+    default: hello
+`+tt.interpreter)
+
+			manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			plugin := plugins.NewPluginFromManifest(manifest)
+			if _, ok := plugin.(*plugins.ExecPlugin); !ok {
+				t.Fatalf("expected *ExecPlugin, got %T", plugin)
+			}
+
+			configured, err := plugin.WithOptions(map[string]string{"greeting": "hi"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			response, err := configured.Invoke(context.Background(), &plugins.Request{IR: ir.NewDocument()})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output := response.Output(); output != "hi from sh" {
+				t.Errorf("unexpected output %q", output)
+			}
+		})
+	}
+}
+
+func TestExecPlugin_MissingInterpreter(t *testing.T) {
+	requireUnix(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.txt"), shellScript)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: missing
+version: 1.0.0
+language: exec
+entrypoint: gen.txt
+interpreter: devtoolbox-no-such-interpreter
+`)
+
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = plugins.NewPluginFromManifest(manifest).Generate("{}")
+	if err == nil || !strings.Contains(err.Error(), "devtoolbox-no-such-interpreter") {
+		t.Errorf("expected interpreter error, got %v", err)
+	}
+}
+
+func TestLoadManifest_ExecEntrypoint(t *testing.T) {
+	requireUnix(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen"), shellScript)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: shell-gen
+version: 1.0.0
+language: exec
+entrypoint: gen
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "not executable") {
+		t.Errorf("expected not executable error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: ruby-gen\nversion: 1.0.0\nlanguage: ruby\nentrypoint: gen\n")
+	_, err = plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `unsupported language "ruby"`) {
+		t.Errorf("expected unsupported language error, got %v", err)
+	}
+}
+
+func TestPluginManager_AddExecPlugin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.js"), "console.log('ok')\n")
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(filepath.Join(dir, "gen.js"), plugins.TrustUntrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, err := manager.LoadPlugins()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "gen" || list[0].Type != plugins.LanguageExec {
+		t.Fatalf("expected exec plugin gen, got %+v", list)
+	}
+
+	plugin, err := plugins.NewExternalPlugin(list[0].Type, list[0].Name, list[0].Description, list[0].Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := plugin.(*plugins.ExecPlugin); !ok {
+		t.Errorf("expected *ExecPlugin, got %T", plugin)
+	}
+	if _, err := plugins.NewExternalPlugin("cobol", "gen", "", list[0].Path); err == nil {
+		t.Error("expected error for unknown plugin type")
+	}
+}
@@ -0,0 +1,447 @@
+package plugins
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func pluginFiles(version string) map[string]string {
+	return map[string]string{
+		"plugin.yaml": "name: greeter\nversion: " + version + "\nentrypoint: main.py\n",
+		"main.py":     "print('hello')\n",
+	}
+}
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(name)), content)
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// prefixed puts files under a top-level folder, as release archives do.
+func prefixed(prefix string, files map[string]string) map[string]string {
+	result := map[string]string{}
+	for name, content := range files {
+		result[prefix+"/"+name] = content
+	}
+	return result
+}
+
+func installSource(t *testing.T, manager *plugins.PluginManager, spec string, options plugins.InstallOptions) *plugins.PluginInfo {
+	t.Helper()
+	source, err := plugins.ParseSource(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := manager.InstallPlugin(source, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return info
+}
+
+func TestParseSource(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		spec     string
+		kind     string
+		location string
+		ref      string
+	}{
+		{"git+https://example.com/acme/gen", plugins.SourceGit, "https://example.com/acme/gen", ""},
+		{"https://example.com/acme/gen.git@v1.2.0", plugins.SourceGit, "https://example.com/acme/gen.git", "v1.2.0"},
+		{"git@example.com:acme/gen.git", plugins.SourceGit, "git@example.com:acme/gen.git", ""},
+		{"git@example.com:acme/gen.git@main", plugins.SourceGit, "git@example.com:acme/gen.git", "main"},
+		{"https://example.com/gen-1.0.0.tar.gz", plugins.SourceArchive, "https://example.com/gen-1.0.0.tar.gz", ""},
+		{"kotlin-data", plugins.SourceIndex, "kotlin-data", ""},
+		{"kotlin-data@1.2.0", plugins.SourceIndex, "kotlin-data", "1.2.0"},
+		{dir, plugins.SourcePath, dir, ""},
+	}
+
+	for _, tt := range tests {
+		source, err := plugins.ParseSource(tt.spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if source.Kind != tt.kind || source.Location != tt.location || source.Ref != tt.ref {
+			t.Errorf("%s: got %+v", tt.spec, source)
+		}
+	}
+
+	for _, spec := range []string{"", "Not A Name", "./missing/plugin.zip/"} {
+		if _, err := plugins.ParseSource(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestPluginManager_InstallFromDirectory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := t.TempDir()
+	writeTree(t, src, pluginFiles("1.0.0"))
+	writeFile(t, filepath.Join(src, ".git", "HEAD"), "ref: refs/heads/main\n")
+
+	manager := plugins.NewPluginManager()
+	info := installSource(t, manager, src, plugins.InstallOptions{})
+
+	dir := filepath.Join(manager.PluginsDir(), "greeter")
+	if info.Path != filepath.Join(dir, "main.py") || info.Source.Kind != plugins.SourcePath {
+		t.Errorf("unexpected plugin %+v", info)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		t.Error("expected .git to be skipped")
+	}
+
+	os.RemoveAll(src)
+	list, err := manager.LoadPlugins()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected one plugin, got %v, %v", list, err)
+	}
+	if _, err := os.Stat(list[0].Path); err != nil {
+		t.Errorf("installed plugin depends on its source: %v", err)
+	}
+
+	if _, err := manager.InstallPlugin(&plugins.Source{Kind: plugins.SourcePath, Location: dir}, plugins.InstallOptions{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+
+	if _, err := manager.UninstallPlugin("greeter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("expected plugin files to be deleted")
+	}
+	if list, _ := manager.LoadPlugins(); len(list) != 0 {
+		t.Errorf("expected no plugins, got %+v", list)
+	}
+}
+
+func TestPluginManager_InstallRefusesUntrustedBuild(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"plugin.yaml": "name: greeter\nversion: 1.0.0\nentrypoint: main.py\nbuild: touch built\n",
+		"main.py":     "print('hello')\n",
+	})
+
+	manager := plugins.NewPluginManager()
+	source := &plugins.Source{Kind: plugins.SourcePath, Location: src}
+	for _, trust := range []plugins.Trust{"", plugins.TrustUntrusted} {
+		_, err := manager.InstallPlugin(source, plugins.InstallOptions{Trust: trust})
+		if err == nil || !strings.Contains(err.Error(), "--trust trusted") {
+			t.Fatalf("expected the build step to be refused, got %v", err)
+		}
+	}
+	dir := filepath.Join(manager.PluginsDir(), "greeter")
+	if _, err := os.Stat(filepath.Join(dir, "built")); err == nil {
+		t.Error("the build command of an untrusted plugin must not run")
+	}
+	if list, _ := manager.LoadPlugins(); len(list) != 0 {
+		t.Errorf("expected no plugins, got %+v", list)
+	}
+
+	info := installSource(t, manager, src, plugins.InstallOptions{Trust: plugins.TrustTrusted})
+	if _, err := os.Stat(filepath.Join(dir, "built")); err != nil || info.Trust != plugins.TrustTrusted {
+		t.Errorf("expected a trusted plugin to be built, got %+v, %v", info, err)
+	}
+}
+
+func TestPluginManager_UninstallKeepsAddedFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, pluginFiles("1.0.0"))
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustUntrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := manager.UpdatePlugin("greeter", ""); err == nil {
+		t.Error("expected added plugins to have no source to update from")
+	}
+	if _, err := manager.UninstallPlugin("greeter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.py")); err != nil {
+		t.Errorf("expected files of added plugin to be kept: %v", err)
+	}
+}
+
+func TestPluginManager_InstallArchives(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	archives := map[string][]byte{
+		"greeter-1.0.0.tar.gz": tarGz(t, prefixed("greeter-1.0.0", pluginFiles("1.0.0"))),
+		"greeter.zip":          zipArchive(t, pluginFiles("1.0.0")),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for name, data := range archives {
+		writeFile(t, filepath.Join(dir, name), string(data))
+	}
+
+	manager := plugins.NewPluginManager()
+	for _, spec := range []string{
+		filepath.Join(dir, "greeter-1.0.0.tar.gz"),
+		filepath.Join(dir, "greeter.zip"),
+		server.URL + "/greeter-1.0.0.tar.gz",
+		server.URL + "/greeter.zip",
+	} {
+		info := installSource(t, manager, spec, plugins.InstallOptions{})
+		if _, err := os.Stat(info.Path); err != nil || info.Source.Kind != plugins.SourceArchive {
+			t.Errorf("%s: unexpected plugin %+v: %v", spec, info, err)
+		}
+		if _, err := manager.UninstallPlugin("greeter"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	source, _ := plugins.ParseSource(server.URL + "/missing.zip")
+	if _, err := manager.InstallPlugin(source, plugins.InstallOptions{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected download error, got %v", err)
+	}
+}
+
+func TestPluginManager_InstallRejectsUnsafeArchive(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	files := pluginFiles("1.0.0")
+	files["../escaped.txt"] = "x"
+	path := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeFile(t, path, string(tarGz(t, files)))
+
+	manager := plugins.NewPluginManager()
+	source, _ := plugins.ParseSource(path)
+	if _, err := manager.InstallPlugin(source, plugins.InstallOptions{}); err == nil || !strings.Contains(err.Error(), "escapes") {
+		t.Errorf("expected unsafe archive to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.PluginsDir(), "escaped.txt")); err == nil {
+		t.Error("archive entry was written outside the plugin directory")
+	}
+	if entries, _ := os.ReadDir(manager.PluginsDir()); len(entries) != 0 {
+		t.Errorf("expected staging to be cleaned up, got %v", entries)
+	}
+}
+
+func TestPluginManager_InstallRejectsSymlinks(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// Each link stays inside the archive on paper, but together they lead
+	// two levels above the staging directory.
+	links := [][2]string{{"d/e/u1", ".."}, {"d/e/u1/u2", "../.."}, {"d/e/u1/u2/u3", ".."}}
+	escaped := "d/e/u1/u2/u3/ESCAPED"
+
+	var tarBuf bytes.Buffer
+	gz := gzip.NewWriter(&tarBuf)
+	tw := tar.NewWriter(gz)
+	for name, content := range pluginFiles("1.0.0") {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	for _, link := range links {
+		tw.WriteHeader(&tar.Header{Name: link[0], Linkname: link[1], Mode: 0777, Typeflag: tar.TypeSymlink})
+	}
+	tw.WriteHeader(&tar.Header{Name: escaped, Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+	tw.Write([]byte("x"))
+	tw.Close()
+	gz.Close()
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, content := range pluginFiles("1.0.0") {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	for _, link := range links {
+		header := &zip.FileHeader{Name: link[0]}
+		header.SetMode(fs.ModeSymlink | 0777)
+		w, _ := zw.CreateHeader(header)
+		w.Write([]byte(link[1]))
+	}
+	w, _ := zw.Create(escaped)
+	w.Write([]byte("x"))
+	zw.Close()
+
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local")
+	writeTree(t, local, pluginFiles("1.0.0"))
+	if err := os.Symlink("/etc", filepath.Join(local, "etc")); err != nil {
+		t.Skipf("Skipping test - symlinks not supported: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "evil.tar.gz"), tarBuf.String())
+	writeFile(t, filepath.Join(dir, "evil.zip"), zipBuf.String())
+
+	manager := plugins.NewPluginManager()
+	for _, spec := range []string{filepath.Join(dir, "evil.tar.gz"), filepath.Join(dir, "evil.zip"), local} {
+		source, _ := plugins.ParseSource(spec)
+		if _, err := manager.InstallPlugin(source, plugins.InstallOptions{}); err == nil || !strings.Contains(err.Error(), "symlink") {
+			t.Errorf("%s: expected symlinks to be rejected, got %v", spec, err)
+		}
+		for _, path := range []string{filepath.Join(manager.PluginsDir(), "ESCAPED"), filepath.Join(filepath.Dir(manager.PluginsDir()), "ESCAPED")} {
+			if _, err := os.Stat(path); err == nil {
+				t.Errorf("%s: archive entry was written to %s", spec, path)
+			}
+		}
+		if entries, _ := os.ReadDir(manager.PluginsDir()); len(entries) != 0 {
+			t.Errorf("%s: expected staging to be cleaned up, got %v", spec, entries)
+		}
+	}
+}
+
+func TestPluginManager_InstallFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("Skipping test - git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	writeTree(t, repo, prefixed("greeter", pluginFiles("1.0.0")))
+	git("add", "-A")
+	git("commit", "-qm", "v1")
+	git("tag", "v1.0.0")
+	writeTree(t, repo, prefixed("greeter", pluginFiles("1.1.0")))
+	git("commit", "-qam", "v1.1")
+	git("tag", "v1.1.0")
+
+	manager := plugins.NewPluginManager()
+	info := installSource(t, manager, "git+"+repo+"@v1.0.0", plugins.InstallOptions{Subdir: "greeter"})
+	if info.Manifest.Version != "1.0.0" || info.Source.Ref != "v1.0.0" || len(info.Source.Resolved) != 40 {
+		t.Errorf("unexpected plugin %+v %+v", info, info.Source)
+	}
+	if _, err := os.Stat(filepath.Join(manager.PluginsDir(), "greeter", ".git")); err == nil {
+		t.Error("expected .git to be removed")
+	}
+
+	old, updated, err := manager.UpdatePlugin("greeter", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if old.Manifest.Version != "1.0.0" || updated.Manifest.Version != "1.0.0" {
+		t.Errorf("expected pinned plugin to stay on 1.0.0, got %s", updated.Manifest.Version)
+	}
+
+	_, updated, err = manager.UpdatePlugin("greeter", "v1.1.0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Manifest.Version != "1.1.0" || updated.Source.Subdir != "greeter" {
+		t.Errorf("expected update to 1.1.0, got %+v", updated.Source)
+	}
+
+	_, _, err = manager.UpdatePlugin("greeter", "no-such-ref")
+	if err == nil {
+		t.Fatal("expected error for unknown ref")
+	}
+	list, _ := manager.LoadPlugins()
+	if len(list) != 1 || list[0].Manifest.Version != "1.1.0" {
+		t.Errorf("failed update changed the plugin: %+v", list)
+	}
+	if _, err := os.Stat(list[0].Path); err != nil {
+		t.Errorf("failed update removed the plugin files: %v", err)
+	}
+}
+
+func TestPluginManager_InstallFromIndex(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "dist", "greeter-1.0.0.tar.gz"), string(tarGz(t, pluginFiles("1.0.0"))))
+	writeFile(t, filepath.Join(dir, "dist", "greeter-1.1.0.zip"), string(zipArchive(t, pluginFiles("1.1.0"))))
+	index := filepath.Join(dir, "index.yaml")
+	writeFile(t, index, `plugins:
+  - name: greeter
+    version: 1.0.0
+    source: dist/greeter-1.0.0.tar.gz
+  - name: greeter
+    version: 1.1.0
+    source: dist/greeter-1.1.0.zip
+`)
+
+	manager := plugins.NewPluginManager()
+	if _, err := manager.InstallPlugin(&plugins.Source{Kind: plugins.SourceIndex, Location: "greeter"}, plugins.InstallOptions{}); err == nil || !strings.Contains(err.Error(), "no plugin index") {
+		t.Errorf("expected missing index error, got %v", err)
+	}
+
+	t.Setenv(plugins.IndexEnv, index)
+	info := installSource(t, manager, "greeter", plugins.InstallOptions{})
+	if info.Manifest.Version != "1.1.0" || !strings.HasSuffix(info.Source.Resolved, "greeter-1.1.0.zip") {
+		t.Errorf("expected latest version, got %s from %s", info.Manifest.Version, info.Source.Resolved)
+	}
+	if _, err := manager.UninstallPlugin("greeter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info = installSource(t, manager, "greeter@1.0.0", plugins.InstallOptions{})
+	if info.Manifest.Version != "1.0.0" || info.Source.Index != index {
+		t.Errorf("expected pinned 1.0.0 from %s, got %+v", index, info.Source)
+	}
+	if _, updated, err := manager.UpdatePlugin("greeter", ""); err != nil || updated.Manifest.Version != "1.0.0" {
+		t.Errorf("expected pinned plugin to stay on 1.0.0, got %v", err)
+	}
+	if _, updated, err := manager.UpdatePlugin("greeter", "1.1.0"); err != nil || updated.Manifest.Version != "1.1.0" {
+		t.Errorf("expected update to 1.1.0, got %v", err)
+	}
+	if _, _, err := manager.UpdatePlugin("greeter", "2.0.0"); err == nil || !strings.Contains(err.Error(), "greeter@2.0.0 not found") {
+		t.Errorf("expected unknown version error, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func addedPlugin(t *testing.T, manager *plugins.PluginManager, name string) plugins.PluginInfo {
+	t.Helper()
+	list, err := manager.LoadPlugins()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, info := range list {
+		if info.Name == name {
+			return info
+		}
+	}
+	t.Fatalf("plugin %s not found in %+v", name, list)
+	return plugins.PluginInfo{}
+}
+
+func signingKey(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	private, public, err := plugins.GenerateKey(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyPath := filepath.Join(dir, name+".key")
+	writeFile(t, keyPath, private+"\n")
+	return keyPath, public
+}
+
+func TestIntegrity_RefusesTamperedPlugin(t *testing.T) {
+	requirePython(t)
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml": "name: greeter\nversion: 1.0.0\nentrypoint: main.py\nsandbox: none\n",
+		"main.py":     "print('hello')\n",
+	})
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustTrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info := addedPlugin(t, manager, "greeter")
+	if info.Integrity == nil || len(info.Integrity.Files) != 2 {
+		t.Fatalf("expected checksums of entrypoint and manifest, got %+v", info.Integrity)
+	}
+
+	plugin, err := plugins.NewExternalPlugin(info.Type, info.Name, info.Description, info.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin.SetManifest(info.Manifest)
+	plugin.SetTrust(info.Trust)
+	plugin.SetIntegrity(info.Integrity)
+	if err := invoke(plugin, context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "main.py"), "print('pwned')\n")
+	err = invoke(plugin, context.Background())
+	var integrityErr *plugins.IntegrityError
+	if !errors.As(err, &integrityErr) || !strings.Contains(err.Error(), "main.py was modified") {
+		t.Fatalf("expected integrity error, got %v", err)
+	}
+
+	if _, err := manager.RecordIntegrity("greeter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := addedPlugin(t, manager, "greeter").Integrity.Verify(); err != nil {
+		t.Errorf("expected recorded checksums to match, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: greeter\nversion: 1.0.0\nentrypoint: main.py\nsandbox: none\ntimeout: 1h\n")
+	if err := addedPlugin(t, manager, "greeter").Integrity.Verify(); err == nil || !strings.Contains(err.Error(), "plugin.yaml was modified") {
+		t.Errorf("expected modified manifest to be detected, got %v", err)
+	}
+	os.Remove(filepath.Join(dir, "main.py"))
+	if err := info.Integrity.Verify(); err == nil || !strings.Contains(err.Error(), "is missing") {
+		t.Errorf("expected missing entrypoint to be detected, got %v", err)
+	}
+}
+
+func TestIntegrity_RecordsManifestOfTemplatePlugin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml": "name: kotlin-data\nversion: 1.0.0\nlanguage: template\nentrypoint: models.tmpl\n",
+		"models.tmpl": `{{range .Models}}{{template "a.tmpl" .}}{{end}}`,
+		"a.tmpl":      "{{.Name}}\n",
+		"b.tmpl":      "{{.Name}}\n",
+	})
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustTrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest := addedPlugin(t, manager, "kotlin-data").Integrity.Manifest; manifest != filepath.Join(dir, "plugin.yaml") {
+		t.Fatalf("expected the manifest to be recorded, got %q", manifest)
+	}
+
+	// The partials are checksummed too and must never be taken for the
+	// manifest, whatever the order of the recorded files.
+	for i := 0; i < 10; i++ {
+		info, err := manager.RecordIntegrity("kotlin-data")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := info.Integrity.Files[filepath.Join(dir, "plugin.yaml")]; !ok || len(info.Integrity.Files) != 4 {
+			t.Fatalf("expected the manifest and all templates to be recorded again, got %+v", info.Integrity.Files)
+		}
+	}
+}
+
+func TestIntegrity_CoversPluginDirectory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml":        "name: greeter\nversion: 1.0.0\nentrypoint: main.py\nsandbox: none\n",
+		"main.py":            "import helpers.names\n",
+		"helpers/names.py":   "NAMES = []\n",
+		".venv/pyvenv.cfg":   "home = /usr/bin\n",
+		".git/HEAD":          "ref: refs/heads/main\n",
+		"plugin.sig":         "not checked here\n",
+		"templates/a.jinja2": "{{ name }}\n",
+	})
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustTrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	integrity := addedPlugin(t, manager, "greeter").Integrity
+	if len(integrity.Files) != 4 {
+		t.Fatalf("expected checksums of every plugin file, got %+v", integrity.Files)
+	}
+
+	writeFile(t, filepath.Join(dir, ".venv", "pyvenv.cfg"), "home = /opt/bin\n")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref: refs/heads/dev\n")
+	if err := integrity.Verify(); err != nil {
+		t.Errorf("expected .venv and .git to be ignored, got %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "json.py"), "print('shadowed')\n")
+	if err := integrity.Verify(); err == nil || !strings.Contains(err.Error(), "json.py appeared") {
+		t.Errorf("expected added module to be detected, got %v", err)
+	}
+	os.Remove(filepath.Join(dir, "json.py"))
+	writeFile(t, filepath.Join(dir, "helpers", "names.py"), "import os\n")
+	if err := integrity.Verify(); err == nil || !strings.Contains(err.Error(), "names.py was modified") {
+		t.Errorf("expected modified module to be detected, got %v", err)
+	}
+}
+
+func TestIntegrity_Signatures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keys := t.TempDir()
+	trustedKeys := filepath.Join(keys, "trusted_keys")
+	t.Setenv(plugins.TrustedKeysEnv, trustedKeys)
+
+	acmeKey, acmePublic := signingKey(t, keys, "acme")
+	otherKey, _ := signingKey(t, keys, "other")
+	writeFile(t, trustedKeys, "# team keys\n"+acmePublic+"\n")
+
+	dir := t.TempDir()
+	writeTree(t, dir, pluginFiles("1.0.0"))
+	signature, err := plugins.SignPlugin(dir, acmeKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signature != filepath.Join(dir, plugins.SignatureFile) {
+		t.Errorf("unexpected signature file %s", signature)
+	}
+
+	manager := plugins.NewPluginManager()
+	installed := installSource(t, manager, dir, plugins.InstallOptions{})
+	if installed.Integrity.Signer != "acme" || installed.Integrity.Verify() != nil {
+		t.Errorf("expected installed copy to keep its signature, got %+v", installed.Integrity)
+	}
+	if _, err := manager.UninstallPlugin("greeter"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := manager.AddPlugin(dir, plugins.TrustUntrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info := addedPlugin(t, manager, "greeter")
+	if info.Integrity.Signer != "acme" {
+		t.Errorf("expected plugin signed by acme, got %+v", info.Integrity)
+	}
+
+	writeFile(t, trustedKeys, "# acme was revoked\n")
+	if err := info.Integrity.Verify(); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected revoked key to be refused, got %v", err)
+	}
+
+	writeFile(t, trustedKeys, acmePublic+"\n")
+	if _, err := plugins.SignPlugin(dir, otherKey); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := manager.RecordIntegrity("greeter"); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected signature of an unknown key to be refused, got %v", err)
+	}
+
+	writeFile(t, signature, "not a signature\n")
+	if _, err := plugins.NewIntegrity(filepath.Join(dir, "main.py"), nil); err != nil {
+		t.Errorf("expected the plugin.sig of a directory not to apply to a bare script, got %v", err)
+	}
+}
+
+func TestIntegrity_SignedScript(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keys := t.TempDir()
+	trustedKeys := filepath.Join(keys, "trusted_keys")
+	t.Setenv(plugins.TrustedKeysEnv, trustedKeys)
+	key, public := signingKey(t, keys, "acme")
+	writeFile(t, trustedKeys, public+"\n")
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "gen.py")
+	writeFile(t, script, "print('ok')\n")
+	signature, err := plugins.SignPlugin(script, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signature != script+".sig" {
+		t.Errorf("unexpected signature file %s", signature)
+	}
+
+	writeFile(t, script, "print('changed')\n")
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(script, plugins.TrustUntrusted); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected script changed after signing to be refused, got %v", err)
+	}
+}
+
+func TestIntegrity_SignedPluginWithBuildStep(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml": "name: built\nversion: 1.0.0\nlanguage: exec\nentrypoint: gen.sh\nbuild: \"true\"\n",
+		"gen.sh":      "#!/bin/sh\necho ok\n",
+	})
+	key, _ := signingKey(t, t.TempDir(), "acme")
+	if _, err := plugins.SignPlugin(dir, key); err == nil || !strings.Contains(err.Error(), "build step") {
+		t.Errorf("expected plugins with a build step not to be signed, got %v", err)
+	}
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	dir := t.TempDir()
+	if keys, err := plugins.LoadTrustedKeys(filepath.Join(dir, "missing")); err != nil || len(keys) != 0 {
+		t.Errorf("expected no keys for a missing file, got %v, %v", keys, err)
+	}
+
+	_, public, err := plugins.GenerateKey("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, "trusted_keys")
+	writeFile(t, path, "\n# comment\n"+public+"\n"+public+" Acme Corp\n")
+	keys, err := plugins.LoadTrustedKeys(path)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("expected two keys, got %v, %v", keys, err)
+	}
+	if keys[0].Name != "trusted_keys:3" || keys[1].Name != "Acme Corp" {
+		t.Errorf("unexpected key names %q, %q", keys[0].Name, keys[1].Name)
+	}
+
+	writeFile(t, path, "c2hvcnQ=\n")
+	if _, err := plugins.LoadTrustedKeys(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected invalid key error, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func limitedPlugin(t *testing.T, script, manifest string) *plugins.PythonPlugin {
+	t.Helper()
+	requirePython(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), script)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: limited\nversion: 1.0.0\nentrypoint: main.py\nprotocol: json\n"+manifest)
+
+	plugin, err := plugins.NewPythonPluginLoader(dir).LoadPlugin(filepath.Join(dir, "main.py"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return plugin
+}
+
+func invoke(plugin plugins.Plugin, ctx context.Context) error {
+	_, err := plugin.Invoke(ctx, &plugins.Request{Input: "{}", IR: ir.NewDocument()})
+	return err
+}
+
+func TestPythonPlugin_TimeoutKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	plugin := limitedPlugin(t, `import subprocess, time
+child = subprocess.Popen(["sleep", "30"])
+open(`+strconv.Quote(pidFile)+`, "w").write(str(child.pid))
+time.sleep(30)
+`, "sandbox: none\nlimits:\n  timeout: 1s\n")
+
+	start := time.Now()
+	err := invoke(plugin, context.Background())
+
+	var timeoutErr *plugins.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != time.Second {
+		t.Fatalf("expected TimeoutError after 1s, got %T: %v", err, err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected TimeoutError to wrap context.DeadlineExceeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("plugin was not stopped in time: %s", elapsed)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("child pid not written: %v", err)
+	}
+	pid, err := strconv.Atoi(string(data))
+	if err != nil || pid <= 0 {
+		t.Fatalf("invalid child pid %q", data)
+	}
+	child, _ := os.FindProcess(pid)
+	deadline := time.Now().Add(3 * time.Second)
+	for child.Signal(syscall.Signal(0)) == nil {
+		if time.Now().After(deadline) {
+			child.Kill()
+			t.Fatal("expected the child process to be killed with the plugin")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestPythonPlugin_Cancellation(t *testing.T) {
+	plugin := limitedPlugin(t, "import time\ntime.sleep(30)\n", "limits:\n  timeout: 30s\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	err := invoke(plugin, ctx)
+	var canceledErr *plugins.CanceledError
+	if !errors.As(err, &canceledErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected CanceledError, got %T: %v", err, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = invoke(plugin, ctx)
+	var timeoutErr *plugins.TimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Timeout != 0 {
+		t.Fatalf("expected TimeoutError for the caller deadline, got %T: %v", err, err)
+	}
+	if !strings.Contains(err.Error(), "request deadline") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestPythonPlugin_CanceledBeforeStart(t *testing.T) {
+	plugin := limitedPlugin(t, "print('never')\n", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := invoke(plugin, ctx)
+	var canceledErr *plugins.CanceledError
+	if !errors.As(err, &canceledErr) {
+		t.Fatalf("expected CanceledError, got %T: %v", err, err)
+	}
+}
+
+func TestPythonPlugin_OutputLimit(t *testing.T) {
+	plugin := limitedPlugin(t, "import sys\nwhile True:\n    sys.stdout.write('x' * 4096)\n", "limits:\n  max_output: 1KB\n")
+
+	err := invoke(plugin, context.Background())
+	var limitErr *plugins.OutputLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != 1024 {
+		t.Fatalf("expected OutputLimitError, got %T: %v", err, err)
+	}
+	if !strings.Contains(err.Error(), "1KB") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestPythonPlugin_ResourceLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rlimits are only applied on Linux")
+	}
+
+	tests := []struct {
+		name     string
+		script   string
+		manifest string
+		resource string
+	}{
+		{"cpu", "while True:\n    pass\n", "limits:\n  cpu: 1s\n  timeout: 20s\n", "cpu"},
+		{"memory", "data = bytearray(1024 * 1024 * 1024)\n", "limits:\n  memory: 256MB\n", "memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := limitedPlugin(t, tt.script, tt.manifest)
+
+			err := invoke(plugin, context.Background())
+			var limitErr *plugins.ResourceLimitError
+			if !errors.As(err, &limitErr) || limitErr.Resource != tt.resource {
+				t.Fatalf("expected %s ResourceLimitError, got %T: %v", tt.resource, err, err)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_ContextCancelsCall(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1})
+	first := callPID(t, pool)
+
+	// The test worker ignores "hang" and never answers.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Call(ctx, "hang", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+
+	if pid := callPID(t, pool); pid == first {
+		t.Error("expected the hanging worker to be replaced")
+	}
+}
+
+func TestLoadManifest_InvalidLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), "")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: limited
+version: 1.0.0
+entrypoint: main.py
+limits:
+  timeout: -1s
+  memory: lots
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "limits.timeout") {
+		t.Errorf("expected limits error, got %v", err)
+	}
+}
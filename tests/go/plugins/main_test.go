@@ -0,0 +1,19 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// The test binary doubles as the sandbox helper, like the devtoolbox binary.
+func TestMain(m *testing.M) {
+	plugins.RunSandboxHelper()
+	code := m.Run()
+	if wasmModule != "" {
+		os.RemoveAll(filepath.Dir(wasmModule))
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,243 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.py"), "print('ok')\n")
+	writeFile(t, filepath.Join(dir, "plugin.json"), `{
+  "name": "my-gen",
+  "version": "1.2.3",
+  "description": "My generator",
+  "entrypoint": "gen.py",
+  "input_formats": ["json"],
+  "output_extension": "kt",
+  "options": {"style": {"enum": ["a", "b"], "default": "a"}}
+}`)
+
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if manifest.Name != "my-gen" || manifest.Version != "1.2.3" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	if manifest.Language != "python" {
+		t.Errorf("expected default language 'python', got '%s'", manifest.Language)
+	}
+	if manifest.OutputExtension != ".kt" {
+		t.Errorf("expected output extension '.kt', got '%s'", manifest.OutputExtension)
+	}
+	if manifest.Options["style"].Type != "string" {
+		t.Errorf("expected default option type 'string', got '%s'", manifest.Options["style"].Type)
+	}
+	if manifest.EntrypointPath() != filepath.Join(dir, "gen.py") {
+		t.Errorf("unexpected entrypoint path: %s", manifest.EntrypointPath())
+	}
+}
+
+func TestLoadManifest_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: Bad Name
+version: one
+aliases: [ts@1]
+language: ruby
+entrypoint: missing.py
+options:
+  level:
+    type: float
+fixtures: [../shared/*.json]
+min_devtoolbox_version: 99.0.0
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, expected := range []string{"name", "alias \"ts@1\"", "invalid version", "unsupported language", "entrypoint", "option level", "fixture", "requires DevToolBox"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to mention %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestLoadManifest_EntrypointOutsideDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "plugin")
+	outside := filepath.Join(root, "outside.py")
+	writeFile(t, outside, "print('outside')\n")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link.py")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	for _, entrypoint := range []string{"../outside.py", outside, "link.py"} {
+		writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: escape\nversion: 1.0.0\nentrypoint: "+entrypoint+"\n")
+		_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "must be inside the plugin directory") {
+			t.Errorf("%s: expected the entrypoint to be rejected, got %v", entrypoint, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "src", "main.py"), "print('inside')\n")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: inside\nversion: 1.0.0\nentrypoint: src/../src/main.py\n")
+	if _, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFindManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "first.py"), "")
+	writeFile(t, filepath.Join(dir, "second.py"), "")
+	writeFile(t, filepath.Join(dir, "other.py"), "")
+	writeFile(t, filepath.Join(dir, "first.plugin.yaml"), "name: first\nversion: 0.1.0\nentrypoint: first.py\n")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: second\nversion: 0.2.0\nentrypoint: second.py\n")
+
+	tests := []struct {
+		script   string
+		expected string
+	}{
+		{"first.py", "first"},
+		{"second.py", "second"},
+		{"other.py", ""},
+	}
+
+	for _, tt := range tests {
+		manifest, err := plugins.FindManifest(filepath.Join(dir, tt.script))
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", tt.script, err)
+		}
+
+		name := ""
+		if manifest != nil {
+			name = manifest.Name
+		}
+		if name != tt.expected {
+			t.Errorf("%s: expected manifest '%s', got '%s'", tt.script, tt.expected, name)
+		}
+	}
+}
+
+func TestPythonPluginLoader_LoadPluginWithManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.py"), "")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: kotlin-gen\nversion: 1.0.0\ndescription: Kotlin data classes\nentrypoint: gen.py\ninput_formats: [json, yaml]\n")
+
+	plugin, err := plugins.NewPythonPluginLoader(dir).LoadPlugin(filepath.Join(dir, "gen.py"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if plugin.GetName() != "kotlin-gen" || plugin.GetDescription() != "Kotlin data classes" {
+		t.Errorf("unexpected plugin: %s - %s", plugin.GetName(), plugin.GetDescription())
+	}
+	if formats := plugin.InputFormats(); len(formats) != 2 || formats[1] != "yaml" {
+		t.Errorf("unexpected input formats: %v", formats)
+	}
+}
+
+func TestPluginManager_AddPluginWithManifest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "gen.py"), "")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), "name: kotlin-gen\nversion: 1.0.0\nauthor: Jane\nentrypoint: gen.py\n")
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustUntrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := manager.AddPlugin(filepath.Join(dir, "gen.py"), plugins.TrustUntrusted); err == nil {
+		t.Error("expected error when adding the same plugin twice")
+	}
+
+	custom, err := manager.LoadPlugins()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(custom) != 1 {
+		t.Fatalf("expected 1 plugin, got %d", len(custom))
+	}
+
+	plugin := custom[0]
+	if plugin.Name != "kotlin-gen" || plugin.Path != filepath.Join(dir, "gen.py") {
+		t.Errorf("unexpected plugin info: %+v", plugin)
+	}
+	if plugin.Description != "Custom plugin: kotlin-gen" {
+		t.Errorf("expected fallback description, got '%s'", plugin.Description)
+	}
+	if plugin.Manifest == nil || plugin.Manifest.Author != "Jane" {
+		t.Errorf("expected manifest to be stored, got %+v", plugin.Manifest)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"0.1.0", "0.1.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"0.9.9", "0.10.0", -1},
+		{"2.0.0-rc1", "1.9.0", 1},
+	}
+
+	for _, tt := range tests {
+		if result := plugins.CompareVersions(tt.a, tt.b); result != tt.expected {
+			t.Errorf("CompareVersions(%s, %s) = %d, expected %d", tt.a, tt.b, result, tt.expected)
+		}
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		expected            bool
+	}{
+		{"1.2.0", "1.2.0", true},
+		{"1.2.1", "1.2", false},
+		{"1.2.0", "=1.2.0", true},
+		{"1.5.0", ">=1.2.0 <2.0.0", true},
+		{"2.0.0", ">=1.2.0, <2.0.0", false},
+		{"1.0.0", "!=1.0.0", false},
+		{"", ">=0.1.0", false},
+	}
+
+	for _, tt := range tests {
+		result, err := plugins.MatchVersion(tt.version, tt.constraint)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tt.constraint, err)
+		}
+		if result != tt.expected {
+			t.Errorf("MatchVersion(%q, %q) = %v, expected %v", tt.version, tt.constraint, result, tt.expected)
+		}
+	}
+
+	for _, constraint := range []string{"", "~1.2", ">=one", "=>1.0"} {
+		if _, err := plugins.MatchVersion("1.0.0", constraint); err == nil {
+			t.Errorf("expected an error for %q", constraint)
+		}
+	}
+}
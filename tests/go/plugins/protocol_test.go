@@ -0,0 +1,211 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+const echoPlugin = `import json, sys
+request = json.load(sys.stdin)
+names = ",".join(m["name"] for m in request["ir"]["models"])
+print(json.dumps({
+    "version": 1,
+    "files": [{"name": "out.txt", "content": names + " " + request["options"]["style"] + " " + request["context"]["generator"]}],
+    "diagnostics": [{"severity": "warning", "message": "check", "location": {"path": "User.id", "line": 2}}],
+}))
+`
+
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("Skipping test - python3 not available")
+	}
+}
+
+func jsonPlugin(t *testing.T, script string) *plugins.PythonPlugin {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), script)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: echo
+version: 1.0.0
+entrypoint: main.py
+protocol: json
+options:
+  style:
+    enum: [short, long]
+    default: short
+`)
+
+	plugin, err := plugins.NewPythonPluginLoader(dir).LoadPlugin(filepath.Join(dir, "main.py"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return plugin
+}
+
+func TestPythonPlugin_InvokeJSONProtocol(t *testing.T) {
+	requirePython(t)
+	plugin := jsonPlugin(t, echoPlugin)
+
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "User", Type: &ir.Type{Kind: ir.KindObject}})
+
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{Input: "{}", Format: "json", IR: doc})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(response.Files) != 1 || response.Files[0].Name != "out.txt" {
+		t.Fatalf("unexpected files: %+v", response.Files)
+	}
+	if response.Files[0].Content != "User short echo" {
+		t.Errorf("unexpected content: %q", response.Files[0].Content)
+	}
+	if len(response.Diagnostics) != 1 || response.Diagnostics[0].String() != "warning: User.id:2: check" {
+		t.Errorf("unexpected diagnostics: %+v", response.Diagnostics)
+	}
+
+	configured, err := plugin.WithOptions(map[string]string{"style": "long"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response, err = configured.Invoke(context.Background(), &plugins.Request{Input: "{}", IR: doc})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Output() != "User long echo" {
+		t.Errorf("expected configured option to be sent, got %q", response.Output())
+	}
+}
+
+func TestPythonPlugin_InvokeErrors(t *testing.T) {
+	requirePython(t)
+
+	tests := []struct {
+		name     string
+		script   string
+		code     plugins.ErrorCode
+		exitCode int
+		protocol bool
+	}{
+		{
+			name:   "structured error",
+			script: `print('{"version": 1, "error": {"code": "invalid_input", "message": "bad", "location": {"path": "a"}}}')`,
+			code:   plugins.CodeInvalidInput,
+		},
+		{
+			name:     "structured error with exit code",
+			script:   "import sys\nprint('{\"version\": 1, \"error\": {\"code\": \"unsupported\", \"message\": \"no\"}}')\nsys.exit(1)",
+			code:     plugins.CodeUnsupported,
+			exitCode: 1,
+		},
+		{
+			name:     "exit code without response",
+			script:   "import sys\nsys.stderr.write('broken options')\nsys.exit(3)",
+			code:     plugins.CodeInvalidOptions,
+			exitCode: 3,
+		},
+		{
+			name:     "not json",
+			script:   "print('hello')",
+			protocol: true,
+		},
+		{
+			name:     "wrong version",
+			script:   `print('{"version": 7, "files": [{"content": "x"}]}')`,
+			protocol: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := jsonPlugin(t, tt.script)
+			_, err := plugin.Invoke(context.Background(), &plugins.Request{Input: "{}", IR: ir.NewDocument()})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+
+			if tt.protocol {
+				var protocolErr *plugins.ProtocolError
+				if !errors.As(err, &protocolErr) {
+					t.Errorf("expected ProtocolError, got %T: %v", err, err)
+				}
+				return
+			}
+
+			var pluginErr *plugins.PluginError
+			if !errors.As(err, &pluginErr) {
+				t.Fatalf("expected PluginError, got %T: %v", err, err)
+			}
+			if pluginErr.Code != tt.code || pluginErr.ExitCode != tt.exitCode {
+				t.Errorf("expected code %s exit %d, got %s exit %d", tt.code, tt.exitCode, pluginErr.Code, pluginErr.ExitCode)
+			}
+		})
+	}
+}
+
+func TestPythonPlugin_TextCompatibility(t *testing.T) {
+	requirePython(t)
+	dir := t.TempDir()
+	script := filepath.Join(dir, "legacy.py")
+	writeFile(t, script, "import sys\ndata = sys.stdin.read()\nprint('Error: nope' if data == 'bad' else 'got ' + data)\n")
+
+	plugin := plugins.NewPythonPlugin("legacy", "Legacy plugin", script)
+
+	result, err := plugin.Generate("ok")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "got ok" {
+		t.Errorf("unexpected result: %q", result)
+	}
+
+	_, err = plugin.Generate("bad")
+	var pluginErr *plugins.PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.Message != "nope" {
+		t.Errorf("expected PluginError with message 'nope', got %v", err)
+	}
+
+	if _, err := plugin.WithOptions(map[string]string{"a": "b"}); err == nil {
+		t.Error("expected text protocol plugin to reject options")
+	}
+}
+
+func TestPythonPlugin_WithOptionsValidation(t *testing.T) {
+	plugin := plugins.NewPythonPlugin("echo", "Echo", "main.py")
+	plugin.SetManifest(&plugins.Manifest{
+		Name:     "echo",
+		Protocol: plugins.ProtocolJSON,
+		Options: map[string]plugins.OptionSpec{
+			"count": {Type: "integer"},
+			"style": {Type: "string", Enum: []string{"a", "b"}},
+		},
+	})
+
+	tests := []struct {
+		options map[string]string
+		valid   bool
+	}{
+		{map[string]string{"count": "3", "style": "a"}, true},
+		{map[string]string{"count": "three"}, false},
+		{map[string]string{"style": "c"}, false},
+		{map[string]string{"other": "1"}, false},
+	}
+
+	for _, tt := range tests {
+		_, err := plugin.WithOptions(tt.options)
+		if (err == nil) != tt.valid {
+			t.Errorf("options %v: expected valid=%v, got error %v", tt.options, tt.valid, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "invalid_options") {
+			t.Errorf("expected invalid_options error, got %v", err)
+		}
+	}
+}
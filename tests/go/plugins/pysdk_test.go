@@ -0,0 +1,128 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// sdkPlugin uses the Python SDK, which DevToolBox puts on PYTHONPATH.
+const sdkPlugin = `from devtoolbox_sdk import CODE_INVALID_OPTIONS, PluginError, serve, to_pascal_case
+
+def generate(request):
+    if request.option("style") == "long":
+        raise PluginError(CODE_INVALID_OPTIONS, "long is not supported")
+    return " ".join(to_pascal_case(name) for name in request.ir.names())
+
+serve(generate)
+`
+
+// unsandboxedPlugin loads a trusted plugin that opts out of the sandbox.
+func unsandboxedPlugin(t *testing.T, script string, worker bool) *plugins.PythonPlugin {
+	t.Helper()
+	requirePython(t)
+	plugin := jsonPlugin(t, script)
+	manifest := *plugin.Manifest()
+	manifest.Sandbox = plugins.SandboxNone
+	if worker {
+		manifest.Worker = &plugins.WorkerSpec{Enabled: true}
+	}
+	plugin.SetManifest(&manifest)
+	plugin.SetTrust(plugins.TrustTrusted)
+	t.Cleanup(func() { plugin.Close() })
+	return plugin
+}
+
+func TestPythonPlugin_SDK(t *testing.T) {
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "user_profile", Type: &ir.Type{Kind: ir.KindObject}})
+
+	tests := []struct {
+		name   string
+		plugin func(t *testing.T) *plugins.PythonPlugin
+	}{
+		{"unsandboxed", func(t *testing.T) *plugins.PythonPlugin { return unsandboxedPlugin(t, sdkPlugin, false) }},
+		{"worker", func(t *testing.T) *plugins.PythonPlugin { return unsandboxedPlugin(t, sdkPlugin, true) }},
+		{"sandboxed", func(t *testing.T) *plugins.PythonPlugin {
+			requirePython(t)
+			requireSandbox(t)
+			plugin := jsonPlugin(t, sdkPlugin)
+			plugin.SetTrust(plugins.TrustUntrusted)
+			return plugin
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := tt.plugin(t)
+
+			response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: doc})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if response.Output() != "UserProfile" {
+				t.Errorf("unexpected output %q", response.Output())
+			}
+
+			_, err = plugin.Invoke(context.Background(), &plugins.Request{IR: doc, Options: map[string]string{"style": "long"}})
+			var pluginErr *plugins.PluginError
+			if !errors.As(err, &pluginErr) || pluginErr.Code != plugins.CodeInvalidOptions {
+				t.Errorf("expected invalid_options error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPythonPlugin_SDKKeepsPythonPath(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "helpers.py"), "NAME = 'from helpers'\n")
+	t.Setenv("PYTHONPATH", dir)
+
+	plugin := unsandboxedPlugin(t, `from devtoolbox_sdk import serve
+from helpers import NAME
+
+serve(lambda request: NAME)
+`, false)
+
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: ir.NewDocument()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Output() != "from helpers" {
+		t.Errorf("unexpected output %q", response.Output())
+	}
+}
+
+func TestPythonPlugin_SDKExtractedAgain(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	if dir, err := os.UserCacheDir(); err != nil || !strings.HasPrefix(dir, cache) {
+		t.Skip("Skipping test - the cache directory does not follow XDG_CACHE_HOME")
+	}
+	plugin := unsandboxedPlugin(t, sdkPlugin, false)
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "user_profile", Type: &ir.Type{Kind: ir.KindObject}})
+
+	for run := 1; run <= 2; run++ {
+		response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: doc})
+		if err != nil {
+			t.Fatalf("run %d: unexpected error: %v", run, err)
+		}
+		if response.Output() != "UserProfile" {
+			t.Errorf("run %d: unexpected output %q", run, response.Output())
+		}
+		if _, err := os.Stat(filepath.Join(cache, "devtoolbox", "python-sdk")); err != nil {
+			t.Fatalf("run %d: SDK was not extracted into the cache: %v", run, err)
+		}
+		// Cleaning the cache between runs must not break the next one.
+		if err := os.RemoveAll(filepath.Join(cache, "devtoolbox")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// probeScript reports what the plugin can reach as a JSON object.
+const probeScript = `import json, os, socket, sys
+request = json.load(sys.stdin)
+secret = request["options"]["secret"]
+here = os.path.dirname(os.path.abspath(__file__))
+
+def attempt(action):
+    try:
+        action()
+        return True
+    except OSError:
+        return False
+
+def write_cwd():
+    open("written", "w").write("x")
+    os.remove("written")
+
+def connect():
+    socket.create_connection(("1.1.1.1", 53), timeout=1).close()
+
+result = {
+    "read_secret": attempt(lambda: open(secret).read()),
+    "write_plugin_dir": attempt(lambda: open(os.path.join(here, "written"), "w").write("x")),
+    "write_cwd": attempt(write_cwd),
+    "network": attempt(connect),
+    "env_secret": os.environ.get("DEVTOOLBOX_TEST_SECRET"),
+    "cwd": os.getcwd(),
+}
+print(json.dumps({"version": 1, "files": [{"content": json.dumps(result)}]}))
+`
+
+type probeResult struct {
+	ReadSecret     bool    `json:"read_secret"`
+	WritePluginDir bool    `json:"write_plugin_dir"`
+	WriteCwd       bool    `json:"write_cwd"`
+	Network        bool    `json:"network"`
+	EnvSecret      *string `json:"env_secret"`
+	Cwd            string  `json:"cwd"`
+}
+
+func probe(t *testing.T, trust plugins.Trust, sandbox string) probeResult {
+	t.Helper()
+	requirePython(t)
+	t.Setenv("DEVTOOLBOX_TEST_SECRET", "hunter2")
+
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	writeFile(t, secret, "secret")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), probeScript)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: probe
+version: 1.0.0
+entrypoint: main.py
+protocol: json
+sandbox: `+strconv.Quote(sandbox)+`
+options:
+  secret: {}
+`)
+
+	plugin, err := plugins.NewPythonPluginLoader(dir).LoadPlugin(filepath.Join(dir, "main.py"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin.SetTrust(trust)
+
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{
+		IR:      ir.NewDocument(),
+		Options: map[string]string{"secret": secret},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var result probeResult
+	if err := json.Unmarshal([]byte(response.Output()), &result); err != nil {
+		t.Fatalf("unexpected output %q: %v", response.Output(), err)
+	}
+	return result
+}
+
+func requireSandbox(t *testing.T) {
+	t.Helper()
+	if !plugins.SandboxSupported() {
+		t.Skip("Skipping test - sandbox not supported on this system")
+	}
+}
+
+func TestSandbox_IsolatesPlugin(t *testing.T) {
+	requireSandbox(t)
+
+	for _, tt := range []struct {
+		trust   plugins.Trust
+		sandbox string
+	}{
+		{plugins.TrustUntrusted, ""},
+		{plugins.TrustUntrusted, plugins.SandboxNone},
+		{plugins.TrustTrusted, plugins.SandboxStrict},
+		{plugins.TrustOfficial, ""},
+	} {
+		t.Run(string(tt.trust)+"/"+tt.sandbox, func(t *testing.T) {
+			result := probe(t, tt.trust, tt.sandbox)
+
+			if result.ReadSecret {
+				t.Error("expected files outside the plugin directory to be hidden")
+			}
+			if result.WritePluginDir {
+				t.Error("expected the plugin directory to be read-only")
+			}
+			if !result.WriteCwd || result.Cwd != "/work" {
+				t.Errorf("expected a writable /work directory, got cwd %s writable=%v", result.Cwd, result.WriteCwd)
+			}
+			if result.Network {
+				t.Error("expected no network access")
+			}
+			if result.EnvSecret != nil {
+				t.Error("expected the environment to be scrubbed")
+			}
+		})
+	}
+}
+
+func TestSandbox_TrustedOptOut(t *testing.T) {
+	result := probe(t, plugins.TrustTrusted, plugins.SandboxNone)
+
+	if !result.ReadSecret || result.EnvSecret == nil {
+		t.Errorf("expected a trusted plugin with sandbox: none to run unrestricted, got %+v", result)
+	}
+}
+
+func TestSandboxed(t *testing.T) {
+	none := &plugins.Manifest{Sandbox: plugins.SandboxNone}
+
+	tests := []struct {
+		trust    plugins.Trust
+		manifest *plugins.Manifest
+		expected bool
+	}{
+		{plugins.TrustUntrusted, none, true},
+		{plugins.TrustUntrusted, nil, true},
+		{plugins.TrustTrusted, nil, true},
+		{plugins.TrustTrusted, none, false},
+		{plugins.TrustOfficial, &plugins.Manifest{}, true},
+		{plugins.TrustOfficial, none, false},
+	}
+
+	for _, tt := range tests {
+		if got := plugins.Sandboxed(tt.trust, tt.manifest); got != tt.expected {
+			t.Errorf("Sandboxed(%s, %+v) = %v, expected %v", tt.trust, tt.manifest, got, tt.expected)
+		}
+	}
+
+	if trust, err := plugins.ParseTrust(""); err != nil || trust != plugins.TrustUntrusted {
+		t.Errorf("expected empty trust to mean untrusted, got %s, %v", trust, err)
+	}
+	if _, err := plugins.ParseTrust("root"); err == nil {
+		t.Error("expected error for unknown trust level")
+	}
+}
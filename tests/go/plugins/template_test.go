@@ -0,0 +1,254 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+const templateManifest = `name: kotlin-data
+version: 1.0.0
+language: template
+entrypoint: models.tmpl
+output_extension: .kt
+options:
+  package:
+    default: com.example
+  suffix: {}
+template:
+  types:
+    string: String
+    integer: Long
+    time: Instant
+    time:date: LocalDate
+    array: List<%s>
+    nullable: "%s?"
+`
+
+func templatePlugin(t *testing.T, manifest string, templates map[string]string) plugins.ExternalPlugin {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), manifest)
+	for name, content := range templates {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+
+	loaded, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := plugins.NewPluginFromManifest(loaded)
+	t.Cleanup(func() { plugin.Close() })
+	return plugin
+}
+
+func sampleDocument() *ir.Document {
+	doc := ir.NewDocument()
+	doc.Add(&ir.Model{Name: "user_account", Type: &ir.Type{Kind: ir.KindObject, Fields: []*ir.Field{
+		{Name: "user_id", Type: ir.Primitive(ir.KindInteger), Required: true},
+		{Name: "birth_date", Type: &ir.Type{Kind: ir.KindTime, Format: "date"}, Required: true},
+		{Name: "tags", Type: ir.ArrayOf(ir.Primitive(ir.KindString))},
+		{Name: "address", Type: &ir.Type{Kind: ir.KindRef, Ref: "home_address", Nullable: true}},
+	}}})
+	doc.Add(&ir.Model{Name: "home_address", Type: &ir.Type{Kind: ir.KindObject, Fields: []*ir.Field{
+		{Name: "street", Type: ir.Primitive(ir.KindString), Required: true},
+	}}})
+	return doc
+}
+
+func TestTemplatePlugin_Invoke(t *testing.T) {
+	plugin := templatePlugin(t, templateManifest, map[string]string{
+		"models.tmpl": `package {{.Options.package}}
+{{range .Models}}
+data class {{pascal .Name}}{{$.Options.suffix}}(
+{{- template "fields.tmpl" .Type.Fields}}
+)
+{{end}}`,
+		"fields.tmpl": `{{range .}}
+{{indent 4 (printf "val %s: %s," (camel .Name) (type .Type))}}
+{{- end}}`,
+	})
+	if plugin.Workers() != nil {
+		t.Error("template plugins should not use workers")
+	}
+
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument(), Options: map[string]string{"suffix": "Dto"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := response.Output()
+	for _, expected := range []string{
+		"package com.example\n",
+		"data class UserAccountDto(\n    val userId: Long,\n    val birthDate: LocalDate,\n    val tags: List<String>,\n    val address: HomeAddress?,\n)",
+		"data class HomeAddressDto(",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	// Unset options are empty rather than missing keys.
+	configured, err := plugin.WithOptions(map[string]string{"package": "org.acme"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response, err = configured.Invoke(context.Background(), &plugins.Request{IR: sampleDocument()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := response.Output(); !strings.Contains(output, "package org.acme\n") || !strings.Contains(output, "data class UserAccount(") {
+		t.Errorf("options were not applied:\n%s", output)
+	}
+	if _, err := plugin.WithOptions(map[string]string{"style": "x"}); err == nil {
+		t.Error("expected an error for an unknown option")
+	}
+}
+
+func TestTemplatePlugin_UnrecordedTemplates(t *testing.T) {
+	plugin := templatePlugin(t, templateManifest, map[string]string{
+		"models.tmpl": `{{range .Models}}{{template "fields.tmpl" .Type.Fields}}{{end}}`,
+		"fields.tmpl": `{{range .}}{{.Name}};{{end}}`,
+	})
+	manifest := plugin.Manifest()
+	integrity, err := plugins.NewIntegrity(manifest.EntrypointPath(), manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin.SetIntegrity(integrity)
+
+	// A template dropped in later is not covered by the checksums and must
+	// not be able to redefine the recorded ones.
+	writeFile(t, filepath.Join(filepath.Dir(manifest.Path), "z.tmpl"), `{{define "fields.tmpl"}}INJECTED{{end}}`)
+	_, err = plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument()})
+	var integrityErr *plugins.IntegrityError
+	if !errors.As(err, &integrityErr) || !strings.Contains(err.Error(), "z.tmpl appeared") {
+		t.Fatalf("expected integrity error, got %v", err)
+	}
+
+	// Records without the manifest path cannot tell added files; the added
+	// template is still not parsed.
+	legacy := *integrity
+	legacy.Manifest = ""
+	plugin.SetIntegrity(&legacy)
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := response.Output(); strings.Contains(output, "INJECTED") || !strings.Contains(output, "user_id;") {
+		t.Errorf("unrecorded template was used:\n%s", output)
+	}
+}
+
+func TestTemplatePlugin_FilePerModel(t *testing.T) {
+	plugin := templatePlugin(t, templateManifest+`  file: "{{snake .Model.Name}}/{{pascal .Model.Name}}.kt"
+`, map[string]string{
+		"models.tmpl": `class {{pascal .Model.Name}}({{len .Models}})`,
+	})
+
+	response, err := plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Files) != 2 {
+		t.Fatalf("expected a file per model, got %+v", response.Files)
+	}
+	if file := response.Files[1]; file.Name != "home_address/HomeAddress.kt" || file.Content != "class HomeAddress(2)" {
+		t.Errorf("unexpected file %+v", file)
+	}
+}
+
+func TestTemplatePlugin_Errors(t *testing.T) {
+	plugin := templatePlugin(t, templateManifest+`limits:
+  max_output: 1KB
+`, map[string]string{
+		"models.tmpl": `{{if eq .Options.suffix "repeat"}}{{range .Models}}{{range .Type.Fields}}{{printf "%2000s" .Name}}{{end}}{{end}}{{else}}{{.Options.suffix}}{{.Missing}}{{end}}`,
+	})
+
+	var pluginErr *plugins.PluginError
+	if _, err := plugin.Generate(`{"id": 1}`); !errors.As(err, &pluginErr) || pluginErr.Code != plugins.CodeInvalidInput {
+		t.Errorf("expected invalid_input without a type model, got %v", err)
+	}
+
+	_, err := plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument()})
+	if !errors.As(err, &pluginErr) || pluginErr.Code != plugins.CodeInternal || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("expected an internal error naming the field, got %v", err)
+	}
+
+	_, err = plugin.Invoke(context.Background(), &plugins.Request{IR: sampleDocument(), Options: map[string]string{"suffix": "repeat"}})
+	var limitErr *plugins.OutputLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf("expected an output limit error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = plugin.Invoke(ctx, &plugins.Request{IR: sampleDocument(), Options: map[string]string{"suffix": "x"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestLoadManifest_InvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "models.tmpl"), "{{range .Models}}")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: bad-template
+version: 1.0.0
+language: template
+entrypoint: models.tmpl
+protocol: text
+interpreter: python3
+template:
+  types:
+    uuid: String
+  file: "{{.Model.Name"
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, expected := range []string{"interpreter", "protocol: json", `unknown kind "uuid"`, "template.file", "models.tmpl"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to mention %q, got: %v", expected, err)
+		}
+	}
+
+	writeFile(t, filepath.Join(dir, "gen.py"), "print('ok')\n")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: not-template
+version: 1.0.0
+entrypoint: gen.py
+template:
+  file: x.kt
+`)
+	if _, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml")); err == nil || !strings.Contains(err.Error(), "only supported for template plugins") {
+		t.Errorf("expected template to be rejected for python plugins, got %v", err)
+	}
+}
+
+func TestMapType(t *testing.T) {
+	types := map[string]string{"integer": "i64", "array": "Vec<%s>", "map": "HashMap<String, %s>", "nullable": "Option<%s>", "ref": "Box<%s>"}
+	for _, tc := range []struct {
+		typ      *ir.Type
+		expected string
+	}{
+		{ir.Primitive(ir.KindInteger), "i64"},
+		{ir.Primitive(ir.KindBoolean), "boolean"},
+		{ir.ArrayOf(ir.MapOf(ir.Primitive(ir.KindInteger))), "Vec<HashMap<String, i64>>"},
+		{&ir.Type{Kind: ir.KindRef, Ref: "order_item", Nullable: true}, "Option<Box<OrderItem>>"},
+		{nil, "any"},
+	} {
+		if got := plugins.MapType(types, tc.typ); got != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, got)
+		}
+	}
+	if got := plugins.MapType(nil, ir.ArrayOf(ir.Ref("user"))); got != "[]User" {
+		t.Errorf("expected Go-style defaults, got %s", got)
+	}
+}
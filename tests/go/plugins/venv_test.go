@@ -0,0 +1,209 @@
+package plugins
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// requirementPlugin imports a third-party package that is not installed in
+// the shared interpreter.
+const requirementPlugin = `import tinydep
+print(tinydep.greeting())
+`
+
+func requireVenv(t *testing.T) {
+	t.Helper()
+	requirePython(t)
+	if err := exec.Command("python3", "-c", "import venv, ensurepip").Run(); err != nil {
+		t.Skip("Skipping test - python3 venv not available")
+	}
+}
+
+// writeWheel builds a minimal pure-Python wheel, so requirements can be
+// installed without network access.
+func writeWheel(t *testing.T, dir, name, version, module string) {
+	t.Helper()
+	distInfo := name + "-" + version + ".dist-info/"
+	files := map[string]string{
+		name + ".py":               module,
+		distInfo + "METADATA":      "Metadata-Version: 2.1\nName: " + name + "\nVersion: " + version + "\n",
+		distInfo + "WHEEL":         "Wheel-Version: 1.0\nGenerator: devtoolbox-tests\nRoot-Is-Purelib: true\nTag: py3-none-any\n",
+		distInfo + "RECORD":        "",
+		distInfo + "top_level.txt": name + "\n",
+	}
+
+	file, err := os.Create(filepath.Join(dir, name+"-"+version+"-py3-none-any.whl"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+	for path, content := range files {
+		w, err := zw.Create(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPythonPlugin_Requirements(t *testing.T) {
+	requireVenv(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+	wheels := t.TempDir()
+	writeWheel(t, wheels, "tinydep", "1.0.0", "def greeting():\n    return 'hello from tinydep'\n")
+	t.Setenv(plugins.WheelsEnv, wheels)
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml": "name: needs-dep\nversion: 1.0.0\nentrypoint: main.py\nsandbox: none\nrequirements:\n  - tinydep==1.0.0\n",
+		"main.py":     requirementPlugin,
+	})
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustTrusted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info := addedPlugin(t, manager, "needs-dep")
+
+	venv, err := plugins.VenvDir(info.Manifest)
+	if err != nil || !strings.HasPrefix(venv, os.Getenv("HOME")) {
+		t.Fatalf("expected environment in the user cache, got %q, %v", venv, err)
+	}
+	if _, err := os.Stat(venv); err != nil {
+		t.Fatalf("expected environment to be created on add: %v", err)
+	}
+
+	run := func(t *testing.T, trust plugins.Trust, sandbox string) {
+		manifest := *info.Manifest
+		manifest.Sandbox = sandbox
+		plugin := plugins.NewPythonPlugin(info.Name, info.Description, info.Path)
+		plugin.SetManifest(&manifest)
+		plugin.SetTrust(trust)
+		output, err := plugin.GenerateContext(context.Background(), "{}")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.TrimSpace(output) != "hello from tinydep" {
+			t.Errorf("unexpected output %q", output)
+		}
+	}
+	t.Run("unsandboxed", func(t *testing.T) { run(t, plugins.TrustTrusted, plugins.SandboxNone) })
+	t.Run("sandboxed", func(t *testing.T) {
+		requireSandbox(t)
+		run(t, plugins.TrustUntrusted, "")
+	})
+	t.Run("recreated", func(t *testing.T) {
+		os.RemoveAll(venv)
+		run(t, plugins.TrustTrusted, plugins.SandboxNone)
+	})
+
+	checks := map[string]plugins.Check{}
+	for _, check := range plugins.Diagnose(info) {
+		checks[check.Name] = check
+		if !check.OK && check.Name != "sandbox" {
+			t.Errorf("unexpected failed check %+v", check)
+		}
+	}
+	if checks["requirement"].Detail != "tinydep==1.0.0: 1.0.0" {
+		t.Errorf("unexpected requirement check %+v", checks["requirement"])
+	}
+	if !strings.HasPrefix(checks["interpreter"].Detail, "python 3.") {
+		t.Errorf("unexpected interpreter check %+v", checks["interpreter"])
+	}
+
+	shared := plugins.NewPythonPlugin("shared", "", filepath.Join(dir, "main.py"))
+	if _, err := shared.GenerateContext(context.Background(), "{}"); err == nil {
+		t.Error("expected requirement not to leak into the shared interpreter")
+	}
+}
+
+func TestPythonPlugin_RequirementsUnavailable(t *testing.T) {
+	requireVenv(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv(plugins.WheelsEnv, t.TempDir())
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"plugin.yaml": "name: needs-dep\nversion: 1.0.0\nentrypoint: main.py\nrequirements: [tinydep>=2]\n",
+		"main.py":     requirementPlugin,
+	})
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checks := plugins.Diagnose(plugins.PluginInfo{Name: "needs-dep", Type: plugins.LanguagePython, Path: manifest.EntrypointPath(), Manifest: manifest})
+	for _, check := range checks {
+		if check.Name == "environment" && (!check.OK || !strings.Contains(check.Detail, "not created yet")) {
+			t.Errorf("unexpected environment check %+v", check)
+		}
+	}
+
+	manager := plugins.NewPluginManager()
+	if err := manager.AddPlugin(dir, plugins.TrustTrusted); err == nil || !strings.Contains(err.Error(), "failed to install requirements of plugin needs-dep") {
+		t.Fatalf("expected install error, got %v", err)
+	}
+	if list, _ := manager.LoadPlugins(); len(list) != 0 {
+		t.Errorf("expected plugin not to be added, got %+v", list)
+	}
+	venv, _ := plugins.VenvDir(manifest)
+	if entries, _ := os.ReadDir(filepath.Dir(venv)); len(entries) != 0 {
+		t.Errorf("expected no leftover environments, got %v", entries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := plugins.EnsureVenv(ctx, manifest); err == nil || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("expected a canceled context to stop the environment build, got %v", err)
+	}
+}
+
+func TestLoadManifest_Requirements(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), "print('ok')\n")
+	writeFile(t, filepath.Join(dir, "gen.sh"), "#!/bin/sh\n")
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{"exec", "language: exec\nentrypoint: gen.sh\ninterpreter: sh\nrequirements: [jinja2]\n", "only supported for python plugins"},
+		{"interpreter", "entrypoint: main.py\ninterpreter: python3.12\nrequirements: [jinja2]\n", "cannot be combined with an interpreter"},
+		{"invalid", "entrypoint: main.py\nrequirements: [\">=1.0\"]\n", `invalid requirement ">=1.0"`},
+		{"option", "entrypoint: main.py\nrequirements: [\"--index-url=https://evil.example\"]\n", `invalid requirement "--index-url`},
+		{"editable", "entrypoint: main.py\nrequirements: [\"-e ./src\"]\n", `invalid requirement "-e ./src"`},
+		{"url", "entrypoint: main.py\nrequirements: [\"jinja2 @ https://evil.example/jinja2.whl\"]\n", `invalid requirement "jinja2 @`},
+		{"newline", "entrypoint: main.py\nrequirements: [\"jinja2\\n--extra-index-url https://evil.example\"]\n", `invalid requirement "jinja2\n`},
+		{"marker", "entrypoint: main.py\nrequirements: [\"jinja2; python_version>'3'\"]\n", `invalid requirement "jinja2;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "plugin.yaml")
+			writeFile(t, path, "name: gen\nversion: 1.0.0\n"+tt.manifest)
+			if _, err := plugins.LoadManifest(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	path := filepath.Join(dir, "plugin.yaml")
+	writeFile(t, path, "name: gen\nversion: 1.0.0\nentrypoint: main.py\nrequirements: [\"Jinja2>=3.1\", \"pydantic[email]==2.7\", \"attrs >= 23, < 25\", \"black~=24.1.0\"]\n")
+	manifest, err := plugins.LoadManifest(path)
+	if err != nil || len(manifest.Requirements) != 4 {
+		t.Fatalf("unexpected manifest %+v, %v", manifest, err)
+	}
+}
@@ -0,0 +1,188 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+// wasmSource behaves according to the option "mode".
+const wasmSource = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	var request struct {
+		Options map[string]string ` + "`json:\"options\"`" + `
+	}
+	json.NewDecoder(os.Stdin).Decode(&request)
+
+	switch request.Options["mode"] {
+	case "loop":
+		for {
+		}
+	case "spam":
+		for {
+			fmt.Print("xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx")
+		}
+	case "fail":
+		fmt.Print(` + "`" + `{"version":1,"error":{"code":"invalid_input","message":"bad input"}}` + "`" + `)
+		os.Exit(3)
+	case "files":
+		_, err := os.ReadDir("/")
+		fmt.Printf(` + "`" + `{"version":1,"files":[{"content":%q}]}` + "`" + `, fmt.Sprint(err != nil))
+	default:
+		fmt.Printf(` + "`" + `{"version":1,"files":[{"content":"hello %s"}]}` + "`" + `, request.Options["mode"])
+	}
+}
+`
+
+var (
+	wasmOnce   sync.Once
+	wasmModule string
+	wasmErr    error
+)
+
+// buildWasm compiles wasmSource once per test run.
+func buildWasm(t *testing.T) string {
+	t.Helper()
+	wasmOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "devtoolbox-wasm")
+		if err != nil {
+			wasmErr = err
+			return
+		}
+		os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module wasmtest\n\ngo 1.21\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte(wasmSource), 0644)
+
+		cmd := exec.Command("go", "build", "-o", "plugin.wasm", ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm", "GOFLAGS=", "GOWORK=off")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			wasmErr = errors.New(string(output))
+			return
+		}
+		wasmModule = filepath.Join(dir, "plugin.wasm")
+	})
+	if wasmErr != nil {
+		t.Skipf("Skipping test - failed to build wasm module: %v", wasmErr)
+	}
+	return wasmModule
+}
+
+func wasmPlugin(t *testing.T, limits string) plugins.ExternalPlugin {
+	t.Helper()
+	module := buildWasm(t)
+	dir := t.TempDir()
+	data, err := os.ReadFile(module)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "plugin.wasm"), string(data))
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: wasm-gen
+version: 1.0.0
+language: wasm
+entrypoint: plugin.wasm
+protocol: json
+options:
+  mode: {}
+`+limits)
+
+	manifest, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := plugins.NewPluginFromManifest(manifest)
+	t.Cleanup(func() { plugin.Close() })
+	return plugin
+}
+
+func invokeMode(plugin plugins.Plugin, mode string) (*plugins.Response, error) {
+	return plugin.Invoke(context.Background(), &plugins.Request{
+		IR:      ir.NewDocument(),
+		Options: map[string]string{"mode": mode},
+	})
+}
+
+func TestWasmPlugin_Invoke(t *testing.T) {
+	plugin := wasmPlugin(t, "")
+	if _, ok := plugin.(*plugins.WasmPlugin); !ok {
+		t.Fatalf("expected *WasmPlugin, got %T", plugin)
+	}
+
+	for _, mode := range []string{"wasm", "again"} {
+		response, err := invokeMode(plugin, mode)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output := response.Output(); output != "hello "+mode {
+			t.Errorf("unexpected output %q", output)
+		}
+	}
+
+	response, err := invokeMode(plugin, "files")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Output() != "true" {
+		t.Error("expected the module to have no filesystem access")
+	}
+}
+
+func TestWasmPlugin_Errors(t *testing.T) {
+	plugin := wasmPlugin(t, "limits:\n  timeout: 1s\n  max_output: 1KB\n")
+
+	_, err := invokeMode(plugin, "fail")
+	var pluginErr *plugins.PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.Code != plugins.CodeInvalidInput || pluginErr.ExitCode != 3 {
+		t.Errorf("expected invalid_input PluginError with exit code 3, got %T: %v", err, err)
+	}
+
+	start := time.Now()
+	_, err = invokeMode(plugin, "loop")
+	var timeoutErr *plugins.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("expected TimeoutError, got %T: %v", err, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("module was not stopped in time: %s", elapsed)
+	}
+
+	_, err = invokeMode(plugin, "spam")
+	var limitErr *plugins.OutputLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf("expected OutputLimitError, got %T: %v", err, err)
+	}
+}
+
+func TestLoadManifest_WasmRestrictions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "plugin.wasm"), "")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: wasm-gen
+version: 1.0.0
+language: wasm
+entrypoint: plugin.wasm
+protocol: json
+interpreter: wasmtime
+worker:
+  enabled: true
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "interpreter is not supported") || !strings.Contains(err.Error(), "worker mode is not supported") {
+		t.Errorf("expected wasm restrictions, got %v", err)
+	}
+}
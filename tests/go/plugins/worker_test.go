@@ -0,0 +1,258 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JIIL07/devtoolbox/internal/ir"
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+)
+
+const workerScript = `import json, os, sys
+for line in sys.stdin:
+    message = json.loads(line)
+    if message["method"] == "shutdown":
+        break
+    if message["method"] == "hang":
+        continue
+    if message["method"] == "crash":
+        sys.exit(5)
+    if message["method"] == "fail":
+        reply = {"error": {"code": -32000, "message": "boom"}}
+    elif message["method"] == "generate":
+        reply = {"result": {"version": 1, "files": [{"content": str(os.getpid())}]}}
+    else:
+        reply = {"result": {"pid": os.getpid()}}
+    reply.update({"jsonrpc": "2.0", "id": message["id"]})
+    print(json.dumps(reply), flush=True)
+`
+
+// slowWorkerScript takes its time to answer pings and to shut down.
+const slowWorkerScript = `import json, os, sys, time
+for line in sys.stdin:
+    message = json.loads(line)
+    if message["method"] in ("ping", "shutdown"):
+        time.sleep(0.3)
+    if message["method"] == "shutdown":
+        break
+    print(json.dumps({"jsonrpc": "2.0", "id": message["id"], "result": {"pid": os.getpid()}}), flush=True)
+`
+
+func workerPool(t *testing.T, config plugins.WorkerConfig) *plugins.WorkerPool {
+	t.Helper()
+	return scriptWorkerPool(t, config, workerScript, nil)
+}
+
+// scriptWorkerPool runs script as the worker and records the started
+// processes in started, if it is not nil.
+func scriptWorkerPool(t *testing.T, config plugins.WorkerConfig, source string, started *[]*exec.Cmd) *plugins.WorkerPool {
+	t.Helper()
+	requirePython(t)
+	script := filepath.Join(t.TempDir(), "worker.py")
+	writeFile(t, script, source)
+
+	pool := plugins.NewWorkerPool("test", config, func() (*exec.Cmd, error) {
+		cmd := exec.Command("python3", script)
+		if started != nil {
+			*started = append(*started, cmd)
+		}
+		return cmd, nil
+	})
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func callPID(t *testing.T, pool *plugins.WorkerPool) int {
+	t.Helper()
+	result, err := pool.Call(context.Background(), "pid", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var reply struct {
+		PID int `json:"pid"`
+	}
+	if err := json.Unmarshal(result, &reply); err != nil {
+		t.Fatalf("unexpected result %s: %v", result, err)
+	}
+	return reply.PID
+}
+
+func TestWorkerPool_ReusesWorker(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1})
+
+	first := callPID(t, pool)
+	for i := 0; i < 3; i++ {
+		if pid := callPID(t, pool); pid != first {
+			t.Fatalf("expected worker %d to be reused, got %d", first, pid)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Started != 1 || stats.Idle != 1 || stats.Busy != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestWorkerPool_RestartsCrashedWorker(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1})
+
+	first := callPID(t, pool)
+
+	_, err := pool.Call(context.Background(), "crash", nil)
+	var pluginErr *plugins.PluginError
+	if !errors.As(err, &pluginErr) || pluginErr.ExitCode != 5 {
+		t.Fatalf("expected PluginError with exit code 5, got %v", err)
+	}
+
+	if pid := callPID(t, pool); pid == first {
+		t.Error("expected a new worker after the crash")
+	}
+}
+
+func TestWorkerPool_MethodError(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1})
+
+	first := callPID(t, pool)
+
+	_, err := pool.Call(context.Background(), "fail", nil)
+	var protocolErr *plugins.ProtocolError
+	if !errors.As(err, &protocolErr) {
+		t.Fatalf("expected ProtocolError, got %T: %v", err, err)
+	}
+
+	if pid := callPID(t, pool); pid != first {
+		t.Error("expected the worker to survive a method error")
+	}
+}
+
+func TestWorkerPool_MaxRequests(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1, MaxRequests: 2})
+
+	first := callPID(t, pool)
+	if pid := callPID(t, pool); pid != first {
+		t.Fatal("expected the worker to serve two requests")
+	}
+	if pid := callPID(t, pool); pid == first {
+		t.Error("expected the worker to be recycled after max_requests")
+	}
+	if stats := pool.Stats(); stats.Started != 2 {
+		t.Errorf("expected 2 started workers, got %+v", stats)
+	}
+}
+
+func TestWorkerPool_RecycledWorkerExitsFirst(t *testing.T) {
+	var started []*exec.Cmd
+	pool := scriptWorkerPool(t, plugins.WorkerConfig{PoolSize: 1, MaxRequests: 1}, slowWorkerScript, &started)
+
+	callPID(t, pool)
+	callPID(t, pool)
+	if len(started) != 2 {
+		t.Fatalf("expected the worker to be recycled, started %d", len(started))
+	}
+	// The replacement may only start once the recycled worker has exited.
+	if started[0].ProcessState == nil {
+		t.Error("expected the recycled worker to exit before its replacement started")
+	}
+}
+
+func TestWorkerPool_HealthCheckHoldsSlot(t *testing.T) {
+	var started []*exec.Cmd
+	pool := scriptWorkerPool(t, plugins.WorkerConfig{PoolSize: 1, HealthInterval: 50 * time.Millisecond}, slowWorkerScript, &started)
+
+	first := callPID(t, pool)
+	// Let the health check take the idle worker, then call while it is
+	// still waiting for the ping.
+	time.Sleep(100 * time.Millisecond)
+	if pid := callPID(t, pool); pid != first || len(started) != 1 {
+		t.Errorf("expected the checked worker to be reused, started %d workers", len(started))
+	}
+}
+
+func TestWorkerPool_IdleTimeout(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{PoolSize: 1, IdleTimeout: 100 * time.Millisecond})
+
+	callPID(t, pool)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for pool.Stats().Idle > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected idle worker to be stopped")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWorkerPool_Closed(t *testing.T) {
+	pool := workerPool(t, plugins.WorkerConfig{})
+
+	callPID(t, pool)
+	pool.Close()
+
+	if _, err := pool.Call(context.Background(), "pid", nil); err == nil {
+		t.Error("expected error from closed pool")
+	}
+}
+
+func TestPythonPlugin_WorkerMode(t *testing.T) {
+	requirePython(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), workerScript)
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: pid
+version: 1.0.0
+entrypoint: main.py
+protocol: json
+worker:
+  enabled: true
+  pool_size: 1
+`)
+
+	plugin, err := plugins.NewPythonPluginLoader(dir).LoadPlugin(filepath.Join(dir, "main.py"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer plugin.Close()
+
+	if plugin.Workers() == nil {
+		t.Fatal("expected worker pool for worker manifest")
+	}
+
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		response, err := plugin.Invoke(context.Background(), &plugins.Request{Input: "{}", IR: ir.NewDocument()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		outputs = append(outputs, response.Output())
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("expected both requests to be served by one worker, got %v", outputs)
+	}
+}
+
+func TestLoadManifest_WorkerRequiresJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.py"), "")
+	writeFile(t, filepath.Join(dir, "plugin.yaml"), `name: text-worker
+version: 1.0.0
+entrypoint: main.py
+worker:
+  enabled: true
+  idle_timeout: soon
+`)
+
+	_, err := plugins.LoadManifest(filepath.Join(dir, "plugin.yaml"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"protocol: json", "worker.idle_timeout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %v", want, err)
+		}
+	}
+}
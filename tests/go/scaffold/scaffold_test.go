@@ -0,0 +1,190 @@
+package scaffold
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/plugins"
+	"github.com/JIIL07/devtoolbox/internal/scaffold"
+)
+
+func create(t *testing.T, options scaffold.Options) *scaffold.Result {
+	t.Helper()
+	if options.Dir == "" {
+		options.Dir = filepath.Join(t.TempDir(), options.Name)
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	result, err := scaffold.New(options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func run(t *testing.T, dir string, env []string, name string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s %s failed: %v\n%s", name, strings.Join(args, " "), err, output)
+	}
+}
+
+func TestNew_Files(t *testing.T) {
+	expected := map[string][]string{
+		plugins.LanguagePython: {"README.md", "main.py", "plugin.yaml", "test_main.py", "testdata/expected.txt", "testdata/input.json", "testdata/request.json"},
+		scaffold.LanguageGo:    {".gitignore", "README.md", "go.mod", "main.go", "main_test.go", "plugin.yaml", "testdata/expected.txt", "testdata/input.json"},
+		plugins.LanguageWasm:   {".gitignore", "README.md", "go.mod", "main.go", "main_test.go", "plugin.yaml", "testdata/expected.txt", "testdata/input.json"},
+		plugins.LanguageExec:   {"README.md", "main.sh", "plugin.yaml", "test.sh", "testdata/expected.js", "testdata/input.json"},
+	}
+	for _, language := range scaffold.Languages {
+		t.Run(language, func(t *testing.T) {
+			result := create(t, scaffold.Options{Name: "my-gen", Language: language})
+			if strings.Join(result.Files, " ") != strings.Join(expected[language], " ") {
+				t.Errorf("unexpected files %v", result.Files)
+			}
+
+			manifest, err := plugins.LoadManifest(filepath.Join(result.Dir, "plugin.yaml"))
+			if err != nil {
+				t.Fatalf("invalid manifest: %v", err)
+			}
+			if manifest.Name != "my-gen" || manifest.Description == "" {
+				t.Errorf("unexpected manifest %+v", manifest)
+			}
+			if fixtures := manifest.FixturePaths(); len(fixtures) != 1 || filepath.Base(fixtures[0]) != "input.json" {
+				t.Errorf("the sample should be a fixture of plugin test, got %v", fixtures)
+			}
+			readme, _ := os.ReadFile(filepath.Join(result.Dir, "README.md"))
+			if !strings.Contains(string(readme), "devtoolbox generate my-gen testdata/input.json") {
+				t.Errorf("README does not explain how to run the plugin:\n%s", readme)
+			}
+		})
+	}
+}
+
+func TestNew_PythonTestsPass(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not available")
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	t.Setenv("PYTHONPATH", "")
+	result := create(t, scaffold.Options{Name: "my-gen", Language: plugins.LanguagePython})
+	if !strings.HasPrefix(result.Test, "PYTHONPATH=") {
+		t.Errorf("the test command should provide the SDK, got %q", result.Test)
+	}
+	run(t, result.Dir, nil, "sh", "-c", result.Test)
+}
+
+func TestNew_ExecTestsPass(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	result := create(t, scaffold.Options{Name: "my-gen", Language: plugins.LanguageExec})
+	if info, err := os.Stat(filepath.Join(result.Dir, "main.sh")); err != nil || info.Mode()&0111 == 0 {
+		t.Errorf("main.sh should be executable: %v", err)
+	}
+	run(t, result.Dir, nil, "sh", "test.sh")
+}
+
+func TestNew_GoTestsPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a Go module")
+	}
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := create(t, scaffold.Options{Name: "my-gen", Language: scaffold.LanguageGo, Module: "example.com/my-gen"})
+	gomod, _ := os.ReadFile(filepath.Join(result.Dir, "go.mod"))
+	if !strings.HasPrefix(string(gomod), "module example.com/my-gen\n") || !strings.Contains(string(gomod), "replace github.com/JIIL07/devtoolbox => "+root+"\n") {
+		t.Errorf("unexpected go.mod:\n%s", gomod)
+	}
+
+	env := []string{"GOFLAGS=-mod=mod", "GOPROXY=off"}
+	run(t, result.Dir, env, "sh", "-c", result.Test)
+}
+
+func TestNew_SDKDir(t *testing.T) {
+	checkout := filepath.Join(t.TempDir(), "dev toolbox")
+	if err := os.MkdirAll(checkout, 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(checkout, "go.mod"), []byte("module github.com/JIIL07/devtoolbox\n\ngo 1.23\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := create(t, scaffold.Options{Name: "my-gen", Language: plugins.LanguageWasm, SDKDir: checkout})
+	gomod, _ := os.ReadFile(filepath.Join(result.Dir, "go.mod"))
+	if !strings.Contains(string(gomod), "replace github.com/JIIL07/devtoolbox => \""+checkout+"\"\n") {
+		t.Errorf("unexpected go.mod:\n%s", gomod)
+	}
+
+	_, err := scaffold.New(scaffold.Options{Name: "my-gen", Language: scaffold.LanguageGo, Dir: filepath.Join(t.TempDir(), "my-gen"), SDKDir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "not a DevToolBox checkout") {
+		t.Errorf("expected an error for a directory without the SDK, got %v", err)
+	}
+}
+
+func TestNew_InsideDevToolBoxModule(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module github.com/JIIL07/devtoolbox\n\ngo 1.23\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := create(t, scaffold.Options{Name: "my-gen", Language: plugins.LanguageWasm, Dir: filepath.Join(root, "plugins", "my_gen")})
+	if _, err := os.Stat(filepath.Join(result.Dir, "go.mod")); !os.IsNotExist(err) {
+		t.Errorf("plugins in the DevToolBox tree should not get a go.mod: %v", err)
+	}
+	if strings.Contains(result.Test, "go mod") {
+		t.Errorf("unexpected test command %q", result.Test)
+	}
+}
+
+func TestNew_Sample(t *testing.T) {
+	result := create(t, scaffold.Options{
+		Name:       "my-gen",
+		Language:   plugins.LanguagePython,
+		Sample:     "user:\n  id: 1\n  roles: [admin]\n",
+		SampleName: "/somewhere/user.yaml",
+	})
+	expected, err := os.ReadFile(filepath.Join(result.Dir, "testdata", "expected.txt"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range []string{"# Data Model\n", "  id: integer\n", "  roles: []string\n"} {
+		if !strings.Contains(string(expected), line) {
+			t.Errorf("expected %q in the golden file:\n%s", line, expected)
+		}
+	}
+	test, _ := os.ReadFile(filepath.Join(result.Dir, "test_main.py"))
+	if !strings.Contains(string(test), "testdata/user.yaml") {
+		t.Error("the tests should mention the sample file")
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	full := t.TempDir()
+	if err := os.WriteFile(filepath.Join(full, "main.py"), nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, options := range map[string]scaffold.Options{
+		"name":      {Name: "My Gen", Language: plugins.LanguagePython},
+		"language":  {Name: "my-gen", Language: "ruby"},
+		"not empty": {Name: "my-gen", Language: plugins.LanguagePython, Dir: full},
+		"sample":    {Name: "my-gen", Language: scaffold.LanguageGo, Sample: "{broken", SampleName: "broken.json"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if options.Dir == "" {
+				options.Dir = filepath.Join(t.TempDir(), "my-gen")
+			}
+			if _, err := scaffold.New(options); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
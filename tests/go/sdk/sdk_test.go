@@ -0,0 +1,210 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JIIL07/devtoolbox/internal/core"
+	"github.com/JIIL07/devtoolbox/internal/version"
+	"github.com/JIIL07/devtoolbox/pkg/sdk"
+	"github.com/JIIL07/devtoolbox/pkg/sdk/python"
+	"github.com/JIIL07/devtoolbox/pkg/sdk/sdktest"
+)
+
+var echo = sdk.HandlerFunc(func(ctx context.Context, request *sdk.Request) (*sdk.Response, error) {
+	switch request.Options["mode"] {
+	case "invalid":
+		return nil, sdk.Errorf(sdk.CodeInvalidInput, "bad %s", "input")
+	case "fail":
+		return nil, errors.New("boom")
+	case "panic":
+		panic("oops")
+	}
+	return sdk.Files(sdk.File{Content: "hello " + request.Input}), nil
+})
+
+func serveOnce(t *testing.T, request string) *sdk.Response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := sdk.ServeIO(context.Background(), echo, false, strings.NewReader(request), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var response sdk.Response
+	if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %q: %v", out.String(), err)
+	}
+	return &response
+}
+
+func TestServeIO_Once(t *testing.T) {
+	response := serveOnce(t, `{"version":1,"input":"world"}`)
+	if response.Error != nil || response.Output() != "hello world" || response.Version != sdk.ProtocolVersion {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestServeIO_Errors(t *testing.T) {
+	tests := []struct {
+		request string
+		code    sdk.ErrorCode
+		message string
+	}{
+		{`{"version":1,"options":{"mode":"invalid"}}`, sdk.CodeInvalidInput, "bad input"},
+		{`{"version":1,"options":{"mode":"fail"}}`, sdk.CodeInternal, "boom"},
+		{`{"version":1,"options":{"mode":"panic"}}`, sdk.CodeInternal, "panic: oops"},
+		{`{"version":2}`, sdk.CodeUnsupported, "unsupported protocol version 2"},
+		{`not json`, sdk.CodeInvalidInput, "invalid request"},
+	}
+
+	for _, tt := range tests {
+		response := serveOnce(t, tt.request)
+		if response.Error == nil || response.Error.Code != tt.code || !strings.Contains(response.Error.Message, tt.message) {
+			t.Errorf("%s: expected %s error %q, got %+v", tt.request, tt.code, tt.message, response.Error)
+		}
+	}
+}
+
+func TestServeIO_Worker(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"generate","params":{"version":1,"input":"one"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"cancel"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"explode"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"generate","params":{"version":1,"options":{"mode":"invalid"}}}`,
+		`{"jsonrpc":"2.0","method":"shutdown"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"ping"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := sdk.ServeIO(context.Background(), echo, true, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 replies, got %d:\n%s", len(lines), out.String())
+	}
+
+	type reply struct {
+		ID     int64           `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	replies := make([]reply, len(lines))
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &replies[i]); err != nil {
+			t.Fatalf("invalid reply %q: %v", line, err)
+		}
+		if replies[i].ID != int64(i+1) {
+			t.Errorf("expected reply %d, got id %d", i+1, replies[i].ID)
+		}
+	}
+
+	var response sdk.Response
+	json.Unmarshal(replies[0].Result, &response)
+	if response.Output() != "hello one" {
+		t.Errorf("unexpected generate result %s", replies[0].Result)
+	}
+	if string(replies[1].Result) != `{"status":"ok"}` {
+		t.Errorf("unexpected ping result %s", replies[1].Result)
+	}
+	if replies[2].Error == nil || replies[2].Error.Code != -32601 {
+		t.Errorf("expected method not found, got %s", lines[2])
+	}
+	response = sdk.Response{}
+	json.Unmarshal(replies[3].Result, &response)
+	if response.Error == nil || response.Error.Code != sdk.CodeInvalidInput {
+		t.Errorf("expected invalid_input error in result, got %s", replies[3].Result)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	options := sdk.Options{"name": "x", "flag": "true", "count": "3", "bad": "maybe"}
+
+	if options.String("name", "y") != "x" || options.String("missing", "y") != "y" {
+		t.Error("unexpected String result")
+	}
+	if value, err := options.Bool("flag", false); err != nil || !value {
+		t.Errorf("expected true, got %v, %v", value, err)
+	}
+	if value, err := options.Int("count", 1); err != nil || value != 3 {
+		t.Errorf("expected 3, got %v, %v", value, err)
+	}
+	if value, err := options.Int("missing", 7); err != nil || value != 7 {
+		t.Errorf("expected fallback 7, got %v, %v", value, err)
+	}
+
+	_, err := options.Bool("bad", false)
+	var pluginErr *sdk.Error
+	if !errors.As(err, &pluginErr) || pluginErr.Code != sdk.CodeInvalidOptions {
+		t.Errorf("expected invalid_options error, got %v", err)
+	}
+}
+
+func TestFromGenerator(t *testing.T) {
+	generator := core.NewGoStructGenerator()
+	handler := sdk.FromGenerator(generator)
+	response := sdktest.Run(t, handler, `{"id": 1, "name": "Ada"}`, nil)
+
+	request, err := sdktest.NewRequest("go-struct", `{"id": 1, "name": "Ada"}`, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, err := generator.GenerateDocument(request.IR)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.Output() != expected {
+		t.Errorf("expected the built-in output, got:\n%s", response.Output())
+	}
+
+	response = sdktest.Run(t, handler, `{"id": 1}`, map[string]string{"package": "api"})
+	if !strings.HasPrefix(response.Output(), "package api\n") {
+		t.Errorf("expected options to configure the generator, got:\n%s", response.Output())
+	}
+
+	request.Options = map[string]string{"unknown": "x"}
+	_, err = handler.Handle(context.Background(), request)
+	var pluginErr *sdk.Error
+	if !errors.As(err, &pluginErr) || pluginErr.Code != sdk.CodeInvalidOptions {
+		t.Errorf("expected invalid_options error, got %v", err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "out.txt")
+
+	t.Setenv(sdktest.UpdateEnv, "1")
+	sdktest.Golden(t, path, "content\n")
+
+	t.Setenv(sdktest.UpdateEnv, "")
+	sdktest.Golden(t, path, "content\n")
+}
+
+func TestPythonSDKVersion(t *testing.T) {
+	init, err := fs.ReadFile(python.FS, python.Package+"/__init__.py")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match := regexp.MustCompile(`__version__ = "([^"]+)"`).FindSubmatch(init); match == nil || string(match[1]) != version.Version {
+		t.Errorf("python SDK version does not match DevToolBox %s", version.Version)
+	}
+
+	protocol, err := fs.ReadFile(python.FS, python.Package+"/protocol.py")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match := regexp.MustCompile(`PROTOCOL_VERSION = (\d+)`).FindSubmatch(protocol); match == nil || string(match[1]) != strconv.Itoa(sdk.ProtocolVersion) {
+		t.Errorf("python SDK protocol version does not match %d", sdk.ProtocolVersion)
+	}
+}
@@ -0,0 +1,151 @@
+import io
+import json
+import os
+import sys
+
+import pytest
+
+sys.path.append(os.path.join(os.path.dirname(__file__), '../../pkg/sdk/python'))
+
+from devtoolbox_sdk import (
+    CODE_INTERNAL,
+    CODE_INVALID_OPTIONS,
+    CODE_UNSUPPORTED,
+    KIND_ARRAY,
+    KIND_OBJECT,
+    KIND_REF,
+    KIND_STRING,
+    Document,
+    Field,
+    File,
+    Model,
+    PluginError,
+    Response,
+    Type,
+    handle,
+    serve,
+    to_camel_case,
+    to_pascal_case,
+    to_snake_case,
+)
+from devtoolbox_sdk.testing import UPDATE_ENV, golden, invoke, make_request, run_plugin
+
+PLUGIN = os.path.join(os.path.dirname(__file__), '../../plugins/custom/kotlin_data/main.py')
+
+
+def user_model():
+    return Model("User", Type(KIND_OBJECT, fields=[
+        Field("user_id", Type("integer"), required=True),
+        Field("tags", Type(KIND_ARRAY, elem=Type(KIND_STRING))),
+        Field("address", Type(KIND_REF, ref="Address", nullable=True)),
+    ]))
+
+
+def test_naming():
+    assert to_pascal_case("user_id") == "UserId"
+    assert to_pascal_case("userID") == "UserID"
+    assert to_camel_case("user-name") == "userName"
+    assert to_snake_case("UserName") == "user_name"
+    assert to_camel_case("") == ""
+
+
+def test_ir_round_trip():
+    doc = Document([user_model()], format="json")
+    data = json.loads(json.dumps(doc.to_dict()))
+
+    assert data["models"][0]["type"]["fields"][1] == {
+        "name": "tags",
+        "type": {"kind": "array", "elem": {"kind": "string"}},
+    }
+    assert Document.from_dict(data) == doc
+    assert doc.model("User").type.refs() == ["Address"]
+    assert doc.names() == ["User"]
+
+
+def test_handle_results():
+    request = make_request([user_model()]).to_dict()
+
+    assert handle(lambda r: "code", request)["files"] == [{"content": "code"}]
+    assert handle(lambda r: [File("a", "a.kt"), File("b")], request)["files"] == [
+        {"name": "a.kt", "content": "a"},
+        {"content": "b"},
+    ]
+
+    def with_warning(r):
+        response = Response([File("x")])
+        response.warn("careful", "User.tags")
+        return response
+
+    assert handle(with_warning, request)["diagnostics"] == [
+        {"severity": "warning", "message": "careful", "location": {"path": "User.tags"}},
+    ]
+
+
+def test_handle_errors():
+    request = make_request([user_model()], options={"count": "many"}).to_dict()
+
+    reply = handle(lambda r: r.int_option("count"), request)
+    assert reply["error"]["code"] == CODE_INVALID_OPTIONS
+
+    def fail(r):
+        raise ValueError("boom")
+
+    reply = handle(fail, request)
+    assert reply["error"]["code"] == CODE_INTERNAL
+    assert reply["error"]["message"].startswith("boom")
+
+    reply = handle(lambda r: "x", {"version": 2})
+    assert reply["error"]["code"] == CODE_UNSUPPORTED
+
+
+def test_serve_once_and_worker():
+    request = make_request([user_model()])
+    out = io.StringIO()
+    serve(lambda r: ",".join(r.ir.names()), argv=[], stdin=io.StringIO(json.dumps(request.to_dict())), stdout=out)
+    assert json.loads(out.getvalue()) == {"version": 1, "files": [{"content": "User"}]}
+
+    lines = [
+        {"jsonrpc": "2.0", "id": 1, "method": "generate", "params": request.to_dict()},
+        {"jsonrpc": "2.0", "id": 2, "method": "ping"},
+        {"jsonrpc": "2.0", "id": 3, "method": "explode"},
+        {"jsonrpc": "2.0", "method": "shutdown"},
+        {"jsonrpc": "2.0", "id": 4, "method": "ping"},
+    ]
+    out = io.StringIO()
+    serve(lambda r: "ok", argv=["--worker"], stdin=io.StringIO("\n".join(json.dumps(m) for m in lines)), stdout=out)
+    replies = [json.loads(line) for line in out.getvalue().splitlines()]
+
+    assert [r["id"] for r in replies] == [1, 2, 3]
+    assert replies[0]["result"]["files"] == [{"content": "ok"}]
+    assert replies[1]["result"] == {"status": "ok"}
+    assert replies[2]["error"]["code"] == -32601
+
+
+def test_invoke_raises_plugin_errors():
+    def fail(r):
+        raise PluginError(CODE_INVALID_OPTIONS, "bad")
+
+    with pytest.raises(PluginError) as e:
+        invoke(fail, make_request())
+    assert e.value.code == CODE_INVALID_OPTIONS
+
+
+@pytest.mark.parametrize("worker", [False, True])
+def test_run_plugin(worker):
+    response = run_plugin(PLUGIN, make_request([user_model()], options={"package": "app"}), worker=worker)
+
+    assert response.output().startswith("package app\n\ndata class User(")
+    assert "val userId: Long," in response.output()
+    assert "val address: Address? = null," in response.output()
+
+
+def test_golden(tmp_path, monkeypatch):
+    path = str(tmp_path / "testdata" / "out.kt")
+
+    monkeypatch.setenv(UPDATE_ENV, "1")
+    golden(path, "content\n")
+
+    monkeypatch.delenv(UPDATE_ENV)
+    golden(path, "content\n")
+    with pytest.raises(AssertionError):
+        golden(path, "other\n")